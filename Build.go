@@ -39,6 +39,14 @@ func LaunchCommand(prefix string) error {
 		compile.InitCompile()
 		cmd.InitCmd()
 
+		// custom environments are validated against platforms and configurations registered above
+		if err := compile.LoadEnvironmentModels(compile.GetEnvironmentModelsFile()); err != nil {
+			return err
+		}
+		if err := compile.LoadModuleAliases(compile.GetModuleAliasesFile()); err != nil {
+			return err
+		}
+
 		return env.Run()
 	})
 	return err
@@ -2,6 +2,7 @@ package action
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -88,6 +89,7 @@ type ActionRules struct {
 	OutputFiles   utils.FileSet // all output files that should be tracked
 	Prerequisites ActionAliases // actions to run dynamically only if cache missed (PCH)
 	ExportIndex   int32         // index of export file in outputs files
+	ExcludedDirs  utils.DirSet  // input files in those directories are not tracked (system headers for instance)
 
 	Options OptionFlags
 }
@@ -102,6 +104,16 @@ func (x *ActionRules) GetActionAlias() ActionAlias {
 }
 func (x *ActionRules) GetGeneratedFile() utils.Filename { return x.OutputFiles[x.ExportIndex] }
 
+// IsExcludedDependency returns true when given file should not be tracked as a dynamic dependency of this action
+func (x *ActionRules) IsExcludedDependency(file utils.Filename) bool {
+	for _, dir := range x.ExcludedDirs {
+		if file.IsIn(dir) {
+			return true
+		}
+	}
+	return false
+}
+
 func (x *ActionRules) AppendDependentActions(bg utils.BuildGraphReadPort, result *ActionSet) error {
 	buildNode, err := bg.Expect(x.Alias())
 	if err != nil {
@@ -143,6 +155,7 @@ func (x *ActionRules) Serialize(ar base.Archive) {
 	ar.Serializable(&x.OutputFiles)
 	base.SerializeSlice(ar, x.Prerequisites.Ref())
 	ar.Int32(&x.ExportIndex)
+	ar.Serializable(&x.ExcludedDirs)
 	ar.Serializable(&x.Options)
 
 }
@@ -191,6 +204,15 @@ func (x *ActionRules) BuildWithSourceDependencies(bc utils.BuildContext, sourceD
 		}
 	}
 
+	// written before running the process, to inspect command-line of failed actions
+	var responseFile utils.Filename
+	if flags.WriteResponseFiles.Get() {
+		var err error
+		if responseFile, err = x.writeResponseFileArtifact(); err != nil {
+			return err
+		}
+	}
+
 	// run process if the cache missed
 	if !wasRetrievedFromCache {
 		// need prerequisites before building if cache missed
@@ -239,6 +261,11 @@ func (x *ActionRules) BuildWithSourceDependencies(bc utils.BuildContext, sourceD
 	if err := bc.OutputFile(x.OutputFiles...); err != nil {
 		return err
 	}
+	if responseFile.Valid() {
+		if err := bc.OutputFile(responseFile); err != nil {
+			return err
+		}
+	}
 
 	// check if source dependencies need to be parsed
 	if !base.IsNil(sourceDependencies) {
@@ -246,6 +273,11 @@ func (x *ActionRules) BuildWithSourceDependencies(bc utils.BuildContext, sourceD
 		if err == nil {
 			sourceInputFiles.Remove(staticInputFiles...)
 			sourceInputFiles.Remove(excludedInputFiles...)
+			if len(x.ExcludedDirs) > 0 {
+				sourceInputFiles = base.RemoveUnless(func(file utils.Filename) bool {
+					return !x.IsExcludedDependency(file)
+				}, sourceInputFiles...)
+			}
 
 			if flags := GetActionFlags(); flags.ShowFiles.Get() {
 				for _, file := range sourceInputFiles {
@@ -266,16 +298,28 @@ func (x *ActionRules) BuildWithSourceDependencies(bc utils.BuildContext, sourceD
 }
 
 func harvestActionInputFiles(bc utils.BuildContext, br utils.BuildResult, results, excludeds *utils.FileSet) error {
+	// generated files are tracked by the action using them as inputs
 	switch buildable := br.Buildable.(type) {
 	case Action:
-		rules := buildable.GetAction()
-
-		if err := bc.NeedFiles(rules.GetGeneratedFile()); err != nil {
+		if err := bc.NeedFiles(buildable.GetAction().GetGeneratedFile()); err != nil {
 			return err
 		}
+	case utils.BuildableGeneratedFile:
+		if err := bc.NeedFiles(buildable.GetGeneratedFile()); err != nil {
+			return err
+		}
+	}
+	return appendActionInputFiles(bc, br.BuildAlias, br.Buildable, results, excludeds)
+}
+
+// appendActionInputFiles is shared by action execution and GetCacheKeyInputs(), so both compute the same cache key
+func appendActionInputFiles(bg utils.BuildGraphReadPort, alias utils.BuildAlias, buildable utils.Buildable, results, excludeds *utils.FileSet) error {
+	switch buildable := buildable.(type) {
+	case Action:
+		rules := buildable.GetAction()
 
 		if rules.Options.Has(OPT_PROPAGATE_INPUTS) {
-			inputs, err := bc.GetDependencyInputFiles(false, br.BuildAlias)
+			inputs, err := bg.GetDependencyInputFiles(false, alias)
 			if err != nil {
 				return err
 			}
@@ -286,12 +330,7 @@ func harvestActionInputFiles(bc utils.BuildContext, br utils.BuildResult, result
 		}
 
 	case utils.BuildableGeneratedFile:
-		file := buildable.GetGeneratedFile()
-		if err := bc.NeedFiles(file); err != nil {
-			return err
-		}
-
-		results.Append(file)
+		results.Append(buildable.GetGeneratedFile())
 
 	case utils.BuildableSourceFile:
 		results.Append(buildable.GetSourceFile())
@@ -325,6 +364,65 @@ func asyncCacheWriteAction(bg utils.BuildGraphWritePort, cacheKey ActionCacheKey
 	return nil
 }
 
+// GetCacheKeyInputs recomputes inputs of the cache key of an action found in build graph, like they are computed when
+// the action is built: static input files are collected from its static dependencies, which should be up-to-date
+func (x *ActionRules) GetCacheKeyInputs(bg utils.BuildGraphWritePort) (ActionCacheKeyInputs, error) {
+	node, err := bg.Expect(x.Alias())
+	if err != nil {
+		return ActionCacheKeyInputs{}, err
+	}
+
+	var staticInputFiles, excludedInputFiles utils.FileSet
+	for _, alias := range node.GetStaticDependencies() {
+		dependency, err := bg.Expect(alias)
+		if err != nil {
+			return ActionCacheKeyInputs{}, err
+		}
+		if err := appendActionInputFiles(bg, alias, dependency.GetBuildable(), &staticInputFiles, &excludedInputFiles); err != nil {
+			return ActionCacheKeyInputs{}, err
+		}
+	}
+
+	var cacheArtifact CacheArtifact
+	cacheArtifact.Command = x.CommandRules
+	cacheArtifact.InputFiles = staticInputFiles
+	cacheArtifact.InputFiles.Sort()
+	cacheArtifact.OutputFiles = utils.NewFileSet(x.OutputFiles...) // don't sort the action in place
+	cacheArtifact.OutputFiles.Sort()
+
+	return GetActionCache().CacheKeyInputs(bg, &cacheArtifact)
+}
+
+// GetResponseFileArtifact returns where response file of this action is written with -WriteResponseFiles: next
+// to object files in intermediate directory, or mirroring path of other outputs (like executables) under it
+func (x *ActionRules) GetResponseFileArtifact() (utils.Filename, bool) {
+	if len(x.OutputFiles) == 0 {
+		return utils.Filename{}, false
+	}
+
+	output := x.OutputFiles[0]
+	switch {
+	case output.IsIn(utils.UFS.Intermediate):
+		return output.Dirname.File(output.Basename + ".rsp"), true
+	case output.IsIn(utils.UFS.Output):
+		return utils.UFS.Intermediate.AbsoluteFile(output.Relative(utils.UFS.Output) + ".rsp"), true
+	default:
+		return utils.UFS.Intermediate.File(output.Basename + ".rsp"), true
+	}
+}
+
+func (x *ActionRules) writeResponseFileArtifact() (utils.Filename, error) {
+	responseFile, ok := x.GetResponseFileArtifact()
+	if !ok {
+		return utils.Filename{}, nil
+	}
+
+	base.LogVeryVerbose(LogAction, "%v: write response file %q", x.Alias(), responseFile)
+	return responseFile, utils.UFS.CreateBuffered(responseFile, func(w io.Writer) error {
+		return internal_io.WriteResponseFile(w, utils.LongPathArguments(x.Arguments))
+	}, base.TransientPage4KiB)
+}
+
 func createActionCacheArtifact(bg utils.BuildGraphWritePort, command *CommandRules, inputFiles, outputFiles utils.FileSet) (CacheArtifact, ActionCacheKey, error) {
 	var cacheArtifact CacheArtifact
 	cacheArtifact.Command = *command
@@ -353,19 +451,63 @@ func executeOrDistributeAction(bc utils.BuildContext, action *ActionRules, flags
 		staticFiles[it] = true
 	}
 
+	useResponseFile := action.Options.Has(OPT_ALLOW_RESPONSEFILE) && flags.ResponseFile.Get()
+
+	// output of concurrent actions would interleave in the log: buffer it, then flush it in one block when action completes
+	var syncOutput strings.Builder
+	if flags.OutputSync.Get() {
+		defer func() {
+			if syncOutput.Len() > 0 {
+				base.LogForward(fmt.Sprintf("%v:\n%s", action.Alias(), syncOutput.String()))
+			}
+		}()
+	}
+	if flags.TraceCommands.Valid() {
+		getActionTraceScript().Record(action, useResponseFile)
+	}
+
+	// diagnostics are also reported by successful actions, so output must always be captured to parse them:
+	// unless shown or synchronized, this output is then only forwarded to the log when action failed
+	parseDiagnostics := flags.DiagSummary.Get()
+	var quietOutput strings.Builder
+
 	// run the external process with action command-line and file access hooking
 	processOptions.Init(
 		// internal_io.OptionProcessNewProcessGroup, // do not catch parent's signals
 		internal_io.OptionProcessEnvironment(action.Environment),
+		internal_io.OptionProcessSanitizeEnvironmentIf(flags.SanitizeEnv.Get()),
 		internal_io.OptionProcessWorkingDir(action.WorkingDir),
-		internal_io.OptionProcessCaptureOutputIf(flags.ShowOutput.Get()),
-		internal_io.OptionProcessUseResponseFileIf(action.Options.Has(OPT_ALLOW_RESPONSEFILE) && flags.ResponseFile.Get()),
+		internal_io.OptionProcessCaptureOutputIf(flags.ShowOutput.Get() || parseDiagnostics),
+		internal_io.OptionProcessUseResponseFileIf(useResponseFile),
+		internal_io.OptionProcessOutputIf(flags.OutputSync.Get() || parseDiagnostics, func(output string) error {
+			if parseDiagnostics {
+				getActionDiagnosticsSummary().Parse(output)
+			}
+
+			var buffer *strings.Builder
+			switch {
+			case flags.OutputSync.Get():
+				buffer = &syncOutput
+			case flags.ShowOutput.Get():
+				base.LogForwardln(output)
+				return nil
+			default:
+				buffer = &quietOutput
+			}
+
+			buffer.WriteString(output)
+			if !strings.HasSuffix(output, "\n") {
+				buffer.WriteRune('\n')
+			}
+			return nil
+		}),
 		internal_io.OptionProcessFileAccess(func(far internal_io.FileAccessRecord) error {
 			ignoreFile := true
 
 			// only file access read/execute: output files could mess with writable mapped system Dll on Windows :'(
 			if far.Access.HasRead() && !(far.Access.HasWrite() || far.Access.HasExecute()) {
 				_, ignoreFile = staticFiles[far.Path]
+				ignoreFile = ignoreFile || action.IsExcludedDependency(far.Path)
 			}
 
 			if !ignoreFile {
@@ -391,6 +533,9 @@ func executeOrDistributeAction(bc utils.BuildContext, action *ActionRules, flags
 			if wasDistributed = (peer != nil); wasDistributed {
 				bc.Annotate(utils.AnnocateBuildComment(peer.GetAddress()))
 				if err != nil {
+					if quietOutput.Len() > 0 {
+						base.LogForward(quietOutput.String())
+					}
 					return readFiles, err
 				}
 			}
@@ -401,8 +546,9 @@ func executeOrDistributeAction(bc utils.BuildContext, action *ActionRules, flags
 	if !wasDistributed {
 		// task priority can be set above normal while generating unit's actions,
 		// it can help reduce overall build latency when many tasks are dependent from this one.
+		// it can also be given when launching the build, for actions of high priority modules.
 		priority := base.TASKPRIORITY_NORMAL
-		if action.Options.Has(OPT_HIGH_PRIORITY) {
+		if action.Options.Has(OPT_HIGH_PRIORITY) || bc.GetBuildOptions().HighPriority {
 			priority = base.TASKPRIORITY_HIGH
 		}
 
@@ -425,10 +571,18 @@ func executeOrDistributeAction(bc utils.BuildContext, action *ActionRules, flags
 
 			// check if we should log executed command-line
 			if flags.ShowCmds.Get() {
-				base.LogForwardln("\"", action.Executable.String(), "\" \"", strings.Join(action.Arguments, "\" \""), "\"")
+				base.LogForwardln(formatCommandLine(action.Executable, action.Arguments, flags.MaxCmdLength.Get()))
+			} else {
+				base.LogTrace(LogAction, "%v: run %v", action.Alias(), base.MakeStringer(func() string {
+					return formatCommandLine(action.Executable, action.Arguments, flags.MaxCmdLength.Get())
+				}))
 			}
 
-			return 0, internal_io.RunProcess(action.Executable, action.Arguments, internal_io.OptionProcessStruct(&processOptions))
+			err := internal_io.RunProcess(action.Executable, action.Arguments, internal_io.OptionProcessStruct(&processOptions))
+			if err != nil && quietOutput.Len() > 0 {
+				base.LogForward(quietOutput.String())
+			}
+			return 0, err
 		}, priority, base.ThreadPoolDebugId{Category: "ExecuteAction", Arg: action.Alias()})
 
 		if err := future.Join().Failure(); err != nil {
@@ -439,6 +593,27 @@ func executeOrDistributeAction(bc utils.BuildContext, action *ActionRules, flags
 	return readFiles, bc.NeedFiles(readFiles...)
 }
 
+// formatCommandLine quotes executable and arguments for logging: when longer than maxLength, only the executable,
+// the number of arguments and the first/last arguments are shown, unless very-verbose logging is enabled.
+func formatCommandLine(executable utils.Filename, arguments base.StringSet, maxLength int) string {
+	commandLine := fmt.Sprintf("\"%v\" \"%s\"", executable, strings.Join(arguments, "\" \""))
+	if maxLength <= 0 || len(commandLine) <= maxLength || base.IsLogLevelActive(base.LOG_VERYVERBOSE) {
+		return commandLine
+	}
+
+	const numArgsShown = 3
+	if len(arguments) <= 2*numArgsShown {
+		return fmt.Sprintf("%s... (truncated %d/%d chars)", commandLine[:maxLength], maxLength, len(commandLine))
+	}
+
+	return fmt.Sprintf("\"%v\" \"%s\" ... (%d more) ... \"%s\" (%d arguments, use -V to see full command-line)",
+		executable,
+		strings.Join(arguments[:numArgsShown], "\" \""),
+		len(arguments)-2*numArgsShown,
+		strings.Join(arguments[len(arguments)-numArgsShown:], "\" \""),
+		len(arguments))
+}
+
 /***************************************
  * Action Set
  ***************************************/
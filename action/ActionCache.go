@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 
@@ -37,20 +38,24 @@ type ActionCache interface {
 	GetBulkExtname() string
 
 	CacheKey(bg BuildGraphWritePort, artifact *CacheArtifact) (ActionCacheKey, error)
+	CacheKeyInputs(bg BuildGraphWritePort, artifact *CacheArtifact) (ActionCacheKeyInputs, error)
 	CacheRead(bg BuildGraphWritePort, key ActionCacheKey, artifact *CacheArtifact) error
 	CacheWrite(bg BuildGraphWritePort, key ActionCacheKey, artifact *CacheArtifact) error
 }
 
 type actionCache struct {
-	path  Directory
-	seed  base.Fingerprint
-	stats ActionCacheStats
+	path        Directory
+	seed        base.Fingerprint
+	sanitizeEnv bool
+	stats       ActionCacheStats
 }
 
 var getActionCache = base.Memoize(func() *actionCache {
 	result := &actionCache{
-		path: GetActionFlags().CachePath,
-		seed: base.StringFingerprint("ActionCache-1.0.0"),
+		// keys computed with different algorithms must never collide, so each algorithm has its own cache folder
+		path:        GetActionFlags().CachePath.Folder(GetBuildFingerprintAlgorithm().String()),
+		seed:        base.StringFingerprint("ActionCache-1.1.0"),
+		sanitizeEnv: GetActionFlags().SanitizeEnv.Get(),
 	}
 	// create cache folder IFN
 	if err := UFS.MkdirEx(result.path); err != nil {
@@ -85,30 +90,173 @@ func (x *actionCache) GetCachePath() Directory {
 }
 
 func (x *actionCache) CacheKey(bg BuildGraphWritePort, artitfact *CacheArtifact) (ActionCacheKey, error) {
+	inputs, err := x.CacheKeyInputs(bg, artitfact)
+	return inputs.Key, err
+}
+func (x *actionCache) CacheKeyInputs(bg BuildGraphWritePort, artitfact *CacheArtifact) (inputs ActionCacheKeyInputs, err error) {
 	digests := internal_io.PrepareFileDigests(
 		bg, len(artitfact.InputFiles),
 		func(i int) Filename { return artitfact.InputFiles[i] })
 
-	fingerprint, err := base.SerializeAnyFingerprint(func(ar base.Archive) error {
-		// serialize all command properties
-		ar.Serializable(&artitfact.Command)
-		// serialize input and output fileset (*NOT* dependencies here)
-		ar.Serializable(&artitfact.InputFiles)
-		ar.Serializable(&artitfact.OutputFiles)
-		// serialize all input files content
-		for _, it := range digests {
-			if fd, err := it.Join().Get(); err == nil {
-				base.Assert(fd.Digest.Valid)
-				ar.Serializable(fd)
-			} else {
-				return err
-			}
+	normalizer := makeCacheKeyNormalizer()
+	inputs = ActionCacheKeyInputs{
+		Seed: x.seed,
+		// all command properties, from logical command line
+		Arguments:   normalizer.NormalizeStrings(normalizer.ExpandArguments(artitfact.Command.WorkingDir.String(), artitfact.Command.Arguments)...),
+		Environment: normalizer.NormalizeEnvironment(artitfact.Command.Environment),
+		SanitizeEnv: x.sanitizeEnv,
+		Executable:  normalizer.NormalizePath(artitfact.Command.Executable.String()),
+		WorkingDir:  normalizer.NormalizePath(artitfact.Command.WorkingDir.String()),
+		// input and output fileset (*NOT* dependencies here)
+		InputFiles:  normalizer.NormalizeFiles(artitfact.InputFiles),
+		OutputFiles: normalizer.NormalizeFiles(artitfact.OutputFiles),
+		Digests:     make([]base.Fingerprint, len(digests)),
+	}
+
+	// all input files content, sources were already serialized above
+	for i, it := range digests {
+		if fd, err := it.Join().Get(); err == nil {
+			base.Assert(fd.Digest.Valid)
+			inputs.Digests[i] = fd.Digest
+		} else {
+			return inputs, err
 		}
+	}
 
+	var fingerprint base.Fingerprint
+	fingerprint, err = base.SerializeAnyFingerprintWith(GetBuildFingerprintAlgorithm(), func(ar base.Archive) error {
+		inputs.Serialize(ar)
 		return nil
 	}, x.seed)
 
-	return ActionCacheKey(fingerprint), err
+	inputs.Key = ActionCacheKey(fingerprint)
+	return
+}
+
+/***************************************
+ * Cache Key Inputs
+ ***************************************/
+
+// ActionCacheKeyInputs holds everything hashed to compute an action cache key, already normalized:
+// diffing them between two machines explains why an action missed the cache.
+type ActionCacheKeyInputs struct {
+	Seed        base.Fingerprint
+	Arguments   []string
+	Environment internal_io.ProcessEnvironment
+	SanitizeEnv bool // host environment is not inherited, see -SanitizeEnv
+	Executable  string
+	WorkingDir  string
+	InputFiles  []string
+	OutputFiles []string
+	Digests     []base.Fingerprint // content of input files, in the same order
+	Key         ActionCacheKey
+}
+
+// Serialize writes the exact stream hashed by the cache key, thus Seed and Key are omitted
+func (x *ActionCacheKeyInputs) Serialize(ar base.Archive) {
+	serializeCacheKeyStrings(ar, x.Arguments...)
+	ar.Serializable(&x.Environment)
+	ar.Bool(&x.SanitizeEnv)
+	serializeCacheKeyStrings(ar, x.Executable, x.WorkingDir)
+	serializeCacheKeyStrings(ar, x.InputFiles...)
+	serializeCacheKeyStrings(ar, x.OutputFiles...)
+	for i := range x.Digests {
+		ar.Serializable(&x.Digests[i])
+	}
+}
+
+func serializeCacheKeyStrings(ar base.Archive, values ...string) {
+	n := int32(len(values))
+	ar.Int32(&n)
+	for _, it := range values {
+		ar.String(&it)
+	}
+}
+
+/***************************************
+ * Cache Key Normalizer
+ ***************************************/
+
+// Cache keys are computed from the logical command-line, so physical differences like response files
+// or the location of root directory don't defeat caching between different machines.
+
+type cacheKeyNormalizer struct {
+	rootPrefixes []string
+}
+
+func makeCacheKeyNormalizer() cacheKeyNormalizer {
+	root := UFS.Root.String()
+	rootPrefixes := base.NewStringSet(root + string(filepath.Separator))
+	rootPrefixes.AppendUniq(filepath.ToSlash(root) + "/")
+	return cacheKeyNormalizer{rootPrefixes: rootPrefixes}
+}
+
+func (x cacheKeyNormalizer) NormalizePath(arg string) string {
+	for _, prefix := range x.rootPrefixes {
+		arg = strings.ReplaceAll(arg, prefix, "")
+	}
+	return arg
+}
+func (x cacheKeyNormalizer) ExpandArguments(workingDir string, args []string) (result []string) {
+	result = make([]string, 0, len(args))
+	for _, arg := range args {
+		// expand explicit response files, their path is irrelevant but not their content
+		if responseFile, ok := strings.CutPrefix(arg, "@"); ok && len(responseFile) > 0 {
+			if !filepath.IsAbs(responseFile) && len(workingDir) > 0 {
+				responseFile = filepath.Join(workingDir, responseFile)
+			}
+			if content, err := os.ReadFile(responseFile); err == nil {
+				result = append(result, x.ExpandArguments(workingDir, splitResponseFileArguments(base.UnsafeStringFromBytes(content)))...)
+				continue
+			}
+		}
+		result = append(result, arg)
+	}
+	return
+}
+func (x cacheKeyNormalizer) NormalizeFiles(files FileSet) []string {
+	return x.NormalizeStrings(base.Map(func(f Filename) string { return f.String() }, files...)...)
+}
+func (x cacheKeyNormalizer) NormalizeStrings(values ...string) []string {
+	return base.Map(x.NormalizePath, values...)
+}
+func (x cacheKeyNormalizer) NormalizeEnvironment(env internal_io.ProcessEnvironment) internal_io.ProcessEnvironment {
+	// don't modify the action in place
+	return base.Map(func(it internal_io.EnvironmentDefinition) internal_io.EnvironmentDefinition {
+		return internal_io.EnvironmentDefinition{
+			Name:   it.Name,
+			Values: x.NormalizeStrings(it.Values...),
+		}
+	}, env...)
+}
+
+func splitResponseFileArguments(content string) (result []string) {
+	var arg strings.Builder
+	inQuotes, hasArg := false, false
+	for i := 0; i < len(content); i++ {
+		switch ch := content[i]; {
+		case ch == '\\' && i+1 < len(content) && content[i+1] == '"':
+			arg.WriteByte('"')
+			hasArg = true
+			i++
+		case ch == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case !inQuotes && (ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'):
+			if hasArg {
+				result = append(result, arg.String())
+				arg.Reset()
+				hasArg = false
+			}
+		default:
+			arg.WriteByte(ch)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		result = append(result, arg.String())
+	}
+	return
 }
 
 func (x *actionCache) CacheRead(bg BuildGraphWritePort, key ActionCacheKey, artifact *CacheArtifact) error {
@@ -205,7 +353,7 @@ func NewActionCacheBulk(bg BuildGraphWritePort, cachePath Directory, key ActionC
 		func(i int) Filename { return inputs[i] })
 
 	var fingerprint base.Fingerprint
-	fingerprint, err = base.SerializeAnyFingerprint(func(ar base.Archive) error {
+	fingerprint, err = base.SerializeAnyFingerprintWith(GetBuildFingerprintAlgorithm(), func(ar base.Archive) error {
 		for i, it := range digests {
 			if fd, err := it.Join().Get(); err == nil {
 				base.Assert(fd.Digest.Valid)
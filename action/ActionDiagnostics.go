@@ -0,0 +1,155 @@
+package action
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Action Diagnostics
+ ***************************************/
+
+// actionDiagnosticsSummary counts compiler diagnostics by code across the whole build, to prioritize cleanup.
+// Only output of executed actions is parsed: diagnostics of actions retrieved from cache are not counted.
+type actionDiagnosticsSummary struct {
+	Json bool
+
+	entries map[actionDiagnosticKey]*ActionDiagnosticEntry
+	barrier sync.Mutex
+}
+
+type actionDiagnosticKey struct {
+	Severity string
+	Code     string
+}
+
+type ActionDiagnosticEntry struct {
+	Severity string
+	Code     string
+	Count    int
+	Example  string // first occurrence, to give some context
+}
+
+var (
+	// MSVC: file(12,5): warning C4996: 'strcpy': This function or variable may be unsafe.
+	//       LINK : warning LNK4098: defaultlib 'LIBCMT' conflicts with use of other libs
+	reMsvcDiagnostic = regexp.MustCompile(`\b(warning|error) ([A-Z]+[0-9]+)\s*:`)
+	// clang/gcc: file:12:5: warning: 'strcpy' is deprecated [-Wdeprecated-declarations]
+	reGnuDiagnostic = regexp.MustCompile(`:\s(warning|error):\s.*?(?:\[(-W[^\],]+)[^\]]*\])?\s*$`)
+)
+
+// ParseActionDiagnostic extracts severity and code of a diagnostic line, for both MSVC and clang/gcc formats
+func ParseActionDiagnostic(line string) (severity, code string, ok bool) {
+	if m := reMsvcDiagnostic.FindStringSubmatch(line); m != nil {
+		return m[1], m[2], true
+	}
+	if m := reGnuDiagnostic.FindStringSubmatch(line); m != nil {
+		if code = m[2]; len(code) == 0 {
+			code = "(none)" // errors usually have no associated warning flag
+		}
+		return m[1], code, true
+	}
+	return
+}
+
+var getActionDiagnosticsSummary = base.Memoize(func() *actionDiagnosticsSummary {
+	summary := &actionDiagnosticsSummary{
+		Json:    GetActionFlags().Json.Get(),
+		entries: make(map[actionDiagnosticKey]*ActionDiagnosticEntry),
+	}
+	utils.CommandEnv.OnExit(func(*utils.CommandEnvT) error {
+		return summary.Print(10)
+	})
+	return summary
+})
+
+// PrepareActionDiagnostics registers the summary before running any action when enabled, so it is
+// also printed when no executed action reported a diagnostic
+func PrepareActionDiagnostics() {
+	if GetActionFlags().DiagSummary.Get() {
+		getActionDiagnosticsSummary()
+	}
+}
+
+func (x *actionDiagnosticsSummary) Parse(output string) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if severity, code, ok := ParseActionDiagnostic(line); ok {
+			x.Add(severity, code, line)
+		}
+	}
+}
+func (x *actionDiagnosticsSummary) Add(severity, code, line string) {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	key := actionDiagnosticKey{Severity: severity, Code: code}
+	if entry, ok := x.entries[key]; ok {
+		entry.Count++
+	} else {
+		x.entries[key] = &ActionDiagnosticEntry{
+			Severity: severity,
+			Code:     code,
+			Count:    1,
+			Example:  line,
+		}
+	}
+}
+
+// Entries are sorted by decreasing count, top offenders first
+func (x *actionDiagnosticsSummary) Entries() []ActionDiagnosticEntry {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	result := make([]ActionDiagnosticEntry, 0, len(x.entries))
+	for _, it := range x.entries {
+		result = append(result, *it)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Severity != result[j].Severity {
+			return result[i].Severity < result[j].Severity
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result
+}
+
+func (x *actionDiagnosticsSummary) Print(maxEntries int) error {
+	entries := x.Entries()
+
+	if x.Json {
+		return base.JsonSerialize(entries, base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+
+	if len(entries) == 0 {
+		base.LogInfo(LogAction, "no compiler diagnostic reported by executed actions")
+		return nil
+	}
+
+	total := 0
+	for _, it := range entries {
+		total += it.Count
+	}
+
+	base.LogForwardf("%d compiler diagnostics reported with %d distinct codes, top offenders:\n", total, len(entries))
+	for i, it := range entries {
+		if i == maxEntries {
+			base.LogForwardf("  ... and %d more codes\n", len(entries)-maxEntries)
+			break
+		}
+		example := it.Example
+		if len(example) > 120 {
+			example = example[:117] + "..."
+		}
+		base.LogForwardf("  %-8s %-32s x%-6d %s\n", it.Severity, it.Code, it.Count, example)
+	}
+	return nil
+}
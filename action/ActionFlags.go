@@ -17,11 +17,19 @@ type ActionFlags struct {
 	CacheMode             CacheModeType
 	CachePath             utils.Directory
 	DistMode              DistModeType
+	DiagSummary           utils.BoolVar
 	AdaptiveCache         utils.BoolVar
+	Json                  utils.BoolVar
+	MaxCmdLength          utils.IntVar
+	OutputSync            utils.BoolVar
 	ResponseFile          utils.BoolVar
+	SanitizeEnv           utils.BoolVar
 	ShowCmds              utils.BoolVar
 	ShowFiles             utils.BoolVar
 	ShowOutput            utils.BoolVar
+	TraceCommands         utils.Filename
+	TraceInlineRsp        utils.BoolVar
+	WriteResponseFiles    utils.BoolVar
 }
 
 func (x *ActionFlags) Flags(cfv utils.CommandFlagsVisitor) {
@@ -31,10 +39,18 @@ func (x *ActionFlags) Flags(cfv utils.CommandFlagsVisitor) {
 	cfv.Persistent("CacheCompression", "set compression format for cached bulk entries", &x.CacheCompression)
 	cfv.Persistent("CacheCompressionLevel", "set compression level for cached bulk entries", &x.CacheCompressionLevel)
 	cfv.Persistent("DistMode", "distribute actions to a cluster of remote workers", &x.DistMode)
+	cfv.Variable("DiagSummary", "count compiler diagnostics by warning code in output of executed actions, and print top offenders when build finished", &x.DiagSummary)
+	cfv.Variable("Json", "output diagnostics summary as json, instead of raw text", &x.Json)
+	cfv.Variable("MaxCmdLength", "truncate logged command-lines longer than this many characters, unless very-verbose (0 to disable)", &x.MaxCmdLength)
+	cfv.Variable("OutputSync", "buffer output of each action and print it in one block when it completes, instead of interleaving concurrent actions", &x.OutputSync)
 	cfv.Persistent("ResponseFile", "control response files usage", &x.ResponseFile)
+	cfv.Persistent("SanitizeEnv", "run actions with only their declared environment variables and a minimal allowlist, improves determinism for cache and distribution", &x.SanitizeEnv)
 	cfv.Variable("ShowCmds", "print executed compilation commands", &x.ShowCmds)
 	cfv.Variable("ShowFiles", "print file accesses for external commands", &x.ShowFiles)
 	cfv.Variable("ShowOutput", "always show compilation commands output", &x.ShowOutput)
+	cfv.Variable("TraceCommands", "write executed commands with their environment in a standalone .sh/.bat script, to reproduce the build outside", &x.TraceCommands)
+	cfv.Variable("TraceInlineRsp", "expand response files inline in script written by TraceCommands, instead of writing them next to it", &x.TraceInlineRsp)
+	cfv.Variable("WriteResponseFiles", "keep a .rsp file with command-line arguments of each built action in intermediate directory, to inspect what tools received (only for actions which are not up-to-date)", &x.WriteResponseFiles)
 }
 
 var GetActionFlags = utils.NewCommandParsableFlags(&ActionFlags{
@@ -49,10 +65,22 @@ var GetActionFlags = utils.NewCommandParsableFlags(&ActionFlags{
 
 	DistMode: DIST_NONE,
 
+	DiagSummary: base.INHERITABLE_FALSE,
+	Json:        base.INHERITABLE_FALSE,
+
+	// unity and response files can generate huge command-lines, which makes logs unreadable
+	MaxCmdLength: 2048,
+
+	OutputSync: base.INHERITABLE_FALSE,
+
 	ResponseFile: base.INHERITABLE_TRUE,
+	SanitizeEnv:  base.INHERITABLE_FALSE,
 	ShowCmds:     base.INHERITABLE_FALSE,
 	ShowFiles:    base.INHERITABLE_FALSE,
 	ShowOutput:   base.INHERITABLE_FALSE,
+
+	TraceInlineRsp:     base.INHERITABLE_FALSE,
+	WriteResponseFiles: base.INHERITABLE_FALSE,
 })
 
 /***************************************
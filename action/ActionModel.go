@@ -61,6 +61,7 @@ type ActionModel struct {
 	DynamicInputs     ActionSet
 	DynamicInputFiles utils.FileSet
 	StaticInputFiles  utils.FileSet
+	ImplicitInputs    utils.FileSet // tracked like static input files, but not substituted in command-line
 
 	ExportFile utils.Filename
 	OutputFile utils.Filename
@@ -69,6 +70,8 @@ type ActionModel struct {
 	Options       OptionFlags
 	Prerequisites ActionSet
 	StaticDeps    utils.BuildAliases
+
+	ExcludedDirs utils.DirSet // files read in those directories are not tracked as dependencies
 }
 
 func (x *ActionModel) GetCommandInputFiles() (results utils.FileSet) {
@@ -86,6 +89,7 @@ func (x *ActionModel) CreateActionRules() ActionRules {
 		CommandRules:  x.Command,
 		OutputFiles:   utils.FileSet{x.OutputFile}.Concat(x.ExtraFiles...),
 		Prerequisites: x.Prerequisites.Aliases(),
+		ExcludedDirs:  x.ExcludedDirs,
 		Options:       x.Options,
 	}
 	rules.OutputFiles.Sort()
@@ -116,6 +120,11 @@ func BuildAction(model *ActionModel, factory func(*ActionModel) (Action, error))
 			return nil, err
 		}
 
+		// track implicit inputs, which are given to the command by other means than input substitution
+		if err := bi.NeedFiles(model.ImplicitInputs...); err != nil {
+			return nil, err
+		}
+
 		// track dynamic inputs
 		if err := bi.DependsOn(utils.MakeBuildAliases(model.DynamicInputs...)...); err != nil {
 			return nil, err
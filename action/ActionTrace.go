@@ -0,0 +1,176 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Action Trace
+ ***************************************/
+
+// actionTraceScript records every executed command-line, with its environment, to write a standalone
+// reproducer script when the process exits. An action only runs once all its dependencies completed,
+// so execution order is also a valid dependency order for the script.
+// Only actions outdated are executed and recorded: combine with -f to trace a full build.
+type actionTraceScript struct {
+	Output         utils.Filename
+	Batch          bool
+	InlineResponse bool
+
+	commands []actionTraceCommand
+	barrier  sync.Mutex
+}
+
+type actionTraceCommand struct {
+	Alias ActionAlias
+	CommandRules
+	UseResponseFile bool
+}
+
+var getActionTraceScript = base.Memoize(func() *actionTraceScript {
+	flags := GetActionFlags()
+	trace := &actionTraceScript{
+		Output:         flags.TraceCommands.Normalize(),
+		InlineResponse: flags.TraceInlineRsp.Get(),
+	}
+
+	switch strings.ToLower(trace.Output.Ext()) {
+	case ".bat", ".cmd":
+		trace.Batch = true
+	case ".sh":
+		trace.Batch = false
+	default:
+		trace.Batch = base.GetCurrentHost().Id == base.HOST_WINDOWS
+	}
+
+	utils.CommandEnv.OnExit(func(*utils.CommandEnvT) error {
+		return trace.Flush()
+	})
+	return trace
+})
+
+func (x *actionTraceScript) Record(action *ActionRules, useResponseFile bool) {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+	x.commands = append(x.commands, actionTraceCommand{
+		Alias:           action.GetActionAlias(),
+		CommandRules:    action.CommandRules,
+		UseResponseFile: useResponseFile && !x.InlineResponse,
+	})
+}
+
+// ResponseDir is where response files are written, next to the script so it can be moved with it
+func (x *actionTraceScript) ResponseDir() utils.Directory {
+	return x.Output.Dirname.Folder(x.Output.TrimExt() + "-rsp")
+}
+
+func (x *actionTraceScript) Flush() error {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	base.LogInfo(LogAction, "trace %d executed commands in %q", len(x.commands), x.Output)
+
+	if err := utils.UFS.CreateBuffered(x.Output, func(w io.Writer) error {
+		if x.Batch {
+			fmt.Fprintln(w, "@echo off")
+			fmt.Fprintf(w, ":: generated by %s, reproduces %d commands in dependency order\n", utils.CommandEnv.Prefix(), len(x.commands))
+		} else {
+			fmt.Fprintln(w, "#!/bin/sh")
+			fmt.Fprintf(w, "# generated by %s, reproduces %d commands in dependency order\n", utils.CommandEnv.Prefix(), len(x.commands))
+		}
+
+		for i, it := range x.commands {
+			arguments := it.Arguments
+			if it.UseResponseFile {
+				responseFile := x.ResponseDir().File(fmt.Sprintf("%04d.rsp", i+1))
+				if err := utils.UFS.CreateBuffered(responseFile, func(w io.Writer) error {
+					return internal_io.WriteResponseFile(w, it.Arguments)
+				}, base.TransientPage4KiB); err != nil {
+					return err
+				}
+
+				if x.Batch {
+					arguments = base.StringSet{fmt.Sprintf(`"@%%~dp0%s\%s"`, x.ResponseDir().Basename(), responseFile.Basename)}
+				} else {
+					arguments = base.StringSet{fmt.Sprintf(`@"$(dirname "$0")/%s/%s"`, x.ResponseDir().Basename(), responseFile.Basename)}
+				}
+			}
+
+			var err error
+			if x.Batch {
+				err = writeActionTraceBatch(w, it, arguments, !it.UseResponseFile)
+			} else {
+				err = writeActionTraceShell(w, it, arguments, !it.UseResponseFile)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, base.TransientPage4KiB); err != nil {
+		return err
+	}
+
+	if !x.Batch {
+		return os.Chmod(x.Output.String(), 0755)
+	}
+	return nil
+}
+
+func writeActionTraceShell(w io.Writer, cmd actionTraceCommand, arguments base.StringSet, quoteArguments bool) error {
+	fmt.Fprintf(w, "\n# %v\n(\n", cmd.Alias)
+	if cmd.WorkingDir.Valid() {
+		fmt.Fprintf(w, "  cd %s || exit 1\n", quoteShellArg(cmd.WorkingDir.String()))
+	}
+	for _, it := range cmd.Environment {
+		fmt.Fprintf(w, "  export %s\n", quoteShellArg(it.String()))
+	}
+	fmt.Fprintf(w, "  %s", quoteShellArg(cmd.Executable.String()))
+	for _, it := range arguments {
+		if quoteArguments {
+			it = quoteShellArg(it)
+		}
+		fmt.Fprintf(w, " %s", it)
+	}
+	_, err := fmt.Fprintln(w, "\n) || exit $?")
+	return err
+}
+
+func writeActionTraceBatch(w io.Writer, cmd actionTraceCommand, arguments base.StringSet, quoteArguments bool) error {
+	// setlocal restores both environment and working directory on endlocal
+	fmt.Fprintf(w, "\n:: %v\nsetlocal\n", cmd.Alias)
+	if cmd.WorkingDir.Valid() {
+		fmt.Fprintf(w, "cd /d %s || exit /b 1\n", quoteBatchArg(cmd.WorkingDir.String()))
+	}
+	for _, it := range cmd.Environment {
+		fmt.Fprintf(w, "set \"%s\"\n", it.String())
+	}
+	fmt.Fprint(w, quoteBatchArg(cmd.Executable.String()))
+	for _, it := range arguments {
+		if quoteArguments {
+			it = quoteBatchArg(it)
+		}
+		fmt.Fprintf(w, " %s", it)
+	}
+	_, err := fmt.Fprintln(w, " || exit /b 1\nendlocal")
+	return err
+}
+
+func quoteShellArg(a string) string {
+	return "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+}
+func quoteBatchArg(a string) string {
+	a = strings.ReplaceAll(a, "%", "%%")
+	if len(a) > 0 && !strings.ContainsAny(a, " \t\"&|<>^()") {
+		return a
+	}
+	return `"` + strings.ReplaceAll(a, `"`, `\"`) + `"`
+}
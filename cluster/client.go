@@ -253,7 +253,7 @@ func (x *Client) runTaskOnTunnelImmediate(tunnel *Tunnel, executable Filename, a
 	}
 
 	bootstrapDispatch := NewMessageTaskDispatch(executable, arguments,
-		options.WorkingDir, options.Environment, options.MountedPaths, options.UseResponseFile)
+		options.WorkingDir, options.Environment, options.SanitizeEnv, options.MountedPaths, options.UseResponseFile)
 	return MessageLoop(tunnel, x.context, x.Cluster.GetTimeoutDuration(), &bootstrapDispatch)
 }
 
@@ -37,7 +37,7 @@ type Cluster struct {
 func NewCluster(options ...ClusterOption) Cluster {
 	settings := NewClusterOptions(options...)
 	return Cluster{
-		PeerDiscovery:  NewPeerDiscovery(settings.BrokeragePath, CURRENT_PEERVERSION, settings.MaxPeers.Get()),
+		PeerDiscovery:  NewPeerDiscovery(settings.GetBrokeragePath(), CURRENT_PEERVERSION, settings.MaxPeers.Get()),
 		ClusterOptions: settings,
 	}
 }
@@ -80,7 +80,7 @@ type ClusterFlags struct {
 }
 
 var GetClusterFlags = utils.NewCommandParsableFlags(&ClusterFlags{
-	BrokeragePath: utils.UFS.Transient.Folder("Brokerage"),
+	BrokeragePath: utils.Directory{},
 	MaxPeers:      32,
 	IfIndex:       0,
 	RetryCount:    5,
@@ -89,6 +89,13 @@ var GetClusterFlags = utils.NewCommandParsableFlags(&ClusterFlags{
 	WebdavPort:    0,
 })
 
+// brokerage path defaults to transient directory, which is resolved lazily since it can be moved with -TempDir
+func (x *ClusterFlags) GetBrokeragePath() utils.Directory {
+	if x.BrokeragePath.Valid() {
+		return x.BrokeragePath
+	}
+	return utils.UFS.Transient.Folder("Brokerage")
+}
 func (x *ClusterFlags) GetTimeoutDuration() time.Duration {
 	return x.Timeout.Duration()
 }
@@ -100,7 +107,7 @@ func (x *ClusterFlags) GetWebdavPort() string {
 }
 
 func (x *ClusterFlags) Flags(cfv utils.CommandFlagsVisitor) {
-	cfv.Persistent("BrokeragePath", "set peer discovery brokerage path", &x.BrokeragePath)
+	cfv.Persistent("BrokeragePath", "set peer discovery brokerage path (default: Brokerage folder in transient directory)", &x.BrokeragePath)
 	cfv.Persistent("MaxPeers", "set maximum number of connected peers allowed", &x.MaxPeers)
 	cfv.Persistent("NetInterface", "set index of network interface for the cluster", &x.IfIndex)
 	cfv.Persistent("RetryCount", "set peer retry count when an error occured", &x.RetryCount)
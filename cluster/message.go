@@ -458,6 +458,7 @@ type MessageTaskDispatch struct {
 	Executable      Filename
 	Arguments       base.StringSet
 	Environment     internal_io.ProcessEnvironment
+	SanitizeEnv     bool
 	MountedPaths    []internal_io.MountedPath
 	UseResponseFile bool
 	WorkingDir      Directory
@@ -465,12 +466,13 @@ type MessageTaskDispatch struct {
 	timedMessageBody
 }
 
-func NewMessageTaskDispatch(executable Filename, arguments base.StringSet, workingDir Directory, env internal_io.ProcessEnvironment, mountedPaths []internal_io.MountedPath, useResponseFile bool) MessageTaskDispatch {
+func NewMessageTaskDispatch(executable Filename, arguments base.StringSet, workingDir Directory, env internal_io.ProcessEnvironment, sanitizeEnv bool, mountedPaths []internal_io.MountedPath, useResponseFile bool) MessageTaskDispatch {
 	return MessageTaskDispatch{
 		Executable:      executable,
 		Arguments:       arguments,
 		WorkingDir:      workingDir,
 		Environment:     env,
+		SanitizeEnv:     sanitizeEnv,
 		MountedPaths:    mountedPaths,
 		UseResponseFile: useResponseFile,
 
@@ -505,6 +507,7 @@ func (x *MessageTaskDispatch) Accept(wr MessageWriter) (err error) {
 		internal_io.OptionProcessCaptureOutput,
 		internal_io.OptionProcessExitCode(&exitCode),
 		internal_io.OptionProcessEnvironment(x.Environment),
+		internal_io.OptionProcessSanitizeEnvironmentIf(x.SanitizeEnv),
 		internal_io.OptionProcessWorkingDir(x.WorkingDir),
 		internal_io.OptionProcessMountedPath(x.MountedPaths...),
 		internal_io.OptionProcessUseResponseFileIf(x.UseResponseFile),
@@ -528,6 +531,7 @@ func (x *MessageTaskDispatch) Serialize(ar base.Archive) {
 	ar.Serializable(&x.Executable)
 	ar.Serializable(&x.Arguments)
 	ar.Serializable(&x.Environment)
+	ar.Bool(&x.SanitizeEnv)
 	base.SerializeSlice(ar, &x.MountedPaths)
 	ar.Bool(&x.UseResponseFile)
 	ar.Serializable(&x.WorkingDir)
@@ -0,0 +1,270 @@
+package compile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Header Amalgamation
+ ***************************************/
+
+// Amalgamation concatenates every header of a module in a single distributable header. Dependencies recorded by
+// compilation are flattened and can't order headers between them (see FindOrderedSourceFiles), so includes are
+// parsed from headers instead, then ordered with the same topological sort. Include guards and includes of
+// amalgamated headers are stripped, other includes are only kept for their first occurrence outside of any
+// conditional block (includes nested in #if/#ifdef blocks are kept verbatim, since they are not always active). Inline files (.inl)
+// usually complete declarations of the header including them, often at its end: they are expanded in place of
+// their include instead of being sorted, so they always come after what they depend on.
+
+var amalgamateHeaderExts = base.NewStringSet(".h", ".hh", ".hpp", ".hxx", ".inl")
+
+var (
+	amalgamateIncludeRe    = regexp.MustCompile(`^\s*#\s*include\s*[<"]([^>"]+)[>"]`)
+	amalgamatePragmaOnceRe = regexp.MustCompile(`^\s*#\s*pragma\s+once\b`)
+	amalgamateIfRe         = regexp.MustCompile(`^\s*#\s*if(n?def)?\b`)
+	amalgamateIfndefRe     = regexp.MustCompile(`^\s*#\s*ifndef\s+(\w+)\s*$`)
+	amalgamateDefineRe     = regexp.MustCompile(`^\s*#\s*define\s+(\w+)\s*$`)
+	amalgamateEndifRe      = regexp.MustCompile(`^\s*#\s*endif\b`)
+)
+
+type amalgamatedHeader struct {
+	File    Filename
+	Lines   []string
+	Inlines map[int]Filename // inline files expanded at given line
+}
+
+// AmalgamateHeaders returns a single header concatenating all headers of given target module, where included
+// headers always come before headers including them
+func AmalgamateHeaders(bg BuildGraphWritePort, targetAlias TargetAlias) (string, error) {
+	unit, err := FindBuildUnit(bg, targetAlias)
+	if err != nil {
+		return "", err
+	}
+	module, err := FindBuildModule(bg, targetAlias.ModuleAlias)
+	if err != nil {
+		return "", err
+	}
+	rules := module.GetModule()
+
+	headers := make(map[Filename]*amalgamatedHeader)
+	for _, dir := range append(DirSet{rules.ModuleDir}, rules.Source.SourceDirs...) {
+		if err := dir.MatchFilesRec(func(f Filename) error {
+			if amalgamateHeaderExts.Contains(strings.ToLower(f.Ext())) {
+				headers[f] = &amalgamatedHeader{File: f}
+			}
+			return nil
+		}, base.Regexp{}); err != nil {
+			return "", err
+		}
+	}
+	if len(headers) == 0 {
+		return "", fmt.Errorf("amalgamate: module %q does not have any header", targetAlias.ModuleAlias)
+	}
+
+	// resolve includes like the compiler would, first relative to the header then with unit include paths
+	resolveInclude := func(header Filename, include string) (Filename, bool) {
+		for _, dir := range append(DirSet{header.Dirname}, unit.IncludePaths...) {
+			if f := dir.AbsoluteFile(include); headers[f] != nil {
+				return f, true
+			}
+		}
+		return Filename{}, false
+	}
+
+	includes := make(map[Filename]FileSet, len(headers))
+	for file, header := range headers {
+		if err := UFS.ReadLines(file, func(line string) error {
+			header.Lines = append(header.Lines, strings.TrimRight(line, "\r"))
+			return nil
+		}); err != nil {
+			return "", err
+		}
+
+		header.Lines = stripIncludeGuard(header.Lines)
+
+		deps := FileSet{}
+		lines := header.Lines[:0]
+		for _, line := range header.Lines {
+			if amalgamatePragmaOnceRe.MatchString(line) {
+				continue
+			}
+			if m := amalgamateIncludeRe.FindStringSubmatch(line); m != nil {
+				if dep, ok := resolveInclude(file, m[1]); ok {
+					switch {
+					case dep.Equals(file):
+					case isAmalgamatedInline(dep):
+						if header.Inlines == nil {
+							header.Inlines = make(map[int]Filename)
+						}
+						header.Inlines[len(lines)] = dep
+						lines = append(lines, line) // replaced by inline file content
+					default:
+						deps.AppendUniq(dep)
+					}
+					continue // internal include, already amalgamated
+				}
+			}
+			lines = append(lines, line)
+		}
+		header.Lines = lines
+		includes[file] = deps
+	}
+
+	// inline files expanded in place are not sorted, but their includes must still come before the header expanding them
+	inlined := FileSet{}
+	for _, header := range headers {
+		for _, inl := range header.Inlines {
+			inlined.AppendUniq(inl)
+		}
+	}
+	var gatherInlineIncludes func(header *amalgamatedHeader, deps *FileSet, visited *FileSet)
+	gatherInlineIncludes = func(header *amalgamatedHeader, deps *FileSet, visited *FileSet) {
+		for _, inl := range header.Inlines {
+			if visited.Contains(inl) {
+				continue
+			}
+			visited.Append(inl)
+			deps.AppendUniq(includes[inl]...)
+			gatherInlineIncludes(headers[inl], deps, visited)
+		}
+	}
+	sortables := make(map[Filename]FileSet, len(includes))
+	for file, deps := range includes {
+		if inlined.Contains(file) {
+			continue
+		}
+		deps = base.CopySlice(deps...)
+		gatherInlineIncludes(headers[file], &deps, &FileSet{file})
+		deps.Remove(file)
+		sortables[file] = deps
+	}
+
+	var oss strings.Builder
+	fmt.Fprintf(&oss, "// %v amalgamated header, generated by ppb: do not edit\n", targetAlias.ModuleAlias)
+	fmt.Fprintln(&oss, "#pragma once")
+
+	externalIncludes := base.StringSet{}
+	expandedInlines := FileSet{}
+	var writeHeader func(file Filename, depth int)
+	writeHeader = func(file Filename, depth int) {
+		fmt.Fprintf(&oss, "\n// --- %s ---\n", SanitizePath(file.Relative(rules.ModuleDir), '/'))
+
+		header := headers[file]
+		for i, line := range header.Lines {
+			if inl, ok := header.Inlines[i]; ok {
+				if !expandedInlines.Contains(inl) {
+					expandedInlines.Append(inl)
+					writeHeader(inl, depth)
+					fmt.Fprintf(&oss, "\n// --- %s (continued) ---\n", SanitizePath(file.Relative(rules.ModuleDir), '/'))
+				}
+				continue
+			}
+			switch {
+			case amalgamateIfRe.MatchString(line):
+				depth++
+			case amalgamateEndifRe.MatchString(line):
+				depth--
+			case depth == 0 && amalgamateIncludeRe.MatchString(line):
+				directive := strings.TrimSpace(line)
+				if externalIncludes.Contains(directive) {
+					continue
+				}
+				externalIncludes.Append(directive)
+			}
+			fmt.Fprintln(&oss, line)
+		}
+	}
+
+	for _, file := range sortFilesTopologically(sortables) {
+		writeHeader(file, 0)
+	}
+	return oss.String(), nil
+}
+
+func isAmalgamatedInline(file Filename) bool {
+	return strings.EqualFold(file.Ext(), ".inl")
+}
+
+// stripIncludeGuard removes classic #ifndef/#define/#endif include guard, only when it wraps the whole header
+func stripIncludeGuard(lines []string) []string {
+	significant := make([]int, 0, len(lines))
+	inComment := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inComment:
+			inComment = !strings.Contains(trimmed, "*/")
+		case len(trimmed) == 0, strings.HasPrefix(trimmed, "//"):
+		case strings.HasPrefix(trimmed, "/*"):
+			inComment = !strings.Contains(trimmed, "*/")
+		default:
+			significant = append(significant, i)
+		}
+	}
+	if len(significant) < 3 {
+		return lines
+	}
+
+	first, second, last := significant[0], significant[1], significant[len(significant)-1]
+	ifndef := amalgamateIfndefRe.FindStringSubmatch(lines[first])
+	define := amalgamateDefineRe.FindStringSubmatch(lines[second])
+	if ifndef == nil || define == nil || ifndef[1] != define[1] || !amalgamateEndifRe.MatchString(lines[last]) {
+		return lines
+	}
+
+	results := make([]string, 0, len(lines)-3)
+	for i, line := range lines {
+		if i != first && i != second && i != last {
+			results = append(results, line)
+		}
+	}
+	return results
+}
+
+// CheckAmalgamatedHeader compiles amalgamated header alone with target compiler, to validate it is self-contained
+func CheckAmalgamatedHeader(bg BuildGraphWritePort, targetAlias TargetAlias, content string) error {
+	unit, err := FindBuildUnit(bg, targetAlias)
+	if err != nil {
+		return err
+	}
+
+	define := "AMALGAMATE_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, targetAlias.ModuleAlias.String())
+
+	compiler, err := unit.GetBuildCompiler(bg)
+	if err != nil {
+		return err
+	}
+
+	// external includes of amalgamated header are resolved like they were when compiling the module
+	facet := NewFacet()
+	compiler.Define(&facet, unit.Defines...)
+	compiler.IncludePath(&facet, unit.IncludePaths...)
+	compiler.ExternIncludePath(&facet, unit.ExternIncludePaths...)
+	compiler.SystemIncludePath(&facet, unit.SystemIncludePaths...)
+
+	result, err := NeedCompilerProbeResult(unit.CompilerAlias, unit.CppStd, CompilerProbe{
+		Define:  define,
+		Source:  content,
+		Options: facet.CompilerOptions,
+	}).Need(bg.GlobalContext())
+	if err != nil {
+		return err
+	}
+
+	if !result.Supported {
+		return fmt.Errorf("amalgamate: header of %q failed to compile with %v, run with -v to see compiler output", targetAlias.ModuleAlias, unit.CompilerAlias)
+	}
+	return nil
+}
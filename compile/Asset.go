@@ -0,0 +1,189 @@
+package compile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/internal/base"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Asset Rules
+ ***************************************/
+
+// AssetRule compiles every file matched by its globs with an external tool, for data shipped along with
+// module output (shaders, textures...). Each input gets its own action, tracked like compilation actions.
+// In arguments, %1 is replaced by input file and %2 by output file. Output is relative to the directory of
+// unit output file, where %n is replaced by input basename without extension and %d by input directory
+// relative to source directory.
+
+type AssetRule struct {
+	Name         string
+	SourceDir    utils.Directory
+	Globs        base.StringSet
+	Executable   utils.Filename
+	Arguments    base.StringSet
+	Output       string
+	Dependencies utils.FileSet // additional inputs shared by every asset, like included files
+	Cacheable    bool          // only when tool output is deterministic
+}
+
+func (x *AssetRule) String() string {
+	return x.Name
+}
+func (x *AssetRule) Serialize(ar base.Archive) {
+	ar.String(&x.Name)
+	ar.Serializable(&x.SourceDir)
+	ar.Serializable(&x.Globs)
+	ar.Serializable(&x.Executable)
+	ar.Serializable(&x.Arguments)
+	ar.String(&x.Output)
+	ar.Serializable(&x.Dependencies)
+	ar.Bool(&x.Cacheable)
+}
+
+// GetInputFiles is tracked by build graph, so adding or removing an asset will generate actions again
+func (x *AssetRule) GetInputFiles(bc utils.BuildContext) (utils.FileSet, error) {
+	return internal_io.GlobDirectory(bc, x.SourceDir, x.Globs, base.StringSet{}, utils.FileSet{})
+}
+func (x *AssetRule) GetOutputFile(outputDir utils.Directory, input utils.Filename) utils.Filename {
+	relativeDir := input.Dirname.Relative(x.SourceDir)
+	if relativeDir == "." {
+		relativeDir = ""
+	}
+	output := strings.ReplaceAll(x.Output, "%n", input.TrimExt())
+	output = strings.ReplaceAll(output, "%d", relativeDir)
+	return outputDir.AbsoluteFile(output).Normalize()
+}
+
+type AssetRuleList []AssetRule
+
+func (list *AssetRuleList) Append(it ...AssetRule) {
+	*list = append(*list, it...)
+}
+func (list *AssetRuleList) Prepend(it ...AssetRule) {
+	*list = append(it, *list...)
+}
+func (list *AssetRuleList) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]AssetRule)(list))
+}
+
+// AssetRuleModel is the json representation of an asset rule, where paths are relative to module directory:
+//
+//	"Assets": [{ "Name": "Shaders", "Globs": ["*.hlsl"], "Executable": "dxc", "Arguments": ["-T", "ps_6_0", "-Fo", "%2", "%1"], "Output": "Shaders/%d/%n.cso" }]
+type AssetRuleModel struct {
+	Name         string
+	SourceDir    string
+	Globs        base.StringSet
+	Executable   string
+	Arguments    base.StringSet
+	Output       string
+	Dependencies base.StringSet
+	Cacheable    utils.BoolVar
+}
+
+func (x *AssetRuleModel) Serialize(ar base.Archive) {
+	ar.String(&x.Name)
+	ar.String(&x.SourceDir)
+	ar.Serializable(&x.Globs)
+	ar.String(&x.Executable)
+	ar.Serializable(&x.Arguments)
+	ar.String(&x.Output)
+	ar.Serializable(&x.Dependencies)
+	ar.Serializable(&x.Cacheable)
+}
+func (x *AssetRuleModel) CreateAssetRule(moduleDir utils.Directory) (AssetRule, error) {
+	rule := AssetRule{
+		Name:         x.Name,
+		SourceDir:    moduleDir,
+		Globs:        x.Globs,
+		Arguments:    x.Arguments,
+		Output:       x.Output,
+		Dependencies: utils.MakeFileSet(moduleDir, x.Dependencies...).Normalize(),
+		Cacheable:    x.Cacheable.Get(),
+	}
+
+	if len(x.SourceDir) > 0 {
+		rule.SourceDir = moduleDir.AbsoluteFolder(x.SourceDir).Normalize()
+	}
+
+	switch {
+	case len(x.Name) == 0:
+		return rule, fmt.Errorf("asset rule in %q has no name", moduleDir)
+	case len(x.Globs) == 0:
+		return rule, fmt.Errorf("asset rule %q in %q has no input glob", x.Name, moduleDir)
+	case !strings.Contains(x.Output, "%n"):
+		return rule, fmt.Errorf("asset rule %q in %q must use %%n in output, or every asset would overwrite the same file", x.Name, moduleDir)
+	}
+
+	var err error
+	rule.Executable, err = findCustomExecutable(moduleDir, x.Executable)
+	if err != nil {
+		return rule, fmt.Errorf("asset rule %q in %q: %w", x.Name, moduleDir, err)
+	}
+	return rule, nil
+}
+
+/***************************************
+ * Asset Actions
+ ***************************************/
+
+func (x *buildActionGenerator) AssetActions(dependencies action.ActionSet) (action.ActionSet, error) {
+	result := action.ActionSet{}
+	outputDir := x.Unit.OutputFile.Dirname
+
+	for _, rule := range x.Unit.Assets {
+		inputFiles, err := rule.GetInputFiles(x.BuildContext)
+		if err != nil {
+			return action.ActionSet{}, err
+		}
+
+		base.LogVeryVerbose(LogCompile, "%v: asset rule %q matched %d files", x.Unit, &rule, len(inputFiles))
+
+		for _, input := range inputFiles {
+			output := rule.GetOutputFile(outputDir, input)
+
+			arguments := make(base.StringSet, len(rule.Arguments))
+			for i, arg := range rule.Arguments {
+				arg = strings.ReplaceAll(arg, "%1", input.String())
+				arg = strings.ReplaceAll(arg, "%2", output.String())
+				arguments[i] = arg
+			}
+
+			model := action.ActionModel{
+				Command: action.CommandRules{
+					Arguments:   arguments,
+					Environment: x.Unit.Environment,
+					Executable:  rule.Executable,
+					WorkingDir:  rule.SourceDir,
+				},
+				StaticInputFiles: utils.FileSet{input},
+				ImplicitInputs:   rule.Dependencies,
+				ExportFile:       output,
+				OutputFile:       output,
+				StaticDeps:       utils.MakeBuildAliases(dependencies...),
+			}
+
+			// unlike custom commands, inputs and outputs of assets are fully known and can be stored in cache
+			if rule.Cacheable {
+				model.Options.Add(action.OPT_ALLOW_CACHEREAD, action.OPT_ALLOW_CACHEWRITE)
+			}
+
+			actionFactory := action.BuildAction(&model,
+				func(model *action.ActionModel) (action.Action, error) {
+					rules := model.CreateActionRules()
+					return &rules, nil
+				})
+
+			buildable, err := x.BuildContext.OutputFactory(actionFactory, utils.OptionBuildForce)
+			if err != nil {
+				return action.ActionSet{}, err
+			}
+			result.Append(buildable.(action.Action))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
+)
+
+/***************************************
+ * Build Info generator
+ ***************************************/
+
+// BUILDINFO_GENERATED_HEADER is the name of the private header generated for executables and shared libraries
+// when -BuildInfo is enabled: include it to report how a binary was built (guarded by BUILD_HAS_BUILDINFO).
+const BUILDINFO_GENERATED_HEADER = "BuildInfo.generated.h"
+
+var BuildInfoHeaderVersion = "BuildInfoGeneratedHeader-1.0.0"
+
+type BuildInfoGenerator struct {
+	Version string
+}
+
+func (x *BuildInfoGenerator) Serialize(ar base.Archive) {
+	ar.String(&x.Version)
+}
+func (x BuildInfoGenerator) CreateGenerated(unit *Unit, output utils.Filename) (Generated, error) {
+	pi := utils.GetProcessInfo()
+	return &BuildInfoGeneratedHeader{
+		Version:       x.Version,
+		TargetAlias:   unit.TargetAlias,
+		PpbVersion:    pi.Version,
+		PpbSeed:       pi.Checksum,
+		Deterministic: unit.Deterministic.Get(),
+	}, nil
+}
+
+/***************************************
+ * Build Info generated header
+ ***************************************/
+
+type BuildInfoGeneratedHeader struct {
+	Version       string
+	TargetAlias   TargetAlias
+	PpbVersion    string
+	PpbSeed       base.Fingerprint
+	Deterministic bool
+}
+
+func (x *BuildInfoGeneratedHeader) Serialize(ar base.Archive) {
+	ar.String(&x.Version)
+	ar.Serializable(&x.TargetAlias)
+	ar.String(&x.PpbVersion)
+	ar.Serializable(&x.PpbSeed)
+	ar.Bool(&x.Deterministic)
+}
+func (x BuildInfoGeneratedHeader) Generate(bc utils.BuildContext, generated *BuildGenerated, dst io.Writer) error {
+	// timestamp is fixed to zero under determinism, or binaries would differ for each build
+	var timestamp time.Time
+	if !x.Deterministic {
+		timestamp = time.Now().UTC()
+	}
+
+	cpp := internal_io.NewCppFile(dst, false)
+	cpp.Comment("Build info header generated by %v - v%v", utils.CommandEnv.Prefix(), x.Version)
+	cpp.Pragma("once")
+
+	cpp.Define("BUILD_INFO_TARGET", strconv.Quote(x.TargetAlias.String()))
+	cpp.Define("BUILD_INFO_PPB_VERSION", strconv.Quote(x.PpbVersion))
+	cpp.Define("BUILD_INFO_PPB_SEED", strconv.Quote(x.PpbSeed.String()))
+
+	if x.Deterministic {
+		cpp.Define("BUILD_INFO_TIMESTAMP", "0")
+		cpp.Define("BUILD_INFO_DATE", `""`)
+	} else {
+		cpp.Define("BUILD_INFO_TIMESTAMP", fmt.Sprintf("%dLL", timestamp.Unix()))
+		cpp.Define("BUILD_INFO_DATE", strconv.Quote(timestamp.Format(time.RFC3339)))
+	}
+
+	cpp.Define("BUILD_INFO_STRING", strconv.Quote(fmt.Sprintf("%v built by ppb v%v [%v]",
+		x.TargetAlias, x.PpbVersion, x.PpbSeed.ShortString())))
+	return nil
+}
@@ -2,6 +2,7 @@ package compile
 
 import (
 	"io"
+	"strings"
 
 	"github.com/poppolopoppo/ppb/action"
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -39,6 +40,58 @@ func (x *CompilationDatabase) Append(cmd CompileCommand) {
 	*x = append(*x, cmd)
 }
 
+// MakeCompileCommand returns the command-line of an action compiling a source file, false if the action has no file input
+func MakeCompileCommand(rules *action.ActionRules, inputFiles utils.FileSet) (CompileCommand, bool) {
+	if len(inputFiles) == 0 {
+		return CompileCommand{}, false
+	}
+
+	commandArgs := make([]string, len(rules.Arguments)+1)
+	commandArgs[0] = rules.Executable.String()
+	for j, arg := range rules.Arguments {
+		commandArgs[j+1] = arg
+	}
+
+	return CompileCommand{
+		Directory: rules.WorkingDir,
+		File:      inputFiles[0],
+		Output:    rules.OutputFiles[0],
+		Arguments: commandArgs,
+	}, true
+}
+
+// GetCompilationFlags returns arguments without the executable and paths specific to this command (input, outputs
+// and options introducing them), so they can be used to compile other files of the same unit
+func (x *CompileCommand) GetCompilationFlags(outputFiles ...utils.Filename) (flags base.StringSet) {
+	paths := base.NewStringSet()
+	for _, it := range append([]utils.Filename{x.File, x.Output}, outputFiles...) {
+		paths.AppendUniq(it.String(), utils.MakeLocalFilename(it))
+	}
+
+	args := x.Arguments[1:]
+	drop := make([]bool, len(args))
+	for i, arg := range args {
+		for _, path := range paths {
+			if !strings.Contains(arg, path) {
+				continue
+			}
+			drop[i] = true
+			// option with a separate value, ex: `-o <output>` or `/sourceDependencies <json>`
+			if arg == path && i > 0 && !drop[i-1] && len(args[i-1]) > 1 && strings.ContainsRune("-/", rune(args[i-1][0])) && !strings.ContainsAny(args[i-1], "=:") && !strings.ContainsAny(args[i-1][1:], "/\\") {
+				drop[i-1] = true
+			}
+			break
+		}
+	}
+
+	for i, arg := range args {
+		if !drop[i] {
+			flags.Append(arg)
+		}
+	}
+	return
+}
+
 /***************************************
  * Compilation Database Builder
  ***************************************/
@@ -108,23 +161,12 @@ func (x *CompilationDatabaseBuilder) Build(bc utils.BuildContext) error {
 		rules := a.GetAction()
 
 		inputFiles := rules.GetStaticInputFiles(bc)
-		if len(inputFiles) == 0 {
+		actionCmd, ok := MakeCompileCommand(rules, inputFiles)
+		if !ok {
 			base.LogTrace(LogCompileDb, "%v: action %q has no file input", x.EnvironmentAlias, rules.Alias())
 			continue // librarian or linker actions have dynamic inputs, but we are not interested in them here anyway
 		}
 
-		commandArgs := make([]string, len(rules.Arguments)+1)
-		commandArgs[0] = rules.Executable.String()
-		for j, arg := range rules.Arguments {
-			commandArgs[j+1] = arg
-		}
-
-		actionCmd := CompileCommand{
-			Directory: rules.WorkingDir,
-			File:      inputFiles[0],
-			Output:    rules.OutputFiles[0],
-			Arguments: commandArgs,
-		}
 		database.Append(actionCmd)
 		base.LogVeryVerbose(LogCompileDb, "%v: found source file -> %q", x.EnvironmentAlias, actionCmd.File)
 
@@ -0,0 +1,26 @@
+package compile
+
+import (
+	"testing"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+func TestCompileCommandFlags(t *testing.T) {
+	input, output, depFile := MakeFilename("/src/foo.cpp"), MakeFilename("/obj/foo.o"), MakeFilename("/obj/foo.d")
+	command := CompileCommand{
+		File:   input,
+		Output: output,
+		Arguments: base.NewStringSet("clang++",
+			"-o", output.String(), input.String(), "-Wall", "-I/src/include", "-DFOO=1",
+			"--write-dependencies", "-MF"+depFile.String(), "-c"),
+	}
+
+	flags := command.GetCompilationFlags(depFile)
+	if expected := base.NewStringSet("-Wall", "-I/src/include", "-DFOO=1", "--write-dependencies", "-c"); !flags.Equals(expected) {
+		t.Errorf("compile command flags: expected %v, got %v", expected, flags)
+	}
+}
@@ -1,6 +1,10 @@
 package compile
 
 import (
+	"fmt"
+	"path"
+	"strings"
+
 	"github.com/poppolopoppo/ppb/internal/base"
 	//lint:ignore ST1001 ignore dot imports warning
 	. "github.com/poppolopoppo/ppb/utils"
@@ -17,14 +21,18 @@ func InitCompile() {
 
 	base.RegisterSerializable[BuildConfig]()
 	base.RegisterSerializable[BuildGenerated]()
+	base.RegisterSerializable[BuildInfoGeneratedHeader]()
+	base.RegisterSerializable[BuildInfoGenerator]()
 	base.RegisterSerializable[CompilationDatabaseBuilder]()
 	base.RegisterSerializable[CompileEnv]()
 	base.RegisterSerializable[CompilerAlias]()
+	base.RegisterSerializable[CompilerProbeResult]()
 	base.RegisterSerializable[CompilerRules]()
 	base.RegisterSerializable[ConfigRules]()
 	base.RegisterSerializable[ConfigurationAlias]()
 	base.RegisterSerializable[CustomUnit]()
 	base.RegisterSerializable[EnvironmentAlias]()
+	base.RegisterSerializable[EnvironmentIncludePaths]()
 	base.RegisterSerializable[Facet]()
 	base.RegisterSerializable[GeneratorRules]()
 	base.RegisterSerializable[ModuleAlias]()
@@ -32,17 +40,26 @@ func InitCompile() {
 	base.RegisterSerializable[NamespaceRules]()
 	base.RegisterSerializable[PlatformAlias]()
 	base.RegisterSerializable[PlatformRules]()
+	base.RegisterSerializable[SharedHeaderUnit]()
+	base.RegisterSerializable[SourceFileActions]()
 	base.RegisterSerializable[TargetActions]()
 	base.RegisterSerializable[TargetAlias]()
 	base.RegisterSerializable[TargetPayload]()
 	base.RegisterSerializable[Unit]()
 	base.RegisterSerializable[UnityFile]()
+	base.RegisterSerializable[UnityLayout]()
 
 	AllConfigurations.Add("Debug", Configuration_Debug)
 	AllConfigurations.Add("FastDebug", Configuration_FastDebug)
 	AllConfigurations.Add("Devel", Configuration_Devel)
 	AllConfigurations.Add("Test", Configuration_Test)
 	AllConfigurations.Add("Shipping", Configuration_Shipping)
+
+	AllFlagProfiles.Add(FlagProfile_Strict.ProfileName, FlagProfile_Strict)
+	AllFlagProfiles.Add(FlagProfile_Release.ProfileName, FlagProfile_Release)
+
+	AllModuleValidations.Add("ModuleType", validateModuleTypeLink)
+	AllModuleValidations.Add("PCH", validateModulePrecompiledHeader)
 }
 
 var AllCompilationFlags []struct {
@@ -97,34 +114,45 @@ func OptionCommandAllCompilationFlags() CommandOptionFunc {
 type CompileFlags CppRules
 
 var GetCompileFlags = NewCompilationFlags("GenericCompilation", "cross-platform compilation flags", CompileFlags{
-	AdaptiveUnity:   base.INHERITABLE_TRUE,
-	Benchmark:       base.INHERITABLE_FALSE,
-	CompilerVerbose: base.INHERITABLE_FALSE,
-	CppRtti:         CPPRTTI_INHERIT,
-	CppStd:          CPPSTD_INHERIT,
-	DebugFastLink:   base.INHERITABLE_FALSE,
-	DebugInfo:       DEBUGINFO_INHERIT,
-	Deterministic:   base.INHERITABLE_TRUE,
-	Exceptions:      EXCEPTION_INHERIT,
-	Incremental:     base.INHERITABLE_INHERIT,
-	Instructions:    base.NewEnumSet(INSTRUCTIONSET_AVX2, INSTRUCTIONSET_SSE3),
-	Link:            LINK_INHERIT,
-	LinkerVerbose:   base.INHERITABLE_FALSE,
-	LTO:             base.INHERITABLE_INHERIT,
-	Optimize:        OPTIMIZE_INHERIT,
-	PCH:             PCH_INHERIT,
-	RuntimeChecks:   base.INHERITABLE_INHERIT,
-	RuntimeLib:      RUNTIMELIB_INHERIT,
-	Sanitizer:       SANITIZER_NONE,
-	SizePerUnity:    150 * 1024.0, // 150 KiB
-	Unity:           UNITY_INHERIT,
+	AdaptiveUnity:          base.INHERITABLE_TRUE,
+	Benchmark:              base.INHERITABLE_FALSE,
+	BuildInfo:              base.INHERITABLE_FALSE,
+	CompilerVerbose:        base.INHERITABLE_FALSE,
+	CppRtti:                CPPRTTI_INHERIT,
+	CppStd:                 CPPSTD_INHERIT,
+	DebugFastLink:          base.INHERITABLE_FALSE,
+	DebugInfo:              DEBUGINFO_INHERIT,
+	Deterministic:          base.INHERITABLE_TRUE,
+	Exceptions:             EXCEPTION_INHERIT,
+	ExcludeSystemHeaders:   base.INHERITABLE_INHERIT,
+	FloatModel:             FLOATMODEL_INHERIT,
+	FunctionLevelLinking:   base.INHERITABLE_INHERIT,
+	HeaderUnitCache:        base.INHERITABLE_FALSE,
+	IdenticalComdatFolding: ICF_INHERIT,
+	Incremental:            INCREMENTAL_AUTO,
+	Instructions:           base.NewEnumSet(INSTRUCTIONSET_AVX2, INSTRUCTIONSET_SSE3),
+	Link:                   LINK_INHERIT,
+	LinkerVerbose:          base.INHERITABLE_FALSE,
+	LTO:                    base.INHERITABLE_INHERIT,
+	Optimize:               OPTIMIZE_INHERIT,
+	PCH:                    PCH_INHERIT,
+	PostLink:               PostLinkActionNames{},
+	RuntimeChecks:          base.INHERITABLE_INHERIT,
+	RuntimeLib:             RUNTIMELIB_INHERIT,
+	Sanitizer:              SANITIZER_NONE,
+	SizePerUnity:           150 * 1024.0, // 150 KiB
+	StableUnity:            base.INHERITABLE_FALSE,
+	Unity:                  UNITY_INHERIT,
 	Warnings: CppWarnings{
-		Default:        WARNING_ERROR,
-		Deprecation:    WARNING_ERROR,
-		Pedantic:       WARNING_ERROR,
-		ShadowVariable: WARNING_ERROR,
-		UndefinedMacro: WARNING_ERROR,
-		UnsafeTypeCast: WARNING_ERROR,
+		Default:          WARNING_ERROR,
+		Deprecation:      WARNING_ERROR,
+		Pedantic:         WARNING_ERROR,
+		PrivateInclude:   WARNING_DISABLED,
+		ShadowVariable:   WARNING_ERROR,
+		UndefinedMacro:   WARNING_ERROR,
+		UnsafeTypeCast:   WARNING_ERROR,
+		UnmanagedLibrary: WARNING_WARN,
+		Overrides:        WarningOverrides{},
 	},
 })
 
@@ -132,6 +160,7 @@ func (flags *CompileFlags) GetCpp() *CppRules { return (*CppRules)(flags) }
 func (flags *CompileFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Persistent("AdaptiveUnity", "exclude sources from unity when locally modified (requires source control)", &flags.AdaptiveUnity)
 	cfv.Persistent("Benchmark", "enable/disable compilation benchmarks", &flags.Benchmark)
+	cfv.Persistent("BuildInfo", "generate "+BUILDINFO_GENERATED_HEADER+" in executables and shared libraries, with ppb version, build seed and timestamp", &flags.BuildInfo)
 	cfv.Persistent("CompilerVerbose", "enable/disable compiler verbose output", &flags.CompilerVerbose)
 	cfv.Persistent("CppRtti", "override C++ rtti support", &flags.CppRtti)
 	cfv.Persistent("CppStd", "override C++ standard", &flags.CppStd)
@@ -139,21 +168,407 @@ func (flags *CompileFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Persistent("DebugInfo", "override debug symbols mode", &flags.DebugInfo)
 	cfv.Persistent("Deterministic", "enable/disable deterministic compilation output", &flags.Deterministic)
 	cfv.Persistent("Exceptions", "override exceptions mode", &flags.Exceptions)
+	cfv.Persistent("ExcludeSystemHeaders", "do not track headers found in system/extern include paths as dependencies, so SDK updates do not trigger rebuilds", &flags.ExcludeSystemHeaders)
+	cfv.Persistent("FloatModel", "override floating-point model", &flags.FloatModel)
+	cfv.Persistent("FunctionLevelLinking", "override packaging of each function in its own COMDAT, required to remove or fold unreferenced functions", &flags.FunctionLevelLinking)
+	cfv.Persistent("HeaderUnitCache", "share identical header units between modules when using PCH_HEADERUNIT, keyed by header and compilation flags", &flags.HeaderUnitCache)
+	cfv.Persistent("IdenticalComdatFolding", "override linker folding of identical functions and data, to disable it for code relying on distinct function addresses", &flags.IdenticalComdatFolding)
 	cfv.Persistent("Instructions", "enable/disable CPU instruction sets", &flags.Instructions)
-	cfv.Persistent("Incremental", "enable/disable incremental linker", &flags.Incremental)
+	cfv.Persistent("Incremental", "override incremental linker with on|off|auto, takes precedence over module and configuration settings", &flags.Incremental)
 	cfv.Persistent("Link", "override link type", &flags.Link)
 	cfv.Persistent("LinkerVerbose", "enable/disable linker verbose output", &flags.LinkerVerbose)
 	cfv.Persistent("LTO", "enable/disable link time optimization", &flags.LTO)
 	cfv.Persistent("Optimize", "override compiler optimization level", &flags.Optimize)
 	cfv.Persistent("PCH", "override size limit for splitting unity files", &flags.PCH)
+	cfv.Persistent("PostLink", "comma-separated list of post-link actions applied to executables and shared libraries, ex: strip,copy=Deploy", &flags.PostLink)
 	cfv.Persistent("RuntimeChecks", "enable/disable runtime security checks", &flags.RuntimeChecks)
 	cfv.Persistent("RuntimeLib", "override runtime library selection", &flags.RuntimeLib)
 	cfv.Persistent("Sanitizer", "override sanitizer mode", &flags.Sanitizer)
 	cfv.Persistent("SizePerUnity", "size limit for splitting unity files", &flags.SizePerUnity)
+	cfv.Persistent("StableUnity", "assign sources to unity files by hashing their path, so adding or removing a file only rebuilds the unity file containing it", &flags.StableUnity)
 	cfv.Persistent("Unity", "override unity build mode", &flags.Unity)
 	cfv.Persistent("Warning", "override default warning level", &flags.Warnings.Default)
 	cfv.Persistent("Warning:Deprecation", "override deprecation warning level", &flags.Warnings.Deprecation)
+	cfv.Persistent("Warning:PrivateInclude", "check after build that units do not include headers from private directory of another module", &flags.Warnings.PrivateInclude)
 	cfv.Persistent("Warning:ShadowVariable", "override shadow variable warning level", &flags.Warnings.ShadowVariable)
 	cfv.Persistent("Warning:UndefinedMacro", "override undefined macro identifier warning level", &flags.Warnings.UndefinedMacro)
 	cfv.Persistent("Warning:UnsafeTypeCast", "override unsafe type cast warning level", &flags.Warnings.UnsafeTypeCast)
+	cfv.Persistent("Warning:UnmanagedLibrary", "check before build that linker options do not reference libraries which are not provided by declared dependencies", &flags.Warnings.UnmanagedLibrary)
+	cfv.Persistent("Warning:Overrides", "comma-separated list of specific compiler warnings with their level, applied after compiler defaults, ex: 4100=DISABLED,4189=ERROR", &flags.Warnings.Overrides)
+	cfv.Persistent("WarningsAsErrors", "override promotion of warnings to errors, modules can still opt out individually", &flags.WarningsAsErrors)
+}
+
+/***************************************
+ * Strict Warnings Flags
+ ***************************************/
+
+// StrictWarningsFlags are kept apart from CompileFlags, since CppRules.WarningsAsErrors can still be overriden by
+// modules: ForceWarningsAsErrors is applied on every unit after all inheritance layers were resolved.
+type StrictWarningsFlags struct {
+	ForceWarningsAsErrors BoolVar
+}
+
+var GetStrictWarningsFlags = NewCompilationFlags("StrictWarnings", "strict warnings policy, for continuous integration", StrictWarningsFlags{
+	ForceWarningsAsErrors: base.INHERITABLE_FALSE,
+})
+
+func (flags *StrictWarningsFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("ForceWarningsAsErrors", "treat warnings as errors on every unit, even for modules which opted out with WarningsAsErrors", &flags.ForceWarningsAsErrors)
+}
+
+// GetNumForcedWarningsAsErrors counts modules of targets where ForceWarningsAsErrors overrode module settings, a module
+// is only counted once even if it is compiled for several environments
+func GetNumForcedWarningsAsErrors(bg BuildGraphReadPort, targets ...*TargetActions) (int, error) {
+	modules := base.NewSet[ModuleAlias]()
+	for _, it := range targets {
+		unit, err := FindBuildUnit(bg, it.TargetAlias)
+		if err != nil {
+			return 0, err
+		}
+		if unit.ForcedWarningsAsErrors {
+			modules.AppendUniq(unit.TargetAlias.ModuleAlias)
+		}
+	}
+	return len(modules), nil
+}
+
+/***************************************
+ * Debug Info Selection Flags
+ ***************************************/
+
+// DebugInfoSelectionFlags restricts debug symbols to modules being debugged: selected units are built with
+// DEBUGINFO_SYMBOLS and every other unit with DEBUGINFO_DISABLED, overriding environment settings.
+type DebugInfoSelectionFlags struct {
+	DebugModules StringVar
+	DebugLabel   StringVar
+}
+
+var GetDebugInfoSelectionFlags = NewCompilationFlags("DebugInfoSelection", "restrict debug symbols to a subset of modules", DebugInfoSelectionFlags{})
+
+func (flags *DebugInfoSelectionFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("DebugModules", "comma-separated list of modules built with debug symbols, accepts wildcards (ex: Runtime/Core,Tools/*)", &flags.DebugModules)
+	cfv.Persistent("DebugLabel", "label expression selecting modules built with debug symbols (ex: tools&!tests)", &flags.DebugLabel)
+}
+
+// Resolve returns DEBUGINFO_INHERIT when no selection was specified
+func (flags *DebugInfoSelectionFlags) Resolve(module *ModuleRules) (DebugInfoType, error) {
+	if flags.DebugModules.Empty() && flags.DebugLabel.Empty() {
+		return DEBUGINFO_INHERIT, nil
+	}
+
+	for _, pattern := range strings.Split(flags.DebugModules.Get(), ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) == 0 {
+			continue
+		}
+		if matched, err := path.Match(pattern, module.ModuleAlias.String()); err != nil {
+			return DEBUGINFO_INHERIT, fmt.Errorf("invalid DebugModules pattern %q: %w", pattern, err)
+		} else if matched {
+			return DEBUGINFO_SYMBOLS, nil
+		}
+	}
+
+	if !flags.DebugLabel.Empty() {
+		expr, err := ParseLabelExpression(flags.DebugLabel.Get())
+		if err != nil {
+			return DEBUGINFO_INHERIT, err
+		}
+		if expr.Match(module.Labels) {
+			return DEBUGINFO_SYMBOLS, nil
+		}
+	}
+
+	return DEBUGINFO_DISABLED, nil
+}
+
+/***************************************
+ * Global Force Include Flags
+ ***************************************/
+
+// GlobalForceIncludeFlags inject project-wide headers in every translation unit of selected modules and environments,
+// prepended to unit force-includes. Precompiled header is always force-included first, since compilers require it, then
+// global force-includes come before platform and module ones, so build-wide macros are defined when those are parsed.
+// Headers included by the precompiled header are compiled before global force-includes and won't see their macros.
+type GlobalForceIncludeFlags struct {
+	ForceInclude             FileSet
+	ForceIncludeModules      StringVar
+	ForceIncludeEnvironments StringVar
+}
+
+var GetGlobalForceIncludeFlags = NewCompilationFlags("GlobalForceInclude", "force-include project-wide headers in every translation unit", GlobalForceIncludeFlags{})
+
+func (flags *GlobalForceIncludeFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("ForceInclude", "comma-separated list of headers force-included in every translation unit (relative paths are resolved from root directory)", &flags.ForceInclude)
+	cfv.Persistent("ForceIncludeModules", "comma-separated list of modules receiving global force-includes, accepts wildcards (default: all modules, ex: Runtime/*)", &flags.ForceIncludeModules)
+	cfv.Persistent("ForceIncludeEnvironments", "comma-separated list of environments receiving global force-includes, accepts wildcards (default: all environments, ex: Win64-*)", &flags.ForceIncludeEnvironments)
+}
+
+// Resolve returns headers to force-include in given unit, which are empty when unit was not selected
+func (flags *GlobalForceIncludeFlags) Resolve(unit *Unit) (FileSet, error) {
+	if len(flags.ForceInclude) == 0 {
+		return FileSet{}, nil
+	}
+
+	if selected, err := matchGlobalForceIncludeSelection("ForceIncludeModules", flags.ForceIncludeModules, unit.TargetAlias.ModuleAlias.String()); !selected || err != nil {
+		return FileSet{}, err
+	}
+	if selected, err := matchGlobalForceIncludeSelection("ForceIncludeEnvironments", flags.ForceIncludeEnvironments, unit.TargetAlias.EnvironmentAlias.String()); !selected || err != nil {
+		return FileSet{}, err
+	}
+
+	for _, header := range flags.ForceInclude {
+		if !header.Exists() {
+			return FileSet{}, fmt.Errorf("%v: global force-included header %q does not exist", unit, header)
+		}
+	}
+	return flags.ForceInclude, nil
+}
+
+func matchGlobalForceIncludeSelection(flagName string, patterns StringVar, name string) (bool, error) {
+	if patterns.Empty() {
+		return true, nil
+	}
+	for _, pattern := range strings.Split(patterns.Get(), ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) == 0 {
+			continue
+		}
+		if matched, err := path.Match(pattern, name); err != nil {
+			return false, fmt.Errorf("invalid %s pattern %q: %w", flagName, pattern, err)
+		} else if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/***************************************
+ * Action Priority Flags
+ ***************************************/
+
+// ActionPriorityFlags select modules whose actions are queued before others, to reduce latency while iterating on them.
+// Priority is given when launching the build and is not part of action fingerprints, so changing it never invalidates actions.
+type ActionPriorityFlags struct {
+	PriorityModules StringVar
+}
+
+var GetActionPriorityFlags = NewCompilationFlags("ActionPriority", "queue actions of selected modules before others", ActionPriorityFlags{})
+
+func (flags *ActionPriorityFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("PriorityModules", "comma-separated list of modules whose actions are queued before others, accepts wildcards (ex: Runtime/Core,Tools/*)", &flags.PriorityModules)
+}
+
+// IsHighPriority returns true when module opted in with HighPriority, or was selected from command-line
+func (flags *ActionPriorityFlags) IsHighPriority(module *ModuleRules) (bool, error) {
+	if module.HighPriority.Get() {
+		return true, nil
+	}
+
+	for _, pattern := range strings.Split(flags.PriorityModules.Get(), ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) == 0 {
+			continue
+		}
+		if matched, err := path.Match(pattern, module.ModuleAlias.String()); err != nil {
+			return false, fmt.Errorf("invalid PriorityModules pattern %q: %w", pattern, err)
+		} else if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SelectHighPriorityTargets returns given targets and their link/runtime dependencies which have a high priority
+func SelectHighPriorityTargets(bg BuildGraphWritePort, targets ...TargetAlias) (TargetAliases, error) {
+	flags, err := GetActionPriorityFlags(bg.GlobalContext())
+	if err != nil {
+		return nil, err
+	}
+
+	var results TargetAliases
+	visiteds := make(map[TargetAlias]bool)
+
+	queue := base.CopySlice(targets...)
+	for len(queue) > 0 {
+		targetAlias := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if _, ok := visiteds[targetAlias]; ok {
+			continue
+		}
+		visiteds[targetAlias] = true
+
+		unit, err := FindBuildUnit(bg, targetAlias)
+		if err != nil {
+			return nil, err
+		}
+		module, err := FindBuildModule(bg, targetAlias.ModuleAlias)
+		if err != nil {
+			return nil, err
+		}
+
+		if highPriority, err := flags.IsHighPriority(module.GetModule()); err != nil {
+			return nil, err
+		} else if highPriority {
+			results.Append(targetAlias)
+		}
+
+		queue = append(queue, unit.LinkDependencies...)
+		queue = append(queue, unit.RuntimeDependencies...)
+	}
+
+	results.Sort(func(a, b TargetAlias) bool {
+		return a.Compare(b) < 0
+	})
+	return results, nil
+}
+
+/***************************************
+ * Platform Defines Flags
+ ***************************************/
+
+// PlatformDefinesFlags extend base definitions of each platform, see GetPlatformDefinitions
+type PlatformDefinesFlags struct {
+	PlatformDefines StringVar
+}
+
+var GetPlatformDefinesFlags = NewCompilationFlags("PlatformDefines", "extend base defines contributed by platforms to every unit", PlatformDefinesFlags{})
+
+func (flags *PlatformDefinesFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("PlatformDefines", "comma-separated list of <platform>:<define> added to every unit of matching platforms, accepts wildcards (ex: Win*:USE_D3D12,Linux64:USE_VULKAN=1)", &flags.PlatformDefines)
+}
+
+/***************************************
+ * Compiler Path Flags
+ ***************************************/
+
+// CompilerPathFlags pin toolchain executables to explicit paths, bypassing their detection. Each entry is keyed by
+// compiler, matched with wildcards against compiler alias or name, so a pin never leaks to another toolchain (ex:
+// -CompilerPath=VisualStudio=C:/VC/bin/cl.exe,llvm=/opt/llvm/bin/clang++). Pinned paths are baked in CompilerRules,
+// which are serialized with the compiler: changing them invalidates dependent actions.
+type CompilerPathFlags struct {
+	CompilerPath  StringVar
+	LinkerPath    StringVar
+	LibrarianPath StringVar
+}
+
+var GetCompilerPathFlags = NewCompilationFlags("CompilerPath", "pin toolchain executables to explicit paths, bypassing detection", CompilerPathFlags{})
+
+func (flags *CompilerPathFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("CompilerPath", "comma-separated list of <compiler>=<path> compiler executables used instead of the ones found by toolchain detection, accepts wildcards (ex: VisualStudio=C:/VC/bin/cl.exe)", &flags.CompilerPath)
+	cfv.Persistent("LinkerPath", "comma-separated list of <compiler>=<path> linker executables used instead of the ones found by toolchain detection, accepts wildcards", &flags.LinkerPath)
+	cfv.Persistent("LibrarianPath", "comma-separated list of <compiler>=<path> librarian executables used instead of the ones found by toolchain detection, accepts wildcards", &flags.LibrarianPath)
+}
+
+// CompilerPathPins holds executables pinned for a compiler, invalid when not pinned
+type CompilerPathPins struct {
+	Compiler  Filename
+	Linker    Filename
+	Librarian Filename
+}
+
+// GetCompilerPathPins returns executables pinned for given compiler, which should be resolved before detection
+// so compilers can skip looking for them. Pinned files are tracked as dependencies of the compiler.
+func GetCompilerPathPins(bc BuildContext, compilerAlias CompilerAlias) (pins CompilerPathPins, err error) {
+	flags, err := GetCompilerPathFlags(bc)
+	if err != nil {
+		return
+	}
+
+	for _, it := range []struct {
+		Name   string
+		Flag   StringVar
+		Pinned *Filename
+	}{
+		{"CompilerPath", flags.CompilerPath, &pins.Compiler},
+		{"LinkerPath", flags.LinkerPath, &pins.Linker},
+		{"LibrarianPath", flags.LibrarianPath, &pins.Librarian},
+	} {
+		if *it.Pinned, err = findCompilerPathPin(it.Name, it.Flag.Get(), compilerAlias); err != nil {
+			return
+		}
+		if !it.Pinned.Valid() {
+			continue
+		}
+		if !it.Pinned.Exists() {
+			err = fmt.Errorf("%v: pinned %s %q does not exist", compilerAlias, it.Name, *it.Pinned)
+			return
+		}
+		if err = bc.NeedFiles(*it.Pinned); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// first entry matching compiler alias or compiler name wins
+func findCompilerPathPin(name, in string, compilerAlias CompilerAlias) (Filename, error) {
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) == 0 {
+			continue
+		}
+
+		pattern, pinned, ok := strings.Cut(it, "=")
+		if !ok || len(pattern) == 0 || len(pinned) == 0 {
+			return Filename{}, fmt.Errorf("invalid %s entry %q, expected <compiler>=<path>", name, it)
+		}
+		for _, key := range []string{compilerAlias.String(), compilerAlias.CompilerName} {
+			if matched, err := path.Match(pattern, key); err != nil {
+				return Filename{}, fmt.Errorf("invalid %s pattern %q: %w", name, pattern, err)
+			} else if matched {
+				return MakeFilename(pinned).Normalize(), nil
+			}
+		}
+	}
+	return Filename{}, nil
+}
+
+// Apply overrides executables of given rules with pinned paths, should be called by compilers after detected
+// executables were assigned
+func (pins CompilerPathPins) Apply(rules *CompilerRules) {
+	for _, it := range []struct {
+		Name       string
+		Pinned     Filename
+		Executable *Filename
+	}{
+		{"compiler", pins.Compiler, &rules.Executable},
+		{"linker", pins.Linker, &rules.Linker},
+		{"librarian", pins.Librarian, &rules.Librarian},
+	} {
+		if it.Pinned.Valid() {
+			base.LogVerbose(LogCompile, "%v: use pinned %s %q instead of %q", rules.CompilerAlias, it.Name, it.Pinned, *it.Executable)
+			*it.Executable = it.Pinned
+		}
+	}
+}
+
+/***************************************
+ * Source Roots Flags
+ ***************************************/
+
+// SourceRootsFlags declare root directories outside of UFS.Source where modules can also be found: each
+// root holds its own namespace file, named like the root one, whose children and modules are merged in the
+// root namespace
+type SourceRootsFlags struct {
+	SourceRoots DirSet
+}
+
+var GetSourceRootsFlags = NewCompilationFlags("SourceRoots", "declare additional source roots, where modules can live outside of primary source tree", SourceRootsFlags{})
+
+func (flags *SourceRootsFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("SourceRoots", "comma separated list of additional source directories, each with its own namespace file (relative paths are resolved from root directory)", &flags.SourceRoots)
+}
+
+// GetAllSourceRoots returns primary source directory followed by additional source roots
+func (flags *SourceRootsFlags) GetAllSourceRoots() DirSet {
+	return NewDirSet(UFS.Source).ConcatUniq(flags.SourceRoots.Normalize()...)
+}
+
+// GetSourceRoot returns the innermost source root containing given directory, or primary source directory
+func (flags *SourceRootsFlags) GetSourceRoot(dir Directory) (result Directory) {
+	result = UFS.Source
+	for _, root := range flags.SourceRoots {
+		root = root.Normalize()
+		if (dir.Equals(root) || dir.IsIn(root)) && (!(dir.Equals(result) || dir.IsIn(result)) || root.IsIn(result)) {
+			result = root
+		}
+	}
+	return
 }
@@ -0,0 +1,292 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Translation Unit Timings
+ ***************************************/
+
+// TranslationUnitTiming records how long compiling a source file took during last build
+type TranslationUnitTiming struct {
+	TargetAlias TargetAlias
+	SourceFile  Filename
+	ObjectFile  Filename
+	Duration    time.Duration
+}
+
+// ForeachTranslationUnit visits every object action of given targets, with the source file it compiles
+func ForeachTranslationUnit(bg BuildGraphReadPort, each func(TargetAlias, Filename, *action.ActionRules) error, targets ...*TargetActions) error {
+	for _, ta := range targets {
+		if !ta.PresentPayloads.Has(PAYLOAD_OBJECTLIST) {
+			continue
+		}
+
+		payload, err := ta.GetPayload(bg, PAYLOAD_OBJECTLIST)
+		if err != nil {
+			return err
+		}
+
+		actions, err := payload.GetActions(bg)
+		if err != nil {
+			return err
+		}
+
+		for _, it := range actions {
+			rules := it.GetAction()
+			inputFiles := rules.GetStaticInputFiles(bg)
+			if len(inputFiles) == 0 {
+				continue
+			}
+			if err := each(ta.TargetAlias, inputFiles[0], rules); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FindSlowestTranslationUnits returns the n slowest compile actions executed by this build port,
+// using build stats recorded for each action node (up-to-date actions are ignored).
+func FindSlowestTranslationUnits(bg BuildGraphWritePort, n int, targets ...*TargetActions) (results []TranslationUnitTiming, err error) {
+	err = ForeachTranslationUnit(bg, func(ta TargetAlias, source Filename, rules *action.ActionRules) error {
+		node, err := bg.Expect(rules.Alias())
+		if err != nil {
+			return err
+		}
+		if stats, ok := bg.GetBuildStats(node); ok && stats.Duration.Exclusive > 0 {
+			results = append(results, TranslationUnitTiming{
+				TargetAlias: ta,
+				SourceFile:  source,
+				ObjectFile:  rules.GetGeneratedFile(),
+				Duration:    stats.Duration.Exclusive,
+			})
+		}
+		return nil
+	}, targets...)
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Duration > results[j].Duration
+	})
+	if n > 0 && len(results) > n {
+		results = results[:n]
+	}
+	return
+}
+
+// ReportSlowestTranslationUnits prints the n slowest translation units compiled by last build
+func ReportSlowestTranslationUnits(bg BuildGraphWritePort, n int, targets ...*TargetActions) error {
+	timings, err := FindSlowestTranslationUnits(bg, n, targets...)
+	if err != nil || len(timings) == 0 {
+		return err
+	}
+
+	base.LogForwardf("\nSlowest %d translation units compiled:", len(timings))
+	for i, it := range timings {
+		base.LogForwardf("%v[%02d]%v %7.3f  %v  %v(%v)%v", base.ANSI_FAINT, i+1, base.ANSI_RESET,
+			it.Duration.Seconds(), it.SourceFile.Relative(UFS.Root),
+			base.ANSI_FAINT, it.TargetAlias, base.ANSI_RESET)
+	}
+	return nil
+}
+
+/***************************************
+ * Precompiled Header Usage
+ ***************************************/
+
+// PrecompiledHeaderUsage summarizes how much a precompiled header is worth for a target
+type PrecompiledHeaderUsage struct {
+	TargetAlias       TargetAlias
+	PrecompiledHeader Filename
+	Size              int64
+	NumTranslations   int
+	// Duration of PCH compilation, only known when it was compiled by last build
+	Duration time.Duration
+}
+
+// EstimatedSavings assumes every translation unit would have parsed the header again without PCH
+func (x PrecompiledHeaderUsage) EstimatedSavings() time.Duration {
+	if x.NumTranslations <= 1 {
+		return 0
+	}
+	return x.Duration * time.Duration(x.NumTranslations-1)
+}
+
+// SizePerTranslation is the ranking criteria: bloated headers used by few translation units should be trimmed first
+func (x PrecompiledHeaderUsage) SizePerTranslation() int64 {
+	if x.NumTranslations == 0 {
+		return x.Size
+	}
+	return x.Size / int64(x.NumTranslations)
+}
+
+// FindPrecompiledHeaderUsages returns usage of every monolithic PCH compiled by given targets, ranked from worst to best
+func FindPrecompiledHeaderUsages(bg BuildGraphWritePort, targets ...*TargetActions) (results []PrecompiledHeaderUsage, err error) {
+	for _, ta := range targets {
+		if !ta.PresentPayloads.Has(PAYLOAD_PRECOMPILEDHEADER) {
+			continue
+		}
+
+		var unit *Unit
+		if unit, err = FindBuildUnit(bg, ta.TargetAlias); err != nil {
+			return
+		}
+
+		usage := PrecompiledHeaderUsage{
+			TargetAlias:       ta.TargetAlias,
+			PrecompiledHeader: unit.PrecompiledHeader,
+		}
+		if info, err := unit.PrecompiledObject.Info(); err == nil {
+			usage.Size = info.Size()
+		}
+
+		var payload *TargetPayload
+		if payload, err = ta.GetPayload(bg, PAYLOAD_PRECOMPILEDHEADER); err != nil {
+			return
+		}
+		var actions action.ActionSet
+		if actions, err = payload.GetActions(bg); err != nil {
+			return
+		}
+		for _, it := range actions {
+			node, err := bg.Expect(it.GetAction().Alias())
+			if err != nil {
+				return nil, err
+			}
+			if stats, ok := bg.GetBuildStats(node); ok {
+				usage.Duration += stats.Duration.Exclusive
+			}
+		}
+
+		if err = ForeachTranslationUnit(bg, func(TargetAlias, Filename, *action.ActionRules) error {
+			usage.NumTranslations++
+			return nil
+		}, ta); err != nil {
+			return
+		}
+
+		results = append(results, usage)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SizePerTranslation() > results[j].SizePerTranslation()
+	})
+	return
+}
+
+// ReportPrecompiledHeaderUsages prints a ranked list of precompiled headers, worth trimming first
+func ReportPrecompiledHeaderUsages(bg BuildGraphWritePort, targets ...*TargetActions) error {
+	usages, err := FindPrecompiledHeaderUsages(bg, targets...)
+	if err != nil || len(usages) == 0 {
+		return err
+	}
+
+	base.LogForwardf("\nPrecompiled header usage, worth trimming first:")
+	base.LogForwardf("%v%4s %10s %6s %10s %10s  %s%v", base.ANSI_FAINT, "rank", "size", "TUs", "compile", "saved", "header", base.ANSI_RESET)
+	for i, it := range usages {
+		compileTime, saved := "-", "-"
+		if it.Duration > 0 { // PCH was up-to-date: can't estimate parse time saved
+			compileTime = fmt.Sprintf("%.3fs", it.Duration.Seconds())
+			saved = fmt.Sprintf("%.3fs", it.EstimatedSavings().Seconds())
+		}
+		base.LogForwardf("[%02d] %10v %6d %10s %10s  %v  %v(%v)%v", i+1,
+			base.SizeInBytes(it.Size), it.NumTranslations, compileTime, saved,
+			it.PrecompiledHeader.Relative(UFS.Root),
+			base.ANSI_FAINT, it.TargetAlias, base.ANSI_RESET)
+	}
+	return nil
+}
+
+/***************************************
+ * Clang Time Traces
+ ***************************************/
+
+// MergeTimeTraces collects json traces written by clang -ftime-trace next to each object file, and merges
+// them in a single chrome trace where each translation unit is shown as a separate process.
+func MergeTimeTraces(bg BuildGraphReadPort, output Filename, targets ...*TargetActions) error {
+	type timeTrace struct {
+		Source Filename
+		Trace  base.JsonMap
+	}
+
+	var traces []timeTrace
+	if err := ForeachTranslationUnit(bg, func(ta TargetAlias, source Filename, rules *action.ActionRules) error {
+		// clang replaces object extension with .json for its trace file
+		traceFile := rules.GetGeneratedFile().ReplaceExt(".json")
+		if !traceFile.Exists() {
+			return nil
+		}
+
+		trace := timeTrace{Source: source}
+		if err := UFS.OpenBuffered(traceFile, func(r io.Reader) error {
+			return base.JsonDeserialize(&trace.Trace, r)
+		}); err != nil {
+			return fmt.Errorf("time-trace: failed to parse %q: %w", traceFile, err)
+		}
+
+		traces = append(traces, trace)
+		return nil
+	}, targets...); err != nil {
+		return err
+	}
+
+	if len(traces) == 0 {
+		return fmt.Errorf("time-trace: no trace found, time traces are only generated by clang when compiling with -Benchmark")
+	}
+
+	// align all traces on the same timeline, since each starts at the beginning of its own translation unit
+	beginningOfTime := func(trace base.JsonMap) float64 {
+		if it, ok := trace["beginningOfTime"].(float64); ok {
+			return it
+		}
+		return 0
+	}
+	minBeginningOfTime := beginningOfTime(traces[0].Trace)
+	for _, it := range traces[1:] {
+		if t := beginningOfTime(it.Trace); t < minBeginningOfTime {
+			minBeginningOfTime = t
+		}
+	}
+
+	mergedEvents := []any{}
+	for pid, it := range traces {
+		offset := beginningOfTime(it.Trace) - minBeginningOfTime
+
+		mergedEvents = append(mergedEvents, base.JsonMap{
+			"ph":   "M",
+			"name": "process_name",
+			"pid":  pid + 1,
+			"tid":  0,
+			"args": base.JsonMap{"name": it.Source.Relative(UFS.Root)},
+		})
+
+		events, _ := it.Trace["traceEvents"].([]any)
+		for _, evt := range events {
+			if evt, ok := evt.(map[string]any); ok {
+				evt["pid"] = pid + 1
+				if ts, ok := evt["ts"].(float64); ok {
+					evt["ts"] = ts + offset
+				}
+				mergedEvents = append(mergedEvents, evt)
+			}
+		}
+	}
+
+	base.LogInfo(LogCompile, "merged %d time traces in %q", len(traces), output)
+	return UFS.CreateBuffered(output, func(w io.Writer) error {
+		return base.JsonSerialize(base.JsonMap{
+			"traceEvents":     mergedEvents,
+			"displayTimeUnit": "ms",
+		}, w)
+	}, base.TransientPage64KiB)
+}
@@ -2,6 +2,7 @@ package compile
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/poppolopoppo/ppb/action"
@@ -26,6 +27,37 @@ func (x CompilerName) Compare(o CompilerName) int {
 
 var AllCompilerNames = base.SetT[CompilerName]{}
 
+/***************************************
+ * Compiler Detection
+ ***************************************/
+
+// CompilerDetection describes a toolchain installed on host, as it would be selected when configuring
+type CompilerDetection struct {
+	CompilerAlias CompilerAlias
+	Version       string
+	Host          string
+	Target        string
+	Executable    Filename
+	Features      CompilerFeatureFlags
+}
+
+func NewCompilerDetection(compiler Compiler, version, host string) CompilerDetection {
+	rules := compiler.GetCompiler()
+	return CompilerDetection{
+		CompilerAlias: rules.CompilerAlias,
+		Version:       version,
+		Host:          host,
+		Target:        rules.CompilerAlias.CompilerVariant,
+		Executable:    rules.Executable,
+		Features:      rules.Features,
+	}
+}
+
+// CompilerDetector probes a toolchain targeting given architecture, it fails when toolchain is not installed
+type CompilerDetector func(bc BuildContext, arch ArchType) (CompilerDetection, error)
+
+var AllCompilerDetectors base.SharedMapT[string, CompilerDetector]
+
 /***************************************
  * Compiler Alias
  ***************************************/
@@ -104,6 +136,9 @@ type Compiler interface {
 	Link(f *Facet, link LinkType)
 	PrecompiledHeader(u *Unit)
 	Sanitizer(*Facet, SanitizerType)
+	// OptimizeFile returns compiler options of the unit with another optimization level, for a single source file
+	// compiled without the precompiled header of the unit (which can't be shared with other code generation flags)
+	OptimizeFile(u *Unit, level OptimizationLevel) base.StringSet
 
 	ForceInclude(*Facet, ...Filename)
 	IncludePath(*Facet, ...Directory)
@@ -111,6 +146,9 @@ type Compiler interface {
 	SystemIncludePath(*Facet, ...Directory)
 	Library(*Facet, ...string)
 	LibraryPath(*Facet, ...Directory)
+	Framework(*Facet, ...string)
+	FrameworkPath(*Facet, ...Directory)
+	ExportMap(*Facet, Filename)
 
 	GetPayloadOutput(*Unit, PayloadType, Filename) Filename
 	CreateAction(*Unit, PayloadType, *action.ActionModel) action.Action
@@ -125,6 +163,91 @@ type Compiler interface {
 	base.Serializable
 }
 
+/***************************************
+ * Payload Extnames
+ ***************************************/
+
+// PayloadExtnames overrides default output extensions of a compiler, ex: "SHAREDLIB=.so.1,EXECUTABLE="
+type PayloadExtnames map[PayloadType]StringVar
+
+func (x PayloadExtnames) IsInheritable() bool {
+	return len(x) == 0
+}
+func (x PayloadExtnames) Get(payload PayloadType) (string, bool) {
+	if extname, ok := x[payload]; ok {
+		return extname.Get(), true
+	}
+	return "", false
+}
+func (x PayloadExtnames) String() string {
+	payloads := make([]PayloadType, 0, len(x))
+	for payload := range x {
+		payloads = append(payloads, payload)
+	}
+	sort.Slice(payloads, func(i, j int) bool {
+		return payloads[i].Compare(payloads[j]) < 0
+	})
+
+	entries := make([]string, len(payloads))
+	for i, payload := range payloads {
+		entries[i] = fmt.Sprint(payload, "=", x[payload])
+	}
+	return strings.Join(entries, ",")
+}
+func (x *PayloadExtnames) Set(in string) error {
+	*x = make(PayloadExtnames)
+	if len(strings.TrimSpace(in)) == 0 {
+		return nil
+	}
+
+	for _, it := range strings.Split(in, ",") {
+		key, extname, ok := strings.Cut(strings.TrimSpace(it), "=")
+		if !ok {
+			return fmt.Errorf("invalid payload extname %q, expected PAYLOAD=.ext", it)
+		}
+
+		var payload PayloadType
+		if err := payload.Set(key); err != nil {
+			return err
+		}
+		(*x)[payload] = StringVar(extname)
+	}
+	return nil
+}
+func (x *PayloadExtnames) Serialize(ar base.Archive) {
+	base.SerializeMap(ar, (*map[PayloadType]StringVar)(x))
+}
+
+/***************************************
+ * Compiler Flag Conflicts
+ ***************************************/
+
+// CompilerFlagConflicts lists groups of mutually exclusive compiler options, ex: "/MD" and "/MT".
+// Units accumulate options from many sources (platform, configuration, modules, command-line...)
+// and can end up with contradictory ones, where the compiler would silently keep the last one.
+type CompilerFlagConflicts []base.StringSet
+
+func (x *CompilerFlagConflicts) Append(exclusives ...string) {
+	*x = append(*x, base.NewStringSet(exclusives...))
+}
+func (x CompilerFlagConflicts) Detect(options base.StringSet) (conflicts []base.StringSet) {
+	for _, exclusives := range x {
+		var founds base.StringSet
+		for _, it := range exclusives {
+			if options.Contains(it) {
+				founds.Append(it)
+			}
+		}
+		if len(founds) > 1 {
+			conflicts = append(conflicts, founds)
+		}
+	}
+	return
+}
+func (x *CompilerFlagConflicts) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]base.StringSet)(x))
+}
+
 /***************************************
  * Compiler Rules
  ***************************************/
@@ -142,6 +265,8 @@ type CompilerRules struct {
 
 	Environment internal_io.ProcessEnvironment
 	ExtraFiles  FileSet
+	Extnames    PayloadExtnames
+	Conflicts   CompilerFlagConflicts
 
 	Facet
 }
@@ -175,6 +300,8 @@ func (rules *CompilerRules) Serialize(ar base.Archive) {
 
 	ar.Serializable(&rules.Environment)
 	ar.Serializable(&rules.ExtraFiles)
+	ar.Serializable(&rules.Extnames)
+	ar.Serializable(&rules.Conflicts)
 
 	ar.Serializable(&rules.Facet)
 }
@@ -198,6 +325,10 @@ func (rules *CompilerRules) Decorate(bg BuildGraphReadPort, env *CompileEnv, uni
 		base.UnexpectedValuePanic(unit.PCH, unit.PCH)
 	}
 
+	// compilers clamp requested standard to what they support, see ReportCppStdDowngrades() for a summary
+	if supported := compiler.GetCompiler().CppStd; IsCppStdDowngraded(unit.CppStd, supported) {
+		base.LogWarningOnce(LogCompile, "%v: requested %v, but %v only supports up to %v", unit.TargetAlias.ModuleAlias, unit.CppStd, unit.CompilerAlias, supported)
+	}
 	compiler.CppStd(&unit.Facet, unit.CppStd)
 	compiler.CppRtti(&unit.Facet, unit.CppRtti == CPPRTTI_ENABLED)
 
@@ -216,5 +347,33 @@ func (rules *CompilerRules) Decorate(bg BuildGraphReadPort, env *CompileEnv, uni
 	compiler.LibraryPath(&unit.Facet, unit.Facet.LibraryPaths...)
 	compiler.Library(&unit.Facet, unit.Facet.Libraries...)
 
+	if len(unit.Facet.Frameworks) > 0 || len(unit.Facet.FrameworkPaths) > 0 {
+		if !rules.Features.Has(COMPILER_ALLOW_FRAMEWORKS) {
+			return fmt.Errorf("%v: frameworks %v are only supported by Apple toolchains, but unit is compiled by %v", unit, unit.Facet.Frameworks, rules.CompilerAlias)
+		}
+		compiler.FrameworkPath(&unit.Facet, unit.Facet.FrameworkPaths...)
+		compiler.Framework(&unit.Facet, unit.Facet.Frameworks...)
+	}
+
+	if unit.ExportMap.Valid() {
+		compiler.ExportMap(&unit.Facet, unit.ExportMap)
+	}
+
+	return rules.checkFlagConflicts(unit)
+}
+
+// checkFlagConflicts warns about mutually exclusive options, or fails when unit treats warnings as errors
+func (rules *CompilerRules) checkFlagConflicts(unit *Unit) error {
+	conflicts := rules.Conflicts.Detect(unit.CompilerOptions)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%v: conflicting compiler options %v", unit, conflicts)
+	if unit.GetWarningsAsErrors() {
+		return err
+	}
+
+	base.LogWarning(LogCompile, "%v", err)
 	return nil
 }
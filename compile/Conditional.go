@@ -0,0 +1,72 @@
+package compile
+
+import (
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Conditional Defines
+ ***************************************/
+
+// ConditionalDefine declares defines depending on properties resolved for each environment, so the same
+// module can be configured without duplicating define lists, ex:
+//
+//	"ConditionalDefines": [{ "Optimized": "true", "Defines": ["NDEBUG"], "Otherwise": ["_DEBUG"] }]
+//
+// Defines are added when all conditions set are matching, or Otherwise is added instead.
+type ConditionalDefine struct {
+	Optimized utils.BoolVar     // optimization level is not OPTIMIZE_NONE
+	Optimize  OptimizationLevel // exact optimization level
+	Sanitizer SanitizerType
+	Tags      TagFlags // every tag must be set
+
+	Defines   base.StringSet
+	Otherwise base.StringSet
+}
+
+func (x *ConditionalDefine) Serialize(ar base.Archive) {
+	ar.Serializable(&x.Optimized)
+	ar.Serializable(&x.Optimize)
+	ar.Serializable(&x.Sanitizer)
+	ar.Serializable(&x.Tags)
+
+	ar.Serializable(&x.Defines)
+	ar.Serializable(&x.Otherwise)
+}
+func (x *ConditionalDefine) Match(unit *Unit) bool {
+	if !x.Optimized.IsInheritable() && x.Optimized.Get() != unit.Optimize.IsEnabled() {
+		return false
+	}
+	if !x.Optimize.IsInheritable() && x.Optimize != unit.Optimize {
+		return false
+	}
+	if !x.Sanitizer.IsInheritable() && x.Sanitizer != unit.Sanitizer {
+		return false
+	}
+	return unit.Tags.Intersect(x.Tags) == x.Tags
+}
+
+type ConditionalDefines []ConditionalDefine
+
+func (list *ConditionalDefines) Append(it ...ConditionalDefine) {
+	*list = append(*list, it...)
+}
+func (list *ConditionalDefines) Prepend(it ...ConditionalDefine) {
+	*list = append(it, *list...)
+}
+func (list *ConditionalDefines) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]ConditionalDefine)(list))
+}
+
+// Evaluate should be called once unit properties are resolved for its environment
+func (list ConditionalDefines) Evaluate(unit *Unit) {
+	for _, it := range list {
+		if it.Match(unit) {
+			base.LogVeryVerbose(LogCompile, "%v: conditional defines matched: %v", unit, it.Defines)
+			unit.Defines.Append(it.Defines...)
+		} else {
+			unit.Defines.Append(it.Otherwise...)
+		}
+	}
+}
@@ -62,6 +62,12 @@ var AllConfigurations base.SharedMapT[string, Configuration]
 
 type ConfigRules struct {
 	ConfigurationAlias ConfigurationAlias
+	Platforms          base.StringSet // restrict environments to these platforms, all platforms when empty
+	Profiles           base.StringSet // named flag profiles, see AllFlagProfiles
+
+	// decorate names of outputs and static libraries, to copy artifacts of several environments in the same folder (ex: "_d")
+	ArtifactPrefix string
+	ArtifactSuffix string
 
 	CppRules
 	Facet
@@ -88,6 +94,10 @@ func (rules *ConfigRules) GetFacet() *Facet {
 }
 func (rules *ConfigRules) Serialize(ar base.Archive) {
 	ar.Serializable(&rules.ConfigurationAlias)
+	ar.Serializable(&rules.Platforms)
+	ar.Serializable(&rules.Profiles)
+	ar.String(&rules.ArtifactPrefix)
+	ar.String(&rules.ArtifactSuffix)
 	ar.Serializable(&rules.CppRules)
 	ar.Serializable(&rules.Facet)
 }
@@ -112,7 +122,7 @@ var Configuration_Debug = &ConfigRules{
 		DebugInfo:     DEBUGINFO_EMBEDDED,
 		DebugFastLink: base.INHERITABLE_INHERIT,
 		Exceptions:    EXCEPTION_ENABLED,
-		Incremental:   base.INHERITABLE_TRUE,
+		Incremental:   INCREMENTAL_ON,
 		Link:          LINK_STATIC,
 		LTO:           base.INHERITABLE_FALSE,
 		Optimize:      OPTIMIZE_NONE,
@@ -134,7 +144,7 @@ var Configuration_FastDebug = &ConfigRules{
 		DebugInfo:     DEBUGINFO_HOTRELOAD,
 		DebugFastLink: base.INHERITABLE_TRUE,
 		Exceptions:    EXCEPTION_ENABLED,
-		Incremental:   base.INHERITABLE_TRUE,
+		Incremental:   INCREMENTAL_ON,
 		Link:          LINK_DYNAMIC,
 		LTO:           base.INHERITABLE_FALSE,
 		Optimize:      OPTIMIZE_FOR_DEBUG,
@@ -156,7 +166,7 @@ var Configuration_Devel = &ConfigRules{
 		DebugInfo:     DEBUGINFO_EMBEDDED,
 		DebugFastLink: base.INHERITABLE_INHERIT,
 		Exceptions:    EXCEPTION_ENABLED,
-		Incremental:   base.INHERITABLE_INHERIT,
+		Incremental:   INCREMENTAL_AUTO,
 		Link:          LINK_STATIC,
 		LTO:           base.INHERITABLE_INHERIT,
 		Optimize:      OPTIMIZE_FOR_SIZE,
@@ -178,7 +188,7 @@ var Configuration_Test = &ConfigRules{
 		DebugInfo:     DEBUGINFO_EMBEDDED,
 		DebugFastLink: base.INHERITABLE_INHERIT,
 		Exceptions:    EXCEPTION_ENABLED,
-		Incremental:   base.INHERITABLE_INHERIT,
+		Incremental:   INCREMENTAL_AUTO,
 		Link:          LINK_STATIC,
 		LTO:           base.INHERITABLE_TRUE,
 		Optimize:      OPTIMIZE_FOR_SPEED,
@@ -201,7 +211,7 @@ var Configuration_Shipping = &ConfigRules{
 		DebugFastLink: base.INHERITABLE_FALSE,
 		Deterministic: base.INHERITABLE_TRUE,
 		Exceptions:    EXCEPTION_ENABLED,
-		Incremental:   base.INHERITABLE_INHERIT,
+		Incremental:   INCREMENTAL_AUTO,
 		Link:          LINK_STATIC,
 		LTO:           base.INHERITABLE_TRUE,
 		PCH:           PCH_MONOLITHIC,
@@ -9,9 +9,14 @@ type CppWarnings struct {
 	Default        WarningLevel
 	Deprecation    WarningLevel
 	Pedantic       WarningLevel
+	PrivateInclude WarningLevel
 	ShadowVariable WarningLevel
 	UndefinedMacro WarningLevel
 	UnsafeTypeCast WarningLevel
+	// UnmanagedLibrary is not a compiler warning: it is checked by ppb before linking
+	UnmanagedLibrary WarningLevel
+
+	Overrides WarningOverrides
 }
 
 type CppRules struct {
@@ -24,6 +29,7 @@ type CppRules struct {
 	CppRtti    CppRttiType
 	DebugInfo  DebugInfoType
 	Exceptions ExceptionType
+	FloatModel FloatModelType
 	Link       LinkType
 	Optimize   OptimizationLevel
 	PCH        PrecompiledHeaderType
@@ -31,14 +37,25 @@ type CppRules struct {
 	Sanitizer  SanitizerType
 	Unity      UnityType
 
+	PostLink PostLinkActionNames
+
 	AdaptiveUnity utils.BoolVar
+	StableUnity   utils.BoolVar
 	Benchmark     utils.BoolVar
+	BuildInfo     utils.BoolVar
 	Deterministic utils.BoolVar
 	DebugFastLink utils.BoolVar
-	Incremental   utils.BoolVar
+	Incremental   IncrementalLinkerType
 	LTO           utils.BoolVar
 	RuntimeChecks utils.BoolVar
 
+	FunctionLevelLinking   utils.BoolVar
+	IdenticalComdatFolding IdenticalComdatFoldingType
+
+	HeaderUnitCache      utils.BoolVar
+	WarningsAsErrors     utils.BoolVar
+	ExcludeSystemHeaders utils.BoolVar
+
 	CompilerVerbose utils.BoolVar
 	LinkerVerbose   utils.BoolVar
 }
@@ -51,6 +68,15 @@ type Cpp interface {
 func (rules *CppRules) GetCpp() *CppRules {
 	return rules
 }
+
+// WarningsAsErrors takes precedence over default warning level when explicitly set,
+// so a third-party module can opt out of warnings as errors without lowering its warning level
+func (rules *CppRules) GetWarningsAsErrors() bool {
+	if !rules.WarningsAsErrors.IsInheritable() {
+		return rules.WarningsAsErrors.Get()
+	}
+	return rules.Warnings.Default == WARNING_ERROR
+}
 func (rules *CppRules) DeepCopy(src *CppRules) {
 	*rules = *src
 }
@@ -61,14 +87,18 @@ func (rules *CppRules) Serialize(ar base.Archive) {
 	ar.Serializable(&rules.Warnings.Default)
 	ar.Serializable(&rules.Warnings.Deprecation)
 	ar.Serializable(&rules.Warnings.Pedantic)
+	ar.Serializable(&rules.Warnings.PrivateInclude)
 	ar.Serializable(&rules.Warnings.ShadowVariable)
 	ar.Serializable(&rules.Warnings.UndefinedMacro)
 	ar.Serializable(&rules.Warnings.UnsafeTypeCast)
+	ar.Serializable(&rules.Warnings.UnmanagedLibrary)
+	ar.Serializable(&rules.Warnings.Overrides)
 
 	ar.Serializable(&rules.CppStd)
 	ar.Serializable(&rules.CppRtti)
 	ar.Serializable(&rules.DebugInfo)
 	ar.Serializable(&rules.Exceptions)
+	ar.Serializable(&rules.FloatModel)
 	ar.Serializable(&rules.Link)
 	ar.Serializable(&rules.Optimize)
 	ar.Serializable(&rules.PCH)
@@ -76,14 +106,25 @@ func (rules *CppRules) Serialize(ar base.Archive) {
 	ar.Serializable(&rules.Sanitizer)
 	ar.Serializable(&rules.Unity)
 
+	ar.Serializable(&rules.PostLink)
+
 	ar.Serializable(&rules.AdaptiveUnity)
+	ar.Serializable(&rules.StableUnity)
 	ar.Serializable(&rules.Benchmark)
+	ar.Serializable(&rules.BuildInfo)
 	ar.Serializable(&rules.Deterministic)
 	ar.Serializable(&rules.DebugFastLink)
 	ar.Serializable(&rules.Incremental)
 	ar.Serializable(&rules.LTO)
 	ar.Serializable(&rules.RuntimeChecks)
 
+	ar.Serializable(&rules.FunctionLevelLinking)
+	ar.Serializable(&rules.IdenticalComdatFolding)
+
+	ar.Serializable(&rules.HeaderUnitCache)
+	ar.Serializable(&rules.WarningsAsErrors)
+	ar.Serializable(&rules.ExcludeSystemHeaders)
+
 	ar.Serializable(&rules.CompilerVerbose)
 	ar.Serializable(&rules.LinkerVerbose)
 }
@@ -92,6 +133,7 @@ func (rules *CppRules) Inherit(other *CppRules) {
 	base.Inherit(&rules.CppRtti, other.CppRtti)
 	base.Inherit(&rules.DebugInfo, other.DebugInfo)
 	base.Inherit(&rules.Exceptions, other.Exceptions)
+	base.Inherit(&rules.FloatModel, other.FloatModel)
 	base.Inherit(&rules.Instructions, other.Instructions)
 	base.Inherit(&rules.PCH, other.PCH)
 	base.Inherit(&rules.Link, other.Link)
@@ -99,23 +141,35 @@ func (rules *CppRules) Inherit(other *CppRules) {
 	base.Inherit(&rules.RuntimeLib, other.RuntimeLib)
 	base.Inherit(&rules.Sanitizer, other.Sanitizer)
 	base.Inherit(&rules.Unity, other.Unity)
+	base.Inherit(&rules.PostLink, other.PostLink)
 
 	base.Inherit(&rules.Warnings.Default, other.Warnings.Default)
 	base.Inherit(&rules.Warnings.Deprecation, other.Warnings.Deprecation)
 	base.Inherit(&rules.Warnings.Pedantic, other.Warnings.Pedantic)
+	base.Inherit(&rules.Warnings.PrivateInclude, other.Warnings.PrivateInclude)
 	base.Inherit(&rules.Warnings.ShadowVariable, other.Warnings.ShadowVariable)
 	base.Inherit(&rules.Warnings.UndefinedMacro, other.Warnings.UndefinedMacro)
 	base.Inherit(&rules.Warnings.UnsafeTypeCast, other.Warnings.UnsafeTypeCast)
+	base.Inherit(&rules.Warnings.UnmanagedLibrary, other.Warnings.UnmanagedLibrary)
+	base.Inherit(&rules.Warnings.Overrides, other.Warnings.Overrides)
 
 	base.Inherit(&rules.AdaptiveUnity, other.AdaptiveUnity)
+	base.Inherit(&rules.StableUnity, other.StableUnity)
 	base.Inherit(&rules.Benchmark, other.Benchmark)
+	base.Inherit(&rules.BuildInfo, other.BuildInfo)
 	base.Inherit(&rules.Deterministic, other.Deterministic)
 	base.Inherit(&rules.DebugFastLink, other.DebugFastLink)
 	base.Inherit(&rules.Incremental, other.Incremental)
 	base.Inherit(&rules.LTO, other.LTO)
 	base.Inherit(&rules.RuntimeChecks, other.RuntimeChecks)
+	base.Inherit(&rules.FunctionLevelLinking, other.FunctionLevelLinking)
+	base.Inherit(&rules.IdenticalComdatFolding, other.IdenticalComdatFolding)
 	base.Inherit(&rules.SizePerUnity, other.SizePerUnity)
 
+	base.Inherit(&rules.HeaderUnitCache, other.HeaderUnitCache)
+	base.Inherit(&rules.WarningsAsErrors, other.WarningsAsErrors)
+	base.Inherit(&rules.ExcludeSystemHeaders, other.ExcludeSystemHeaders)
+
 	base.Inherit(&rules.CompilerVerbose, other.CompilerVerbose)
 	base.Inherit(&rules.LinkerVerbose, other.LinkerVerbose)
 }
@@ -124,6 +178,7 @@ func (rules *CppRules) Overwrite(other *CppRules) {
 	base.Overwrite(&rules.CppRtti, other.CppRtti)
 	base.Overwrite(&rules.DebugInfo, other.DebugInfo)
 	base.Overwrite(&rules.Exceptions, other.Exceptions)
+	base.Overwrite(&rules.FloatModel, other.FloatModel)
 	base.Overwrite(&rules.Instructions, other.Instructions)
 	base.Overwrite(&rules.PCH, other.PCH)
 	base.Overwrite(&rules.Link, other.Link)
@@ -131,23 +186,35 @@ func (rules *CppRules) Overwrite(other *CppRules) {
 	base.Overwrite(&rules.RuntimeLib, other.RuntimeLib)
 	base.Overwrite(&rules.Sanitizer, other.Sanitizer)
 	base.Overwrite(&rules.Unity, other.Unity)
+	base.Overwrite(&rules.PostLink, other.PostLink)
 
 	base.Overwrite(&rules.Warnings.Default, other.Warnings.Default)
 	base.Overwrite(&rules.Warnings.Deprecation, other.Warnings.Deprecation)
 	base.Overwrite(&rules.Warnings.Pedantic, other.Warnings.Pedantic)
+	base.Overwrite(&rules.Warnings.PrivateInclude, other.Warnings.PrivateInclude)
 	base.Overwrite(&rules.Warnings.ShadowVariable, other.Warnings.ShadowVariable)
 	base.Overwrite(&rules.Warnings.UndefinedMacro, other.Warnings.UndefinedMacro)
 	base.Overwrite(&rules.Warnings.UnsafeTypeCast, other.Warnings.UnsafeTypeCast)
+	base.Overwrite(&rules.Warnings.UnmanagedLibrary, other.Warnings.UnmanagedLibrary)
+	base.Overwrite(&rules.Warnings.Overrides, other.Warnings.Overrides)
 
 	base.Overwrite(&rules.AdaptiveUnity, other.AdaptiveUnity)
+	base.Overwrite(&rules.StableUnity, other.StableUnity)
 	base.Overwrite(&rules.Benchmark, other.Benchmark)
+	base.Overwrite(&rules.BuildInfo, other.BuildInfo)
 	base.Overwrite(&rules.Deterministic, other.Deterministic)
 	base.Overwrite(&rules.DebugFastLink, other.DebugFastLink)
 	base.Overwrite(&rules.Incremental, other.Incremental)
 	base.Overwrite(&rules.LTO, other.LTO)
 	base.Overwrite(&rules.RuntimeChecks, other.RuntimeChecks)
+	base.Overwrite(&rules.FunctionLevelLinking, other.FunctionLevelLinking)
+	base.Overwrite(&rules.IdenticalComdatFolding, other.IdenticalComdatFolding)
 	base.Overwrite(&rules.SizePerUnity, other.SizePerUnity)
 
+	base.Overwrite(&rules.HeaderUnitCache, other.HeaderUnitCache)
+	base.Overwrite(&rules.WarningsAsErrors, other.WarningsAsErrors)
+	base.Overwrite(&rules.ExcludeSystemHeaders, other.ExcludeSystemHeaders)
+
 	base.Overwrite(&rules.CompilerVerbose, other.CompilerVerbose)
 	base.Overwrite(&rules.LinkerVerbose, other.LinkerVerbose)
 }
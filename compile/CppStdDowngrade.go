@@ -0,0 +1,101 @@
+package compile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * C++ Standard Downgrades
+ ***************************************/
+
+// CppStdDowngrade records a module which requested a C++ standard above what its compiler supports: compilers
+// silently clamp to their maximum supported standard, so feature assumptions of this module may be invalid.
+type CppStdDowngrade struct {
+	ModuleAlias ModuleAlias
+	Requested   CppStdType
+	Supported   CppStdType
+	Compilers   base.SetT[CompilerAlias]
+}
+
+// IsCppStdDowngraded returns true when requested standard is above the maximum supported by compiler,
+// which is unknown when compiler did not report it (supported is inherited).
+func IsCppStdDowngraded(requested, supported CppStdType) bool {
+	if requested.IsInheritable() || supported.IsInheritable() || requested == CPPSTD_LATEST {
+		return false
+	}
+	return int32(requested) > int32(supported)
+}
+
+// FindCppStdDowngrades aggregates downgrades per module, since the same module is usually configured
+// for several environments sharing the same compiler.
+func FindCppStdDowngrades(bg BuildGraphReadPort, targets ...*TargetActions) ([]CppStdDowngrade, error) {
+	perModule := make(map[ModuleAlias]*CppStdDowngrade)
+
+	for _, ta := range targets {
+		unit, err := FindBuildUnit(bg, ta.TargetAlias)
+		if err != nil {
+			return nil, err
+		}
+
+		compiler, err := unit.GetBuildCompiler(bg)
+		if err != nil {
+			return nil, err
+		}
+
+		supported := compiler.GetCompiler().CppStd
+		if !IsCppStdDowngraded(unit.CppStd, supported) {
+			continue
+		}
+
+		moduleAlias := unit.TargetAlias.ModuleAlias
+		downgrade, ok := perModule[moduleAlias]
+		if !ok {
+			downgrade = &CppStdDowngrade{
+				ModuleAlias: moduleAlias,
+				Requested:   unit.CppStd,
+				Supported:   supported,
+			}
+			perModule[moduleAlias] = downgrade
+		}
+
+		// keep the worst downgrade when module is compiled by several compilers
+		if int32(supported) < int32(downgrade.Supported) {
+			downgrade.Supported = supported
+		}
+		downgrade.Compilers.AppendUniq(unit.CompilerAlias)
+	}
+
+	result := make([]CppStdDowngrade, 0, len(perModule))
+	for _, it := range perModule {
+		result = append(result, *it)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ModuleAlias.Compare(result[j].ModuleAlias) < 0
+	})
+	return result, nil
+}
+
+// ReportCppStdDowngrades prints an actionable summary of modules whose requested C++ standard was downgraded
+func ReportCppStdDowngrades(bg BuildGraphReadPort, targets ...*TargetActions) error {
+	downgrades, err := FindCppStdDowngrades(bg, targets...)
+	if err != nil || len(downgrades) == 0 {
+		return err
+	}
+
+	base.LogWarning(LogCompile, "%d module(s) requested a C++ standard unsupported by their compiler, and were downgraded:", len(downgrades))
+	for _, it := range downgrades {
+		compilers := make([]string, len(it.Compilers))
+		for i, compiler := range it.Compilers {
+			compilers[i] = compiler.String()
+		}
+		base.LogWarning(LogCompile, "  %v: requested %v, compiled as %v by %v", it.ModuleAlias, it.Requested, it.Supported, strings.Join(compilers, ", "))
+	}
+	base.LogWarning(LogCompile, "upgrade the compiler, or lower CppStd of these modules to match the features they actually rely on")
+	return nil
+}
@@ -2,8 +2,12 @@ package compile
 
 import (
 	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/poppolopoppo/ppb/internal/base"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
 	"github.com/poppolopoppo/ppb/utils"
 )
 
@@ -57,10 +61,127 @@ func (list *CustomList) Serialize(ar base.Archive) {
 	}, (*[]Custom)(list))
 }
 
+/***************************************
+ * Custom Command
+ ***************************************/
+
+// CustomCommand runs an arbitrary executable before or after building a module (codegen, copy assets...).
+// Declared inputs and outputs are tracked by the build graph, so the command is only executed again when
+// one of them changed: at least one output is mandatory, or the command could never be up-to-date.
+
+type CustomCommand struct {
+	Executable utils.Filename
+	Arguments  base.StringSet
+	WorkingDir utils.Directory
+	Inputs     utils.FileSet
+	Outputs    utils.FileSet
+	// Environment overrides variables inherited from the unit, only for this command
+	Environment internal_io.ProcessEnvironment
+}
+
+func (x *CustomCommand) String() string {
+	return fmt.Sprint(x.Executable.Basename, " ", strings.Join(x.Arguments, " "))
+}
+func (x *CustomCommand) Serialize(ar base.Archive) {
+	ar.Serializable(&x.Executable)
+	ar.Serializable(&x.Arguments)
+	ar.Serializable(&x.WorkingDir)
+	ar.Serializable(&x.Inputs)
+	ar.Serializable(&x.Outputs)
+	ar.Serializable(&x.Environment)
+}
+
+type CustomCommandList []CustomCommand
+
+func (list *CustomCommandList) Append(it ...CustomCommand) {
+	*list = append(*list, it...)
+}
+func (list *CustomCommandList) Prepend(it ...CustomCommand) {
+	*list = append(it, *list...)
+}
+func (list *CustomCommandList) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]CustomCommand)(list))
+}
+
+// CustomCommandModel is the json representation of a custom command, where paths are relative to module directory
+type CustomCommandModel struct {
+	Executable  string
+	Arguments   base.StringSet
+	WorkingDir  string
+	Inputs      base.StringSet
+	Outputs     base.StringSet
+	Environment internal_io.ProcessEnvironment
+}
+
+func (x *CustomCommandModel) Serialize(ar base.Archive) {
+	ar.String(&x.Executable)
+	ar.Serializable(&x.Arguments)
+	ar.String(&x.WorkingDir)
+	ar.Serializable(&x.Inputs)
+	ar.Serializable(&x.Outputs)
+	ar.Serializable(&x.Environment)
+}
+func (x *CustomCommandModel) CreateCustomCommand(moduleDir utils.Directory) (CustomCommand, error) {
+	command := CustomCommand{
+		Arguments:   x.Arguments,
+		WorkingDir:  moduleDir,
+		Inputs:      utils.MakeFileSet(moduleDir, x.Inputs...).Normalize(),
+		Outputs:     utils.MakeFileSet(moduleDir, x.Outputs...).Normalize(),
+		Environment: x.Environment.Clone(),
+	}
+
+	if len(x.WorkingDir) > 0 {
+		command.WorkingDir = moduleDir.AbsoluteFolder(x.WorkingDir).Normalize()
+	}
+
+	var err error
+	if command.Executable, err = findCustomExecutable(moduleDir, x.Executable); err != nil {
+		return command, fmt.Errorf("custom command in %q: %w", moduleDir, err)
+	}
+
+	if len(command.Outputs) == 0 {
+		return command, fmt.Errorf("custom command %q in %q must declare at least one output", &command, moduleDir)
+	}
+	return command, nil
+}
+
+// findCustomExecutable searches executable in PATH when given without any directory
+func findCustomExecutable(moduleDir utils.Directory, executable string) (utils.Filename, error) {
+	if len(executable) == 0 {
+		return utils.Filename{}, fmt.Errorf("no executable given")
+	} else if strings.ContainsAny(executable, `/\`) {
+		return moduleDir.AbsoluteFile(executable).Normalize(), nil
+	} else if path, err := exec.LookPath(executable); err == nil {
+		return utils.MakeFilename(filepath.Clean(path)), nil
+	} else {
+		return utils.Filename{}, err
+	}
+}
+
+/***************************************
+ * Custom Unit
+ ***************************************/
+
+type CustomUnitStage int32
+
+const (
+	CUSTOM_COMPILE   CustomUnitStage = iota // compiled along with module objects (ex: resources)
+	CUSTOM_PREBUILD                         // command executed before compiling module
+	CUSTOM_POSTBUILD                        // command executed after module output was built
+)
+
 type CustomUnit struct {
+	Stage   CustomUnitStage
+	Command CustomCommand // only for CUSTOM_PREBUILD and CUSTOM_POSTBUILD
 	Unit
 }
 
+func (x *CustomUnit) Serialize(ar base.Archive) {
+	ar.Int32((*int32)(&x.Stage))
+	ar.Serializable(&x.Command)
+	ar.Serializable(&x.Unit)
+}
+
 type CustomUnitList []CustomUnit
 
 func (list *CustomUnitList) Append(it ...CustomUnit) {
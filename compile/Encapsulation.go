@@ -0,0 +1,289 @@
+package compile
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Private include enforcement
+ ***************************************/
+
+// PRIVATE include paths are not propagated to dependent modules, but nothing prevents a module from
+// reaching another module private headers (ex: with a relative path, or an extra include path).
+// Headers read by compilation actions were recorded as dynamic dependencies, which are checked after
+// the build when Warnings.PrivateInclude is enabled.
+
+type privateModuleDir struct {
+	Module ModuleAlias
+	Dir    Directory
+}
+
+func CheckPrivateIncludes(bg BuildGraphWritePort, targets ...*TargetActions) error {
+	var privateDirs []privateModuleDir
+	numErrors := 0
+
+	for _, ta := range targets {
+		unit, err := FindBuildUnit(bg, ta.TargetAlias)
+		if err != nil {
+			return err
+		}
+
+		level := unit.Warnings.PrivateInclude
+		if !level.IsEnabled() {
+			continue
+		}
+
+		// lazily collect private directories, since this pass is disabled by default
+		if privateDirs == nil {
+			if privateDirs, err = getPrivateModuleDirs(bg); err != nil {
+				return err
+			}
+		}
+
+		violations, err := findPrivateIncludes(bg, ta, privateDirs)
+		if err != nil {
+			return err
+		}
+
+		for _, it := range violations {
+			switch level {
+			case WARNING_ERROR:
+				numErrors++
+				base.LogError(LogCompile, "%v: includes %q from private directory of module <%v>", ta.TargetAlias, it.File, it.Module)
+			default:
+				base.LogWarning(LogCompile, "%v: includes %q from private directory of module <%v>", ta.TargetAlias, it.File, it.Module)
+			}
+		}
+	}
+
+	if numErrors > 0 {
+		return fmt.Errorf("found %d private headers included by other modules", numErrors)
+	}
+	return nil
+}
+
+type privateIncludeViolation struct {
+	File   Filename
+	Module ModuleAlias
+}
+
+func findPrivateIncludes(bg BuildGraphReadPort, ta *TargetActions, privateDirs []privateModuleDir) (results []privateIncludeViolation, err error) {
+	visiteds := make(map[Filename]bool)
+	err = ta.ForeachPayload(bg, func(tp *TargetPayload) error {
+		for _, alias := range tp.ActionAliases {
+			files, err := bg.GetDependencyInputFiles(false, alias.Alias())
+			if err != nil {
+				return err
+			}
+
+			for _, file := range files {
+				if _, ok := visiteds[file]; ok {
+					continue
+				}
+				visiteds[file] = true
+
+				for _, it := range privateDirs {
+					if it.Module != ta.TargetAlias.ModuleAlias && file.IsIn(it.Dir) {
+						results = append(results, privateIncludeViolation{File: file, Module: it.Module})
+						break
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return
+}
+
+func getPrivateModuleDirs(bg BuildGraphWritePort) ([]privateModuleDir, error) {
+	modules, err := NeedAllBuildModules(bg.GlobalContext())
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]privateModuleDir, 0, len(modules))
+	for _, it := range modules {
+		rules := it.GetModule()
+		if privateDir := rules.PrivateDir(); privateDir.Exists() {
+			results = append(results, privateModuleDir{Module: rules.ModuleAlias, Dir: privateDir})
+		}
+	}
+	return results, nil
+}
+
+/***************************************
+ * Unmanaged library enforcement
+ ***************************************/
+
+// A library referenced in linker options of a module, or in Libraries of the module and of its dependencies (see
+// TransitiveFacet), which is neither provided by a declared dependency nor found in LibraryPaths of the unit, is resolved
+// by the linker from implicit global search paths: this hidden coupling works on one machine and breaks elsewhere.
+// Libraries added by the toolchain (compiler, platform and configuration) are trusted. Those references are checked
+// before build when Warnings.UnmanagedLibrary is enabled.
+
+func CheckUnmanagedLibraries(bg BuildGraphReadPort, targets ...*TargetActions) error {
+	numErrors := 0
+
+	for _, ta := range targets {
+		unit, err := FindBuildUnit(bg, ta.TargetAlias)
+		if err != nil {
+			return err
+		}
+
+		level := unit.Warnings.UnmanagedLibrary
+		if !level.IsEnabled() || !unit.Payload.HasLinker() {
+			continue
+		}
+
+		libraries, err := findUnmanagedLibraries(bg, unit)
+		if err != nil {
+			return err
+		}
+
+		for _, it := range libraries {
+			switch level {
+			case WARNING_ERROR:
+				numErrors++
+				base.LogError(LogCompile, "%v: links %q from %v, which is not provided by any declared dependency", ta.TargetAlias, it.Library, it.Origin)
+			default:
+				base.LogWarning(LogCompile, "%v: links %q from %v, which is not provided by any declared dependency", ta.TargetAlias, it.Library, it.Origin)
+			}
+		}
+	}
+
+	if numErrors > 0 {
+		return fmt.Errorf("found %d libraries linked without a declared dependency", numErrors)
+	}
+	return nil
+}
+
+type unmanagedLibrary struct {
+	Library string
+	Origin  ModuleAlias
+}
+
+func findUnmanagedLibraries(bg BuildGraphReadPort, unit *Unit) (results []unmanagedLibrary, err error) {
+	module, err := FindBuildModule(bg, unit.TargetAlias.ModuleAlias)
+	if err != nil {
+		return
+	}
+
+	// walk libraries of the module and of its dependencies, while unit libraries also include those of the toolchain
+	var candidates []unmanagedLibrary
+	for _, it := range module.GetModule().LinkerOptions {
+		if library, ok := parseLinkedLibrary(it); ok {
+			candidates = append(candidates, unmanagedLibrary{Library: library, Origin: unit.TargetAlias.ModuleAlias})
+		}
+	}
+	for _, it := range unit.TransitiveFacet.Libraries {
+		candidates = append(candidates, unmanagedLibrary{Library: it, Origin: unit.TargetAlias.ModuleAlias})
+	}
+
+	provided := make(map[string]bool)
+	fromModules := make(map[string]bool)
+	for _, deps := range []TargetAliases{unit.IncludeDependencies, unit.CompileDependencies, unit.LinkDependencies, unit.RuntimeDependencies} {
+		for _, target := range deps {
+			other, err := FindBuildUnit(bg, target)
+			if err != nil {
+				return nil, err
+			}
+			if other.ExportFile.Valid() {
+				provided[getLinkedLibraryName(other.ExportFile.Basename)] = true
+			}
+			for _, it := range other.TransitiveFacet.Libraries {
+				candidates = append(candidates, unmanagedLibrary{Library: it, Origin: target.ModuleAlias})
+			}
+		}
+	}
+	for _, it := range candidates {
+		fromModules[getLinkedLibraryName(it.Library)] = true
+	}
+
+	// libraries which were not added by a module come from compiler, platform or configuration
+	for _, it := range unit.Libraries {
+		if name := getLinkedLibraryName(it); !fromModules[name] {
+			provided[name] = true
+		}
+	}
+
+	reported := base.StringSet{}
+	for _, it := range candidates {
+		if strings.Contains(it.Library, "%") || provided[getLinkedLibraryName(it.Library)] || isLibraryInSearchPaths(it.Library, unit.LibraryPaths) {
+			continue
+		}
+		if !reported.Contains(it.Library) {
+			reported.Append(it.Library)
+			results = append(results, it)
+		}
+	}
+	return
+}
+
+// isLibraryInSearchPaths returns true when library exists, or can be found by the linker in one of given library paths
+func isLibraryInSearchPaths(library string, libraryPaths DirSet) bool {
+	if strings.ContainsAny(library, `/\`) {
+		return MakeFilename(library).Exists()
+	}
+
+	name := strings.TrimPrefix(library, "-l")
+	basenames := []string{name}
+	if len(path.Ext(name)) == 0 {
+		basenames = []string{name + ".lib", "lib" + name + ".a", "lib" + name + ".so", "lib" + name + ".dylib"}
+	}
+
+	for _, dir := range libraryPaths {
+		for _, basename := range basenames {
+			if dir.File(basename).Exists() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseLinkedLibrary recognizes libraries passed to the linker: foo.lib, /DEFAULTLIB:foo, libfoo.a, -lfoo
+func parseLinkedLibrary(option string) (string, bool) {
+	if strings.Contains(option, "%") {
+		return "", false // substituted by ppb, ex: output files
+	}
+	for _, prefix := range []string{"/DEFAULTLIB:", "-DEFAULTLIB:"} {
+		if len(option) > len(prefix) && strings.EqualFold(option[:len(prefix)], prefix) {
+			return option[len(prefix):], true
+		}
+	}
+	if strings.HasPrefix(option, "-l") && len(option) > 2 {
+		return option, true
+	}
+	if strings.HasPrefix(option, "-") || (strings.HasPrefix(option, "/") && !strings.ContainsAny(option[1:], `/\`)) {
+		return "", false // other linker switch, absolute posix paths have more separators
+	}
+	switch strings.ToLower(path.Ext(option)) {
+	case ".lib", ".a", ".so", ".dylib":
+		return option, true
+	default:
+		return "", false
+	}
+}
+
+// getLinkedLibraryName returns a name comparable between toolchains: -lz, libz.a, z and z.lib all give "z"
+func getLinkedLibraryName(library string) string {
+	name := strings.ToLower(strings.TrimPrefix(library, "-l"))
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	switch path.Ext(name) {
+	case ".lib", ".a", ".so", ".dylib":
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	if len(name) > 3 {
+		name = strings.TrimPrefix(name, "lib")
+	}
+	return name
+}
@@ -81,9 +81,9 @@ func (x ArchType) String() string {
 }
 func (x *ArchType) Set(in string) (err error) {
 	switch strings.ToUpper(in) {
-	case ARCH_X86.String():
+	case strings.ToUpper(ARCH_X86.String()):
 		*x = ARCH_X86
-	case ARCH_X64.String():
+	case strings.ToUpper(ARCH_X64.String()):
 		*x = ARCH_X64
 	case ARCH_ARM.String():
 		*x = ARCH_ARM
@@ -123,6 +123,7 @@ const (
 	COMPILER_ALLOW_RESPONSEFILE
 	COMPILER_ALLOW_SOURCEMAPPING
 	COMPILER_ALLOW_EDITANDCONTINUE
+	COMPILER_ALLOW_FRAMEWORKS
 )
 
 func GetCompilerFeatures() []CompilerFeature {
@@ -132,6 +133,7 @@ func GetCompilerFeatures() []CompilerFeature {
 		COMPILER_ALLOW_RESPONSEFILE,
 		COMPILER_ALLOW_SOURCEMAPPING,
 		COMPILER_ALLOW_EDITANDCONTINUE,
+		COMPILER_ALLOW_FRAMEWORKS,
 	}
 }
 func (x CompilerFeature) Ord() int32       { return int32(x) }
@@ -148,6 +150,8 @@ func (x CompilerFeature) Description() string {
 		return "compiler can remap source for debug files"
 	case COMPILER_ALLOW_EDITANDCONTINUE:
 		return "compiler can generate a hot-reloadable payload"
+	case COMPILER_ALLOW_FRAMEWORKS:
+		return "compiler can link against Apple frameworks"
 	default:
 		base.UnexpectedValue(x)
 		return ""
@@ -165,6 +169,8 @@ func (x CompilerFeature) String() string {
 		return "ALLOW_SOURCEMAPPING"
 	case COMPILER_ALLOW_EDITANDCONTINUE:
 		return "ALLOW_EDITANDCONTINUE"
+	case COMPILER_ALLOW_FRAMEWORKS:
+		return "ALLOW_FRAMEWORKS"
 	default:
 		base.UnexpectedValue(x)
 		return ""
@@ -182,6 +188,8 @@ func (x *CompilerFeature) Set(in string) error {
 		*x = COMPILER_ALLOW_SOURCEMAPPING
 	case COMPILER_ALLOW_EDITANDCONTINUE.String():
 		*x = COMPILER_ALLOW_EDITANDCONTINUE
+	case COMPILER_ALLOW_FRAMEWORKS.String():
+		*x = COMPILER_ALLOW_FRAMEWORKS
 	default:
 		return base.MakeUnexpectedValueError(x, in)
 	}
@@ -554,6 +562,174 @@ func (x ExceptionType) AutoComplete(in base.AutoComplete) {
 	}
 }
 
+/***************************************
+ * Floating-point Model
+ ***************************************/
+
+type FloatModelType byte
+
+const (
+	FLOATMODEL_INHERIT FloatModelType = iota
+	FLOATMODEL_FAST
+	FLOATMODEL_PRECISE
+	FLOATMODEL_STRICT
+)
+
+func GetFloatModelTypes() []FloatModelType {
+	return []FloatModelType{
+		FLOATMODEL_INHERIT,
+		FLOATMODEL_FAST,
+		FLOATMODEL_PRECISE,
+		FLOATMODEL_STRICT,
+	}
+}
+func (x FloatModelType) Description() string {
+	switch x {
+	case FLOATMODEL_INHERIT:
+		return "inherit default value from configuration"
+	case FLOATMODEL_FAST:
+		return "allow aggressive floating-point optimizations, results can differ between compilers and builds"
+	case FLOATMODEL_PRECISE:
+		return "preserve floating-point semantics of source code, but allow contractions"
+	case FLOATMODEL_STRICT:
+		return "strict IEEE-754 semantics, with support for floating-point exceptions and rounding modes"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x FloatModelType) String() string {
+	switch x {
+	case FLOATMODEL_INHERIT:
+		return "INHERIT"
+	case FLOATMODEL_FAST:
+		return "FAST"
+	case FLOATMODEL_PRECISE:
+		return "PRECISE"
+	case FLOATMODEL_STRICT:
+		return "STRICT"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x FloatModelType) IsInheritable() bool {
+	return x == FLOATMODEL_INHERIT
+}
+func (x *FloatModelType) Set(in string) (err error) {
+	switch strings.ToUpper(in) {
+	case FLOATMODEL_INHERIT.String():
+		*x = FLOATMODEL_INHERIT
+	case FLOATMODEL_FAST.String():
+		*x = FLOATMODEL_FAST
+	case FLOATMODEL_PRECISE.String():
+		*x = FLOATMODEL_PRECISE
+	case FLOATMODEL_STRICT.String():
+		*x = FLOATMODEL_STRICT
+	default:
+		err = base.MakeUnexpectedValueError(x, in)
+	}
+	return err
+}
+func (x *FloatModelType) Serialize(ar base.Archive) {
+	ar.Byte((*byte)(x))
+}
+func (x FloatModelType) MarshalText() ([]byte, error) {
+	return base.UnsafeBytesFromString(x.String()), nil
+}
+func (x *FloatModelType) UnmarshalText(data []byte) error {
+	return x.Set(base.UnsafeStringFromBytes(data))
+}
+func (x FloatModelType) AutoComplete(in base.AutoComplete) {
+	for _, it := range GetFloatModelTypes() {
+		in.Add(it.String(), it.Description())
+	}
+}
+
+/***************************************
+ * IdenticalComdatFoldingType
+ ***************************************/
+
+type IdenticalComdatFoldingType byte
+
+const (
+	ICF_INHERIT IdenticalComdatFoldingType = iota
+	ICF_DISABLED
+	ICF_ENABLED
+	ICF_AGGRESSIVE
+)
+
+func GetIdenticalComdatFoldingTypes() []IdenticalComdatFoldingType {
+	return []IdenticalComdatFoldingType{
+		ICF_INHERIT,
+		ICF_DISABLED,
+		ICF_ENABLED,
+		ICF_AGGRESSIVE,
+	}
+}
+func (x IdenticalComdatFoldingType) Description() string {
+	switch x {
+	case ICF_INHERIT:
+		return "inherit default value from optimization level"
+	case ICF_DISABLED:
+		return "never fold identical functions or data, distinct symbols keep distinct addresses"
+	case ICF_ENABLED:
+		return "fold identical functions and data with a single linker pass"
+	case ICF_AGGRESSIVE:
+		return "fold identical functions and data with several linker passes, to also fold what becomes identical after folding"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x IdenticalComdatFoldingType) String() string {
+	switch x {
+	case ICF_INHERIT:
+		return "INHERIT"
+	case ICF_DISABLED:
+		return "DISABLED"
+	case ICF_ENABLED:
+		return "ENABLED"
+	case ICF_AGGRESSIVE:
+		return "AGGRESSIVE"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x IdenticalComdatFoldingType) IsInheritable() bool {
+	return x == ICF_INHERIT
+}
+func (x *IdenticalComdatFoldingType) Set(in string) (err error) {
+	switch strings.ToUpper(in) {
+	case ICF_INHERIT.String():
+		*x = ICF_INHERIT
+	case ICF_DISABLED.String():
+		*x = ICF_DISABLED
+	case ICF_ENABLED.String():
+		*x = ICF_ENABLED
+	case ICF_AGGRESSIVE.String():
+		*x = ICF_AGGRESSIVE
+	default:
+		err = base.MakeUnexpectedValueError(x, in)
+	}
+	return err
+}
+func (x *IdenticalComdatFoldingType) Serialize(ar base.Archive) {
+	ar.Byte((*byte)(x))
+}
+func (x IdenticalComdatFoldingType) MarshalText() ([]byte, error) {
+	return base.UnsafeBytesFromString(x.String()), nil
+}
+func (x *IdenticalComdatFoldingType) UnmarshalText(data []byte) error {
+	return x.Set(base.UnsafeStringFromBytes(data))
+}
+func (x IdenticalComdatFoldingType) AutoComplete(in base.AutoComplete) {
+	for _, it := range GetIdenticalComdatFoldingTypes() {
+		in.Add(it.String(), it.Description())
+	}
+}
+
 /***************************************
  * InstructionSet
  ***************************************/
@@ -562,6 +738,8 @@ type InstructionSet byte
 
 type InstructionSets = base.EnumSet[InstructionSet, *InstructionSet]
 
+// Selected instruction sets are always merged with the baseline of the platform (see PlatformRules.Instructions),
+// so INSTRUCTIONSET_INHERIT (an empty set) pulls only the instructions implied by target architecture.
 const (
 	INSTRUCTIONSET_INHERIT InstructionSet = iota
 	INSTRUCTIONSET_AES
@@ -861,6 +1039,85 @@ func (x ModuleType) AutoComplete(in base.AutoComplete) {
 	}
 }
 
+/***************************************
+ * IncrementalLinkerType
+ ***************************************/
+
+type IncrementalLinkerType byte
+
+const (
+	INCREMENTAL_AUTO IncrementalLinkerType = iota
+	INCREMENTAL_OFF
+	INCREMENTAL_ON
+)
+
+func GetIncrementalLinkerTypes() []IncrementalLinkerType {
+	return []IncrementalLinkerType{
+		INCREMENTAL_AUTO,
+		INCREMENTAL_OFF,
+		INCREMENTAL_ON,
+	}
+}
+func (x IncrementalLinkerType) Description() string {
+	switch x {
+	case INCREMENTAL_AUTO:
+		return "inherit incremental linker from module and configuration settings"
+	case INCREMENTAL_OFF:
+		return "force incremental linker off"
+	case INCREMENTAL_ON:
+		return "force incremental linker on"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x IncrementalLinkerType) String() string {
+	switch x {
+	case INCREMENTAL_AUTO:
+		return "AUTO"
+	case INCREMENTAL_OFF:
+		return "OFF"
+	case INCREMENTAL_ON:
+		return "ON"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x IncrementalLinkerType) IsInheritable() bool {
+	return x == INCREMENTAL_AUTO
+}
+func (x IncrementalLinkerType) IsEnabled() bool {
+	return x == INCREMENTAL_ON
+}
+func (x *IncrementalLinkerType) Set(in string) (err error) {
+	switch strings.ToUpper(in) {
+	case INCREMENTAL_AUTO.String(), base.INHERITABLE_INHERIT.String():
+		*x = INCREMENTAL_AUTO
+	case INCREMENTAL_OFF.String(), base.INHERITABLE_FALSE.String():
+		*x = INCREMENTAL_OFF
+	case INCREMENTAL_ON.String(), base.INHERITABLE_TRUE.String():
+		*x = INCREMENTAL_ON
+	default:
+		err = base.MakeUnexpectedValueError(x, in)
+	}
+	return err
+}
+func (x *IncrementalLinkerType) Serialize(ar base.Archive) {
+	ar.Byte((*byte)(x))
+}
+func (x IncrementalLinkerType) MarshalText() ([]byte, error) {
+	return base.UnsafeBytesFromString(x.String()), nil
+}
+func (x *IncrementalLinkerType) UnmarshalText(data []byte) error {
+	return x.Set(base.UnsafeStringFromBytes(data))
+}
+func (x IncrementalLinkerType) AutoComplete(in base.AutoComplete) {
+	for _, it := range GetIncrementalLinkerTypes() {
+		in.Add(it.String(), it.Description())
+	}
+}
+
 /***************************************
  * PrecompiledHeaderType
  ***************************************/
@@ -1330,6 +1587,79 @@ func (x SupportType) Enabled() bool {
 	return false
 }
 
+/***************************************
+ * RetentionPolicy
+ ***************************************/
+
+type RetentionPolicy byte
+
+const (
+	RETENTION_KEEPALL RetentionPolicy = iota
+	RETENTION_OUTPUTSONLY
+	RETENTION_AFTERLINK
+)
+
+func GetRetentionPolicies() []RetentionPolicy {
+	return []RetentionPolicy{
+		RETENTION_KEEPALL,
+		RETENTION_OUTPUTSONLY,
+		RETENTION_AFTERLINK,
+	}
+}
+func (x RetentionPolicy) Description() string {
+	switch x {
+	case RETENTION_KEEPALL:
+		return "keep every intermediate file"
+	case RETENTION_OUTPUTSONLY:
+		return "delete stale objects and precompiled headers which are not tracked by the build graph, without affecting next incremental build"
+	case RETENTION_AFTERLINK:
+		return "also delete objects and precompiled headers of targets successfully linked, which will be compiled again when needed"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x RetentionPolicy) String() string {
+	switch x {
+	case RETENTION_KEEPALL:
+		return "KEEPALL"
+	case RETENTION_OUTPUTSONLY:
+		return "OUTPUTSONLY"
+	case RETENTION_AFTERLINK:
+		return "AFTERLINK"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+func (x *RetentionPolicy) Set(in string) (err error) {
+	switch strings.ToUpper(in) {
+	case RETENTION_KEEPALL.String():
+		*x = RETENTION_KEEPALL
+	case RETENTION_OUTPUTSONLY.String():
+		*x = RETENTION_OUTPUTSONLY
+	case RETENTION_AFTERLINK.String():
+		*x = RETENTION_AFTERLINK
+	default:
+		err = base.MakeUnexpectedValueError(x, in)
+	}
+	return err
+}
+func (x *RetentionPolicy) Serialize(ar base.Archive) {
+	ar.Byte((*byte)(x))
+}
+func (x RetentionPolicy) MarshalText() ([]byte, error) {
+	return base.UnsafeBytesFromString(x.String()), nil
+}
+func (x *RetentionPolicy) UnmarshalText(data []byte) error {
+	return x.Set(base.UnsafeStringFromBytes(data))
+}
+func (x RetentionPolicy) AutoComplete(in base.AutoComplete) {
+	for _, it := range GetRetentionPolicies() {
+		in.Add(it.String(), it.Description())
+	}
+}
+
 /***************************************
  * RuntimeType
  ***************************************/
@@ -82,6 +82,9 @@ type CompileEnv struct {
 
 	CompilerAlias CompilerAlias
 	CompileFlags  CompileFlags
+
+	Profiles     base.StringSet
+	ProfileRules CppRules
 }
 
 func (env *CompileEnv) Family() []string {
@@ -95,6 +98,8 @@ func (env *CompileEnv) Serialize(ar base.Archive) {
 	ar.Serializable(&env.Facet)
 	ar.Serializable(&env.CompilerAlias)
 	SerializeParsableFlags(ar, &env.CompileFlags)
+	ar.Serializable(&env.Profiles)
+	ar.Serializable(&env.ProfileRules)
 }
 
 func (env *CompileEnv) GetBuildPlatform(bg BuildGraphReadPort) (Platform, error) {
@@ -143,19 +148,41 @@ func (env *CompileEnv) IntermediateDir() Directory {
 func (env *CompileEnv) GetCpp(bg BuildGraphReadPort, module *ModuleRules) CppRules {
 	result := CppRules{}
 
+	subject := env.String()
+	layers := make([]base.InheritLayer, 0, 5)
+
 	if module != nil {
 		result.Inherit(&module.CppRules)
+		subject = fmt.Sprint(module.ModuleAlias, "-", subject)
+		layers = append(layers, base.InheritLayer{Name: "module", Value: &module.CppRules})
 	}
 
 	result.Inherit((*CppRules)(&env.CompileFlags))
+	layers = append(layers, base.InheritLayer{Name: "environment", Value: &env.CompileFlags})
 
 	if config := env.GetConfig(bg); config != nil {
 		result.Inherit(&env.GetConfig(bg).CppRules)
+		layers = append(layers, base.InheritLayer{Name: "configuration", Value: &config.CppRules})
 	}
+
+	result.Inherit(&env.ProfileRules)
+	layers = append(layers, base.InheritLayer{Name: "profiles", Value: &env.ProfileRules})
+
 	if compiler := env.GetCompiler(bg); compiler != nil {
 		base.Inherit(&result.CppStd, compiler.CppStd)
+		layers = append(layers, base.InheritLayer{Name: "compiler", Value: struct{ CppStd CppStdType }{compiler.CppStd}})
+	}
+
+	// fast floating-point model is kept as default, modules with numerical code can still opt-in for precise/strict semantics
+	base.Inherit(&result.FloatModel, FLOATMODEL_FAST)
+
+	// instruction sets implied by platform architecture are always available, whatever the unit selected
+	if platform := env.GetPlatform(bg); platform != nil {
+		result.Instructions.Append(platform.Instructions)
+		layers = append(layers, base.InheritLayer{Name: "platform", Value: struct{ Instructions InstructionSets }{platform.Instructions}})
 	}
 
+	base.TraceInherit(subject, &result, layers...)
 	return result
 }
 func (env *CompileEnv) GetPayloadType(module *ModuleRules, link LinkType) (result PayloadType) {
@@ -243,9 +270,30 @@ func (env *CompileEnv) Build(bc BuildContext) error {
 		"BUILD_FAMILY="+strings.Join(env.Family(), "-"),
 		"BUILD_"+strings.Join(env.Family(), "_"))
 
-	env.Facet.IncludePaths.Append(UFS.Source)
+	// documented platform definitions, extended with -PlatformDefines
+	if definitions, err := GetPlatformDefinitions(bc, env.GetPlatform(bc)); err == nil {
+		env.Facet.Defines.Append(definitions.Defines()...)
+	} else {
+		return err
+	}
+
+	if flags, err := GetSourceRootsFlags(bc); err == nil {
+		env.Facet.IncludePaths.Append(flags.GetAllSourceRoots()...)
+	} else {
+		return err
+	}
 	env.Facet.Append(env.GetPlatform(bc), env.GetConfig(bc), env.GetCompiler(bc))
 
+	// configuration profiles take precedence over platform profiles
+	env.Profiles = base.NewStringSet(env.GetConfig(bc).Profiles...)
+	env.Profiles.AppendUniq(env.GetPlatform(bc).Profiles...)
+
+	if profileRules, err := ResolveFlagProfiles(env.Profiles...); err == nil {
+		env.ProfileRules = profileRules
+	} else {
+		return err
+	}
+
 	return nil
 }
 
@@ -273,6 +321,12 @@ func ForeachEnvironmentAlias(each func(EnvironmentAlias) error) error {
 
 	for _, platformName := range plaformNames {
 		for _, configName := range configNames {
+			// custom environments can be restricted to some platforms, see EnvironmentModel
+			if config, ok := AllConfigurations.Get(configName); ok {
+				if platforms := config.GetConfig().Platforms; len(platforms) > 0 && !platforms.Contains(platformName) {
+					continue
+				}
+			}
 			if err := each(EnvironmentAlias{
 				PlatformAlias:      NewPlatformAlias(platformName),
 				ConfigurationAlias: NewConfigurationAlias(configName),
@@ -0,0 +1,87 @@
+package compile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Environment Include Flags
+ ***************************************/
+
+type EnvironmentIncludeFlags struct {
+	IncludeEnv StringVar
+}
+
+var GetEnvironmentIncludeFlags = NewCompilationFlags("EnvironmentIncludes", "extra include paths read from environment", EnvironmentIncludeFlags{})
+
+func (flags *EnvironmentIncludeFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("IncludeEnv", "append directories listed in given environment variable (ex: INCLUDE) to extern include paths of every unit", &flags.IncludeEnv)
+}
+
+/***************************************
+ * Environment Include Paths
+ ***************************************/
+
+// EnvironmentIncludePaths is rebuilt on every run, but its stamp only changes when the variable content changed,
+// so units depending on it are only invalidated when include paths were actually modified.
+
+type EnvironmentIncludePaths struct {
+	VariableName string
+	IncludePaths DirSet
+}
+
+func NeedEnvironmentIncludePaths(variableName string) BuildFactoryTyped[*EnvironmentIncludePaths] {
+	return MakeBuildFactory(func(bi BuildInitializer) (EnvironmentIncludePaths, error) {
+		return EnvironmentIncludePaths{
+			VariableName: variableName,
+		}, nil
+	})
+}
+
+func (x *EnvironmentIncludePaths) Alias() BuildAlias {
+	return MakeBuildAlias("Environment", "IncludePaths", x.VariableName)
+}
+func (x *EnvironmentIncludePaths) String() string {
+	return fmt.Sprint("%", x.VariableName, "%")
+}
+func (x *EnvironmentIncludePaths) Serialize(ar base.Archive) {
+	ar.String(&x.VariableName)
+	ar.Serializable(&x.IncludePaths)
+}
+func (x *EnvironmentIncludePaths) Build(bc BuildContext) error {
+	x.IncludePaths = DirSet{}
+
+	for _, path := range filepath.SplitList(os.Getenv(x.VariableName)) {
+		if path = strings.TrimSpace(path); len(path) == 0 {
+			continue
+		}
+
+		dir := MakeDirectory(path)
+		if !dir.Exists() {
+			return fmt.Errorf("environment variable %q contains an include path which does not exist: %q", x.VariableName, dir)
+		}
+
+		x.IncludePaths.AppendUniq(dir)
+	}
+
+	base.LogVerbose(LogCompile, "environment variable %q adds %d extern include paths", x.VariableName, len(x.IncludePaths))
+
+	// keep previous timestamp when nothing changed, or every dependent unit would be rebuilt
+	if previousStamp := bc.GetPreviousBuildStamp(); previousStamp.Content == MakeBuildFingerprint(x) {
+		bc.Annotate(AnnocateBuildTimestamp(previousStamp.ModTime))
+	}
+	return nil
+}
+
+func (x *EnvironmentIncludePaths) Decorate(_ BuildGraphReadPort, _ *CompileEnv, unit *Unit) error {
+	unit.Facet.ExternIncludePaths.AppendUniq(x.IncludePaths...)
+	return nil
+}
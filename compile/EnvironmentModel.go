@@ -0,0 +1,155 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Environment Model
+ ***************************************/
+
+// EnvironmentModel declares a custom build environment without code changes, loaded from <prefix>-environments.json
+// found next to root namespace: every entry is registered as a new configuration inheriting from an existing one,
+// and environments using it can be restricted to a platform or to the platforms of an architecture. For instance:
+//
+//	{ "ReleaseASAN": { "Config": "Shipping", "Arch": "x64", "Profiles": ["release"], "Sanitizer": "ADDRESS", "ArtifactSuffix": "_asan" } }
+
+const ENVIRONMENTMODEL_EXT = "-environments.json"
+
+type EnvironmentModel struct {
+	Config   StringVar      // name of the inherited configuration, mandatory
+	Platform StringVar      // restrict environment to this platform
+	Arch     StringVar      // restrict environment to platforms of this architecture
+	Tags     TagFlags       // replace tags of inherited configuration when not empty
+	Profiles base.StringSet // named flag profiles, take precedence over inherited configuration profiles
+
+	ArtifactPrefix StringVar // replace artifact name prefix of inherited configuration when set
+	ArtifactSuffix StringVar // replace artifact name suffix of inherited configuration when set
+
+	CppRules
+}
+
+func GetEnvironmentModelsFile() Filename {
+	return UFS.Source.File(CommandEnv.Prefix() + ENVIRONMENTMODEL_EXT)
+}
+
+// LoadEnvironmentModels registers configurations declared in given file, if it exists:
+// it must be called after all platforms and configurations were registered, to validate references.
+func LoadEnvironmentModels(source Filename) error {
+	if !source.Exists() {
+		return nil
+	}
+
+	models := make(map[string]EnvironmentModel)
+	if err := UFS.OpenBuffered(source, func(r io.Reader) error {
+		return base.JsonDeserialize(&models, r)
+	}); err != nil {
+		return fmt.Errorf("compile: failed to parse environments in %q: %w", source, err)
+	}
+
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		model := models[name]
+		config, err := model.createConfigRules(name)
+		if err != nil {
+			return fmt.Errorf("compile: invalid environment %q in %q: %w", name, source, err)
+		}
+
+		base.LogVerbose(LogCompile, "register custom environment %q from %q (platforms: %v)", name, source, config.Platforms)
+		AllConfigurations.Add(name, config)
+	}
+	return nil
+}
+
+func (x *EnvironmentModel) createConfigRules(name string) (*ConfigRules, error) {
+	if _, ok := AllConfigurations.Get(name); ok {
+		return nil, fmt.Errorf("configuration %q already exists", name)
+	}
+
+	inherited, ok := AllConfigurations.Get(x.Config.Get())
+	if x.Config.IsInheritable() || !ok {
+		return nil, fmt.Errorf("unknown inherited configuration %q, available configurations: %v", x.Config, AllConfigurations.Keys())
+	}
+
+	platforms, err := x.getAllowedPlatforms()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ResolveFlagProfiles(x.Profiles...); err != nil {
+		return nil, err
+	}
+
+	config := &ConfigRules{
+		ConfigurationAlias: NewConfigurationAlias(name),
+		Platforms:          platforms,
+		Profiles:           base.NewStringSet(x.Profiles...),
+		CppRules:           x.CppRules,
+	}
+	config.Profiles.AppendUniq(inherited.GetConfig().Profiles...)
+	config.ArtifactPrefix = inherited.GetConfig().ArtifactPrefix
+	config.ArtifactSuffix = inherited.GetConfig().ArtifactSuffix
+	if !x.ArtifactPrefix.IsInheritable() {
+		config.ArtifactPrefix = x.ArtifactPrefix.Get()
+	}
+	if !x.ArtifactSuffix.IsInheritable() {
+		config.ArtifactSuffix = x.ArtifactSuffix.Get()
+	}
+	config.CppRules.Inherit(inherited.GetConfig().GetCpp())
+	config.Facet.DeepCopy(inherited.GetConfig().GetFacet())
+
+	if !x.Tags.Empty() {
+		config.Facet.Tags = x.Tags
+	}
+	return config, nil
+}
+
+// getAllowedPlatforms returns names of platforms matching both Platform and Arch, or nil when no restriction was set
+func (x *EnvironmentModel) getAllowedPlatforms() (base.StringSet, error) {
+	if x.Platform.IsInheritable() && x.Arch.IsInheritable() {
+		return nil, nil
+	}
+
+	if !x.Platform.IsInheritable() {
+		if _, ok := AllPlatforms.Get(x.Platform.Get()); !ok {
+			return nil, fmt.Errorf("unknown platform %q, available platforms: %v", x.Platform, AllPlatforms.Keys())
+		}
+	}
+
+	var arch ArchType
+	if !x.Arch.IsInheritable() {
+		if err := arch.Set(x.Arch.Get()); err != nil {
+			return nil, fmt.Errorf("unknown architecture %q, available architectures: %v", x.Arch, GetArchTypes())
+		}
+	}
+
+	platforms := base.StringSet{}
+	for _, name := range AllPlatforms.Keys() {
+		platform, _ := AllPlatforms.Get(name)
+		if !x.Platform.IsInheritable() && name != x.Platform.Get() {
+			continue
+		}
+		if !x.Arch.IsInheritable() && platform.GetPlatform().Arch != arch {
+			continue
+		}
+		platforms.Append(name)
+	}
+
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("no platform registered for platform %q and architecture %q", x.Platform, x.Arch)
+	}
+	sort.Strings(platforms)
+	return platforms, nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/poppolopoppo/ppb/internal/base"
 	"github.com/poppolopoppo/ppb/utils"
+
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
 )
 
 type Facetable interface {
@@ -44,9 +46,16 @@ type Facet struct {
 	Libraries    base.StringSet
 	LibraryPaths utils.DirSet
 
+	// Frameworks are only supported by Apple toolchains (-framework), with their own search paths (-F)
+	Frameworks     base.StringSet
+	FrameworkPaths utils.DirSet
+
 	LibrarianOptions base.StringSet
 	LinkerOptions    base.StringSet
 
+	// ActionEnvironment is merged onto compiler environment for every action of the unit, ex: ASAN_OPTIONS
+	ActionEnvironment internal_io.ProcessEnvironment
+
 	Tags    TagFlags
 	Exports VariableDefinitions
 }
@@ -71,9 +80,14 @@ func (facet *Facet) Serialize(ar base.Archive) {
 	ar.Serializable(&facet.Libraries)
 	ar.Serializable(&facet.LibraryPaths)
 
+	ar.Serializable(&facet.Frameworks)
+	ar.Serializable(&facet.FrameworkPaths)
+
 	ar.Serializable(&facet.LibrarianOptions)
 	ar.Serializable(&facet.LinkerOptions)
 
+	ar.Serializable(&facet.ActionEnvironment)
+
 	ar.Serializable(&facet.Tags)
 	ar.Serializable(&facet.Exports)
 }
@@ -92,8 +106,11 @@ func NewFacet() Facet {
 		PrecompiledHeaderOptions: base.StringSet{},
 		Libraries:                base.StringSet{},
 		LibraryPaths:             utils.DirSet{},
+		Frameworks:               base.StringSet{},
+		FrameworkPaths:           utils.DirSet{},
 		LibrarianOptions:         base.StringSet{},
 		LinkerOptions:            base.StringSet{},
+		ActionEnvironment:        internal_io.NewProcessEnvironment(),
 		Tags:                     TagFlags(0),
 		Exports:                  VariableDefinitions{},
 	}
@@ -111,8 +128,11 @@ func (x *Facet) DeepCopy(src *Facet) {
 	x.PrecompiledHeaderOptions = base.NewStringSet(src.PrecompiledHeaderOptions...)
 	x.Libraries = base.NewStringSet(src.Libraries...)
 	x.LibraryPaths = utils.NewDirSet(src.LibraryPaths...)
+	x.Frameworks = base.NewStringSet(src.Frameworks...)
+	x.FrameworkPaths = utils.NewDirSet(src.FrameworkPaths...)
 	x.LibrarianOptions = base.NewStringSet(src.LibrarianOptions...)
 	x.LinkerOptions = base.NewStringSet(src.LinkerOptions...)
+	x.ActionEnvironment = src.ActionEnvironment.Clone()
 	x.Tags = src.Tags
 	x.Exports = base.CopySlice(src.Exports...)
 }
@@ -135,8 +155,11 @@ func (facet *Facet) Append(others ...Facetable) {
 		facet.PrecompiledHeaderOptions.Append(x.PrecompiledHeaderOptions...)
 		facet.Libraries.Append(x.Libraries...)
 		facet.LibraryPaths.Append(x.LibraryPaths...)
+		facet.Frameworks.Append(x.Frameworks...)
+		facet.FrameworkPaths.Append(x.FrameworkPaths...)
 		facet.LibrarianOptions.Append(x.LibrarianOptions...)
 		facet.LinkerOptions.Append(x.LinkerOptions...)
+		facet.ActionEnvironment.Inherit(x.ActionEnvironment)
 		facet.Tags.Append(x.Tags)
 		facet.Exports.Append(x.Exports)
 	}
@@ -156,8 +179,11 @@ func (facet *Facet) AppendUniq(others ...Facetable) {
 		facet.PrecompiledHeaderOptions.AppendUniq(x.PrecompiledHeaderOptions...)
 		facet.Libraries.AppendUniq(x.Libraries...)
 		facet.LibraryPaths.AppendUniq(x.LibraryPaths...)
+		facet.Frameworks.AppendUniq(x.Frameworks...)
+		facet.FrameworkPaths.AppendUniq(x.FrameworkPaths...)
 		facet.LibrarianOptions.AppendUniq(x.LibrarianOptions...)
 		facet.LinkerOptions.AppendUniq(x.LinkerOptions...)
+		facet.ActionEnvironment.Inherit(x.ActionEnvironment)
 		facet.Tags.Append(x.Tags)
 		facet.Exports.Append(x.Exports)
 	}
@@ -177,8 +203,11 @@ func (facet *Facet) Prepend(others ...Facetable) {
 		facet.PrecompiledHeaderOptions.Prepend(x.PrecompiledHeaderOptions...)
 		facet.Libraries.Prepend(x.Libraries...)
 		facet.LibraryPaths.Prepend(x.LibraryPaths...)
+		facet.Frameworks.Prepend(x.Frameworks...)
+		facet.FrameworkPaths.Prepend(x.FrameworkPaths...)
 		facet.LibrarianOptions.Prepend(x.LibrarianOptions...)
 		facet.LinkerOptions.Prepend(x.LinkerOptions...)
+		facet.ActionEnvironment.Overwrite(x.ActionEnvironment)
 		facet.Tags.Append(facet.Tags)
 		facet.Exports.Prepend(x.Exports)
 	}
@@ -221,6 +250,8 @@ func (facet *Facet) PerformSubstitutions() {
 		facet.PrecompiledHeaderOptions = base.Map(subst.ExpandString, facet.PrecompiledHeaderOptions...)
 		facet.Libraries = base.Map(subst.ExpandString, facet.Libraries...)
 		facet.LibraryPaths = base.Map(subst.ExpandDirectory, facet.LibraryPaths...)
+		facet.Frameworks = base.Map(subst.ExpandString, facet.Frameworks...)
+		facet.FrameworkPaths = base.Map(subst.ExpandDirectory, facet.FrameworkPaths...)
 		facet.LibrarianOptions = base.Map(subst.ExpandString, facet.LibrarianOptions...)
 		facet.LinkerOptions = base.Map(subst.ExpandString, facet.LinkerOptions...)
 	}
@@ -0,0 +1,153 @@
+package compile
+
+import (
+	"strings"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Shared Header Unit
+ ***************************************/
+
+// With PCH_HEADERUNIT every module compiles its own header unit, even when the same header is
+// compiled with the exact same flags by other modules (ex: a common header including STL).
+// When HeaderUnitCache is enabled, those units are redirected to a SharedHeaderUnit keyed by
+// header and compilation flags: the .ifc is compiled once and referenced by every module.
+
+type SharedHeaderUnit struct {
+	CompilerAlias CompilerAlias
+	Header        Filename
+	ExportFile    Filename
+	OutputFile    Filename
+	Command       action.CommandRules
+	Options       action.OptionFlags
+	ExcludedDirs  DirSet
+}
+
+func MakeSharedHeaderUnitAlias(outputFile Filename) BuildAlias {
+	return MakeBuildAlias("HeaderUnit", outputFile.String())
+}
+
+func (x *SharedHeaderUnit) Alias() BuildAlias {
+	return MakeSharedHeaderUnitAlias(x.OutputFile)
+}
+func (x *SharedHeaderUnit) GetActionAlias() action.ActionAlias {
+	return action.NewActionAlias(x.ExportFile)
+}
+func (x *SharedHeaderUnit) Build(bc BuildContext) error {
+	compiler, err := FindBuildable[Compiler](bc, x.CompilerAlias.Alias())
+	if err != nil {
+		return err
+	}
+
+	model := action.ActionModel{
+		Command:          x.Command,
+		StaticInputFiles: FileSet{x.Header},
+		ExportFile:       x.ExportFile,
+		OutputFile:       x.OutputFile,
+		Options:          x.Options,
+		ExcludedDirs:     x.ExcludedDirs,
+	}
+
+	_, err = bc.OutputFactory(action.BuildAction(&model,
+		func(model *action.ActionModel) (action.Action, error) {
+			// shared action does not belong to any unit, compilers must not rely on it for header units
+			return compiler.CreateAction(nil, PAYLOAD_HEADERUNIT, model), nil
+		}), OptionBuildForce)
+	return err
+}
+func (x *SharedHeaderUnit) Serialize(ar base.Archive) {
+	ar.Serializable(&x.CompilerAlias)
+	ar.Serializable(&x.Header)
+	ar.Serializable(&x.ExportFile)
+	ar.Serializable(&x.OutputFile)
+	ar.Serializable(&x.Command)
+	ar.Serializable(&x.Options)
+	ar.Serializable(&x.ExcludedDirs)
+}
+
+func (unit *Unit) shareHeaderUnit(compileEnv *CompileEnv, compiler Compiler) error {
+	if unit.PCH != PCH_HEADERUNIT || !unit.HeaderUnitCache.Get() {
+		unit.HeaderUnitCache = base.INHERITABLE_FALSE
+		return nil
+	}
+
+	// generated headers and custom units are dependencies of the module, they can't be shared
+	if len(unit.GeneratedFiles) > 0 || len(unit.CustomUnits) > 0 {
+		base.LogVeryVerbose(LogCompile, "%v: header unit won't be shared, since module has generated files or custom units", unit)
+		unit.HeaderUnitCache = base.INHERITABLE_FALSE
+		return nil
+	}
+
+	// target defines are unique to each module, so they are removed from header unit compilation
+	targetDefines := NewFacet()
+	for _, it := range unit.Defines {
+		if strings.HasPrefix(it, "BUILD_TARGET_") {
+			compiler.Define(&targetDefines, it)
+		}
+	}
+	unit.HeaderUnitOptions.Remove(targetDefines.HeaderUnitOptions...)
+
+	// per-module output paths can't be shared: debug symbols are redirected next to the shared unit, and
+	// the unit is not shared if any other option still writes in module directories
+	intermediateDir, outputDir := MakeLocalDirectory(unit.IntermediateDir), MakeLocalDirectory(unit.OutputFile.Dirname)
+	sharedOptions := base.NewStringSet()
+	hasSymbols := false
+	for _, it := range unit.HeaderUnitOptions {
+		if strings.HasPrefix(it, "/Fd") || strings.HasPrefix(it, "-Fd") {
+			hasSymbols = true
+			continue
+		}
+		if (len(intermediateDir) > 0 && strings.Contains(it, intermediateDir)) || (len(outputDir) > 0 && strings.Contains(it, outputDir)) {
+			base.LogVeryVerbose(LogCompile, "%v: header unit won't be shared, since option %q references module outputs", unit, it)
+			unit.HeaderUnitCache = base.INHERITABLE_FALSE
+			return nil
+		}
+		sharedOptions.Append(it)
+	}
+
+	cacheMode := compiler.AllowCaching(unit, PAYLOAD_HEADERUNIT)
+	distMode := compiler.AllowDistribution(unit, PAYLOAD_HEADERUNIT)
+	responseFile := compiler.AllowResponseFile(unit, PAYLOAD_HEADERUNIT)
+
+	// only hash what ends up in the shared action: unit environment is ignored, since it also holds the action
+	// environment of the module, and the shared unit is compiled with the environment of the compiler
+	fingerprint, err := base.SerializeAnyFingerprint(func(ar base.Archive) error {
+		ar.Serializable(&unit.CompilerAlias)
+		ar.Serializable(&unit.PrecompiledHeader)
+		ar.Serializable(&sharedOptions)
+		ar.Serializable(&compiler.GetCompiler().Environment)
+		ar.Serializable(&cacheMode)
+		ar.Serializable(&distMode)
+		ar.Serializable(&responseFile)
+		return nil
+	}, base.Fingerprint{})
+	if err != nil {
+		return err
+	}
+
+	sharedDir := compileEnv.IntermediateDir().Folder("HeaderUnits", fingerprint.ShortString())
+	sharedObject := compiler.GetPayloadOutput(unit, PAYLOAD_HEADERUNIT, sharedDir.File(unit.PrecompiledHeader.Basename))
+	if hasSymbols {
+		sharedOptions.Append("/Fd" + MakeLocalFilename(sharedDir.File(unit.PrecompiledHeader.Basename+".pdb")))
+	}
+	unit.HeaderUnitOptions = sharedOptions
+
+	base.LogVeryVerbose(LogCompile, "%v: share header unit %q with fingerprint %v", unit, sharedObject, fingerprint.ShortString())
+
+	// compiler options were already decorated with previous header unit, redirect them to the shared one
+	previousObject, nextObject := MakeLocalFilename(unit.PrecompiledObject), MakeLocalFilename(sharedObject)
+	for _, options := range []*base.StringSet{&unit.AnalysisOptions, &unit.CompilerOptions, &unit.PreprocessorOptions} {
+		for i, it := range *options {
+			(*options)[i] = strings.ReplaceAll(it, previousObject, nextObject)
+		}
+	}
+
+	unit.PrecompiledObject = sharedObject
+	return nil
+}
@@ -0,0 +1,134 @@
+package compile
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+)
+
+/***************************************
+ * Label Expression
+ ***************************************/
+
+// Label expressions select modules by their labels, with following syntax:
+//   - `tools` match modules labeled with `tools`
+//   - `!tools` match modules not labeled with `tools`
+//   - `tools&tests` match modules labeled with both `tools` and `tests`
+//   - `tools|tests` match modules labeled with `tools` or `tests`
+//   - parenthesis can be used for grouping, `!` binds tighter than `&`, which binds tighter than `|`
+
+type LabelExpression interface {
+	Match(labels base.StringSet) bool
+	fmt.Stringer
+}
+
+type labelMatch string
+type labelNot struct{ inner LabelExpression }
+type labelAnd struct{ lhs, rhs LabelExpression }
+type labelOr struct{ lhs, rhs LabelExpression }
+
+func (x labelMatch) Match(labels base.StringSet) bool {
+	for _, it := range labels {
+		if strings.EqualFold(it, string(x)) {
+			return true
+		}
+	}
+	return false
+}
+func (x labelNot) Match(labels base.StringSet) bool { return !x.inner.Match(labels) }
+func (x labelAnd) Match(labels base.StringSet) bool {
+	return x.lhs.Match(labels) && x.rhs.Match(labels)
+}
+func (x labelOr) Match(labels base.StringSet) bool {
+	return x.lhs.Match(labels) || x.rhs.Match(labels)
+}
+
+func (x labelMatch) String() string { return string(x) }
+func (x labelNot) String() string   { return fmt.Sprint("!", x.inner) }
+func (x labelAnd) String() string   { return fmt.Sprint("(", x.lhs, "&", x.rhs, ")") }
+func (x labelOr) String() string    { return fmt.Sprint("(", x.lhs, "|", x.rhs, ")") }
+
+func ParseLabelExpression(in string) (LabelExpression, error) {
+	parser := labelParser{input: in}
+	expr, err := parser.parseOr()
+	if err == nil && parser.peek() != 0 {
+		err = parser.unexpected()
+	}
+	return expr, err
+}
+
+type labelParser struct {
+	input  string
+	offset int
+}
+
+func (x *labelParser) peek() rune {
+	for x.offset < len(x.input) && unicode.IsSpace(rune(x.input[x.offset])) {
+		x.offset++
+	}
+	if x.offset < len(x.input) {
+		return rune(x.input[x.offset])
+	}
+	return 0
+}
+func (x *labelParser) unexpected() error {
+	if x.offset < len(x.input) {
+		return fmt.Errorf("label: unexpected %q at offset %d in %q", x.input[x.offset], x.offset, x.input)
+	}
+	return fmt.Errorf("label: unexpected end of expression in %q", x.input)
+}
+func (x *labelParser) parseOr() (LabelExpression, error) {
+	lhs, err := x.parseAnd()
+	for err == nil && x.peek() == '|' {
+		x.offset++
+		var rhs LabelExpression
+		if rhs, err = x.parseAnd(); err == nil {
+			lhs = labelOr{lhs: lhs, rhs: rhs}
+		}
+	}
+	return lhs, err
+}
+func (x *labelParser) parseAnd() (LabelExpression, error) {
+	lhs, err := x.parseUnary()
+	for err == nil && x.peek() == '&' {
+		x.offset++
+		var rhs LabelExpression
+		if rhs, err = x.parseUnary(); err == nil {
+			lhs = labelAnd{lhs: lhs, rhs: rhs}
+		}
+	}
+	return lhs, err
+}
+func (x *labelParser) parseUnary() (LabelExpression, error) {
+	switch x.peek() {
+	case '!':
+		x.offset++
+		inner, err := x.parseUnary()
+		return labelNot{inner: inner}, err
+	case '(':
+		x.offset++
+		inner, err := x.parseOr()
+		if err == nil {
+			if x.peek() != ')' {
+				return nil, x.unexpected()
+			}
+			x.offset++
+		}
+		return inner, err
+	default:
+		start := x.offset
+		for x.offset < len(x.input) {
+			if ch := rune(x.input[x.offset]); ch == '_' || ch == '-' || ch == '.' || unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+				x.offset++
+			} else {
+				break
+			}
+		}
+		if start == x.offset {
+			return nil, x.unexpected()
+		}
+		return labelMatch(x.input[start:x.offset]), nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"sort"
 	"strings"
 
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -95,8 +96,24 @@ func (x *NamespaceModel) Build(bc utils.BuildContext) error {
 		absoluteName = ""
 	}
 
-	for _, it := range x.Children {
-		filename := rules.NamespaceDir.Folder(it).File(it + NAMESPACEMODEL_EXT)
+	if err := x.buildNamespaceEntries(bc, rules, rules.NamespaceDir, x.Children, x.Modules, absoluteName); err != nil {
+		return err
+	}
+
+	if x.RootNamespace {
+		if err := x.buildSourceRoots(bc, rules); err != nil {
+			return err
+		}
+	}
+
+	_, err := bc.OutputFactory(utils.WrapBuildFactory(func(bi utils.BuildInitializer) (*NamespaceRules, error) {
+		return rules, nil
+	}), utils.OptionBuildForce)
+	return err
+}
+func (x *NamespaceModel) buildNamespaceEntries(bc utils.BuildContext, rules *NamespaceRules, dir utils.Directory, children, modules base.StringSet, absoluteName string) error {
+	for _, it := range children {
+		filename := dir.Folder(it).File(it + NAMESPACEMODEL_EXT)
 		if namespace, err := BuildNamespaceModel(filename, absoluteName).Output(bc); err == nil {
 			rules.NamespaceChildren.Append(namespace.GetNamespaceAlias())
 		} else {
@@ -104,19 +121,61 @@ func (x *NamespaceModel) Build(bc utils.BuildContext) error {
 		}
 	}
 
-	for _, it := range x.Modules {
-		filename := rules.NamespaceDir.Folder(it).File(it + MODULEMODEL_EXT)
+	for _, it := range modules {
+		filename := dir.Folder(it).File(it + MODULEMODEL_EXT)
 		if module, err := BuildModuleModel(filename, absoluteName).Output(bc); err == nil {
 			rules.NamespaceModules.Append(module.GetModuleAlias())
 		} else {
 			return err
 		}
 	}
+	return nil
+}
 
-	_, err := bc.OutputFactory(utils.WrapBuildFactory(func(bi utils.BuildInitializer) (*NamespaceRules, error) {
-		return rules, nil
-	}), utils.OptionBuildForce)
-	return err
+// buildSourceRoots merges children and modules declared by additional source roots in root namespace,
+// other settings of their namespace file are ignored
+func (x *NamespaceModel) buildSourceRoots(bc utils.BuildContext, rules *NamespaceRules) error {
+	flags, err := GetSourceRootsFlags(bc)
+	if err != nil {
+		return err
+	}
+
+	sourceRoots := flags.SourceRoots.Normalize()
+	if len(sourceRoots) == 0 {
+		return nil
+	}
+
+	// track source roots content for invalidation, since their namespace file may be added later
+	if err := bc.NeedDirectories(sourceRoots...); err != nil {
+		return err
+	}
+
+	for _, root := range sourceRoots {
+		if root.Equals(rules.NamespaceDir) {
+			continue
+		}
+
+		filename := root.File(x.Source.Basename)
+		if !filename.Exists() {
+			return fmt.Errorf("source root %q does not contain a namespace file, expected %q", root, filename)
+		}
+		if err := bc.NeedFiles(filename); err != nil {
+			return err
+		}
+
+		var extra NamespaceModel
+		if err := utils.UFS.OpenBuffered(filename, func(r io.Reader) error {
+			return base.JsonDeserialize(&extra, r)
+		}); err != nil {
+			return err
+		}
+
+		base.LogVerbose(LogModel, "merge %d children and %d modules from source root %q", len(extra.Children), len(extra.Modules), root)
+		if err := x.buildNamespaceEntries(bc, rules, root, extra.Children, extra.Modules, ""); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 func (x *NamespaceModel) Serialize(ar base.Archive) {
 	ar.Serializable(&x.Children)
@@ -142,7 +201,10 @@ func (x *NamespaceModel) Prepend(o *NamespaceModel) {
 const MODULEMODEL_EXT = "-module.json"
 
 type ModuleModel struct {
-	ModuleType ModuleType
+	ModuleType              ModuleType
+	Labels                  base.StringSet
+	HighPriority            utils.BoolVar
+	ExcludeFromDefaultBuild utils.BoolVar
 
 	SourceDirs    base.StringSet
 	SourceGlobs   base.StringSet
@@ -153,14 +215,25 @@ type ModuleModel struct {
 	IsolatedFiles base.StringSet
 	ExtraFiles    base.StringSet
 	ExtraDirs     base.StringSet
+	TaggedGlobs   map[TagFlags]base.StringSet
+
+	OptimizeOverrides OptimizeOverrides
 
 	PrecompiledHeader utils.StringVar
 	PrecompiledSource utils.StringVar
+	ExportMap         utils.StringVar
+
+	ConditionalDefines ConditionalDefines
+	Probes             CompilerProbes
 
 	PrivateDependencies ModuleAliases
 	PublicDependencies  ModuleAliases
 	RuntimeDependencies ModuleAliases
 
+	PreBuildCommands  []CustomCommandModel
+	PostBuildCommands []CustomCommandModel
+	Assets            []AssetRuleModel
+
 	CppRules
 	ExtensionModel
 }
@@ -213,6 +286,12 @@ func (x *ModuleModel) Build(bc utils.BuildContext) error {
 		return err
 	}
 
+	if flags, err := GetSourceRootsFlags(bc); err == nil {
+		rules.SourceRoot = flags.GetSourceRoot(moduleDir)
+	} else {
+		return err
+	}
+
 	if err := x.applyArchetypes(&rules, moduleAlias); err != nil {
 		return err
 	}
@@ -232,6 +311,9 @@ func (x *ModuleModel) Build(bc utils.BuildContext) error {
 	} else if f := moduleDir.File(PCH_DEFAULT_SOURCE); f.Exists() {
 		rules.PrecompiledSource = f
 	}
+	if !x.ExportMap.IsInheritable() {
+		rules.ExportMap = moduleDir.AbsoluteFile(x.ExportMap.Get()).Normalize()
+	}
 
 	_, err = bc.OutputFactory(utils.WrapBuildFactory(func(bi utils.BuildInitializer) (*ModuleRules, error) {
 		dependencyAliases := make(utils.BuildAliases, 0, len(x.PrivateDependencies)+len(x.PublicDependencies)+len(x.RuntimeDependencies))
@@ -257,10 +339,13 @@ func (x *ModuleModel) createModuleRules(moduleAlias ModuleAlias) (ModuleRules, e
 
 	moduleDir := x.Source.Dirname
 	rules := ModuleRules{
-		ModuleAlias: moduleAlias,
-		ModuleDir:   moduleDir,
-		ModuleType:  x.ModuleType,
-		CppRules:    x.CppRules,
+		ModuleAlias:             moduleAlias,
+		ModuleDir:               moduleDir,
+		ModuleType:              x.ModuleType,
+		Labels:                  x.Labels,
+		HighPriority:            x.HighPriority,
+		ExcludeFromDefaultBuild: x.ExcludeFromDefaultBuild,
+		CppRules:                x.CppRules,
 		Source: ModuleSource{
 			SourceGlobs:   x.SourceGlobs,
 			ExcludedGlobs: x.ExcludedGlobs,
@@ -270,7 +355,12 @@ func (x *ModuleModel) createModuleRules(moduleAlias ModuleAlias) (ModuleRules, e
 			IsolatedFiles: utils.MakeFileSet(moduleDir, x.IsolatedFiles...).Normalize(),
 			ExtraFiles:    utils.MakeFileSet(moduleDir, x.ExtraFiles...).Normalize(),
 			ExtraDirs:     utils.MakeDirSet(moduleDir, x.ExtraDirs...).Normalize(),
+			TaggedGlobs:   make([]ModuleSourceTagged, 0, len(x.TaggedGlobs)),
+
+			OptimizeOverrides: x.OptimizeOverrides,
 		},
+		ConditionalDefines:  x.ConditionalDefines,
+		Probes:              x.Probes,
 		PrivateDependencies: x.PrivateDependencies,
 		PublicDependencies:  x.PublicDependencies,
 		RuntimeDependencies: x.RuntimeDependencies,
@@ -278,6 +368,42 @@ func (x *ModuleModel) createModuleRules(moduleAlias ModuleAlias) (ModuleRules, e
 		PerTags:             map[TagFlags]ModuleRules{},
 	}
 
+	for _, it := range x.PreBuildCommands {
+		command, err := it.CreateCustomCommand(moduleDir)
+		if err != nil {
+			return ModuleRules{}, err
+		}
+		rules.PreBuildCommands.Append(command)
+	}
+	for _, it := range x.PostBuildCommands {
+		command, err := it.CreateCustomCommand(moduleDir)
+		if err != nil {
+			return ModuleRules{}, err
+		}
+		rules.PostBuildCommands.Append(command)
+	}
+	for _, it := range x.Assets {
+		asset, err := it.CreateAssetRule(moduleDir)
+		if err != nil {
+			return ModuleRules{}, err
+		}
+		rules.Assets.Append(asset)
+	}
+
+	if err := x.OptimizeOverrides.Validate(); err != nil {
+		return ModuleRules{}, fmt.Errorf("%v: %w", moduleAlias, err)
+	}
+
+	for tags, globs := range x.TaggedGlobs {
+		rules.Source.TaggedGlobs = append(rules.Source.TaggedGlobs, ModuleSourceTagged{
+			Tags:  tags,
+			Globs: globs,
+		})
+	}
+	sort.Slice(rules.Source.TaggedGlobs, func(i, j int) bool {
+		return rules.Source.TaggedGlobs[i].Tags.Compare(rules.Source.TaggedGlobs[j].Tags) < 0
+	})
+
 	for tags, model := range x.TAG {
 		if model.hasAllowedPlatforms(moduleAlias) {
 			var err error
@@ -292,6 +418,9 @@ func (x *ModuleModel) createModuleRules(moduleAlias ModuleAlias) (ModuleRules, e
 }
 func (x *ModuleModel) Serialize(ar base.Archive) {
 	ar.Serializable(&x.ModuleType)
+	ar.Serializable(&x.Labels)
+	ar.Serializable(&x.HighPriority)
+	ar.Serializable(&x.ExcludeFromDefaultBuild)
 
 	ar.Serializable(&x.SourceDirs)
 	ar.Serializable(&x.SourceGlobs)
@@ -302,19 +431,31 @@ func (x *ModuleModel) Serialize(ar base.Archive) {
 	ar.Serializable(&x.IsolatedFiles)
 	ar.Serializable(&x.ExtraFiles)
 	ar.Serializable(&x.ExtraDirs)
+	base.SerializeMap(ar, &x.TaggedGlobs)
+	ar.Serializable(&x.OptimizeOverrides)
 
 	ar.Serializable(&x.PrecompiledHeader)
 	ar.Serializable(&x.PrecompiledSource)
+	ar.Serializable(&x.ExportMap)
+
+	ar.Serializable(&x.ConditionalDefines)
+	ar.Serializable(&x.Probes)
 
 	base.SerializeSlice(ar, x.PrivateDependencies.Ref())
 	base.SerializeSlice(ar, x.PublicDependencies.Ref())
 	base.SerializeSlice(ar, x.RuntimeDependencies.Ref())
 
+	base.SerializeSlice(ar, &x.PreBuildCommands)
+	base.SerializeSlice(ar, &x.PostBuildCommands)
+	base.SerializeSlice(ar, &x.Assets)
+
 	ar.Serializable(&x.CppRules)
 	ar.Serializable(&x.ExtensionModel)
 }
 func (x *ModuleModel) Append(o *ModuleModel) {
 	base.Inherit(&x.ModuleType, o.ModuleType)
+	base.Inherit(&x.HighPriority, o.HighPriority)
+	base.Inherit(&x.ExcludeFromDefaultBuild, o.ExcludeFromDefaultBuild)
 
 	x.SourceDirs.Append(o.SourceDirs...)
 	x.SourceGlobs.Append(o.SourceGlobs...)
@@ -325,19 +466,38 @@ func (x *ModuleModel) Append(o *ModuleModel) {
 	x.IsolatedFiles.Append(o.IsolatedFiles...)
 	x.ExtraFiles.Append(o.ExtraFiles...)
 	x.ExtraDirs.Append(o.ExtraDirs...)
+	for k, v := range o.TaggedGlobs {
+		if x.TaggedGlobs == nil {
+			x.TaggedGlobs = make(map[TagFlags]base.StringSet, len(o.TaggedGlobs))
+		}
+		globs := x.TaggedGlobs[k]
+		globs.AppendUniq(v...)
+		x.TaggedGlobs[k] = globs
+	}
+	x.OptimizeOverrides = append(x.OptimizeOverrides, o.OptimizeOverrides...)
 
 	x.PrecompiledHeader.Inherit(o.PrecompiledHeader)
 	x.PrecompiledSource.Inherit(o.PrecompiledSource)
+	x.ExportMap.Inherit(o.ExportMap)
+
+	x.ConditionalDefines.Append(o.ConditionalDefines...)
+	x.Probes.Append(o.Probes...)
 
 	x.PrivateDependencies.Append(o.PrivateDependencies...)
 	x.PublicDependencies.Append(o.PublicDependencies...)
 	x.RuntimeDependencies.Append(o.RuntimeDependencies...)
 
+	x.PreBuildCommands = append(x.PreBuildCommands, o.PreBuildCommands...)
+	x.PostBuildCommands = append(x.PostBuildCommands, o.PostBuildCommands...)
+	x.Assets = append(x.Assets, o.Assets...)
+
 	x.CppRules.Inherit(&o.CppRules)
 	x.ExtensionModel.Append(&o.ExtensionModel)
 }
 func (x *ModuleModel) Prepend(o *ModuleModel) {
 	base.Overwrite(&x.ModuleType, o.ModuleType)
+	base.Overwrite(&x.HighPriority, o.HighPriority)
+	base.Overwrite(&x.ExcludeFromDefaultBuild, o.ExcludeFromDefaultBuild)
 
 	x.SourceDirs.Prepend(o.SourceDirs...)
 	x.SourceGlobs.Prepend(o.SourceGlobs...)
@@ -348,14 +508,31 @@ func (x *ModuleModel) Prepend(o *ModuleModel) {
 	x.IsolatedFiles.Prepend(o.IsolatedFiles...)
 	x.ExtraFiles.Prepend(o.ExtraFiles...)
 	x.ExtraDirs.Prepend(o.ExtraDirs...)
+	for k, v := range o.TaggedGlobs {
+		if x.TaggedGlobs == nil {
+			x.TaggedGlobs = make(map[TagFlags]base.StringSet, len(o.TaggedGlobs))
+		}
+		globs := x.TaggedGlobs[k]
+		globs.PrependUniq(v...)
+		x.TaggedGlobs[k] = globs
+	}
+	x.OptimizeOverrides = append(base.CopySlice(o.OptimizeOverrides...), x.OptimizeOverrides...)
 
 	x.PrecompiledHeader.Overwrite(o.PrecompiledHeader)
 	x.PrecompiledSource.Overwrite(o.PrecompiledSource)
+	x.ExportMap.Overwrite(o.ExportMap)
+
+	x.ConditionalDefines.Prepend(o.ConditionalDefines...)
+	x.Probes.Prepend(o.Probes...)
 
 	x.PrivateDependencies.Prepend(o.PrivateDependencies...)
 	x.PublicDependencies.Prepend(o.PublicDependencies...)
 	x.RuntimeDependencies.Prepend(o.RuntimeDependencies...)
 
+	x.PreBuildCommands = append(base.CopySlice(o.PreBuildCommands...), x.PreBuildCommands...)
+	x.PostBuildCommands = append(base.CopySlice(o.PostBuildCommands...), x.PostBuildCommands...)
+	x.Assets = append(base.CopySlice(o.Assets...), x.Assets...)
+
 	x.CppRules.Inherit(&o.CppRules)
 	x.ExtensionModel.Prepend(&o.ExtensionModel)
 }
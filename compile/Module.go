@@ -3,6 +3,7 @@ package compile
 import (
 	"fmt"
 	"path"
+	"regexp"
 	"strings"
 
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -87,6 +88,13 @@ func (x ModuleAlias) AutoComplete(in base.AutoComplete) {
 	}
 }
 func (x *ModuleAlias) Set(in string) (err error) {
+	if err = x.parse(in); err == nil {
+		// old names of renamed modules are still accepted, see LoadModuleAliases()
+		*x = ResolveModuleAlias(*x)
+	}
+	return
+}
+func (x *ModuleAlias) parse(in string) (err error) {
 	if parts := SplitPath(in); len(parts) > 1 {
 		x.ModuleName = parts[len(parts)-1]
 		return x.NamespaceAlias.Set(path.Join(parts[0 : len(parts)-1]...))
@@ -104,15 +112,107 @@ func (x *ModuleAlias) UnmarshalText(data []byte) error {
  * Module Source
  ***************************************/
 
+// ModuleSourceTagged restricts source files matching globs to compile environments with any of the tags,
+// they are excluded from every other environment (ex: `*_debug.cpp` only compiled with DEBUG tag).
+type ModuleSourceTagged struct {
+	Tags  TagFlags
+	Globs base.StringSet
+}
+
+func (x *ModuleSourceTagged) Serialize(ar base.Archive) {
+	ar.Serializable(&x.Tags)
+	ar.Serializable(&x.Globs)
+}
+
+// OptimizeOverrides lists source file globs with the optimization level they should be compiled with, ex:
+// "Private/Hot*.cpp=FOR_SHIPPING,Private/Miscompiled.cpp=NONE". The first matching glob wins, and those files
+// are compiled in their own action, never bundled in a unity file with code optimized differently.
+type OptimizeOverrides base.StringSet
+
+func (x OptimizeOverrides) IsInheritable() bool {
+	return len(x) == 0
+}
+func (x OptimizeOverrides) String() string {
+	return strings.Join(x, ",")
+}
+func (x *OptimizeOverrides) Set(in string) error {
+	*x = OptimizeOverrides{}
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			if _, _, err := parseOptimizeOverride(it); err != nil {
+				return err
+			}
+			*x = append(*x, it)
+		}
+	}
+	return nil
+}
+func (x *OptimizeOverrides) Serialize(ar base.Archive) {
+	ar.Serializable((*base.StringSet)(x))
+}
+
+// Validate checks every override can be parsed, so errors are reported when loading the module
+func (x OptimizeOverrides) Validate() error {
+	for _, it := range x {
+		if _, _, err := parseOptimizeOverride(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the optimization level of the first glob matching given source file, relative to module directory
+func (x OptimizeOverrides) Find(moduleDir Directory, file Filename) (OptimizationLevel, bool) {
+	if len(x) == 0 {
+		return OPTIMIZE_INHERIT, false
+	}
+	relative := file.Relative(moduleDir)
+	for _, it := range x {
+		glob, level, err := parseOptimizeOverride(it)
+		if err == nil && getOptimizeOverrideRegexp(glob).MatchString(relative) {
+			return level, true
+		}
+	}
+	return OPTIMIZE_INHERIT, false
+}
+
+// globs are anchored, so "Hot*.cpp" does not match "Private/NotHot.cpp", and compiled only once
+var optimizeOverrideRegexps = base.NewSharedMapT[string, base.Regexp]()
+
+func getOptimizeOverrideRegexp(glob string) base.Regexp {
+	if re, ok := optimizeOverrideRegexps.Get(glob); ok {
+		return re
+	}
+	re, _ := optimizeOverrideRegexps.FindOrAdd(glob, base.Regexp{
+		Regexp: regexp.MustCompile("^(?:" + MakeGlobRegexpExpr(glob) + ")$"),
+	})
+	return re
+}
+
+func parseOptimizeOverride(in string) (glob string, level OptimizationLevel, err error) {
+	var levelStr string
+	var ok bool
+	if glob, levelStr, ok = strings.Cut(in, "="); !ok || len(glob) == 0 {
+		err = fmt.Errorf("compile: invalid optimize override %q, expected GLOB=LEVEL (ex: Private/Hot*.cpp=FOR_SPEED)", in)
+		return
+	}
+	if err = level.Set(levelStr); err == nil && level.IsInheritable() {
+		err = fmt.Errorf("compile: invalid optimize override %q, level can't be %v", in, level)
+	}
+	return
+}
+
 type ModuleSource struct {
-	SourceDirs    DirSet
-	SourceGlobs   base.StringSet
-	ExcludedGlobs base.StringSet
-	SourceFiles   FileSet
-	ExcludedFiles FileSet
-	IsolatedFiles FileSet
-	ExtraFiles    FileSet
-	ExtraDirs     DirSet
+	SourceDirs        DirSet
+	SourceGlobs       base.StringSet
+	ExcludedGlobs     base.StringSet
+	SourceFiles       FileSet
+	ExcludedFiles     FileSet
+	IsolatedFiles     FileSet
+	ExtraFiles        FileSet
+	ExtraDirs         DirSet
+	TaggedGlobs       []ModuleSourceTagged
+	OptimizeOverrides OptimizeOverrides
 }
 
 func (x *ModuleSource) Append(o ModuleSource) {
@@ -124,6 +224,8 @@ func (x *ModuleSource) Append(o ModuleSource) {
 	x.IsolatedFiles.Append(o.IsolatedFiles...)
 	x.ExtraFiles.Append(o.ExtraFiles...)
 	x.ExtraDirs.Append(o.ExtraDirs...)
+	x.TaggedGlobs = append(x.TaggedGlobs, o.TaggedGlobs...)
+	x.OptimizeOverrides = append(x.OptimizeOverrides, o.OptimizeOverrides...)
 }
 func (x *ModuleSource) Prepend(o ModuleSource) {
 	x.SourceDirs.Prepend(o.SourceDirs...)
@@ -134,6 +236,8 @@ func (x *ModuleSource) Prepend(o ModuleSource) {
 	x.IsolatedFiles.Prepend(o.IsolatedFiles...)
 	x.ExtraFiles.Prepend(o.ExtraFiles...)
 	x.ExtraDirs.Prepend(o.ExtraDirs...)
+	x.TaggedGlobs = append(base.CopySlice(o.TaggedGlobs...), x.TaggedGlobs...)
+	x.OptimizeOverrides = append(base.CopySlice(o.OptimizeOverrides...), x.OptimizeOverrides...)
 }
 func (x *ModuleSource) Serialize(ar base.Archive) {
 	ar.Serializable(&x.SourceDirs)
@@ -144,6 +248,29 @@ func (x *ModuleSource) Serialize(ar base.Archive) {
 	ar.Serializable(&x.IsolatedFiles)
 	ar.Serializable(&x.ExtraFiles)
 	ar.Serializable(&x.ExtraDirs)
+	base.SerializeSlice(ar, &x.TaggedGlobs)
+	ar.Serializable(&x.OptimizeOverrides)
+}
+func (x *ModuleSource) ExpandTags(tags TagFlags) {
+	if len(x.TaggedGlobs) == 0 {
+		return
+	}
+
+	// copy globs before modifying them, since they can be shared with source module rules
+	sourceGlobs := base.NewStringSet(x.SourceGlobs...)
+	excludedGlobs := base.NewStringSet(x.ExcludedGlobs...)
+
+	for _, it := range x.TaggedGlobs {
+		if selectedTags := tags.Intersect(it.Tags); !selectedTags.Empty() {
+			sourceGlobs.AppendUniq(it.Globs...)
+		} else {
+			excludedGlobs.AppendUniq(it.Globs...)
+		}
+	}
+
+	x.SourceGlobs = sourceGlobs
+	x.ExcludedGlobs = excludedGlobs
+	x.TaggedGlobs = nil // tagged globs are now resolved
 }
 func (x *ModuleSource) GetFileSet(bc BuildContext) (FileSet, error) {
 	result := FileSet{}
@@ -171,13 +298,23 @@ type ModuleRules struct {
 	ModuleAlias ModuleAlias
 
 	ModuleDir  Directory
+	SourceRoot Directory // source root owning module directory, see SourceRootsFlags
 	ModuleType ModuleType
+	Labels     base.StringSet
+
+	HighPriority            BoolVar // actions are queued before those of other modules, see ActionPriorityFlags
+	ExcludeFromDefaultBuild BoolVar // only built when selected explicitly, see NeedDefaultBuildModules
 
 	CppRules
 
 	PrecompiledHeader Filename
 	PrecompiledSource Filename
 
+	ExportMap Filename // symbols exported by shared library: module definition file (.def) or linker version script
+
+	ConditionalDefines ConditionalDefines
+	Probes             CompilerProbes
+
 	PublicDependencies  ModuleAliases
 	PrivateDependencies ModuleAliases
 	RuntimeDependencies ModuleAliases
@@ -185,6 +322,10 @@ type ModuleRules struct {
 	Customs    CustomList
 	Generators GeneratorList
 
+	PreBuildCommands  CustomCommandList
+	PostBuildCommands CustomCommandList
+	Assets            AssetRuleList
+
 	Facet
 	Source ModuleSource
 
@@ -211,7 +352,7 @@ func (rules *ModuleRules) String() string {
 }
 
 func (rules *ModuleRules) RelativePath() string {
-	return rules.ModuleDir.Relative(UFS.Source)
+	return rules.ModuleDir.Relative(rules.SourceRoot)
 }
 func (rules *ModuleRules) PublicDir() Directory {
 	return rules.ModuleDir.Folder("Public")
@@ -237,6 +378,7 @@ func (x *ModuleRules) DeepCopy(src *ModuleRules) {
 	// then, clone reference values
 	x.CppRules.DeepCopy(&src.CppRules)
 
+	x.Labels = base.CopySlice(src.Labels...)
 	x.PublicDependencies = base.CopySlice(src.PublicDependencies...)
 	x.PrivateDependencies = base.CopySlice(src.PrivateDependencies...)
 	x.RuntimeDependencies = base.CopySlice(src.RuntimeDependencies...)
@@ -244,6 +386,10 @@ func (x *ModuleRules) DeepCopy(src *ModuleRules) {
 	x.Customs = base.CopySlice(src.Customs...)
 	x.Generators = base.CopySlice(src.Generators...)
 
+	x.PreBuildCommands = base.CopySlice(src.PreBuildCommands...)
+	x.PostBuildCommands = base.CopySlice(src.PostBuildCommands...)
+	x.Assets = base.CopySlice(src.Assets...)
+
 	x.Facet.DeepCopy(&src.Facet)
 
 	x.PerTags = base.CopyMap(x.PerTags)
@@ -270,6 +416,11 @@ func (rules *ModuleRules) ExpandModule(env *CompileEnv) ModuleRules {
 		rules.expandTagsRec(env, &expanded)
 	}
 
+	// select source files restricted to some tags, so the file set can differ by environment
+	if env != nil {
+		expanded.Source.ExpandTags(env.Tags)
+	}
+
 	// always return a copy: rules should not be modified outside of Build()
 	return expanded
 }
@@ -283,6 +434,8 @@ func (rules *ModuleRules) Decorate(bg BuildGraphReadPort, env *CompileEnv, unit
 	// unit.TransitiveFacet.ForceIncludes.Append(rules.ForceIncludes...)
 	unit.TransitiveFacet.Libraries.Append(rules.Libraries...)
 	unit.TransitiveFacet.LibraryPaths.Append(rules.LibraryPaths...)
+	unit.TransitiveFacet.Frameworks.Append(rules.Frameworks...)
+	unit.TransitiveFacet.FrameworkPaths.Append(rules.FrameworkPaths...)
 
 	if publicDir := rules.PublicDir(); publicDir.Exists() {
 		unit.IncludePaths.Append(publicDir)
@@ -313,13 +466,22 @@ func (rules *ModuleRules) Serialize(ar base.Archive) {
 	ar.Serializable(&rules.ModuleAlias)
 
 	ar.Serializable(&rules.ModuleDir)
+	ar.Serializable(&rules.SourceRoot)
 	ar.Serializable(&rules.ModuleType)
+	ar.Serializable(&rules.Labels)
+	ar.Serializable(&rules.HighPriority)
+	ar.Serializable(&rules.ExcludeFromDefaultBuild)
 
 	ar.Serializable(&rules.CppRules)
 
 	ar.Serializable(&rules.PrecompiledHeader)
 	ar.Serializable(&rules.PrecompiledSource)
 
+	ar.Serializable(&rules.ExportMap)
+
+	ar.Serializable(&rules.ConditionalDefines)
+	ar.Serializable(&rules.Probes)
+
 	base.SerializeSlice(ar, rules.PublicDependencies.Ref())
 	base.SerializeSlice(ar, rules.PrivateDependencies.Ref())
 	base.SerializeSlice(ar, rules.RuntimeDependencies.Ref())
@@ -327,6 +489,10 @@ func (rules *ModuleRules) Serialize(ar base.Archive) {
 	ar.Serializable(&rules.Customs)
 	ar.Serializable(&rules.Generators)
 
+	ar.Serializable(&rules.PreBuildCommands)
+	ar.Serializable(&rules.PostBuildCommands)
+	ar.Serializable(&rules.Assets)
+
 	ar.Serializable(&rules.Facet)
 	ar.Serializable(&rules.Source)
 
@@ -344,6 +510,9 @@ func (rules *ModuleRules) Generate(vis VisibilityType, name string, gen Generato
 func (x *ModuleRules) Append(other *ModuleRules) {
 	x.CppRules.Inherit(other.GetCpp())
 
+	x.Labels.AppendUniq(other.Labels...)
+	base.Inherit(&x.HighPriority, other.HighPriority)
+	base.Inherit(&x.ExcludeFromDefaultBuild, other.ExcludeFromDefaultBuild)
 	x.ForceIncludes.Append(other.ForceIncludes...)
 
 	x.Source.Append(other.Source)
@@ -354,6 +523,12 @@ func (x *ModuleRules) Append(other *ModuleRules) {
 	if !x.PrecompiledSource.Valid() {
 		x.PrecompiledSource = other.PrecompiledSource
 	}
+	if !x.ExportMap.Valid() {
+		x.ExportMap = other.ExportMap
+	}
+
+	x.ConditionalDefines.Append(other.ConditionalDefines...)
+	x.Probes.Append(other.Probes...)
 
 	x.PrivateDependencies.Append(other.PrivateDependencies...)
 	x.PublicDependencies.Append(other.PublicDependencies...)
@@ -362,11 +537,18 @@ func (x *ModuleRules) Append(other *ModuleRules) {
 	x.Customs.Append(other.Customs...)
 	x.Generators.Append(other.Generators...)
 
+	x.PreBuildCommands.Append(other.PreBuildCommands...)
+	x.PostBuildCommands.Append(other.PostBuildCommands...)
+	x.Assets.Append(other.Assets...)
+
 	x.Facet.Append(other)
 }
 func (x *ModuleRules) Prepend(other *ModuleRules) {
 	x.Overwrite(other.GetCpp())
 
+	x.Labels.PrependUniq(other.Labels...)
+	base.Overwrite(&x.HighPriority, other.HighPriority)
+	base.Overwrite(&x.ExcludeFromDefaultBuild, other.ExcludeFromDefaultBuild)
 	x.ForceIncludes.Prepend(other.ForceIncludes...)
 
 	x.Source.Prepend(other.Source)
@@ -377,6 +559,12 @@ func (x *ModuleRules) Prepend(other *ModuleRules) {
 	if other.PrecompiledSource.Valid() {
 		x.PrecompiledSource = other.PrecompiledSource
 	}
+	if other.ExportMap.Valid() {
+		x.ExportMap = other.ExportMap
+	}
+
+	x.ConditionalDefines.Prepend(other.ConditionalDefines...)
+	x.Probes.Prepend(other.Probes...)
 
 	x.PrivateDependencies.Prepend(other.PrivateDependencies...)
 	x.PublicDependencies.Prepend(other.PublicDependencies...)
@@ -385,6 +573,10 @@ func (x *ModuleRules) Prepend(other *ModuleRules) {
 	x.Customs.Prepend(other.Customs...)
 	x.Generators.Prepend(other.Generators...)
 
+	x.PreBuildCommands.Prepend(other.PreBuildCommands...)
+	x.PostBuildCommands.Prepend(other.PostBuildCommands...)
+	x.Assets.Prepend(other.Assets...)
+
 	x.Facet.Prepend(other)
 }
 
@@ -416,7 +608,7 @@ func (x *ModuleRules) Build(bc BuildContext) error {
 }
 
 func FindBuildModule(bg BuildGraphReadPort, module ModuleAlias) (Module, error) {
-	return FindBuildable[Module](bg, module.Alias())
+	return FindBuildable[Module](bg, ResolveModuleAlias(module).Alias())
 }
 
 func NeedBuildModules(bc BuildContext, moduleAliases ...ModuleAlias) (modules []Module, err error) {
@@ -449,6 +641,24 @@ func NeedAllBuildModules(bc BuildContext) (modules []Module, err error) {
 	return NeedBuildModules(bc, moduleAliases...)
 }
 
+// NeedDefaultBuildModules returns all modules, except those which opted out with ExcludeFromDefaultBuild
+func NeedDefaultBuildModules(bc BuildContext) (modules []Module, err error) {
+	allModules, err := NeedAllBuildModules(bc)
+	if err != nil {
+		return
+	}
+
+	modules = make([]Module, 0, len(allModules))
+	for _, module := range allModules {
+		if module.GetModule().ExcludeFromDefaultBuild.Get() {
+			base.LogVeryVerbose(LogCompile, "%v: excluded from default build", module.GetModule())
+			continue
+		}
+		modules = append(modules, module)
+	}
+	return
+}
+
 func NeedAllModuleAliases(bc BuildContext) (moduleAliases []ModuleAlias, err error) {
 	rootModel, err := BuildRootNamespaceModel().Need(bc)
 	if err != nil {
@@ -0,0 +1,105 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Module Aliases
+ ***************************************/
+
+// Renamed modules can still be referenced with their old name, with a deprecation warning, when declared
+// in <prefix>-module-aliases.json found next to root namespace. This way saved configs, scripts and modules
+// depending on the old name keep working during migrations. For instance:
+//
+//	{ "Runtime/OldName": "Runtime/NewName" }
+
+const MODULEALIASES_EXT = "-module-aliases.json"
+
+var AllModuleAliases base.SharedMapT[ModuleAlias, ModuleAlias]
+
+// deprecation warning is only printed once for each old name
+var deprecatedModuleAliases base.SharedMapT[ModuleAlias, bool]
+
+func GetModuleAliasesFile() Filename {
+	return UFS.Source.File(CommandEnv.Prefix() + MODULEALIASES_EXT)
+}
+
+// LoadModuleAliases registers module renames declared in given file, if it exists:
+// it must be called before parsing command-line, so old names are resolved everywhere.
+func LoadModuleAliases(source Filename) error {
+	if !source.Exists() {
+		return nil
+	}
+
+	renames := make(map[string]string)
+	if err := UFS.OpenBuffered(source, func(r io.Reader) error {
+		return base.JsonDeserialize(&renames, r)
+	}); err != nil {
+		return fmt.Errorf("compile: failed to parse module aliases in %q: %w", source, err)
+	}
+
+	oldNames := make([]string, 0, len(renames))
+	for oldName := range renames {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+
+	for _, oldName := range oldNames {
+		var from, to ModuleAlias
+		if err := from.parse(oldName); err != nil {
+			return fmt.Errorf("compile: invalid module alias %q in %q: %w", oldName, source, err)
+		}
+		if err := to.parse(renames[oldName]); err != nil {
+			return fmt.Errorf("compile: invalid module alias %q in %q: %w", renames[oldName], source, err)
+		}
+
+		base.LogVerbose(LogCompile, "register module alias %q -> %q from %q", from, to, source)
+		AllModuleAliases.Add(from, to)
+	}
+
+	// renames can be chained, but must not loop
+	for _, from := range AllModuleAliases.Keys() {
+		if _, err := resolveModuleAlias(from); err != nil {
+			return fmt.Errorf("compile: invalid module aliases in %q: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// ResolveModuleAlias returns the current name of a renamed module, or the module itself when it was not renamed
+func ResolveModuleAlias(module ModuleAlias) ModuleAlias {
+	resolved, err := resolveModuleAlias(module)
+	base.LogPanicIfFailed(LogCompile, err) // loops were already checked when loading aliases
+
+	if resolved != module {
+		if _, loaded := deprecatedModuleAliases.FindOrAdd(module, true); !loaded {
+			base.LogWarning(LogCompile, "module %q was renamed to %q, please update your references", module, resolved)
+		}
+	}
+	return resolved
+}
+
+func resolveModuleAlias(module ModuleAlias) (ModuleAlias, error) {
+	visiteds := []ModuleAlias{module}
+	for {
+		renamed, ok := AllModuleAliases.Get(module)
+		if !ok {
+			return module, nil
+		}
+		for _, it := range visiteds {
+			if it == renamed {
+				return module, fmt.Errorf("module alias loop detected: %v -> %v", visiteds, renamed)
+			}
+		}
+		visiteds = append(visiteds, renamed)
+		module = renamed
+	}
+}
@@ -0,0 +1,86 @@
+package compile
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Source File Ownership
+ ***************************************/
+
+// Directory.IsIn() only compares prefixes, which would match sibling directories (ex: Core and CoreUObject)
+func isFileInDirectory(file Filename, dir Directory) bool {
+	return file.Dirname.Equals(dir) || strings.HasPrefix(file.Dirname.Path, dir.Path+string(os.PathSeparator))
+}
+
+// IsModuleOwningFile is a cheap test, which does not need to expand source globs of the module
+func IsModuleOwningFile(rules *ModuleRules, file Filename) bool {
+	if isFileInDirectory(file, rules.ModuleDir) || rules.Source.SourceFiles.Contains(file) {
+		return true
+	}
+	for _, dir := range rules.Source.SourceDirs {
+		if isFileInDirectory(file, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindOwningModules returns modules whose directories (or explicit source files) contain given file
+func FindOwningModules(bc BuildContext, file Filename) (owners []Module, err error) {
+	modules, err := NeedAllBuildModules(bc)
+	if err != nil {
+		return
+	}
+
+	for _, module := range modules {
+		if IsModuleOwningFile(module.GetModule(), file) {
+			owners = append(owners, module)
+		}
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].GetModule().ModuleAlias.Compare(owners[j].GetModule().ModuleAlias) < 0
+	})
+	return
+}
+
+// FindOwningTargets returns targets of given environment which actually compile given source file,
+// by expanding source globs of every module owning it
+func FindOwningTargets(bg BuildGraphWritePort, ea EnvironmentAlias, sourceFile Filename, modules ...Module) (targets TargetAliases, err error) {
+	if len(modules) == 0 {
+		if modules, err = FindOwningModules(bg.GlobalContext(), sourceFile); err != nil {
+			return
+		}
+	}
+
+	for _, module := range modules {
+		target := TargetAlias{
+			EnvironmentAlias: ea,
+			ModuleAlias:      module.GetModule().ModuleAlias,
+		}
+
+		if _, future := bg.Build(&target); future.Join().Failure() != nil {
+			return nil, future.Join().Failure()
+		}
+
+		var unit *Unit
+		if unit, err = FindBuildUnit(bg, target); err != nil {
+			return
+		}
+
+		var sourceFiles FileSet
+		if sourceFiles, err = unit.Source.GetFileSet(bg.GlobalContext()); err != nil {
+			return
+		}
+		if sourceFiles.Contains(sourceFile) {
+			targets.Append(target)
+		}
+	}
+	return
+}
@@ -2,6 +2,7 @@ package compile
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -70,8 +71,15 @@ type Platform interface {
 type PlatformRules struct {
 	PlatformAlias PlatformAlias
 
-	Os   string
-	Arch ArchType
+	Os       string
+	Arch     ArchType
+	Profiles base.StringSet // named flag profiles, see AllFlagProfiles
+
+	// Instructions is the baseline implied by Arch (ex: x64 implies SSE2), always merged with unit instruction sets
+	Instructions InstructionSets
+
+	// Definitions are documented base defines contributed to every unit, see GetPlatformDefinitions
+	Definitions PlatformDefinitions
 
 	Facet
 }
@@ -90,6 +98,9 @@ func (rules *PlatformRules) Serialize(ar base.Archive) {
 
 	ar.String(&rules.Os)
 	ar.Serializable(&rules.Arch)
+	ar.Serializable(&rules.Profiles)
+	ar.Serializable(&rules.Instructions)
+	ar.Serializable(&rules.Definitions)
 
 	ar.Serializable(&rules.Facet)
 }
@@ -102,25 +113,84 @@ func (rules *PlatformRules) Decorate(_ BuildGraphReadPort, _ *CompileEnv, unit *
 var Platform_X86 = &PlatformRules{
 	PlatformAlias: NewPlatformAlias("x86"),
 	Arch:          ARCH_X86,
-	Facet: Facet{
-		Defines: []string{"ARCH_X86", "ARCH_32BIT"},
+	Instructions:  base.NewEnumSet(INSTRUCTIONSET_SSE2),
+	Definitions: PlatformDefinitions{
+		{Define: "ARCH_X86", Description: "target x86 architecture"},
+		{Define: "ARCH_32BIT", Description: "target has 32-bit pointers"},
 	},
 }
 var Platform_X64 = &PlatformRules{
 	PlatformAlias: NewPlatformAlias("x64"),
 	Arch:          ARCH_X64,
-	Facet: Facet{
-		Defines: []string{"ARCH_X64", "ARCH_64BIT"},
+	Instructions:  base.NewEnumSet(INSTRUCTIONSET_SSE2),
+	Definitions: PlatformDefinitions{
+		{Define: "ARCH_X64", Description: "target x64 architecture"},
+		{Define: "ARCH_64BIT", Description: "target has 64-bit pointers"},
 	},
 }
 var Platform_ARM = &PlatformRules{
 	PlatformAlias: NewPlatformAlias("arm"),
 	Arch:          ARCH_ARM,
-	Facet: Facet{
-		Defines: []string{"ARCH_ARM", "ARCH_64BIT"},
+	Definitions: PlatformDefinitions{
+		{Define: "ARCH_ARM", Description: "target ARM architecture"},
+		{Define: "ARCH_64BIT", Description: "target has 64-bit pointers"},
 	},
 }
 
+/***************************************
+ * Platform Definitions
+ ***************************************/
+
+// PlatformDefinition is a base define contributed by a platform to every unit, with a description of its meaning.
+// Like compiler defines (ex: CPP_VISUALSTUDIO), they are merged in compile environment, thus before module defines.
+type PlatformDefinition struct {
+	Define      string // can hold a value, ex: PLATFORM_PAGESIZE=4096
+	Description string
+}
+
+func (x *PlatformDefinition) Serialize(ar base.Archive) {
+	ar.String(&x.Define)
+	ar.String(&x.Description)
+}
+
+type PlatformDefinitions []PlatformDefinition
+
+func (list *PlatformDefinitions) Append(it ...PlatformDefinition) {
+	*list = append(*list, it...)
+}
+func (list *PlatformDefinitions) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]PlatformDefinition)(list))
+}
+func (list PlatformDefinitions) Defines() base.StringSet {
+	return base.Map(func(it PlatformDefinition) string { return it.Define }, list...)
+}
+
+// GetPlatformDefinitions returns base definitions of given platform, followed by definitions added from command-line
+func GetPlatformDefinitions(bi BuildInitializer, platform *PlatformRules) (PlatformDefinitions, error) {
+	flags, err := GetPlatformDefinesFlags(bi)
+	if err != nil {
+		return nil, err
+	}
+
+	results := base.CopySlice(platform.Definitions...)
+	for _, it := range strings.Split(flags.PlatformDefines.Get(), ",") {
+		if it = strings.TrimSpace(it); len(it) == 0 {
+			continue
+		}
+
+		pattern, define, ok := strings.Cut(it, ":")
+		if !ok || len(pattern) == 0 || len(define) == 0 {
+			return nil, fmt.Errorf("invalid PlatformDefines entry %q, expected <platform>:<define>", it)
+		}
+		if matched, err := path.Match(pattern, platform.PlatformAlias.String()); err != nil {
+			return nil, fmt.Errorf("invalid PlatformDefines pattern %q: %w", pattern, err)
+		} else if matched {
+			results = append(results, PlatformDefinition{Define: define, Description: "user-defined with -PlatformDefines"})
+		}
+	}
+	return results, nil
+}
+
 /***************************************
  * Build Platform Factory
  ***************************************/
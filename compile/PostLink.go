@@ -0,0 +1,133 @@
+package compile
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Post-Link Action
+ ***************************************/
+
+// PostLinkAction processes the output of a linked module (codesigning, compression, manifest embedding...).
+// Actions are registered by name in AllPostLinkActions, usually by a HAL, and selected per module or
+// environment with CppRules.PostLink: every selected action receives the linked output file of the unit,
+// and the files it produces are tracked by the build graph as post-build outputs of the module.
+type PostLinkAction interface {
+	// PostLinkModel returns the command processing input file, where argument is the optional value given
+	// after the action name (ex: "copy=Deploy"). Output file of returned model must differ from input file.
+	PostLinkModel(unit *Unit, input Filename, argument string) (action.ActionModel, error)
+	// AllowCaching should only return true when outputs depend exclusively on input file and command-line
+	AllowCaching() bool
+}
+
+var AllPostLinkActions base.SharedMapT[string, PostLinkAction]
+
+/***************************************
+ * Post-Link Action Names
+ ***************************************/
+
+// PostLinkActionNames lists post-link actions applied to a module output, ex: "strip,copy=Deploy/Bin"
+type PostLinkActionNames base.StringSet
+
+func (x PostLinkActionNames) IsInheritable() bool {
+	return len(x) == 0
+}
+func (x PostLinkActionNames) String() string {
+	return strings.Join(x, ",")
+}
+func (x *PostLinkActionNames) Set(in string) error {
+	*x = PostLinkActionNames{}
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			*x = append(*x, it)
+		}
+	}
+	return nil
+}
+func (x *PostLinkActionNames) Serialize(ar base.Archive) {
+	ar.Serializable((*base.StringSet)(x))
+}
+
+// Resolve returns registered post-link action for each name, along with the argument given after '='
+func (x PostLinkActionNames) Resolve(each func(string, PostLinkAction, string) error) error {
+	for _, it := range x {
+		name, argument, _ := strings.Cut(it, "=")
+		postLink, ok := AllPostLinkActions.Get(name)
+		if !ok {
+			return fmt.Errorf("compile: unknown post-link action %q, available actions: %v", name, AllPostLinkActions.Keys())
+		}
+		if err := each(name, postLink, argument); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/***************************************
+ * Post-Link Command
+ ***************************************/
+
+// PostLinkCommand is a generic post-link action running an executable found in PATH: %1 is substituted
+// with the linked output file and %2 with the file produced by the command in arguments.
+// Produced file has the same basename than input and is written in the directory given as argument,
+// relative to root, or in DefaultDir (relative to input directory) when no argument was given.
+type PostLinkCommand struct {
+	Executable string
+	Arguments  base.StringSet
+	DefaultDir string
+	Cacheable  bool
+}
+
+func (x *PostLinkCommand) AllowCaching() bool {
+	return x.Cacheable
+}
+func (x *PostLinkCommand) PostLinkModel(unit *Unit, input Filename, argument string) (action.ActionModel, error) {
+	var outputDir Directory
+	switch {
+	case len(argument) > 0 && filepath.IsAbs(argument):
+		outputDir = MakeDirectory(argument)
+	case len(argument) > 0:
+		outputDir = UFS.Root.AbsoluteFolder(argument)
+	case len(x.DefaultDir) > 0:
+		outputDir = input.Dirname.Folder(x.DefaultDir)
+	default:
+		return action.ActionModel{}, fmt.Errorf("missing destination directory, expected NAME=DIR (ex: copy=Deploy)")
+	}
+
+	output := outputDir.File(input.Basename)
+	if output.Equals(input) {
+		return action.ActionModel{}, fmt.Errorf("%q can't overwrite its own input", input)
+	}
+
+	executable, err := exec.LookPath(x.Executable)
+	if err != nil {
+		return action.ActionModel{}, err
+	}
+
+	arguments := make(base.StringSet, len(x.Arguments))
+	for i, arg := range x.Arguments {
+		arg = strings.ReplaceAll(arg, "%1", input.String())
+		arguments[i] = strings.ReplaceAll(arg, "%2", output.String())
+	}
+
+	return action.ActionModel{
+		Command: action.CommandRules{
+			Arguments:   arguments,
+			Environment: unit.Environment,
+			Executable:  MakeFilename(filepath.Clean(executable)),
+			WorkingDir:  UFS.Root,
+		},
+		StaticInputFiles: FileSet{input},
+		ExportFile:       output,
+		OutputFile:       output,
+	}, nil
+}
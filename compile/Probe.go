@@ -0,0 +1,167 @@
+package compile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Compiler Probes
+ ***************************************/
+
+// CompilerProbe compiles a small snippet with the actual compiler to detect a feature, ex:
+//
+//	"Probes": [{ "Define": "HAS_STD_EXPECTED", "Source": "#include <expected>\nstd::expected<int, int> e;" }]
+//
+// Define is set to 1 when the snippet compiled successfully, or to 0 otherwise.
+type CompilerProbe struct {
+	Define  string
+	Source  string
+	Options base.StringSet // extra compiler options, appended after compiler rules options
+}
+
+func (x *CompilerProbe) Serialize(ar base.Archive) {
+	ar.String(&x.Define)
+	ar.String(&x.Source)
+	ar.Serializable(&x.Options)
+}
+
+type CompilerProbes []CompilerProbe
+
+func (list *CompilerProbes) Append(it ...CompilerProbe) {
+	*list = append(*list, it...)
+}
+func (list *CompilerProbes) Prepend(it ...CompilerProbe) {
+	*list = append(it, *list...)
+}
+func (list *CompilerProbes) Serialize(ar base.Archive) {
+	base.SerializeSlice(ar, (*[]CompilerProbe)(list))
+}
+
+// Evaluate should be called once unit properties are resolved for its environment, results are cached in build graph
+func (list CompilerProbes) Evaluate(bc BuildContext, unit *Unit) error {
+	for _, it := range list {
+		if len(it.Define) == 0 {
+			return fmt.Errorf("%v: compiler probe is missing a define name", unit)
+		}
+
+		result, err := NeedCompilerProbeResult(unit.CompilerAlias, unit.CppStd, it).Need(bc)
+		if err != nil {
+			return err
+		}
+
+		base.LogVeryVerbose(LogCompile, "%v: compiler probe %q supported: %v", unit, it.Define, result.Supported)
+		if result.Supported {
+			unit.Defines.Append(it.Define + "=1")
+		} else {
+			unit.Defines.Append(it.Define + "=0")
+		}
+	}
+	return nil
+}
+
+/***************************************
+ * Compiler Probe Result
+ ***************************************/
+
+// CompilerProbeResult depends on the compiler, so it is only compiled again when the compiler changed.
+// Snippet is compiled with compiler rules options and requested C++ standard, but without options from
+// any unit: a probe can't depend on the defines it is computing.
+
+type CompilerProbeResult struct {
+	CompilerAlias CompilerAlias
+	CppStd        CppStdType
+	CompilerProbe
+	Supported bool
+}
+
+func NeedCompilerProbeResult(compilerAlias CompilerAlias, cppStd CppStdType, probe CompilerProbe) BuildFactoryTyped[*CompilerProbeResult] {
+	return MakeBuildFactory(func(bi BuildInitializer) (CompilerProbeResult, error) {
+		return CompilerProbeResult{
+			CompilerAlias: compilerAlias,
+			CppStd:        cppStd,
+			CompilerProbe: probe,
+		}, bi.DependsOn(compilerAlias.Alias())
+	})
+}
+
+func (x *CompilerProbeResult) Fingerprint() base.Fingerprint {
+	fingerprint, err := base.SerializeAnyFingerprint(func(ar base.Archive) error {
+		ar.Serializable(&x.CppStd)
+		ar.Serializable(&x.CompilerProbe)
+		return nil
+	}, base.Fingerprint{})
+	base.LogPanicIfFailed(LogCompile, err)
+	return fingerprint
+}
+func (x *CompilerProbeResult) Alias() BuildAlias {
+	return MakeBuildAlias("Probe", x.CompilerAlias.String(), x.Define, x.Fingerprint().ShortString())
+}
+func (x *CompilerProbeResult) Serialize(ar base.Archive) {
+	ar.Serializable(&x.CompilerAlias)
+	ar.Serializable(&x.CppStd)
+	ar.Serializable(&x.CompilerProbe)
+	ar.Bool(&x.Supported)
+}
+func (x *CompilerProbeResult) Build(bc BuildContext) error {
+	x.Supported = false
+
+	compiler, err := FindBuildable[Compiler](bc, x.CompilerAlias.Alias())
+	if err != nil {
+		return err
+	}
+	rules := compiler.GetCompiler()
+
+	facet := NewFacet()
+	facet.Append(rules)
+	facet.CompilerOptions.Append(x.Options...)
+
+	compiler.CppStd(&facet, x.CppStd)
+	compiler.Define(&facet, facet.Defines...)
+	compiler.SystemIncludePath(&facet, facet.SystemIncludePaths...)
+
+	probeDir := UFS.Intermediate.Folder("Probe", x.CompilerAlias.String())
+	if err := internal_io.CreateDirectory(bc, probeDir); err != nil {
+		return err
+	}
+
+	sourceFile := probeDir.File(fmt.Sprintf("%s-%s.cpp", x.Define, x.Fingerprint().ShortString()))
+	objectFile := sourceFile.ReplaceExt(compiler.Extname(PAYLOAD_OBJECTLIST))
+
+	if err := UFS.CreateBuffered(sourceFile, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, x.Source)
+		return err
+	}, base.TransientPage4KiB); err != nil {
+		return err
+	}
+
+	arguments := make(base.StringSet, len(facet.CompilerOptions))
+	for i, arg := range facet.CompilerOptions {
+		arg = strings.ReplaceAll(arg, "%1", sourceFile.String())
+		arg = strings.ReplaceAll(arg, "%2", objectFile.String())
+		arguments[i] = arg
+	}
+
+	// run from root, like compilation actions, since probe options can contain local paths
+	err = internal_io.RunProcess(rules.Executable, arguments,
+		internal_io.OptionProcessEnvironment(rules.Environment),
+		internal_io.OptionProcessWorkingDir(UFS.Root),
+		internal_io.OptionProcessNoSpinner,
+		internal_io.OptionProcessOutput(func(output string) error {
+			base.LogVerbose(LogCompile, "compiler probe %q failed:\n%s", x.Define, output)
+			return nil
+		}))
+
+	// a compilation failure is the expected result of a probe, not an error
+	x.Supported = (err == nil)
+
+	base.LogVerbose(LogCompile, "%v: compiler probe %q supported: %v", x.CompilerAlias, x.Define, x.Supported)
+	return nil
+}
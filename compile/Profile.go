@@ -0,0 +1,80 @@
+package compile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+)
+
+/***************************************
+ * Flag Profile
+ ***************************************/
+
+// FlagProfile is a named set of C++ rules which can be shared by many environments (ex: "release"),
+// instead of duplicating the same defaults in every platform or configuration referencing them.
+// A profile can be composed of other profiles, which are inherited after the profile own rules.
+
+var AllFlagProfiles base.SharedMapT[string, *FlagProfile]
+
+type FlagProfile struct {
+	ProfileName string
+	Profiles    base.StringSet
+
+	CppRules
+}
+
+func (x *FlagProfile) String() string {
+	return x.ProfileName
+}
+func (x *FlagProfile) Serialize(ar base.Archive) {
+	ar.String(&x.ProfileName)
+	ar.Serializable(&x.Profiles)
+	ar.Serializable(&x.CppRules)
+}
+
+// ResolveFlagProfiles flattens given profiles and the profiles they are composed of:
+// first profiles take precedence, and a profile always takes precedence over its own composed profiles.
+func ResolveFlagProfiles(names ...string) (result CppRules, err error) {
+	err = resolveFlagProfiles(&result, []string{}, names...)
+	return
+}
+
+func resolveFlagProfiles(result *CppRules, stack []string, names ...string) error {
+	for _, name := range names {
+		for _, it := range stack {
+			if it == name {
+				return fmt.Errorf("compile: recursive flag profile %q (%s)", name, strings.Join(append(stack, name), " -> "))
+			}
+		}
+
+		profile, ok := AllFlagProfiles.Get(name)
+		if !ok {
+			return fmt.Errorf("compile: unknown flag profile %q", name)
+		}
+
+		result.Inherit(&profile.CppRules)
+
+		if err := resolveFlagProfiles(result, append(stack, name), profile.Profiles...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var FlagProfile_Strict = &FlagProfile{
+	ProfileName: "strict",
+	CppRules: CppRules{
+		Warnings: CppWarnings{
+			Default: WARNING_ERROR,
+		},
+	},
+}
+var FlagProfile_Release = &FlagProfile{
+	ProfileName: "release",
+	Profiles:    base.NewStringSet(FlagProfile_Strict.ProfileName),
+	CppRules: CppRules{
+		Optimize:   OPTIMIZE_FOR_SHIPPING,
+		RuntimeLib: RUNTIMELIB_STATIC,
+	},
+}
@@ -0,0 +1,96 @@
+package compile
+
+import (
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Intermediate Files Retention
+ ***************************************/
+
+// only stale compilation outputs are deleted when untracked: other files in intermediate dir can still be used by
+// tools outside of the build graph (ex: EditAndContinue.pdb, written by the compiler and read by the debugger)
+var retentionIntermediateExts = base.NewStringSet(".obj", ".o", ".pch", ".gch", ".ifc", ".res", ".d", ".tmp")
+
+// ApplyRetentionPolicy deletes intermediate files of given targets after a successful build. The build graph is never modified:
+// untracked files are unknown to the next build, while deleted tracked files are detected as missing and produced again when needed.
+func ApplyRetentionPolicy(bg BuildGraphWritePort, policy RetentionPolicy, targets ...*TargetActions) error {
+	if policy == RETENTION_KEEPALL {
+		return nil
+	}
+
+	numFiles := 0
+	totalSize := int64(0)
+	removeFile := func(file Filename) error {
+		if info, err := file.Info(); err == nil {
+			totalSize += info.Size()
+		}
+		numFiles++
+		base.LogVeryVerbose(LogCompile, "retention: remove intermediate file %q", file)
+		return UFS.Remove(file)
+	}
+
+	for _, ta := range targets {
+		unit, err := FindBuildUnit(bg, ta.TargetAlias)
+		if err != nil {
+			return err
+		}
+		if !unit.IntermediateDir.Exists() {
+			continue
+		}
+
+		// tracked files needed by next build are kept, unless they can be deleted after link
+		deletables := FileSet{}
+		if policy == RETENTION_AFTERLINK && unit.Payload.HasLinker() {
+			for _, payloadType := range []PayloadType{PAYLOAD_OBJECTLIST, PAYLOAD_PRECOMPILEDHEADER} {
+				if !ta.PresentPayloads.Has(payloadType) {
+					continue
+				}
+				payload, err := ta.GetPayload(bg, payloadType)
+				if err != nil {
+					return err
+				}
+				actions, err := payload.GetActions(bg)
+				if err != nil {
+					return err
+				}
+				deletables.AppendUniq(actions.GetOutputFiles()...)
+			}
+		}
+
+		if err := unit.IntermediateDir.MatchFilesRec(func(file Filename) error {
+			companion := file.ReplaceExt("") // ex: Object.obj.pdb -> Object.obj
+			switch {
+			case deletables.Contains(file):
+				return removeFile(file)
+			case isTrackedIntermediateFile(bg, file):
+				return nil
+			case isTrackedIntermediateFile(bg, companion) && !deletables.Contains(companion):
+				// companion of a tracked file which is kept (ex: PDB per object), could still be referenced by next link
+				return nil
+			case retentionIntermediateExts.Contains(strings.ToLower(file.Ext())):
+				return removeFile(file)
+			default:
+				base.LogDebug(LogCompile, "retention: keep untracked file %q", file)
+				return nil
+			}
+		}, base.Regexp{}); err != nil {
+			return err
+		}
+	}
+
+	if numFiles > 0 {
+		base.LogInfo(LogCompile, "retention: removed %d intermediate files (%v) with %v policy", numFiles, base.SizeInBytes(totalSize), policy)
+	}
+	return nil
+}
+
+func isTrackedIntermediateFile(bg BuildGraphReadPort, file Filename) bool {
+	_, err := bg.Expect(file.Alias())
+	return err == nil
+}
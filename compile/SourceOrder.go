@@ -0,0 +1,113 @@
+package compile
+
+import (
+	"github.com/poppolopoppo/ppb/action"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Dependency-Ordered Source Files
+ ***************************************/
+
+// Include relationships are recovered from dynamic dependencies recorded by compilation actions (like private
+// include enforcement), so the target should have been built first. Those dependencies are flattened: a header
+// is known to be included by a translation unit, but not by which header, thus headers are only ordered
+// against sources. Files without recorded dependencies and include cycles fall back to sorted order.
+
+// FindOrderedSourceFiles returns sources of given target and headers of its module they include, where
+// included files always come before files including them
+func FindOrderedSourceFiles(bg BuildGraphWritePort, ta *TargetActions) (FileSet, error) {
+	unit, err := FindBuildUnit(bg, ta.TargetAlias)
+	if err != nil {
+		return FileSet{}, err
+	}
+	module, err := FindBuildModule(bg, ta.TargetAlias.ModuleAlias)
+	if err != nil {
+		return FileSet{}, err
+	}
+
+	sourceFiles, err := unit.Source.GetFileSet(bg.GlobalContext())
+	if err != nil {
+		return FileSet{}, err
+	}
+
+	includes := make(map[Filename]FileSet, len(sourceFiles))
+	for _, it := range sourceFiles {
+		includes[it] = FileSet{}
+	}
+
+	if err := ForeachTranslationUnit(bg, func(_ TargetAlias, input Filename, rules *action.ActionRules) error {
+		files, err := bg.GetDependencyInputFiles(false, rules.Alias())
+		if err != nil {
+			return err
+		}
+
+		var headers, sources FileSet
+		for _, it := range files {
+			switch {
+			case it.Equals(input):
+				continue
+			case sourceFiles.Contains(it):
+				sources.AppendUniq(it)
+			case IsModuleOwningFile(module.GetModule(), it):
+				headers.AppendUniq(it)
+			}
+		}
+
+		if _, ok := includes[input]; ok {
+			includes[input] = includes[input].ConcatUniq(headers...).ConcatUniq(sources...)
+		} else {
+			// generated unity file: headers can't be attributed to each source it includes, assume they all need them
+			for _, source := range sources {
+				includes[source] = includes[source].ConcatUniq(headers...)
+			}
+		}
+		for _, it := range headers {
+			if _, ok := includes[it]; !ok {
+				includes[it] = FileSet{}
+			}
+		}
+		return nil
+	}, ta); err != nil {
+		return FileSet{}, err
+	}
+
+	return sortFilesTopologically(includes), nil
+}
+
+// sortFilesTopologically runs Kahn's algorithm, always picking the smallest path among ready files to stay stable
+func sortFilesTopologically(includes map[Filename]FileSet) (results FileSet) {
+	pending := make(FileSet, 0, len(includes))
+	numIncludes := make(map[Filename]int, len(includes))
+	includedBy := make(map[Filename]FileSet, len(includes))
+	for file, deps := range includes {
+		pending.Append(file)
+		numIncludes[file] = len(deps)
+		for _, it := range deps {
+			includedBy[it] = append(includedBy[it], file)
+		}
+	}
+	pending.Sort()
+
+	results = make(FileSet, 0, len(pending))
+	for len(pending) > 0 {
+		next := 0
+		for i, it := range pending {
+			if numIncludes[it] == 0 {
+				next = i
+				break
+			}
+		} // when no file is ready, there is an include cycle: break it with the smallest path
+
+		file := pending[next]
+		pending = append(pending[:next], pending[next+1:]...) // preserve order
+		results.Append(file)
+
+		for _, it := range includedBy[file] {
+			numIncludes[it]--
+		}
+	}
+	return
+}
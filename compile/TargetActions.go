@@ -18,11 +18,13 @@ import (
 // TargetPayload is separated from TargetActions to avoid
 // invalidation of *ALL* actions when any source file was changed.
 // They also serve as a build alias for all actions associated to a payload.
+// Post-build actions are built with the payload, but their outputs are not exported to dependent targets.
 
 type TargetPayload struct {
-	TargetAlias   TargetAlias
-	PayloadType   PayloadType
-	ActionAliases action.ActionAliases
+	TargetAlias      TargetAlias
+	PayloadType      PayloadType
+	ActionAliases    action.ActionAliases
+	PostBuildAliases action.ActionAliases
 }
 
 func MakeTargetPayloadAlias(ta TargetAlias, payload PayloadType) BuildAlias {
@@ -47,6 +49,7 @@ func (x *TargetPayload) Serialize(ar base.Archive) {
 	ar.Serializable(&x.TargetAlias)
 	ar.Serializable(&x.PayloadType)
 	base.SerializeSlice(ar, x.ActionAliases.Ref())
+	base.SerializeSlice(ar, x.PostBuildAliases.Ref())
 }
 
 func (x *TargetPayload) GetActions(bg BuildGraphReadPort) (action.ActionSet, error) {
@@ -179,6 +182,105 @@ func ForeachTargetActions(bg BuildGraphReadPort, ea EnvironmentAlias, each func(
 	return nil
 }
 
+/***************************************
+ * Source File Actions
+ ***************************************/
+
+// SourceFileActions compiles a single source file with the flags of its owning unit, without linking
+// or compiling other files of the unit. When the file is merged in a unity file, a dedicated object
+// is created where it would be written without unity, so it can still be cached like any other object.
+
+type SourceFileActions struct {
+	TargetAlias TargetAlias
+	SourceFile  Filename
+	ActionAlias action.ActionAlias
+}
+
+func NeedSourceFileActions(targetAlias TargetAlias, sourceFile Filename) BuildFactoryTyped[*SourceFileActions] {
+	return MakeBuildFactory(func(bi BuildInitializer) (SourceFileActions, error) {
+		return SourceFileActions{
+			TargetAlias: targetAlias,
+			SourceFile:  sourceFile,
+		}, bi.DependsOn(MakeTargetActionsAlias(targetAlias))
+	})
+}
+
+func (x *SourceFileActions) Alias() BuildAlias {
+	// a source file can be compiled by several modules, ex: shared sources or overlapping source roots
+	return MakeBuildAlias("SourceFile", x.TargetAlias.ModuleAlias.String(), x.TargetAlias.PlatformName, x.TargetAlias.ConfigName, x.SourceFile.String())
+}
+func (x *SourceFileActions) Build(bc BuildContext) error {
+	targetActions, err := FindTargetActions(bc, x.TargetAlias)
+	if err != nil {
+		return err
+	}
+
+	unit, err := FindBuildUnit(bc, x.TargetAlias)
+	if err != nil {
+		return err
+	}
+
+	sourceFiles, err := unit.Source.GetFileSet(bc)
+	if err != nil {
+		return err
+	}
+	if !sourceFiles.Contains(x.SourceFile) {
+		return fmt.Errorf("source file %q is not part of <%v>", x.SourceFile, x.TargetAlias)
+	}
+
+	compiler, err := unit.GetBuildCompiler(bc)
+	if err != nil {
+		return err
+	}
+
+	// reuse the object action of the unit when the file is not merged in a unity file
+	x.ActionAlias = action.NewActionAlias(unit.GetPayloadOutput(compiler, x.SourceFile, PAYLOAD_OBJECTLIST))
+	if targetPayload, err := targetActions.GetPayload(bc, PAYLOAD_OBJECTLIST); err == nil && targetPayload.ActionAliases.Contains(x.ActionAlias) {
+		return nil
+	}
+
+	// precompiled header and header units are shared with the unit, custom prebuild actions are expected to be up-to-date
+	var pchs, headerUnits action.ActionSet
+	if targetActions.PresentPayloads.Any(PAYLOAD_PRECOMPILEDHEADER) {
+		if pchs, err = x.getPayloadActions(bc, targetActions, PAYLOAD_PRECOMPILEDHEADER); err != nil {
+			return err
+		}
+	}
+	if targetActions.PresentPayloads.Any(PAYLOAD_HEADERUNIT) {
+		if headerUnits, err = x.getPayloadActions(bc, targetActions, PAYLOAD_HEADERUNIT); err != nil {
+			return err
+		}
+	}
+
+	generator := buildActionGenerator{
+		Environment:   x.TargetAlias.EnvironmentAlias,
+		Unit:          unit,
+		Compiler:      compiler,
+		TargetActions: targetActions,
+		BuildContext:  bc,
+	}
+
+	objs, err := generator.ObjectActions(FileSet{x.SourceFile}, action.ActionSet{}, headerUnits, pchs)
+	if err != nil {
+		return err
+	}
+
+	x.ActionAlias = objs[0].GetAction().GetActionAlias()
+	return nil
+}
+func (x *SourceFileActions) Serialize(ar base.Archive) {
+	ar.Serializable(&x.TargetAlias)
+	ar.Serializable(&x.SourceFile)
+	ar.Serializable(&x.ActionAlias)
+}
+func (x *SourceFileActions) getPayloadActions(bc BuildContext, targetActions *TargetActions, payloadType PayloadType) (action.ActionSet, error) {
+	targetPayload, err := targetActions.GetPayload(bc, payloadType)
+	if err != nil {
+		return action.ActionSet{}, err
+	}
+	return targetPayload.GetActions(bc)
+}
+
 /***************************************
  * Build Action Generator
  ***************************************/
@@ -194,16 +296,21 @@ type buildActionGenerator struct {
 }
 
 func (x *buildActionGenerator) CreateActions() error {
-	var targetOutputs action.ActionSet
+	var targetOutputs, postlinks action.ActionSet
 	x.OutputType = x.Unit.Payload
 
-	customs, err := x.CustomActions()
+	prebuilds, err := x.CustomActions(CUSTOM_PREBUILD, action.ActionSet{})
+	if err != nil {
+		return err
+	}
+
+	customs, err := x.CustomActions(CUSTOM_COMPILE, prebuilds)
 	if err != nil {
 		return err
 	}
 
 	if x.Unit.Payload != PAYLOAD_HEADERS {
-		pchs, err := x.PrecompilerHeaderActions(customs)
+		pchs, err := x.PrecompilerHeaderActions(customs.Concat(prebuilds...))
 		if err != nil {
 			return err
 		}
@@ -212,7 +319,7 @@ func (x *buildActionGenerator) CreateActions() error {
 			return err
 		}
 
-		headerUnits, err := x.HeaderUnitActions(customs)
+		headerUnits, err := x.HeaderUnitActions(customs.Concat(prebuilds...))
 		if err != nil {
 			return err
 		}
@@ -221,15 +328,11 @@ func (x *buildActionGenerator) CreateActions() error {
 			return err
 		}
 
-		objs, err := x.ObjectListActions(headerUnits, pchs)
+		objs, err := x.ObjectListActions(prebuilds, headerUnits, pchs)
 		if err != nil {
 			return err
 		}
 
-		if err := x.CreatePayload(PAYLOAD_OBJECTLIST, objs.Aliases()); err != nil {
-			return err
-		}
-
 		switch x.Unit.Payload {
 		case PAYLOAD_EXECUTABLE, PAYLOAD_SHAREDLIB:
 			link, err := x.LinkActions(headerUnits, pchs, objs.Concat(customs...))
@@ -238,12 +341,13 @@ func (x *buildActionGenerator) CreateActions() error {
 			}
 			base.AssertNotIn(len(link), 0)
 
-			if err := x.CreatePayload(x.Unit.Payload, link.Aliases()); err != nil {
+			targetOutputs = link
+
+			postlinks, err = x.PostLinkActions(link)
+			if err != nil {
 				return err
 			}
 
-			targetOutputs = link
-
 		case PAYLOAD_STATICLIB:
 			lib, err := x.LibrarianActions(headerUnits, pchs, objs.Concat(customs...))
 			if err != nil {
@@ -251,10 +355,6 @@ func (x *buildActionGenerator) CreateActions() error {
 			}
 			base.AssertNotIn(len(lib), 0)
 
-			if err := x.CreatePayload(PAYLOAD_STATICLIB, lib.Aliases()); err != nil {
-				return err
-			}
-
 			targetOutputs = lib
 
 		case PAYLOAD_OBJECTLIST:
@@ -264,14 +364,36 @@ func (x *buildActionGenerator) CreateActions() error {
 			base.UnexpectedValuePanic(x.Unit.Payload, x.Unit.Payload)
 		}
 
-	} else {
-		if err := x.ForceCreatePayload(PAYLOAD_HEADERS, customs.Aliases()); err != nil {
-			return err
+		// output payload is created below, with post-build actions
+		if x.Unit.Payload != PAYLOAD_OBJECTLIST {
+			if err := x.CreatePayload(PAYLOAD_OBJECTLIST, objs.Aliases()); err != nil {
+				return err
+			}
 		}
 
+	} else {
 		targetOutputs = customs
 	}
 
+	postbuilds, err := x.CustomActions(CUSTOM_POSTBUILD, targetOutputs.Concat(postlinks...).Concat(prebuilds...))
+	if err != nil {
+		return err
+	}
+
+	// assets do not depend on module output, only on pre-build commands
+	assets, err := x.AssetActions(prebuilds)
+	if err != nil {
+		return err
+	}
+
+	postbuilds = postlinks.Concat(postbuilds...).Concat(assets...)
+
+	if x.Unit.Payload == PAYLOAD_HEADERS || len(targetOutputs) > 0 || len(postbuilds) > 0 {
+		if err := x.ForceCreatePayload(x.Unit.Payload, targetOutputs.Aliases(), postbuilds.Aliases()...); err != nil {
+			return err
+		}
+	}
+
 	base.AssertErr(func() error {
 		if x.OutputType == PAYLOAD_HEADERS || len(targetOutputs) > 0 {
 			return nil
@@ -299,17 +421,21 @@ func (x *buildActionGenerator) NumActions() (total int) {
 	}
 	return
 }
-func (x *buildActionGenerator) ForceCreatePayload(payloadType PayloadType, actionAliases action.ActionAliases) error {
+func (x *buildActionGenerator) ForceCreatePayload(payloadType PayloadType, actionAliases action.ActionAliases, postBuildAliases ...action.ActionAlias) error {
 	targetPayload := &TargetPayload{
-		TargetAlias:   x.TargetAlias,
-		PayloadType:   payloadType,
-		ActionAliases: actionAliases,
+		TargetAlias:      x.TargetAlias,
+		PayloadType:      payloadType,
+		ActionAliases:    actionAliases,
+		PostBuildAliases: postBuildAliases,
 	}
 
 	x.PresentPayloads.Add(payloadType)
 	x.TargetPayloads[payloadType] = targetPayload
 
 	return x.BuildContext.OutputNode(WrapBuildFactory(func(bi BuildInitializer) (*TargetPayload, error) {
+		if err := bi.DependsOn(MakeBuildAliases(targetPayload.PostBuildAliases...)...); err != nil {
+			return nil, err
+		}
 		return targetPayload, bi.DependsOn(MakeBuildAliases(targetPayload.ActionAliases...)...)
 	}))
 }
@@ -320,10 +446,7 @@ func (x *buildActionGenerator) CreatePayload(payloadType PayloadType, actionAlia
 	return nil
 }
 
-func (x *buildActionGenerator) CreateAction(
-	payload PayloadType,
-	model action.ActionModel,
-) (action.Action, error) {
+func (x *buildActionGenerator) PrepareActionModel(payload PayloadType, model *action.ActionModel) {
 	// check if caching is allowed by compiler for this payload
 	cacheMode := x.Compiler.AllowCaching(x.Unit, payload)
 	base.AssertNotIn(cacheMode, action.CACHE_INHERIT)
@@ -348,8 +471,19 @@ func (x *buildActionGenerator) CreateAction(
 		model.Options.Add(action.OPT_ALLOW_RESPONSEFILE)
 	}
 
+	// ignore headers from system/extern include paths when tracking dependencies
+	if x.Unit.ExcludeSystemHeaders.Get() {
+		model.ExcludedDirs = x.Unit.SystemIncludePaths.Concat(x.Unit.ExternIncludePaths...)
+	}
+
 	// expand %1, %2 and %3: this is the final step, after every other side-effect has been applied
-	model.Command.Arguments = performArgumentSubstitution(payload, &model)
+	model.Command.Arguments = performArgumentSubstitution(payload, model)
+}
+func (x *buildActionGenerator) CreateAction(
+	payload PayloadType,
+	model action.ActionModel,
+) (action.Action, error) {
+	x.PrepareActionModel(payload, &model)
 
 	// finally, outputs generated action in build graph
 	actionFactory := action.BuildAction(&model,
@@ -406,23 +540,29 @@ func (x *buildActionGenerator) HeaderUnitActions(dependencies action.ActionSet)
 			Dirname:  x.Unit.PrecompiledObject.Dirname,
 			Basename: x.Unit.PrecompiledObject.Basename + x.Compiler.Extname(PAYLOAD_OBJECTLIST)}
 
-		buildAction, err := x.CreateAction(
-			PAYLOAD_HEADERUNIT,
-			action.ActionModel{
-				Command: action.CommandRules{
-					Arguments:   x.Unit.HeaderUnitOptions,
-					Environment: compilerRules.Environment,
-					Executable:  compilerRules.Executable,
-					WorkingDir:  UFS.Root,
-				},
-				StaticInputFiles: FileSet{x.Unit.PrecompiledHeader},
-				ExportFile:       headerUnitObject,
-				OutputFile:       x.Unit.PrecompiledObject,
-				StaticDeps:       MakeBuildAliases(dependencies...),
-				Options: action.MakeOptionFlags(
-					action.OPT_ALLOW_SOURCEDEPENDENCIES,
-					action.OPT_HIGH_PRIORITY /* bottleneck all compilation actions from this unit */),
-			})
+		model := action.ActionModel{
+			Command: action.CommandRules{
+				Arguments:   x.Unit.HeaderUnitOptions,
+				Environment: x.Unit.Environment,
+				Executable:  compilerRules.Executable,
+				WorkingDir:  UFS.Root,
+			},
+			StaticInputFiles: FileSet{x.Unit.PrecompiledHeader},
+			ExportFile:       headerUnitObject,
+			OutputFile:       x.Unit.PrecompiledObject,
+			StaticDeps:       MakeBuildAliases(dependencies...),
+			Options: action.MakeOptionFlags(
+				action.OPT_ALLOW_SOURCEDEPENDENCIES,
+				action.OPT_HIGH_PRIORITY /* bottleneck all compilation actions from this unit */),
+		}
+
+		var buildAction action.Action
+		var err error
+		if x.Unit.HeaderUnitCache.Get() {
+			buildAction, err = x.SharedHeaderUnitAction(model)
+		} else {
+			buildAction, err = x.CreateAction(PAYLOAD_HEADERUNIT, model)
+		}
 		if err != nil {
 			return action.ActionSet{}, err
 		}
@@ -432,6 +572,32 @@ func (x *buildActionGenerator) HeaderUnitActions(dependencies action.ActionSet)
 	return actions, nil
 }
 
+func (x *buildActionGenerator) SharedHeaderUnitAction(model action.ActionModel) (action.Action, error) {
+	// shared header units can't depend on module actions, see Unit.shareHeaderUnit()
+	if len(model.StaticDeps) > 0 {
+		return nil, fmt.Errorf("%v: shared header unit %q can't depend on module actions: %v", x.Unit, x.Unit.PrecompiledHeader, model.StaticDeps)
+	}
+	model.Command.Environment = x.Compiler.GetCompiler().Environment
+	x.PrepareActionModel(PAYLOAD_HEADERUNIT, &model)
+
+	headerUnit, err := x.BuildContext.NeedFactory(WrapBuildFactory(func(bi BuildInitializer) (*SharedHeaderUnit, error) {
+		return &SharedHeaderUnit{
+			CompilerAlias: x.Unit.CompilerAlias,
+			Header:        x.Unit.PrecompiledHeader,
+			ExportFile:    model.ExportFile,
+			OutputFile:    model.OutputFile,
+			Command:       model.Command,
+			Options:       model.Options,
+			ExcludedDirs:  model.ExcludedDirs,
+		}, bi.DependsOn(x.Unit.CompilerAlias.Alias())
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return action.FindBuildAction(x, headerUnit.(*SharedHeaderUnit).GetActionAlias())
+}
+
 func (x *buildActionGenerator) PrecompilerHeaderActions(dependencies action.ActionSet) (action.ActionSet, error) {
 	actions := action.ActionSet{}
 	switch x.Unit.PCH {
@@ -447,7 +613,7 @@ func (x *buildActionGenerator) PrecompilerHeaderActions(dependencies action.Acti
 			action.ActionModel{
 				Command: action.CommandRules{
 					Arguments:   x.Unit.PrecompiledHeaderOptions,
-					Environment: compilerRules.Environment,
+					Environment: x.Unit.Environment,
 					Executable:  compilerRules.Executable,
 					WorkingDir:  UFS.Root,
 				},
@@ -475,9 +641,21 @@ func (x *buildActionGenerator) PrecompilerHeaderActions(dependencies action.Acti
 	}
 	return actions, nil
 }
-func (x *buildActionGenerator) CustomActions() (action.ActionSet, error) {
+func (x *buildActionGenerator) CustomActions(stage CustomUnitStage, dependencies action.ActionSet) (action.ActionSet, error) {
 	result := action.ActionSet{}
 	for _, custom := range x.Unit.CustomUnits {
+		if custom.Stage != stage {
+			continue
+		}
+		if stage != CUSTOM_COMPILE {
+			if command, err := x.CustomCommandAction(&custom, dependencies); err == nil {
+				result.Append(command)
+			} else {
+				return action.ActionSet{}, err
+			}
+			continue
+		}
+
 		compiler, err := custom.GetBuildCompiler(x)
 		if err != nil {
 			return action.ActionSet{}, err
@@ -489,7 +667,7 @@ func (x *buildActionGenerator) CustomActions() (action.ActionSet, error) {
 			TargetActions: x.TargetActions,
 			BuildContext:  x.BuildContext,
 		}
-		if actions, err := generator.ObjectListActions(action.ActionSet{}, action.ActionSet{}); err == nil {
+		if actions, err := generator.ObjectListActions(dependencies, action.ActionSet{}, action.ActionSet{}); err == nil {
 			result.Append(actions...)
 		} else {
 			return action.ActionSet{}, err
@@ -497,20 +675,101 @@ func (x *buildActionGenerator) CustomActions() (action.ActionSet, error) {
 	}
 	return result, nil
 }
-func (x *buildActionGenerator) ObjectListActions(headerUnits, pchs action.ActionSet) (action.ActionSet, error) {
-	includeDeps, err := x.GetOutputActions(x.Unit.IncludeDependencies...)
+func (x *buildActionGenerator) CustomCommandAction(custom *CustomUnit, dependencies action.ActionSet) (action.Action, error) {
+	command := &custom.Command
+	base.AssertNotIn(len(command.Outputs), 0)
+
+	environment := custom.Environment.Clone()
+	environment.Replace(command.Environment)
+
+	model := action.ActionModel{
+		Command: action.CommandRules{
+			Arguments:   command.Arguments,
+			Environment: environment,
+			Executable:  command.Executable,
+			WorkingDir:  command.WorkingDir,
+		},
+		StaticInputFiles: command.Inputs,
+		ExportFile:       command.Outputs[0],
+		OutputFile:       command.Outputs[0],
+		ExtraFiles:       command.Outputs[1:],
+		StaticDeps:       MakeBuildAliases(dependencies...),
+	}
+
+	// custom commands are opaque: they are neither cached nor distributed, and their arguments are not expanded
+	actionFactory := action.BuildAction(&model,
+		func(model *action.ActionModel) (action.Action, error) {
+			rules := model.CreateActionRules()
+			return &rules, nil
+		})
+
+	if buildable, err := x.BuildContext.OutputFactory(actionFactory, OptionBuildForce); err == nil {
+		return buildable.(action.Action), nil
+	} else {
+		return nil, err
+	}
+}
+func (x *buildActionGenerator) PostLinkActions(link action.ActionSet) (action.ActionSet, error) {
+	result := action.ActionSet{}
+	err := x.Unit.PostLink.Resolve(func(name string, postLink PostLinkAction, argument string) error {
+		model, err := postLink.PostLinkModel(x.Unit, x.Unit.OutputFile, argument)
+		if err != nil {
+			return fmt.Errorf("post-link action %q failed for %q: %w", name, x.Unit, err)
+		}
+		base.AssertErr(func() error {
+			if model.OutputFile.Equals(x.Unit.OutputFile) {
+				return fmt.Errorf("post-link action %q can't overwrite linked output %q", name, x.Unit.OutputFile)
+			}
+			return nil
+		})
+
+		model.StaticDeps.Append(MakeBuildAliases(link...)...)
+
+		// only cache post-link actions when both the action and the compiler allow it for linked payload
+		if postLink.AllowCaching() {
+			cacheMode := x.Compiler.AllowCaching(x.Unit, x.Unit.Payload)
+			if cacheMode.HasRead() {
+				model.Options.Add(action.OPT_ALLOW_CACHEREAD)
+			}
+			if cacheMode.HasWrite() {
+				model.Options.Add(action.OPT_ALLOW_CACHEWRITE)
+			}
+		}
+
+		actionFactory := action.BuildAction(&model,
+			func(model *action.ActionModel) (action.Action, error) {
+				rules := model.CreateActionRules()
+				return &rules, nil
+			})
+
+		buildable, err := x.BuildContext.OutputFactory(actionFactory, OptionBuildForce)
+		if err == nil {
+			result.Append(buildable.(action.Action))
+		}
+		return err
+	})
+	return result, err
+}
+func (x *buildActionGenerator) ObjectListActions(dependencies, headerUnits, pchs action.ActionSet) (action.ActionSet, error) {
+	sourceFiles, err := x.Unit.GetSourceFiles(x.BuildContext)
 	if err != nil {
 		return action.ActionSet{}, err
 	}
 
-	sourceFiles, err := x.Unit.GetSourceFiles(x.BuildContext)
+	return x.ObjectActions(sourceFiles, dependencies, headerUnits, pchs)
+}
+func (x *buildActionGenerator) ObjectActions(sourceFiles FileSet, dependencies, headerUnits, pchs action.ActionSet) (action.ActionSet, error) {
+	includeDeps, err := x.GetOutputActions(x.Unit.IncludeDependencies...)
 	if err != nil {
 		return action.ActionSet{}, err
 	}
 
 	compilerRules := x.Compiler.GetCompiler()
 
-	includeAliases := make(BuildAliases, 0, len(includeDeps)+len(headerUnits)+1)
+	includeAliases := make(BuildAliases, 0, len(dependencies)+len(includeDeps)+len(headerUnits)+1)
+	for _, it := range dependencies {
+		includeAliases.Append(it.Alias())
+	}
 	for _, it := range includeDeps {
 		includeAliases.Append(it.Alias())
 	}
@@ -527,12 +786,21 @@ func (x *buildActionGenerator) ObjectListActions(headerUnits, pchs action.Action
 		staticInputFiles := FileSet{input}
 		dynamicInputFiles := FileSet{}
 
+		// files matching an optimize override are isolated from unity, so input is the overridden source file
+		arguments := x.Unit.CompilerOptions
+		prerequisites := pchs
+		if level, ok := x.Unit.Source.OptimizeOverrides.Find(x.Unit.ModuleDir, input); ok && level != x.Unit.Optimize {
+			base.LogVeryVerbose(LogCompile, "%v: compile %q with %v optimization level", x.Unit, input, level)
+			arguments = x.Compiler.OptimizeFile(x.Unit, level)
+			prerequisites = action.ActionSet{} // compiled without precompiled header, see Compiler.OptimizeFile()
+		}
+
 		objs[i], err = x.CreateAction(
 			PAYLOAD_OBJECTLIST,
 			action.ActionModel{
 				Command: action.CommandRules{
-					Arguments:   x.Unit.CompilerOptions,
-					Environment: compilerRules.Environment,
+					Arguments:   arguments,
+					Environment: x.Unit.Environment,
 					Executable:  compilerRules.Executable,
 					WorkingDir:  UFS.Root,
 				},
@@ -540,7 +808,7 @@ func (x *buildActionGenerator) ObjectListActions(headerUnits, pchs action.Action
 				DynamicInputFiles: dynamicInputFiles,
 				ExportFile:        output,
 				OutputFile:        output,
-				Prerequisites:     pchs,
+				Prerequisites:     prerequisites,
 				StaticDeps:        staticDeps,
 				// allow compiler support for dependency list generation
 				Options: action.MakeOptionFlags(action.OPT_ALLOW_SOURCEDEPENDENCIES),
@@ -574,7 +842,7 @@ func (x *buildActionGenerator) LibrarianActions(headerUnits, pchs, objs action.A
 		action.ActionModel{
 			Command: action.CommandRules{
 				Arguments:   x.Unit.LibrarianOptions,
-				Environment: compilerRules.Environment,
+				Environment: x.Unit.Environment,
 				Executable:  compilerRules.Librarian,
 				WorkingDir:  UFS.Root,
 			},
@@ -611,6 +879,12 @@ func (x *buildActionGenerator) LinkActions(headerUnits, pchs, objs action.Action
 		extraFiles.Append(x.Unit.SymbolsFile)
 	}
 
+	// export map is given to the linker with a dedicated option, see Compiler.ExportMap()
+	var implicitInputs FileSet
+	if x.Unit.ExportMap.Valid() {
+		implicitInputs.Append(x.Unit.ExportMap)
+	}
+
 	compilerRules := x.Compiler.GetCompiler()
 
 	link, err := x.CreateAction(
@@ -618,16 +892,17 @@ func (x *buildActionGenerator) LinkActions(headerUnits, pchs, objs action.Action
 		action.ActionModel{
 			Command: action.CommandRules{
 				Arguments:   x.Unit.LinkerOptions,
-				Environment: compilerRules.Environment,
+				Environment: x.Unit.Environment,
 				Executable:  compilerRules.Linker,
 				WorkingDir:  UFS.Root,
 			},
-			DynamicInputs: objs.Concat(compileDeps...).Concat(linkDeps...).Concat(headerUnits...),
-			ExportFile:    x.Unit.ExportFile,
-			OutputFile:    x.Unit.OutputFile,
-			ExtraFiles:    extraFiles,
-			Prerequisites: pchs,
-			StaticDeps:    MakeBuildAliases(runtimeDeps...),
+			DynamicInputs:  objs.Concat(compileDeps...).Concat(linkDeps...).Concat(headerUnits...),
+			ImplicitInputs: implicitInputs,
+			ExportFile:     x.Unit.ExportFile,
+			OutputFile:     x.Unit.OutputFile,
+			ExtraFiles:     extraFiles,
+			Prerequisites:  pchs,
+			StaticDeps:     MakeBuildAliases(runtimeDeps...),
 		})
 
 	return action.ActionSet{link}, err
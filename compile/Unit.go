@@ -132,9 +132,11 @@ type Unit struct {
 	SymbolsFile Filename
 	ExportFile  Filename
 	ExtraFiles  FileSet
+	ExportMap   Filename
 
 	Source          ModuleSource
 	ModuleDir       Directory
+	SourceRoot      Directory
 	GeneratedDir    Directory
 	IntermediateDir Directory
 
@@ -152,9 +154,12 @@ type Unit struct {
 
 	Environment internal_io.ProcessEnvironment
 
+	ForcedWarningsAsErrors bool // -ForceWarningsAsErrors overrode a module which opted out
+
 	TransitiveFacet Facet // append in case of public dependency
 	GeneratedFiles  FileSet
 	CustomUnits     CustomUnitList
+	Assets          AssetRuleList
 
 	CppRules
 	Facet
@@ -220,9 +225,36 @@ func (unit *Unit) Decorate(bg BuildGraphReadPort, env *CompileEnv, decorator ...
 	return nil
 }
 
+func (unit *Unit) AddCustomCommand(stage CustomUnitStage, command CustomCommand) {
+	base.AssertIn(stage, CUSTOM_PREBUILD, CUSTOM_POSTBUILD)
+	base.LogVeryVerbose(LogCompile, "unit %v: add custom command %q", unit.TargetAlias, &command)
+
+	custom := CustomUnit{
+		Stage:   stage,
+		Command: command,
+		Unit: Unit{
+			TargetAlias:     unit.TargetAlias,
+			ModuleDir:       unit.ModuleDir,
+			GeneratedDir:    unit.GeneratedDir,
+			IntermediateDir: unit.IntermediateDir,
+			CompilerAlias:   unit.CompilerAlias,
+			Environment:     unit.Environment,
+		},
+	}
+
+	switch stage {
+	case CUSTOM_PREBUILD:
+		custom.TargetAlias.ModuleName += fmt.Sprintf("-PreBuild%d", len(unit.CustomUnits))
+	case CUSTOM_POSTBUILD:
+		custom.TargetAlias.ModuleName += fmt.Sprintf("-PostBuild%d", len(unit.CustomUnits))
+	}
+
+	unit.CustomUnits.Append(custom)
+}
+
 func (unit *Unit) GetBinariesOutput(compiler Compiler, src Filename, payload PayloadType) Filename {
 	base.AssertIn(payload, PAYLOAD_EXECUTABLE, PAYLOAD_SHAREDLIB)
-	modulePath := src.Relative(UFS.Source)
+	modulePath := src.Relative(unit.SourceRoot)
 	modulePath = SanitizePath(modulePath, '-')
 	modulePath = fmt.Sprintf("%s-%s", modulePath, unit.TargetAlias.EnvironmentAlias)
 	return compiler.GetPayloadOutput(unit, payload, UFS.Binaries.AbsoluteFile(modulePath))
@@ -237,6 +269,27 @@ func (unit *Unit) GetIntermediateOutput(compiler Compiler, src Filename, payload
 	}
 	return compiler.GetPayloadOutput(unit, payload, unit.IntermediateDir.AbsoluteFile(modulePath))
 }
+
+// splitPayloadExtname splits output name on the full extension configured for its payload, which can contain several
+// dots (ex: SHAREDLIB=.so.1) when Filename.Ext() only returns the last one
+func splitPayloadExtname(output Filename, extname string) (name, ext string) {
+	if strings.HasSuffix(output.Basename, extname) {
+		return strings.TrimSuffix(output.Basename, extname), extname
+	}
+	return output.TrimExt(), output.Ext()
+}
+
+// decorateArtifactName inserts configuration prefix/suffix around the name of the output, keeping its full extension
+func decorateArtifactName(config *ConfigRules, output Filename, payload PayloadType, extname string) Filename {
+	switch payload {
+	case PAYLOAD_EXECUTABLE, PAYLOAD_SHAREDLIB, PAYLOAD_STATICLIB:
+		if len(config.ArtifactPrefix) > 0 || len(config.ArtifactSuffix) > 0 {
+			name, ext := splitPayloadExtname(output, extname)
+			return output.Dirname.File(config.ArtifactPrefix + name + config.ArtifactSuffix + ext)
+		}
+	}
+	return output
+}
 func (unit *Unit) GetPayloadOutput(compiler Compiler, src Filename, payload PayloadType) (result Filename) {
 	switch payload {
 	case PAYLOAD_EXECUTABLE, PAYLOAD_SHAREDLIB:
@@ -260,9 +313,11 @@ func (unit *Unit) Serialize(ar base.Archive) {
 	ar.Serializable(&unit.SymbolsFile)
 	ar.Serializable(&unit.ExportFile)
 	ar.Serializable(&unit.ExtraFiles)
+	ar.Serializable(&unit.ExportMap)
 
 	ar.Serializable(&unit.Source)
 	ar.Serializable(&unit.ModuleDir)
+	ar.Serializable(&unit.SourceRoot)
 	ar.Serializable(&unit.GeneratedDir)
 	ar.Serializable(&unit.IntermediateDir)
 
@@ -280,9 +335,12 @@ func (unit *Unit) Serialize(ar base.Archive) {
 
 	ar.Serializable(&unit.Environment)
 
+	ar.Bool(&unit.ForcedWarningsAsErrors)
+
 	ar.Serializable(&unit.TransitiveFacet)
 	ar.Serializable(&unit.GeneratedFiles)
 	ar.Serializable(&unit.CustomUnits)
+	ar.Serializable(&unit.Assets)
 
 	ar.Serializable(&unit.CppRules)
 	ar.Serializable(&unit.Facet)
@@ -326,20 +384,29 @@ func (unit *Unit) Build(bc BuildContext) error {
 	unit.Ordinal = 0
 	unit.Source = expandedModule.Source
 	unit.ModuleDir = expandedModule.ModuleDir
+	unit.SourceRoot = expandedModule.SourceRoot
 	unit.GeneratedDir = compileEnv.GeneratedDir().AbsoluteFolder(relativePath)
 	unit.IntermediateDir = compileEnv.IntermediateDir().AbsoluteFolder(relativePath)
 	unit.CompilerAlias = compileEnv.CompilerAlias
 	unit.CppRules = compileEnv.GetCpp(bc, &expandedModule)
-	unit.Environment = compiler.GetCompiler().Environment
+
+	// report conflicting settings before they are used below
+	if err := ValidateModule(&expandedModule, &unit.CppRules); err != nil {
+		return err
+	}
+
+	unit.Environment = compiler.GetCompiler().Environment.Clone()
 	unit.Payload = compileEnv.GetPayloadType(&expandedModule, unit.Link)
 	unit.OutputFile = unit.GetPayloadOutput(compiler,
 		unit.ModuleDir.Parent().File(unit.TargetAlias.ModuleAlias.ModuleName),
 		unit.Payload)
+	unit.OutputFile = decorateArtifactName(compileEnv.GetConfig(bc), unit.OutputFile, unit.Payload, compiler.Extname(unit.Payload))
 
 	switch unit.Payload {
 	case PAYLOAD_SHAREDLIB:
 		// when linking against a shared lib we must provide the export .lib/.a, not the produced .dll/.so
-		unit.ExportFile = unit.OutputFile.ReplaceExt(compiler.Extname(PAYLOAD_STATICLIB))
+		name, _ := splitPayloadExtname(unit.OutputFile, compiler.Extname(unit.Payload))
+		unit.ExportFile = unit.OutputFile.Dirname.File(name + compiler.Extname(PAYLOAD_STATICLIB))
 	default:
 		if unit.Payload.HasOutput() {
 			unit.ExportFile = unit.OutputFile
@@ -368,6 +435,24 @@ func (unit *Unit) Build(bc BuildContext) error {
 		base.UnexpectedValuePanic(unit.PCH, unit.PCH)
 	}
 
+	if expandedModule.ExportMap.Valid() {
+		switch {
+		case unit.Payload != PAYLOAD_SHAREDLIB:
+			base.LogWarning(LogCompile, "%v: ignoring export map %q, which only applies to shared libraries (payload: %v)", unit, expandedModule.ExportMap, unit.Payload)
+		case !expandedModule.ExportMap.Exists():
+			return fmt.Errorf("%v: export map %q does not exist", unit, expandedModule.ExportMap)
+		default:
+			unit.ExportMap = expandedModule.ExportMap
+		}
+	}
+
+	// executables and shared libraries can embed how they were built, before module decoration adds generated include paths
+	if unit.BuildInfo.Get() && unit.Payload.HasLinker() {
+		expandedModule.Generate(PRIVATE, BUILDINFO_GENERATED_HEADER, &BuildInfoGenerator{
+			Version: BuildInfoHeaderVersion,
+		})
+	}
+
 	unit.Facet = NewFacet()
 	unit.Facet.Append(compileEnv, &expandedModule)
 
@@ -375,6 +460,70 @@ func (unit *Unit) Build(bc BuildContext) error {
 		return err
 	}
 
+	// extra include paths from environment are opt-in, since they could silently pollute every unit
+	if environmentIncludeFlags, err := GetEnvironmentIncludeFlags(bc); err == nil {
+		if variableName := environmentIncludeFlags.IncludeEnv.Get(); len(variableName) > 0 {
+			environmentIncludes, err := NeedEnvironmentIncludePaths(variableName).Need(bc)
+			if err != nil {
+				return err
+			}
+			if err := unit.Decorate(bc, compileEnv, environmentIncludes); err != nil {
+				return err
+			}
+		}
+	} else {
+		return err
+	}
+
+	// incremental linker can be forced from command-line, layered over module/platform/configuration settings:
+	// compiler decoration below will then recompute caching and determinism interactions accordingly
+	if !compileEnv.CompileFlags.Incremental.IsInheritable() {
+		base.LogVeryVerbose(LogCompile, "%v: incremental linker overriden by command-line to %v", unit, compileEnv.CompileFlags.Incremental)
+		unit.Incremental = compileEnv.CompileFlags.Incremental
+	}
+
+	// warnings as errors can be forced from command-line, overriding modules which opted out
+	if strictWarningsFlags, err := GetStrictWarningsFlags(bc); err == nil {
+		if strictWarningsFlags.ForceWarningsAsErrors.Get() && !unit.GetWarningsAsErrors() {
+			base.LogVerbose(LogCompile, "%v: warnings as errors forced by command-line, overriding module settings", unit)
+			unit.WarningsAsErrors = base.INHERITABLE_TRUE
+			unit.ForcedWarningsAsErrors = true
+		}
+	} else {
+		return err
+	}
+
+	// debug symbols can be restricted to a subset of modules, to keep the rest of the build fast and small
+	if debugInfoSelectionFlags, err := GetDebugInfoSelectionFlags(bc); err == nil {
+		debugInfo, err := debugInfoSelectionFlags.Resolve(&expandedModule)
+		if err != nil {
+			return err
+		}
+		if debugInfo != DEBUGINFO_INHERIT {
+			base.LogVeryVerbose(LogCompile, "%v: debug info selected by command-line: %v", unit, debugInfo)
+			unit.DebugInfo = debugInfo
+		}
+	} else {
+		return err
+	}
+
+	// global force-includes are prepended, see GlobalForceIncludeFlags for ordering with precompiled header
+	if globalForceIncludeFlags, err := GetGlobalForceIncludeFlags(bc); err == nil {
+		headers, err := globalForceIncludeFlags.Resolve(unit)
+		if err != nil {
+			return err
+		}
+		if len(headers) > 0 {
+			if err := bc.NeedFiles(headers...); err != nil {
+				return err
+			}
+			unit.ForceIncludes.Remove(headers...)
+			unit.ForceIncludes.Prepend(headers...)
+		}
+	} else {
+		return err
+	}
+
 	if err := unit.linkModuleDependencies(bc, compileEnv, PRIVATE, expandedModule.PrivateDependencies...); err != nil {
 		return err
 	}
@@ -385,9 +534,18 @@ func (unit *Unit) Build(bc BuildContext) error {
 		return err
 	}
 
+	// evaluated once all properties are resolved for this environment
+	expandedModule.ConditionalDefines.Evaluate(unit)
+	if err := expandedModule.Probes.Evaluate(bc, unit); err != nil {
+		return err
+	}
+
 	unit.Defines.Append(
 		"BUILD_TARGET_NAME="+unit.TargetAlias.ModuleAlias.String(),
 		fmt.Sprintf("BUILD_TARGET_ORDINAL=%d", unit.Ordinal))
+	if unit.BuildInfo.Get() && unit.Payload.HasLinker() {
+		unit.Defines.Append("BUILD_HAS_BUILDINFO=1")
+	}
 
 	unit.Facet.PerformSubstitutions()
 
@@ -395,6 +553,9 @@ func (unit *Unit) Build(bc BuildContext) error {
 		return err
 	}
 
+	// action environment is merged last, so facets can override variables set by the compiler
+	unit.Environment.Replace(unit.ActionEnvironment)
+
 	if err := internal_io.CreateDirectory(bc, unit.OutputFile.Dirname); err != nil {
 		return err
 	}
@@ -414,6 +575,19 @@ func (unit *Unit) Build(bc BuildContext) error {
 		unit.GeneratedFiles.Append(generated.OutputFile)
 	}
 
+	for _, command := range expandedModule.PreBuildCommands {
+		unit.AddCustomCommand(CUSTOM_PREBUILD, command)
+	}
+	for _, command := range expandedModule.PostBuildCommands {
+		unit.AddCustomCommand(CUSTOM_POSTBUILD, command)
+	}
+
+	unit.Assets = expandedModule.Assets
+
+	if err := unit.shareHeaderUnit(compileEnv, compiler); err != nil {
+		return err
+	}
+
 	onUnitCompileEvent.Invoke(UnitCompileEvent{
 		Environment: compileEnv,
 		Unit:        unit,
@@ -449,7 +623,48 @@ func NeedAllBuildUnits(bc BuildContext) (units []*Unit, err error) {
 	if err != nil {
 		return
 	}
+	return needBuildUnitsForModules(bc, modules)
+}
+
+// NeedDefaultBuildUnits returns units of all environments for modules returned by NeedDefaultBuildModules
+func NeedDefaultBuildUnits(bc BuildContext) (units []*Unit, err error) {
+	modules, err := NeedDefaultBuildModules(bc)
+	if err != nil {
+		return
+	}
+	return needBuildUnitsForModules(bc, modules)
+}
+
+// NeedGlobBuildUnits returns units which can be selected by glob patterns: modules excluded from default build are only
+// returned when a pattern names them literally, without any wildcard in module part (ex: 'Tools/Slow-*'), or when
+// includeExcluded is true, so catch-all patterns still respect ExcludeFromDefaultBuild
+func NeedGlobBuildUnits(bc BuildContext, includeExcluded bool, patterns ...string) (units []*Unit, err error) {
+	allModules, err := NeedAllBuildModules(bc)
+	if err != nil {
+		return
+	}
+
+	modules := make([]Module, 0, len(allModules))
+	for _, module := range allModules {
+		if includeExcluded || !module.GetModule().ExcludeFromDefaultBuild.Get() || isModuleNamedByGlob(module.GetModule().ModuleAlias, patterns...) {
+			modules = append(modules, module)
+		}
+	}
+	return needBuildUnitsForModules(bc, modules)
+}
+
+func isModuleNamedByGlob(moduleAlias ModuleAlias, patterns ...string) bool {
+	name := moduleAlias.String()
+	for _, it := range patterns {
+		it = strings.ReplaceAll(it, "\\", "/")
+		if len(it) >= len(name) && strings.EqualFold(it[:len(name)], name) && (len(it) == len(name) || it[len(name)] == '-') {
+			return true
+		}
+	}
+	return false
+}
 
+func needBuildUnitsForModules(bc BuildContext, modules []Module) (units []*Unit, err error) {
 	if err = ForeachEnvironmentAlias(func(ea EnvironmentAlias) error {
 		for _, module := range modules {
 			buildable, err := bc.NeedBuildable(TargetAlias{
@@ -0,0 +1,33 @@
+package compile
+
+import (
+	"testing"
+
+	//lint:ignore ST1001 ignore dot imports warning
+	. "github.com/poppolopoppo/ppb/utils"
+)
+
+func TestSplitPayloadExtname(t *testing.T) {
+	output := MakeFilename("/tmp/libfoo.so.1")
+
+	if name, ext := splitPayloadExtname(output, ".so.1"); name != "libfoo" || ext != ".so.1" {
+		t.Errorf("split payload extname: expected (libfoo, .so.1), got (%v, %v)", name, ext)
+	}
+	if name, ext := splitPayloadExtname(output, ".so"); name != "libfoo.so" || ext != ".1" {
+		t.Errorf("split payload extname: expected fallback to last extension, got (%v, %v)", name, ext)
+	}
+	if name, ext := splitPayloadExtname(MakeFilename("/tmp/foo"), ""); name != "foo" || ext != "" {
+		t.Errorf("split payload extname: expected (foo, ), got (%v, %v)", name, ext)
+	}
+}
+
+func TestDecorateArtifactName(t *testing.T) {
+	config := ConfigRules{ArtifactPrefix: "lib", ArtifactSuffix: "_d"}
+
+	if output := decorateArtifactName(&config, MakeFilename("/tmp/foo.so.1"), PAYLOAD_SHAREDLIB, ".so.1"); output.Basename != "libfoo_d.so.1" {
+		t.Errorf("decorate artifact name: expected libfoo_d.so.1, got %v", output.Basename)
+	}
+	if output := decorateArtifactName(&config, MakeFilename("/tmp/foo.obj"), PAYLOAD_OBJECTLIST, ".obj"); output.Basename != "foo.obj" {
+		t.Errorf("decorate artifact name: expected foo.obj to be left untouched, got %v", output.Basename)
+	}
+}
@@ -2,6 +2,7 @@ package compile
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io"
 	"math"
 	"os"
@@ -92,6 +93,40 @@ func (x *UnityFile) Serialize(ar base.Archive) {
 	ar.Serializable(&x.Excludeds)
 }
 
+/***************************************
+ * Unity Layout
+ ***************************************/
+
+// UnityLayout persists the count of unity files used by a unit with StableUnity: automatic count is estimated from
+// size of sources, and any change of count would reassign every file to another unity file.
+type UnityLayout struct {
+	UnityDir      utils.Directory
+	NumUnityFiles int32
+}
+
+func MakeUnityLayoutAlias(unityDir utils.Directory) utils.BuildAlias {
+	return utils.MakeBuildAlias("Unity", unityDir.Path, "Layout")
+}
+
+func (x *UnityLayout) Alias() utils.BuildAlias {
+	return MakeUnityLayoutAlias(x.UnityDir)
+}
+func (x *UnityLayout) Build(bc utils.BuildContext) error {
+	return nil
+}
+func (x *UnityLayout) Serialize(ar base.Archive) {
+	ar.Serializable(&x.UnityDir)
+	ar.Int32(&x.NumUnityFiles)
+}
+
+// keep previous count while estimate remains within 25%, so adding or removing a few files does not reassign all of them
+func getStableUnityFileCount(previous, estimated int) int {
+	if previous > 0 && estimated*4 >= previous*3 && estimated*4 <= previous*5 {
+		return previous
+	}
+	return estimated
+}
+
 /***************************************
  * Unit helper generating unity files IFN
  ***************************************/
@@ -125,6 +160,12 @@ func (unit *Unit) GetSourceFiles(bc utils.BuildContext) (sourceFiles utils.FileS
 			return
 		}
 
+		if level, ok := unit.Source.OptimizeOverrides.Find(unit.ModuleDir, file); ok && level != unit.Optimize && !isolatedFiles.Contains(file) {
+			// can't share a translation unit with code optimized differently
+			base.LogVerbose(LogCompile, "%v: isolated file %q from unity to optimize it with %v", unit.TargetAlias, file, level)
+			isolatedFiles.Append(file)
+		}
+
 		if !isolatedFiles.Contains(file) {
 			if size := src.Size(); size < int64(unit.SizePerUnity) {
 				totalSize += src.Size()
@@ -153,6 +194,27 @@ func (unit *Unit) GetSourceFiles(bc utils.BuildContext) (sourceFiles utils.FileS
 		}
 	}
 
+	// generate unity files
+	unityDir := unit.GeneratedDir.Folder("Unity")
+
+	if unit.StableUnity.Get() && unit.Unity == UNITY_AUTOMATIC && numUnityFiles > 0 {
+		if previous, err := utils.FindBuildable[*UnityLayout](bc, MakeUnityLayoutAlias(unityDir)); err == nil {
+			if stable := getStableUnityFileCount(int(previous.NumUnityFiles), numUnityFiles); stable != numUnityFiles {
+				base.LogVeryVerbose(LogCompile, "%v: stable unity kept %d unity files (estimated %d)", unit.TargetAlias, stable, numUnityFiles)
+				numUnityFiles = stable
+			}
+		}
+
+		if _, err = bc.OutputFactory(utils.MakeBuildFactory(func(bi utils.BuildInitializer) (UnityLayout, error) {
+			return UnityLayout{
+				UnityDir:      unityDir,
+				NumUnityFiles: int32(numUnityFiles),
+			}, nil
+		}), utils.OptionBuildForce); err != nil {
+			return
+		}
+	}
+
 	if numUnityFiles >= len(sourceFiles) {
 		base.LogWarning(LogCompile, "%v: %d unity files (%.2f KiB) is superior to source files count (%d files), disabling unity (was %v)",
 			unit.TargetAlias, numUnityFiles, float64(totalSize)/1024.0, len(sourceFiles), unit.Unity)
@@ -170,8 +232,6 @@ func (unit *Unit) GetSourceFiles(bc utils.BuildContext) (sourceFiles utils.FileS
 		return fmt.Errorf("unity: invalid count of unity files %d (was %v)", numUnityFiles, unit.Unity)
 	})
 
-	// generate unity files
-	unityDir := unit.GeneratedDir.Folder("Unity")
 	if err = internal_io.CreateDirectory(bc, unityDir); err != nil {
 		return
 	}
@@ -196,7 +256,22 @@ func (unit *Unit) GetSourceFiles(bc utils.BuildContext) (sourceFiles utils.FileS
 	}
 
 	const USE_BEST_FIT = false
-	if USE_BEST_FIT {
+	if unit.StableUnity.Get() {
+		// hash path of each source relative to source directory, which is deterministic across runs and machines:
+		// adding or removing a file only modifies the unity file containing it, instead of shifting all following files
+		for sourceFileIndex, src := range sourceFileInfos {
+			if src.Isolated {
+				continue // this file was isolated
+			}
+
+			digester := fnv.New32a()
+			digester.Write([]byte(utils.SanitizePath(sourceFiles[sourceFileIndex].Relative(utils.UFS.Source), '/')))
+
+			unityFile := &unityFiles[digester.Sum32()%uint32(numUnityFiles)]
+			unityFile.TotalSize += src.Size()
+			unityFile.Inputs.Append(sourceFiles[sourceFileIndex])
+		}
+	} else if USE_BEST_FIT {
 		// sort source files by descending size for best-fit allocation, isolated files goes at end of the slice
 		sort.Slice(sourceFilesSorted, func(i, j int) bool {
 			a, b := sourceFileInfos[sourceFilesSorted[i]], sourceFileInfos[sourceFilesSorted[j]]
@@ -267,6 +342,10 @@ func (unit *Unit) GetSourceFiles(bc utils.BuildContext) (sourceFiles utils.FileS
 	sourceFiles = isolatedFiles
 
 	for _, unityFile := range unityFiles {
+		if len(unityFile.Inputs) == 0 {
+			continue // hashing can leave a unity file empty, when there are only a few files per unity
+		}
+
 		base.Assert(unityFile.Inputs.IsUniq)
 		unityFile.Inputs.Sort()
 
@@ -0,0 +1,79 @@
+package compile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+)
+
+/***************************************
+ * Module Validation
+ ***************************************/
+
+// ModuleValidation checks an invariant of module settings, once resolved for a compilation environment.
+// Validations are registered by name in AllModuleValidations and evaluated when units are configured, so
+// conflicting settings are reported with the module name instead of failing later while generating actions.
+type ModuleValidation func(module *ModuleRules, cpp *CppRules) error
+
+var AllModuleValidations base.SharedMapT[string, ModuleValidation]
+
+// ValidateModule reports every failed validation at once, sorted by name to stay deterministic
+func ValidateModule(module *ModuleRules, cpp *CppRules) error {
+	names := AllModuleValidations.Keys()
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		validation, _ := AllModuleValidations.Get(name)
+		if err := validation(module, cpp); err != nil {
+			errs = append(errs, fmt.Errorf("module %q has invalid settings (%s): %w", module.ModuleAlias, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateModuleTypeLink(module *ModuleRules, cpp *CppRules) error {
+	switch module.ModuleType {
+	case MODULE_PROGRAM:
+		if cpp.Link == LINK_DYNAMIC {
+			return fmt.Errorf("ModuleType=%v is always linked statically, but Link=%v", module.ModuleType, cpp.Link)
+		}
+	case MODULE_HEADERS:
+		// only check settings declared by the module, since environment defaults also apply to header-only modules
+		if module.Link != LINK_INHERIT {
+			return fmt.Errorf("ModuleType=%v produces no binary, remove Link=%v from module", module.ModuleType, module.Link)
+		}
+		if module.ExportMap.Valid() {
+			return fmt.Errorf("ModuleType=%v produces no binary, remove ExportMap=%q from module", module.ModuleType, module.ExportMap)
+		}
+	}
+	return nil
+}
+func validateModulePrecompiledHeader(module *ModuleRules, cpp *CppRules) error {
+	switch cpp.PCH {
+	case PCH_MONOLITHIC, PCH_SHARED, PCH_HEADERUNIT:
+	default:
+		return nil
+	}
+
+	hasHeader, hasSource := module.PrecompiledHeader.Valid(), module.PrecompiledSource.Valid()
+	switch {
+	case !hasHeader && !hasSource:
+		return nil // PCH will be disabled for this module
+	case !hasSource:
+		return fmt.Errorf("PCH=%v needs PrecompiledSource along with PrecompiledHeader=%q", cpp.PCH, module.PrecompiledHeader)
+	case !hasHeader:
+		return fmt.Errorf("PCH=%v needs PrecompiledHeader along with PrecompiledSource=%q", cpp.PCH, module.PrecompiledSource)
+	case !module.PrecompiledHeader.Exists():
+		return fmt.Errorf("PCH=%v but PrecompiledHeader=%q does not exist", cpp.PCH, module.PrecompiledHeader)
+	case !module.PrecompiledSource.Exists():
+		return fmt.Errorf("PCH=%v but PrecompiledSource=%q does not exist", cpp.PCH, module.PrecompiledSource)
+	}
+
+	if cpp.PCH == PCH_SHARED {
+		return fmt.Errorf("PCH=%v requires a shared PCH provider, which is not available: use PCH=%v or PCH=%v instead", cpp.PCH, PCH_MONOLITHIC, PCH_HEADERUNIT)
+	}
+	return nil
+}
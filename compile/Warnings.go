@@ -0,0 +1,84 @@
+package compile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/internal/base"
+)
+
+/***************************************
+ * Compiler Warnings
+ ***************************************/
+
+// AllCompilerWarnings maps compiler-specific warning ids to their description, registered by HALs
+// to autocomplete WarningOverrides with commonly tuned warnings.
+var AllCompilerWarnings base.SharedMapT[string, string]
+
+/***************************************
+ * Warning Overrides
+ ***************************************/
+
+// WarningOverrides lists specific compiler warnings with the level they should be set to, ex: "4100=DISABLED,4189=ERROR".
+// Overrides are applied by the compiler after its own defaults, so a module can tune warnings without touching sources.
+type WarningOverrides base.StringSet
+
+func (x WarningOverrides) IsInheritable() bool {
+	return len(x) == 0
+}
+func (x WarningOverrides) String() string {
+	return strings.Join(x, ",")
+}
+func (x *WarningOverrides) Set(in string) error {
+	*x = WarningOverrides{}
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			if _, _, err := parseWarningOverride(it); err != nil {
+				return err
+			}
+			*x = append(*x, it)
+		}
+	}
+	return nil
+}
+func (x *WarningOverrides) Serialize(ar base.Archive) {
+	ar.Serializable((*base.StringSet)(x))
+}
+func (x WarningOverrides) AutoComplete(in base.AutoComplete) {
+	warnings := AllCompilerWarnings.Keys()
+	sort.Strings(warnings)
+	for _, id := range warnings {
+		description, _ := AllCompilerWarnings.Get(id)
+		for _, level := range []WarningLevel{WARNING_DISABLED, WARNING_WARN, WARNING_ERROR} {
+			in.Add(fmt.Sprint(id, "=", level), description)
+		}
+	}
+}
+
+// Resolve returns the level associated to each warning id, in declaration order
+func (x WarningOverrides) Resolve(each func(string, WarningLevel) error) error {
+	for _, it := range x {
+		id, level, err := parseWarningOverride(it)
+		if err != nil {
+			return err
+		}
+		if err := each(id, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseWarningOverride(in string) (id string, level WarningLevel, err error) {
+	var levelStr string
+	var ok bool
+	if id, levelStr, ok = strings.Cut(in, "="); !ok || len(id) == 0 {
+		err = fmt.Errorf("compile: invalid warning override %q, expected ID=LEVEL (ex: 4100=DISABLED)", in)
+		return
+	}
+	if err = level.Set(levelStr); err == nil && level.IsInheritable() {
+		err = fmt.Errorf("compile: invalid warning override %q, level can't be %v", in, level)
+	}
+	return
+}
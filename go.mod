@@ -5,6 +5,7 @@ go 1.23.3
 require (
 	github.com/DataDog/zstd v1.5.6
 	github.com/Showmax/go-fqdn v1.0.0
+	github.com/cespare/xxhash/v2 v2.3.0
 	github.com/danjacques/gofslock v0.0.0-20240212154529-d899e02bfe22
 	github.com/goccy/go-json v0.10.4
 	github.com/klauspost/compress v1.17.11
@@ -9,6 +9,9 @@ type AnsiCode string
 
 var enableAnsiColor bool = true
 
+func EnableAnsiColor() bool {
+	return enableAnsiColor
+}
 func SetEnableAnsiColor(enabled bool) {
 	enableAnsiColor = enabled
 }
@@ -1,19 +1,30 @@
 package base
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
 
 /***************************************
  * ArchiveFile
  ***************************************/
 
+// ArchiveFile is the header written before archive content: ArchiveFileVersion must be bumped when this header
+// or the binary encoding changes, while layout changes of serialized types are detected with GetArchiveSchema().
 type ArchiveFile struct {
 	Magic   FourCC
 	Version FourCC
+	Schema  Fingerprint
 	Tags    []FourCC
 }
 
 var ArchiveFileMagic FourCC = MakeFourCC('A', 'R', 'B', 'F')
-var ArchiveFileVersion FourCC = MakeFourCC('1', '0', '0', '1')
+var ArchiveFileVersion FourCC = MakeFourCC('1', '0', '0', '2')
 
 var ArchiveTags = []FourCC{}
 
@@ -33,36 +44,52 @@ func NewArchiveFile() ArchiveFile {
 	return ArchiveFile{
 		Magic:   ArchiveFileMagic,
 		Version: ArchiveFileVersion,
+		Schema:  getArchiveFileSchema(),
 		Tags:    ArchiveTags,
 	}
 }
 func (x *ArchiveFile) Serialize(ar Archive) {
 	ar.Serializable(&x.Magic)
 	ar.Serializable(&x.Version)
+
+	// layout of the remaining header is unknown when written by another version, see ArchiveMigration
+	if x.Magic != ArchiveFileMagic || x.Version != ArchiveFileVersion {
+		return
+	}
+
+	ar.Serializable(&x.Schema)
 	SerializeSlice(ar, &x.Tags)
 
 	// forward serialized tags to the archive
 	ar.SetTags(x.Tags...)
 }
+func (x *ArchiveFile) checkVersion() error {
+	if x.Magic != ArchiveFileMagic || x.Version != ArchiveFileVersion || x.Schema != getArchiveFileSchema() {
+		return ArchiveVersionError{File: *x}
+	}
+	return nil
+}
 
 func ArchiveFileRead(reader io.Reader, scope func(ar Archive), flags ...ArchiveFlag) (file ArchiveFile, err error) {
-	err = WithArchiveBinaryReader(reader, func(ar Archive) (err error) {
+	err = WithArchiveBinaryReader(reader, func(ar Archive) error {
 		ar.Serializable(&file)
-		if err := ar.Error(); err == nil {
-			if file.Magic != ArchiveFileMagic {
-				ar.OnErrorf("archive: invalid file magic (%q != %q)", file.Magic, ArchiveFileMagic)
-			}
-			if file.Version > ArchiveFileVersion {
-				ar.OnErrorf("archive: newer file version (%q > %q)", file.Version, ArchiveFileVersion)
-			}
-			if file.Version < ArchiveFileVersion {
-				ar.OnErrorf("archive: older file version (%q < %q)", file.Version, ArchiveFileVersion)
-			}
-			if err = ar.Error(); err == nil {
-				scope(NewArchiveGuard(ar))
+		if err := ar.Error(); err != nil {
+			return err
+		}
+
+		if err := file.checkVersion(); err != nil {
+			// older archives can still be loaded when a migration was registered for their version
+			if migration, ok := archiveMigrations.Get(file.Version); ok && file.Magic == ArchiveFileMagic && file.Version != ArchiveFileVersion {
+				LogVerbose(LogSerialize, "archive: migrating file from version %q to %q", file.Version, ArchiveFileVersion)
+				return migration(ar, &file, func(ar Archive) {
+					scope(NewArchiveGuard(ar))
+				})
 			}
+			return err
 		}
-		return
+
+		scope(NewArchiveGuard(ar))
+		return ar.Error()
 	}, flags...)
 	return
 }
@@ -77,6 +104,146 @@ func ArchiveFileWrite(writer io.Writer, scope func(ar Archive)) (err error) {
 	})
 }
 
+/***************************************
+ * ArchiveVersionError
+ ***************************************/
+
+// ArchiveVersionError is returned when an archive was written by another version of the program, and could not be migrated:
+// its content should be discarded and generated again, instead of being decoded with a wrong layout.
+type ArchiveVersionError struct {
+	File ArchiveFile
+}
+
+func (x ArchiveVersionError) Error() string {
+	switch {
+	case x.File.Magic != ArchiveFileMagic:
+		return fmt.Sprintf("archive: invalid file magic (%q != %q)", x.File.Magic, ArchiveFileMagic)
+	case x.File.Version > ArchiveFileVersion:
+		return fmt.Sprintf("archive: newer file version (%q > %q)", x.File.Version, ArchiveFileVersion)
+	case x.File.Version < ArchiveFileVersion:
+		return fmt.Sprintf("archive: older file version (%q < %q)", x.File.Version, ArchiveFileVersion)
+	default:
+		return fmt.Sprintf("archive: serialized types layout or schema variant changed (schema %v != %v)", x.File.Schema.ShortString(), getArchiveFileSchema().ShortString())
+	}
+}
+
+func IsArchiveVersionError(err error) bool {
+	var versionErr ArchiveVersionError
+	return errors.As(err, &versionErr)
+}
+
+/***************************************
+ * ArchiveMigration
+ ***************************************/
+
+// ArchiveMigration reads an archive written with an older ArchiveFileVersion, where only magic and version of the header
+// were read: it must consume the remaining of the older header, then forward an archive decoding the older layout to scope.
+type ArchiveMigration func(ar Archive, file *ArchiveFile, scope func(Archive)) error
+
+var archiveMigrations SharedMapT[FourCC, ArchiveMigration]
+
+func RegisterArchiveMigration(version FourCC, migration ArchiveMigration) {
+	AssertErr(func() error {
+		if version < ArchiveFileVersion {
+			return nil
+		}
+		return fmt.Errorf("archive: can only migrate from older versions (%q >= %q)", version, ArchiveFileVersion)
+	})
+	archiveMigrations.Add(version, migration)
+}
+
+/***************************************
+ * ArchiveSchema
+ ***************************************/
+
+// Archive schema is a fingerprint of the memory layout of every registered serializable type:
+// it changes whenever a field is added, removed, renamed or reordered, which is likely to change serialized content.
+// Types from the standard library are only identified by name, so upgrading Go does not discard every archive.
+
+var archiveSchemaTypes struct {
+	sync.Mutex
+	types []reflect.Type
+}
+
+func registerArchiveSchemaType(rt reflect.Type) {
+	archiveSchemaTypes.Lock()
+	defer archiveSchemaTypes.Unlock()
+	archiveSchemaTypes.types = append(archiveSchemaTypes.types, rt)
+}
+
+var GetArchiveSchema = Memoize(func() Fingerprint {
+	archiveSchemaTypes.Lock()
+	defer archiveSchemaTypes.Unlock()
+
+	layouts := make([]string, len(archiveSchemaTypes.types))
+	for i, rt := range archiveSchemaTypes.types {
+		sb := strings.Builder{}
+		describeArchiveLayout(&sb, rt, make(map[reflect.Type]bool))
+		layouts[i] = sb.String()
+	}
+
+	// registration order depends on package initialization, and should not change the schema
+	sort.Strings(layouts)
+	return StringFingerprint(strings.Join(layouts, "\n"))
+})
+
+// Archive schema variant is appended to the schema of written archives, for settings which change the meaning of serialized
+// content without changing the layout of serialized types (ex: fingerprint algorithm): it must be set before any archive is read.
+
+var archiveSchemaVariant string
+
+func SetArchiveSchemaVariant(variant string) {
+	archiveSchemaVariant = variant
+}
+
+func getArchiveFileSchema() Fingerprint {
+	if len(archiveSchemaVariant) == 0 {
+		return GetArchiveSchema() // unchanged by default, to keep archives written before variants were introduced
+	}
+	return StringFingerprint(GetArchiveSchema().String() + archiveSchemaVariant)
+}
+
+func describeArchiveLayout(sb *strings.Builder, rt reflect.Type, visiteds map[reflect.Type]bool) {
+	if name := rt.Name(); len(name) > 0 {
+		sb.WriteString(reflectTypename(rt))
+
+		// only expand types once, and never expand types from standard library
+		if visiteds[rt] || !strings.Contains(strings.Split(rt.PkgPath(), "/")[0], ".") {
+			return
+		}
+		visiteds[rt] = true
+	}
+
+	switch rt.Kind() {
+	case reflect.Struct:
+		sb.WriteString("{")
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			sb.WriteString(field.Name)
+			sb.WriteString(" ")
+			describeArchiveLayout(sb, field.Type, visiteds)
+			sb.WriteString(";")
+		}
+		sb.WriteString("}")
+	case reflect.Array:
+		fmt.Fprintf(sb, "[%d]", rt.Len())
+		describeArchiveLayout(sb, rt.Elem(), visiteds)
+	case reflect.Slice:
+		sb.WriteString("[]")
+		describeArchiveLayout(sb, rt.Elem(), visiteds)
+	case reflect.Pointer:
+		sb.WriteString("*")
+		describeArchiveLayout(sb, rt.Elem(), visiteds)
+	case reflect.Map:
+		sb.WriteString("map[")
+		describeArchiveLayout(sb, rt.Key(), visiteds)
+		sb.WriteString("]")
+		describeArchiveLayout(sb, rt.Elem(), visiteds)
+	default:
+		sb.WriteString(rt.Kind().String())
+	}
+}
+
 /***************************************
  * CompressedArchiveFile
  ***************************************/
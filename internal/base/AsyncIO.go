@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"runtime"
 	"sync"
 	"sync/atomic"
 )
@@ -23,6 +24,13 @@ var GetIOWriteThreadPool = Memoize(func() (result ThreadPool) {
 	return
 })
 
+// hashing is both IO and CPU bound, and is waited on from global workers: it needs its own pool to avoid starvation
+var GetIOHashThreadPool = Memoize(func() (result ThreadPool) {
+	result = NewFixedSizeThreadPool("IOHash", runtime.NumCPU())
+	allThreadPools = append(allThreadPools, result)
+	return
+})
+
 /***************************************
  * Async IO Copy
  ***************************************/
@@ -1,6 +1,9 @@
 package base
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestFourCC(t *testing.T) {
 	cc0 := MakeFourCC('a', 'b', 'c', 'd')
@@ -9,3 +12,63 @@ func TestFourCC(t *testing.T) {
 		t.Errorf("invalid fourcc: %v != %v", cc0, cc1)
 	}
 }
+
+func TestEnumSetSeparators(t *testing.T) {
+	var set EnumSet[ArchiveFlag, *ArchiveFlag]
+	for _, in := range []string{"LOADING,TOLERANT", "LOADING|TOLERANT", "LOADING, TOLERANT"} {
+		if err := set.Set(in); err != nil {
+			t.Errorf("enum set %q: %v", in, err)
+		}
+		if !set.Has(AR_LOADING) || !set.Has(AR_TOLERANT) || set.Has(AR_DETERMINISM) {
+			t.Errorf("enum set %q: unexpected value %v", in, set)
+		}
+	}
+}
+
+func TestInheritableIntRange(t *testing.T) {
+	for in, expected := range map[string]string{
+		"4":       "4",
+		"2..8":    "2..8",
+		"..8":     "..8",
+		"2..":     "2..",
+		"4..4":    "4",
+		"":        INHERIT_STRING,
+		"INHERIT": INHERIT_STRING,
+	} {
+		var r InheritableIntRange
+		if err := r.Set(in); err != nil {
+			t.Errorf("int range %q: %v", in, err)
+		} else if r.String() != expected {
+			t.Errorf("int range %q: expected %q, got %q", in, expected, r.String())
+		}
+	}
+
+	var r InheritableIntRange
+	if err := r.Set("8..2"); err == nil {
+		t.Errorf("int range %q: expected an error", "8..2")
+	}
+	if err := r.Set("2..8"); err != nil || !r.Contains(2) || !r.Contains(8) || r.Contains(9) || r.Clamp(1) != 2 || r.Clamp(10) != 8 {
+		t.Errorf("int range %v: invalid bounds", r)
+	}
+	if ok, err := r.CommandLine("j", "-j4..6"); !ok || err != nil || r.String() != "4..6" {
+		t.Errorf("int range %v: failed to parse short command-line", r)
+	}
+	if ok, err := r.CommandLine("Jobs", "-Jobs=..2"); !ok || err != nil || r.String() != "..2" {
+		t.Errorf("int range %v: failed to parse command-line", r)
+	}
+}
+
+func TestThreadPoolUtilizationAverage(t *testing.T) {
+	utilization := ThreadPoolUtilization{
+		Arity:     4,
+		Duration:  4 * time.Second,
+		Peak:      4,
+		Durations: []time.Duration{time.Second, time.Second, 0, 0, 2 * time.Second},
+	}
+	if avg := utilization.Average(); avg != 2.25 {
+		t.Errorf("utilization: expected average 2.25, got %v", avg)
+	}
+	if idle := utilization.Idle(); idle != time.Second {
+		t.Errorf("utilization: expected idle %v, got %v", time.Second, idle)
+	}
+}
@@ -149,9 +149,10 @@ func (x EnumSet[T, E]) Compare(other EnumSet[T, E]) int {
 	}
 }
 
+// Set accepts both `|` and `,` as separators, ex: `-Instructions=AVX2,SSE3`
 func (x *EnumSet[T, E]) Set(in string) error {
 	*x = 0
-	for _, s := range strings.Split(in, `|`) {
+	for _, s := range strings.FieldsFunc(in, func(ch rune) bool { return ch == '|' || ch == ',' }) {
 		var it T
 		if err := E(&it).Set(strings.TrimSpace(s)); err == nil {
 			x.Add(it)
@@ -203,5 +204,5 @@ func (x *EnumSet[T, E]) UnmarshalText(data []byte) error {
 
 func (x EnumSet[T, E]) AutoComplete(in AutoComplete) {
 	var defaultValue T
-	defaultValue.AutoComplete(in)
+	AutoCompleteList(in, `|,`, defaultValue)
 }
@@ -1,11 +1,14 @@
 package base
 
 import (
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
+	"strings"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/minio/sha256-simd"
 )
 
@@ -77,20 +80,115 @@ func (x *Fingerprint) UnmarshalText(data []byte) (err error) {
 }
 
 /***************************************
- * Serializable Fingerprint
+ * Fingerprint Algorithm
  ***************************************/
 
-var DigesterPool = NewRecycler(
-	func() hash.Hash {
+// FingerprintAlgorithm selects the digest used for build stamps: digests shorter than Fingerprint are padded with zeros
+type FingerprintAlgorithm byte
+
+const (
+	FINGERPRINT_SHA256 FingerprintAlgorithm = iota
+	FINGERPRINT_XXHASH
+	FINGERPRINT_SHA512_256
+)
+
+func GetFingerprintAlgorithms() []FingerprintAlgorithm {
+	return []FingerprintAlgorithm{
+		FINGERPRINT_SHA256,
+		FINGERPRINT_XXHASH,
+		FINGERPRINT_SHA512_256,
+	}
+}
+func (x FingerprintAlgorithm) Description() string {
+	switch x {
+	case FINGERPRINT_SHA256:
+		return "use SHA-256 cryptographic hash (default)"
+	case FINGERPRINT_XXHASH:
+		return "use 64 bits xxHash non-cryptographic hash from https://github.com/Cyan4973/xxHash, much faster on large inputs"
+	case FINGERPRINT_SHA512_256:
+		return "use SHA-512/256 cryptographic hash, stronger against length extension when sharing a cache"
+	default:
+		UnexpectedValue(x)
+		return ""
+	}
+}
+func (x FingerprintAlgorithm) String() string {
+	switch x {
+	case FINGERPRINT_SHA256:
+		return "SHA256"
+	case FINGERPRINT_XXHASH:
+		return "XXHASH"
+	case FINGERPRINT_SHA512_256:
+		return "SHA512_256"
+	default:
+		UnexpectedValue(x)
+		return ""
+	}
+}
+func (x *FingerprintAlgorithm) Set(in string) (err error) {
+	switch strings.ToUpper(in) {
+	case FINGERPRINT_SHA256.String():
+		*x = FINGERPRINT_SHA256
+	case FINGERPRINT_XXHASH.String():
+		*x = FINGERPRINT_XXHASH
+	case FINGERPRINT_SHA512_256.String():
+		*x = FINGERPRINT_SHA512_256
+	default:
+		err = MakeUnexpectedValueError(x, in)
+	}
+	return err
+}
+func (x *FingerprintAlgorithm) Serialize(ar Archive) {
+	ar.Byte((*byte)(x))
+}
+func (x FingerprintAlgorithm) MarshalText() ([]byte, error) {
+	return UnsafeBytesFromString(x.String()), nil
+}
+func (x *FingerprintAlgorithm) UnmarshalText(data []byte) error {
+	return x.Set(UnsafeStringFromBytes(data))
+}
+func (x *FingerprintAlgorithm) AutoComplete(in AutoComplete) {
+	for _, it := range GetFingerprintAlgorithms() {
+		in.Add(it.String(), it.Description())
+	}
+}
+func (x FingerprintAlgorithm) newDigester() hash.Hash {
+	switch x {
+	case FINGERPRINT_SHA256:
 		return sha256.New()
-	},
-	func(digester hash.Hash) {
-		digester.Reset()
-	})
+	case FINGERPRINT_XXHASH:
+		return xxhash.New()
+	case FINGERPRINT_SHA512_256:
+		return sha512.New512_256()
+	default:
+		UnexpectedValue(x)
+		return nil
+	}
+}
+
+// each algorithm has its own pool, since recycled digesters can not be mixed
+var digesterPools = func() (pools [FINGERPRINT_SHA512_256 + 1]Recycler[hash.Hash]) {
+	for _, algorithm := range GetFingerprintAlgorithms() {
+		pools[algorithm] = NewRecycler(algorithm.newDigester, func(digester hash.Hash) {
+			digester.Reset()
+		})
+	}
+	return
+}()
+
+/***************************************
+ * Serializable Fingerprint
+ ***************************************/
+
+var DigesterPool = digesterPools[FINGERPRINT_SHA256]
 
 func SerializeAnyFingerprint(any func(ar Archive) error, seed Fingerprint) (result Fingerprint, err error) {
-	digester := DigesterPool.Allocate()
-	defer DigesterPool.Release(digester)
+	return SerializeAnyFingerprintWith(FINGERPRINT_SHA256, any, seed)
+}
+func SerializeAnyFingerprintWith(algorithm FingerprintAlgorithm, any func(ar Archive) error, seed Fingerprint) (result Fingerprint, err error) {
+	pool := digesterPools[algorithm]
+	digester := pool.Allocate()
+	defer pool.Release(digester)
 
 	if _, err = digester.Write(seed[:]); err != nil {
 		return
@@ -109,8 +207,12 @@ func SerializeAnyFingerprint(any func(ar Archive) error, seed Fingerprint) (resu
 }
 
 func ReaderFingerprint(rd io.Reader, seed Fingerprint, pageAlloc BytesRecycler, allowAsync bool) (result Fingerprint, err error) {
-	digester := DigesterPool.Allocate()
-	defer DigesterPool.Release(digester)
+	return ReaderFingerprintWith(FINGERPRINT_SHA256, rd, seed, pageAlloc, allowAsync)
+}
+func ReaderFingerprintWith(algorithm FingerprintAlgorithm, rd io.Reader, seed Fingerprint, pageAlloc BytesRecycler, allowAsync bool) (result Fingerprint, err error) {
+	pool := digesterPools[algorithm]
+	digester := pool.Allocate()
+	defer pool.Release(digester)
 
 	digester.Write(seed[:])
 
@@ -138,7 +240,10 @@ func StringFingerprint(in string) Fingerprint {
 }
 
 func SerializeFingerpint(value Serializable, seed Fingerprint) Fingerprint {
-	fingerprint, err := SerializeAnyFingerprint(func(ar Archive) error {
+	return SerializeFingerpintWith(FINGERPRINT_SHA256, value, seed)
+}
+func SerializeFingerpintWith(algorithm FingerprintAlgorithm, value Serializable, seed Fingerprint) Fingerprint {
+	fingerprint, err := SerializeAnyFingerprintWith(algorithm, func(ar Archive) error {
 		ar.Serializable(value)
 		return nil
 	}, seed)
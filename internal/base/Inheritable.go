@@ -3,6 +3,7 @@ package base
 import (
 	"flag"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -56,6 +57,77 @@ func Overwrite[T InheritableBase](result *T, values ...T) {
 	*result = wrapper.Value
 }
 
+/***************************************
+ * Inheritable tracing
+ ***************************************/
+
+var LogInherit = NewLogCategory("Inherit")
+
+// InheritLayer is one level of a resolution chain (ex: module, environment, configuration),
+// Value must point to a struct whose inheritable fields are matched by name.
+type InheritLayer struct {
+	Name  string
+	Value any
+}
+
+func IsInheritTraceEnabled() bool {
+	return IsLogLevelActive(LOG_VERYVERBOSE) || LogInherit.Level.IsVisible(LOG_VERYVERBOSE)
+}
+
+// TraceInherit logs how each inheritable field of result was resolved through given layers,
+// which is useful to understand where an INHERIT value finally took its value from.
+// Enabled with very verbose mode, or with -LogAll=Inherit.
+func TraceInherit(subject string, result any, layers ...InheritLayer) {
+	if !IsInheritTraceEnabled() {
+		return
+	}
+
+	values := make([]reflect.Value, len(layers))
+	for i, it := range layers {
+		values[i] = reflect.Indirect(reflect.ValueOf(it.Value))
+	}
+
+	traceInheritFields(subject, "", reflect.Indirect(reflect.ValueOf(result)), layers, values)
+}
+
+var inheritableBaseType = reflect.TypeOf((*InheritableBase)(nil)).Elem()
+
+func traceInheritFields(subject, prefix string, result reflect.Value, layers []InheritLayer, values []reflect.Value) {
+	var chain strings.Builder
+	for i := 0; i < result.NumField(); i++ {
+		field := result.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// layers are not required to share the same type, fields are matched by name
+		fields := make([]reflect.Value, len(values))
+		for j, it := range values {
+			if it.IsValid() && it.Kind() == reflect.Struct {
+				fields[j] = it.FieldByName(field.Name)
+			}
+		}
+
+		switch {
+		case field.Type.Implements(inheritableBaseType):
+			chain.Reset()
+			for j, it := range fields {
+				if !it.IsValid() {
+					continue
+				}
+				if chain.Len() > 0 {
+					chain.WriteString(" -> ")
+				}
+				fmt.Fprintf(&chain, "%s: %v", layers[j].Name, it.Interface())
+			}
+			LogVeryVerbose(LogInherit, "%s: %s%s resolved to %v (%s)", subject, prefix, field.Name, result.Field(i).Interface(), chain.String())
+
+		case field.Type.Kind() == reflect.Struct:
+			traceInheritFields(subject, prefix+field.Name+".", result.Field(i), layers, fields)
+		}
+	}
+}
+
 func InheritableCommandLine(name, input string, variable flag.Value) (bool, error) {
 	if len(input) > len(name)+1 && input[0] == '-' {
 		if input[1:1+len(name)] == name {
@@ -239,6 +311,101 @@ func (x *InheritableInt) CommandLine(name, input string) (bool, error) {
 	return false, nil
 }
 
+/***************************************
+ * InheritableIntRange
+ ***************************************/
+
+// InheritableIntRange parses an inclusive range of integers in one token, ex: `-Flag=4` or `-Flag=2..8`,
+// while open bounds can be omitted, ex: `-Flag=..8` or `-Flag=2..`.
+type InheritableIntRange struct {
+	Min, Max InheritableInt
+}
+
+func (x InheritableIntRange) Equals(o InheritableIntRange) bool {
+	return x == o
+}
+func (x *InheritableIntRange) Serialize(ar Archive) {
+	ar.Serializable(&x.Min)
+	ar.Serializable(&x.Max)
+}
+func (x InheritableIntRange) IsInheritable() bool {
+	return x.Min.IsInheritable() && x.Max.IsInheritable()
+}
+func (x InheritableIntRange) Contains(value int) bool {
+	return (x.Min.IsInheritable() || value >= x.Min.Get()) && (x.Max.IsInheritable() || value <= x.Max.Get())
+}
+func (x InheritableIntRange) Clamp(value int) int {
+	if !x.Min.IsInheritable() && value < x.Min.Get() {
+		return x.Min.Get()
+	}
+	if !x.Max.IsInheritable() && value > x.Max.Get() {
+		return x.Max.Get()
+	}
+	return value
+}
+
+func (x InheritableIntRange) String() string {
+	switch {
+	case x.IsInheritable():
+		return INHERIT_STRING
+	case x.Min == x.Max:
+		return x.Min.String()
+	case x.Min.IsInheritable():
+		return ".." + x.Max.String()
+	case x.Max.IsInheritable():
+		return x.Min.String() + ".."
+	default:
+		return x.Min.String() + ".." + x.Max.String()
+	}
+}
+func (x *InheritableIntRange) Set(in string) error {
+	if strings.ToUpper(in) == INHERIT_STRING {
+		*x = InheritableIntRange{Min: InheritableInt(INHERIT_VALUE), Max: InheritableInt(INHERIT_VALUE)}
+		return nil
+	}
+
+	lo, hi, isRange := strings.Cut(in, "..")
+	if !isRange {
+		hi = lo
+	}
+
+	result := InheritableIntRange{Min: InheritableInt(INHERIT_VALUE), Max: InheritableInt(INHERIT_VALUE)}
+	if lo = strings.TrimSpace(lo); len(lo) > 0 {
+		if err := result.Min.Set(lo); err != nil {
+			return err
+		}
+	}
+	if hi = strings.TrimSpace(hi); len(hi) > 0 {
+		if err := result.Max.Set(hi); err != nil {
+			return err
+		}
+	}
+
+	if !result.Min.IsInheritable() && !result.Max.IsInheritable() && result.Min.Get() > result.Max.Get() {
+		return fmt.Errorf("invalid range %q: lower bound is greater than upper bound", in)
+	}
+
+	*x = result
+	return nil
+}
+
+func (x InheritableIntRange) MarshalText() ([]byte, error) {
+	return UnsafeBytesFromString(x.String()), nil
+}
+func (x *InheritableIntRange) UnmarshalText(data []byte) error {
+	return x.Set(UnsafeStringFromBytes(data))
+}
+
+func (x *InheritableIntRange) CommandLine(name, input string) (bool, error) {
+	if ok, err := InheritableCommandLine(name, input, x); ok || err != nil {
+		return ok, err
+	}
+	if len(name) == 1 && len(input) > 2 && input[0] == '-' && input[1] == name[0] {
+		return true, x.Set(input[2:])
+	}
+	return false, nil
+}
+
 /***************************************
  * InheritableBigInt
  ***************************************/
@@ -736,18 +903,22 @@ func (x *InheritableSlice[T, P]) AutoComplete(in AutoComplete) {
 	var defaultValue T
 	var anon interface{} = P(&defaultValue)
 	if autocomplete, ok := anon.(AutoCompletable); ok {
-		// try to parse `-Switch=List0,List1,...,UnterminatedInput`
-		if off1 := strings.LastIndex(in.GetInput(), `,`); off1 >= 0 {
-			if off0 := strings.Index(in.GetInput(), `=`); off0 >= 0 {
-				if off0 <= off1 {
-					// prefix results by previous results, eg `List0,List1,...,`, except for `UnterminatedInput`
-					prefixed := NewPrefixedAutoComplete(in.GetInput()[off0+1:off1+1], "", in)
-					autocomplete.AutoComplete(&prefixed)
-					return
-				}
+		AutoCompleteList(in, `,`, autocomplete)
+	}
+}
+
+// AutoCompleteList suggests elements for the last item of a list given in one token, ex: `-Switch=List0,List1,...,UnterminatedInput`
+func AutoCompleteList(in AutoComplete, separators string, element AutoCompletable) {
+	if off1 := strings.LastIndexAny(in.GetInput(), separators); off1 >= 0 {
+		if off0 := strings.Index(in.GetInput(), `=`); off0 >= 0 {
+			if off0 <= off1 {
+				// prefix results by previous results, eg `List0,List1,...,`, except for `UnterminatedInput`
+				prefixed := NewPrefixedAutoComplete(in.GetInput()[off0+1:off1+1], "", in)
+				element.AutoComplete(&prefixed)
+				return
 			}
 		}
-
-		autocomplete.AutoComplete(in)
 	}
+
+	element.AutoComplete(in)
 }
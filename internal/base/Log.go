@@ -969,10 +969,23 @@ func SetEnableInteractiveShell(enabled bool) {
 	}
 }
 
+// terminal width in columns, 0 when unknown (ex: output is redirected to a file or a pipe)
+var terminalWidth int = 0
+
+func GetTerminalWidth() int {
+	return terminalWidth
+}
+func SetTerminalWidth(width int) {
+	terminalWidth = max(0, width)
+}
+
 type interactiveLogPin struct {
 	header atomic.Value
 	writer func(LogWriter)
 
+	eventId      int64
+	eventPercent atomic.Int32
+
 	tick      int
 	first     int64
 	last      atomic.Int64
@@ -994,6 +1007,8 @@ func (x *interactiveLogPin) reset() {
 	x.startedAt = 0
 	x.avgSpeed = 0
 	x.progress.Store(0)
+	x.eventId = 0
+	x.eventPercent.Store(0)
 }
 func (x *interactiveLogPin) format(dst LogWriter) {
 	if x.writer != nil {
@@ -1023,12 +1038,15 @@ func (x *interactiveLogPin) Len() int64 {
 
 func (x *interactiveLogPin) Grow(n int64) {
 	x.last.Add(n)
+	x.notifyProgress()
 }
 func (x *interactiveLogPin) Add(n int64) {
 	x.progress.Add(n)
+	x.notifyProgress()
 }
 func (x *interactiveLogPin) Inc() {
 	x.progress.Add(1)
+	x.notifyProgress()
 }
 func (x *interactiveLogPin) Set(v int64) {
 	for {
@@ -1037,6 +1055,7 @@ func (x *interactiveLogPin) Set(v int64) {
 			break
 		}
 	}
+	x.notifyProgress()
 }
 
 var interactiveLoggerOutput = os.Stderr
@@ -1047,13 +1066,17 @@ type interactiveWriter struct {
 }
 
 func (x *interactiveWriter) Write(buf []byte) (n int, err error) {
-	if x.logger.hasInflightMessages() {
+	if x.logger.hasInflightMessages() || !x.logger.messages.Empty() {
 		x.logger.detachMessages()
 		n, err = x.output.Write(buf)
 		if err == nil {
 			err = FlushWriterIFP(x.output)
 		}
-		x.logger.attachMessages()
+		// large blocks are written in several chunks: pins are only attached again after a complete line,
+		// otherwise they would be drawn in the middle of the block, and erasing them would also erase the partial line
+		if bytes.HasSuffix(buf, []byte{'\n'}) {
+			x.logger.attachMessages()
+		}
 	} else {
 		n, err = x.output.Write(buf)
 	}
@@ -1104,7 +1127,7 @@ func (x *interactiveLogger) Log(category *LogCategory, level LogLevel, msg strin
 	x.basicLogger.Log(category, level, msg, args...)
 }
 func (x *interactiveLogger) Pin(msg string, args ...interface{}) PinScope {
-	if EnableInteractiveShell() {
+	if EnableInteractiveShell() || EnableLogProgressEvents() {
 		pin := x.recycler.Allocate()
 		pin.Log(msg, args...)
 		pin.first = 1 // considered as a spinner
@@ -1115,12 +1138,13 @@ func (x *interactiveLogger) Pin(msg string, args ...interface{}) PinScope {
 		x.refreshMessages(func() {
 			x.messages.Append(pin)
 		})
+		writeLogProgressEvent(pin, LOGPROGRESSEVENT_START)
 		return pin
 	}
 	return basicLogPin{}
 }
 func (x *interactiveLogger) Progress(opts ...ProgressOptionFunc) ProgressScope {
-	if EnableInteractiveShell() {
+	if EnableInteractiveShell() || EnableLogProgressEvents() {
 		po := ProgressOptions{}
 		po.Color = NewPastelizerColor(rand.Float64()).Quantize(true)
 		for _, it := range opts {
@@ -1139,6 +1163,7 @@ func (x *interactiveLogger) Progress(opts ...ProgressOptionFunc) ProgressScope {
 		x.refreshMessages(func() {
 			x.messages.Append(pin)
 		})
+		writeLogProgressEvent(pin, LOGPROGRESSEVENT_START)
 		return pin
 	}
 	return basicLogProgress{}
@@ -1147,6 +1172,7 @@ func (x *interactiveLogger) Close(scope PinScope) error {
 	if !IsNil(scope) {
 		x.refreshMessages(func() {
 			pin := scope.(*interactiveLogPin)
+			writeLogProgressEvent(pin, LOGPROGRESSEVENT_CLOSE)
 			x.messages.Remove(pin)
 			x.recycler.Release(pin)
 		})
@@ -1170,6 +1196,13 @@ func (x *interactiveLogger) Write(buf []byte) (n int, err error) {
 }
 
 func (x *interactiveLogger) refreshMessages(inner func()) {
+	if !EnableInteractiveShell() { // pins are only allocated for progress events
+		if inner != nil {
+			inner()
+		}
+		return
+	}
+
 	defer x.transient.Reset()
 	prepareDetachMessages(&x.transient, x.inflight)
 	if inner != nil {
@@ -1221,7 +1254,7 @@ func prepareDetachMessages(buf LogWriter, inflight int) {
 }
 
 func (x *interactiveLogger) attachMessages() bool {
-	if x.inflight != 0 || x.messages.Empty() {
+	if x.inflight != 0 || x.messages.Empty() || !EnableInteractiveShell() {
 		return false
 	}
 
@@ -1401,6 +1434,104 @@ func (x *interactiveLogPin) writeLogProgress(lw LogWriter) {
 	}
 }
 
+/***************************************
+ * Log Progress Events
+ ***************************************/
+
+// Lifecycle of pins and progress bars can be streamed as json lines to a file or a named pipe, in parallel
+// to the interactive terminal rendering, so external progress UIs do not need to parse ANSI output.
+
+type LogProgressEventType string
+
+const (
+	LOGPROGRESSEVENT_START  LogProgressEventType = "start"
+	LOGPROGRESSEVENT_UPDATE LogProgressEventType = "update"
+	LOGPROGRESSEVENT_CLOSE  LogProgressEventType = "close"
+)
+
+type LogProgressEvent struct {
+	Id       int64                `json:"id"`
+	Event    LogProgressEventType `json:"event"`
+	Text     string               `json:"text,omitempty"`
+	Spinner  bool                 `json:"spinner,omitempty"`
+	Progress int64                `json:"progress"`
+	Len      int64                `json:"len"`
+	Percent  int                  `json:"percent"`
+	Elapsed  float64              `json:"elapsed"` // seconds since process started
+}
+
+var gLogProgressEvents io.Writer
+var gLogProgressEventsFailed atomic.Bool
+var gLogProgressEventNextId int64
+var gLogProgressEventBarrier sync.Mutex
+
+func EnableLogProgressEvents() bool {
+	return gLogProgressEvents != nil && !gLogProgressEventsFailed.Load()
+}
+
+// SetLogProgressEvents should be called before any pin is created, dst is not closed by the logger
+func SetLogProgressEvents(dst io.Writer) {
+	gLogProgressEvents = dst
+}
+
+// notifyProgress is called by progress setters from any thread, it does not wait for the logger to refresh pins:
+// updates are throttled per pin by only streaming an event when percent changed, spinners have nothing to update
+func (x *interactiveLogPin) notifyProgress() {
+	if !EnableLogProgressEvents() || !x.isProgressBar() {
+		return
+	}
+	if x.getProgressPercent() != int(x.eventPercent.Load()) {
+		writeLogProgressEvent(x, LOGPROGRESSEVENT_UPDATE)
+	}
+}
+func (x *interactiveLogPin) getProgressPercent() int {
+	if n := x.Len(); n > 0 {
+		return int(max(0, min(100, x.Progress()*100/n)))
+	}
+	return 0
+}
+
+// writeLogProgressEvent is called from the logger and from progress setters, so events are serialized by a barrier
+func writeLogProgressEvent(pin *interactiveLogPin, eventType LogProgressEventType) {
+	if !EnableLogProgressEvents() {
+		return
+	}
+
+	gLogProgressEventBarrier.Lock()
+	defer gLogProgressEventBarrier.Unlock()
+
+	evt := LogProgressEvent{
+		Event:   eventType,
+		Spinner: !pin.isProgressBar(),
+		Elapsed: Elapsed().Seconds(),
+	}
+	if value := pin.header.Load(); !IsNil(value) {
+		evt.Text = strings.TrimSpace(value.(string))
+	}
+	if !evt.Spinner {
+		evt.Progress = pin.Progress()
+		evt.Len = pin.Len()
+		evt.Percent = pin.getProgressPercent()
+	}
+
+	switch eventType {
+	case LOGPROGRESSEVENT_START:
+		gLogProgressEventNextId++
+		pin.eventId = gLogProgressEventNextId
+	case LOGPROGRESSEVENT_UPDATE:
+		// another thread could already have streamed this percent while waiting for the barrier
+		if evt.Spinner || evt.Percent == int(pin.eventPercent.Load()) {
+			return
+		}
+	}
+	evt.Id = pin.eventId
+	pin.eventPercent.Store(int32(evt.Percent))
+
+	if err := JsonSerialize(evt, gLogProgressEvents); err != nil {
+		gLogProgressEventsFailed.Store(true) // the reader went away (ex: closed pipe), do not fail the build for it
+	}
+}
+
 /***************************************
  * Logger helpers
  ***************************************/
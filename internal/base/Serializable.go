@@ -276,6 +276,8 @@ func RegisterSerializable[T any, S interface {
 	globalSerializableFactory.RegisterName(uintptr(emptyPtr.typ), reflectTypename(rt), func() Serializable {
 		return S(batchNew.Allocate()) // S(new(T)) -> faster with batch new
 	})
+
+	registerArchiveSchemaType(rt)
 }
 
 /***************************************
@@ -182,3 +182,44 @@ func TestArchiveMap(t *testing.T) {
 		newTestSerializableMap(make(map[InheritableString]Fingerprint, 0)),
 		newTestSerializableMap(testMap))
 }
+
+func TestArchiveFileVersion(t *testing.T) {
+	olderVersion := MakeFourCC('0', '0', '0', '1')
+
+	writeOlderArchive := func() *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		if err := WithArchiveBinaryWriter(buf, func(ar Archive) error {
+			magic, version, content := ArchiveFileMagic, olderVersion, "older"
+			ar.Serializable(&magic)
+			ar.Serializable(&version)
+			ar.String(&content)
+			return ar.Error()
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+
+	// older archives are discarded when no migration was registered
+	if _, err := ArchiveFileRead(writeOlderArchive(), func(ar Archive) {
+		t.Error("archive: scope should not be called for an outdated archive")
+	}); !IsArchiveVersionError(err) {
+		t.Errorf("archive: expected version error, but got %v", err)
+	}
+
+	// or migrated when one was
+	archiveMigrations.Add(olderVersion, func(ar Archive, file *ArchiveFile, scope func(Archive)) error {
+		scope(ar)
+		return ar.Error()
+	})
+	defer archiveMigrations.Delete(olderVersion)
+
+	var content string
+	if _, err := ArchiveFileRead(writeOlderArchive(), func(ar Archive) {
+		ar.String(&content)
+	}); err != nil {
+		t.Error(err)
+	} else if content != "older" {
+		t.Errorf("archive: migrated content mismatch (%q != %q)", content, "older")
+	}
+}
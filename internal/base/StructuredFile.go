@@ -8,6 +8,9 @@ import (
 
 const STRUCTUREDFILE_DEFAULT_TAB = "  "
 
+// columns given to Align() are expressed for this width, and scaled to the target width of the file
+const STRUCTUREDFILE_DEFAULT_WIDTH = 80
+
 type StructuredFileFlags int32
 
 const (
@@ -30,6 +33,7 @@ type StructuredFile struct {
 	tab    string
 	flags  StructuredFileFlags
 	site   FileSite
+	width  int
 	writer io.Writer
 }
 
@@ -46,6 +50,7 @@ func NewStructuredFile(writer io.Writer, tab string, minify bool) *StructuredFil
 			Line:   1,
 			Column: 1,
 		},
+		width:  STRUCTUREDFILE_DEFAULT_WIDTH,
 		writer: writer,
 	}
 }
@@ -54,6 +59,19 @@ func (sf *StructuredFile) Minify() bool {
 	return (sf.flags & STRUCTUREDFILE_MINIFY) == STRUCTUREDFILE_MINIFY
 }
 func (sf *StructuredFile) Site() FileSite { return sf.site }
+func (sf *StructuredFile) Width() int     { return sf.width }
+
+// SetWidth changes the target width used to scale alignment columns, STRUCTUREDFILE_DEFAULT_WIDTH is used when width <= 0
+func (sf *StructuredFile) SetWidth(width int) {
+	if width > 0 {
+		sf.width = width
+	} else {
+		sf.width = STRUCTUREDFILE_DEFAULT_WIDTH
+	}
+}
+func (sf *StructuredFile) scaleColumn(column int) int {
+	return max(1, (column*sf.width)/STRUCTUREDFILE_DEFAULT_WIDTH)
+}
 
 func (sf *StructuredFile) IndentIFN() {
 	if sf.site.Column == 1 {
@@ -92,6 +110,11 @@ func (sf *StructuredFile) LineBreak() {
 	}
 }
 func (sf *StructuredFile) Align(column int) {
+	column = sf.scaleColumn(column)
+	if sf.site.Column > 1 && sf.site.Column >= column {
+		// always keep a separator when current line already overflowed the column
+		column = sf.site.Column + 1
+	}
 	sf.Pad(column, " ")
 }
 func (sf *StructuredFile) Pad(column int, in string) {
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type TaskPriority byte
@@ -44,6 +45,10 @@ type ThreadPool interface {
 	Join()
 	Resize(int)
 
+	// utilization is only sampled after being enabled, since every workload change must be recorded
+	EnableUtilization()
+	GetUtilization() ThreadPoolUtilization
+
 	ThreadPoolEvents
 }
 
@@ -128,6 +133,97 @@ type ThreadPoolEvents interface {
 	RemoveOnWorkFinished(DelegateHandle) bool
 }
 
+/***************************************
+ * Thread Pool Utilization
+ ***************************************/
+
+// ThreadPoolUtilization records how long the pool ran with each number of busy workers
+type ThreadPoolUtilization struct {
+	Arity     int
+	Duration  time.Duration
+	Peak      int
+	Durations []time.Duration // indexed by number of busy workers, so Durations[0] is idle time
+}
+
+// Average returns mean number of busy workers over the sampled duration
+func (x ThreadPoolUtilization) Average() float64 {
+	if x.Duration == 0 {
+		return 0
+	}
+	var weighted float64
+	for busy, d := range x.Durations {
+		weighted += float64(busy) * d.Seconds()
+	}
+	return weighted / x.Duration.Seconds()
+}
+func (x ThreadPoolUtilization) Idle() time.Duration {
+	if len(x.Durations) > 0 {
+		return x.Durations[0]
+	}
+	return 0
+}
+
+type threadPoolUtilizationTracker struct {
+	enabled atomic.Bool
+	barrier sync.Mutex
+
+	startedAt  time.Time
+	lastChange time.Time
+	busy       int
+	peak       int
+	durations  []time.Duration
+}
+
+func (x *threadPoolUtilizationTracker) enable(workload int) {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+	if !x.enabled.Load() {
+		x.startedAt = time.Now()
+		x.lastChange = x.startedAt
+		x.busy = workload
+		x.peak = workload
+		x.enabled.Store(true)
+	}
+}
+func (x *threadPoolUtilizationTracker) record(delta int) {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	now := time.Now()
+	x.accumulate(now)
+	x.lastChange = now
+
+	// workload is read without barrier when enabling, so a task finishing concurrently could be missed
+	if x.busy = x.busy + delta; x.busy < 0 {
+		x.busy = 0
+	}
+	x.peak = max(x.peak, x.busy)
+}
+func (x *threadPoolUtilizationTracker) accumulate(now time.Time) {
+	for len(x.durations) <= x.busy {
+		x.durations = append(x.durations, 0)
+	}
+	x.durations[x.busy] += now.Sub(x.lastChange)
+}
+func (x *threadPoolUtilizationTracker) snapshot(arity int) (result ThreadPoolUtilization) {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	result.Arity = arity
+	if !x.enabled.Load() {
+		return
+	}
+
+	now := time.Now()
+	x.accumulate(now)
+	x.lastChange = now
+
+	result.Duration = now.Sub(x.startedAt)
+	result.Peak = x.peak
+	result.Durations = CopySlice(x.durations...)
+	return
+}
+
 /***************************************
  * Fixed Size Thread Pool
  ***************************************/
@@ -147,6 +243,8 @@ type fixedSizeThreadPool struct {
 
 	onWorkStartEvent    ConcurrentEvent[ThreadPoolWorkEvent]
 	onWorkFinishedEvent ConcurrentEvent[ThreadPoolWorkEvent]
+
+	utilization threadPoolUtilizationTracker
 }
 
 func NewFixedSizeThreadPool(name string, numWorkers int) ThreadPool {
@@ -242,6 +340,13 @@ func (x *fixedSizeThreadPool) Resize(n int) {
 	x.numWorkers += delta
 }
 
+func (x *fixedSizeThreadPool) EnableUtilization() {
+	x.utilization.enable(x.GetWorkload())
+}
+func (x *fixedSizeThreadPool) GetUtilization() ThreadPoolUtilization {
+	return x.utilization.snapshot(x.numWorkers)
+}
+
 func (x *fixedSizeThreadPool) OnWorkStart(event EventDelegate[ThreadPoolWorkEvent]) DelegateHandle {
 	return x.onWorkStartEvent.Add(event)
 }
@@ -274,6 +379,9 @@ func onWorkerThreadStop(pool ThreadPool, workerIndex int) {
 
 func (x *fixedSizeThreadPool) runTaskOnWorker(threadContext ThreadContext, task TaskQueued, priority TaskPriority) {
 	x.workload.Add(1)
+	if x.utilization.enabled.Load() {
+		x.utilization.record(1)
+	}
 	if x.onWorkStartEvent.Bound() {
 		x.onWorkStartEvent.Invoke(ThreadPoolWorkEvent{
 			Context:  threadContext,
@@ -289,6 +397,9 @@ func (x *fixedSizeThreadPool) runTaskOnWorker(threadContext ThreadContext, task
 				Priority: priority,
 			})
 		}
+		if x.utilization.enabled.Load() {
+			x.utilization.record(-1)
+		}
 		x.workload.Add(-1)
 	}()
 
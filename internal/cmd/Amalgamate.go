@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type AmalgamateCommand struct {
+	Target  compile.TargetAlias
+	Output  utils.Filename
+	NoCheck utils.BoolVar
+}
+
+var CommandAmalgamate = utils.NewCommandable(
+	"Metadata",
+	"amalgamate",
+	"concatenate headers of target module in a single distributable header, ordered by their include relationships",
+	&AmalgamateCommand{
+		NoCheck: base.INHERITABLE_FALSE,
+	})
+
+func (x *AmalgamateCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Output", "override path of generated header (default: <Binaries>/<module>.h)", &x.Output)
+	cfv.Variable("NoCheck", "do not compile generated header to validate it is self-contained", &x.NoCheck)
+}
+func (x *AmalgamateCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("AmalgamateCommand", "control single header amalgamation", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "target whose module headers should be amalgamated", &x.Target),
+	)
+	return nil
+}
+func (x *AmalgamateCommand) Run(cc utils.CommandContext) error {
+	if !x.Output.Valid() {
+		x.Output = utils.UFS.Binaries.File(strings.ReplaceAll(x.Target.ModuleAlias.String(), "/", "-") + ".h")
+	}
+
+	base.LogClaim(utils.LogCommand, "amalgamate <%v> in %q", x.Target, x.Output)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Amalgamate"})
+	defer bg.Close()
+
+	if _, err := compile.NeedTargetActions(bg.GlobalContext(), x.Target); err != nil {
+		return err
+	}
+
+	content, err := compile.AmalgamateHeaders(bg, x.Target)
+	if err != nil {
+		return err
+	}
+
+	if !x.NoCheck.Get() {
+		if err := compile.CheckAmalgamatedHeader(bg, x.Target, content); err != nil {
+			return err
+		}
+	}
+
+	return utils.UFS.CreateBuffered(x.Output, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	}, base.TransientPage4KiB)
+}
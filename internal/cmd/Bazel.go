@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type BazelCommand struct {
+	Environment compile.EnvironmentAlias
+	Output      utils.Filename
+}
+
+var CommandBazel = utils.NewCommandable(
+	"Export",
+	"export-bazel",
+	"generate a Bazel BUILD file skeleton, with cc_library/cc_binary rules derived from translated units",
+	&BazelCommand{})
+
+func (x *BazelCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Environment", "select compilation environment used to resolve units (default: <LocalHostPlatform>-Debug)", &x.Environment)
+	cfv.Variable("Output", "output BUILD file, paths are written relative to root directory (default: <Root>/BUILD.ppb.bazel)", &x.Output)
+}
+func (x *BazelCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("BazelCommand", "control Bazel BUILD file generation", x),
+		compile.OptionCommandAllCompilationFlags(),
+	)
+	return nil
+}
+func (x *BazelCommand) Run(cc utils.CommandContext) error {
+	if !x.Environment.Valid() {
+		x.Environment = compile.EnvironmentAlias{
+			PlatformAlias:      compile.GetLocalHostPlatformAlias(),
+			ConfigurationAlias: compile.NewConfigurationAlias("Debug"),
+		}
+	}
+	if !x.Output.Valid() {
+		// never overwrite an actual BUILD file by default, this is only a starting point for a migration
+		x.Output = utils.UFS.Root.File("BUILD.ppb.bazel")
+	}
+
+	base.LogClaim(utils.LogCommand, "export-bazel <%v> in %q...", x.Environment, x.Output)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "ExportBazel"})
+	defer bg.Close()
+
+	bc := bg.GlobalContext()
+	modules, err := compile.NeedAllBuildModules(bc)
+	if err != nil {
+		return err
+	}
+
+	// sort everything to be deterministic
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].GetModule().ModuleAlias.Compare(modules[j].GetModule().ModuleAlias) < 0
+	})
+
+	rules := make([]bazelRule, 0, len(modules))
+	for _, module := range modules {
+		buildable, err := bc.NeedBuildable(compile.TargetAlias{
+			ModuleAlias:      module.GetModule().ModuleAlias,
+			EnvironmentAlias: x.Environment,
+		})
+		if err != nil {
+			return err
+		}
+
+		rule, err := newBazelRule(bc, module.GetModule(), buildable.(*compile.Unit))
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+
+	return utils.UFS.CreateBuffered(x.Output, func(w io.Writer) error {
+		fmt.Fprintf(w, "# Generated by %v export-bazel from <%v>, DO NOT EDIT: rename this file to BUILD.bazel at the root of the workspace.\n", utils.CommandEnv.Prefix(), x.Environment)
+		fmt.Fprintln(w, "# This is only a skeleton: toolchain options, generated files, custom actions and assets are not translated.")
+		for _, it := range rules {
+			fmt.Fprintln(w)
+			if err := it.Write(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, base.TransientPage4KiB)
+}
+
+/***************************************
+ * Bazel rule skeleton
+ ***************************************/
+
+type bazelRule struct {
+	Kind         string
+	Name         string
+	Module       compile.ModuleAlias
+	LinkShared   bool
+	Srcs         base.StringSet
+	HdrsGlobs    base.StringSet
+	Includes     base.StringSet
+	Copts        base.StringSet
+	Defines      base.StringSet
+	LocalDefines base.StringSet
+	Deps         base.StringSet
+}
+
+func getBazelRuleName(module compile.ModuleAlias) string {
+	return strings.ReplaceAll(module.String(), "/", "_")
+}
+
+// bazel can only reference files inside the workspace, and outputs of ppb are not sources
+func getBazelRelativePath(path string) (string, bool) {
+	root, output := utils.UFS.Root.String(), utils.UFS.Output.String()
+	if !strings.HasPrefix(path, root) || strings.HasPrefix(path, output) {
+		return "", false
+	}
+	rel := strings.TrimPrefix(path, root)
+	if len(rel) == 0 || (rel[0] != '/' && rel[0] != '\\') {
+		return "", false // sibling directory sharing the same prefix
+	}
+	return strings.ReplaceAll(rel[1:], `\`, `/`), true
+}
+
+func newBazelRule(bc utils.BuildContext, module *compile.ModuleRules, unit *compile.Unit) (rule bazelRule, err error) {
+	rule.Module = module.ModuleAlias
+	rule.Name = getBazelRuleName(module.ModuleAlias)
+
+	switch unit.Payload {
+	case compile.PAYLOAD_EXECUTABLE:
+		rule.Kind = "cc_binary"
+	case compile.PAYLOAD_SHAREDLIB:
+		rule.Kind = "cc_binary"
+		rule.LinkShared = true
+	default:
+		rule.Kind = "cc_library"
+	}
+
+	// unity files are generated: bazel should compile original sources instead
+	sourceFiles, err := unit.Source.GetFileSet(bc)
+	if err != nil {
+		return
+	}
+	for _, it := range sourceFiles {
+		if rel, ok := getBazelRelativePath(it.String()); ok {
+			rule.Srcs.Append(rel)
+		}
+	}
+
+	if publicDir := module.PublicDir(); publicDir.Exists() {
+		if rel, ok := getBazelRelativePath(publicDir.String()); ok {
+			rule.Includes.Append(rel)
+			// cc_binary has no hdrs attribute
+			if rule.Kind == "cc_library" {
+				for _, ext := range []string{"h", "hpp", "inl"} {
+					rule.HdrsGlobs.Append(fmt.Sprintf("%s/**/*.%s", rel, ext))
+				}
+			}
+		}
+	}
+	// private include paths of this module are not propagated to dependents, unlike bazel includes
+	for _, it := range unit.IncludePaths {
+		if !it.IsIn(module.ModuleDir) && !it.Equals(module.ModuleDir) {
+			continue
+		}
+		if rel, ok := getBazelRelativePath(it.String()); ok && !rule.Includes.Contains(rel) {
+			rule.Copts.AppendUniq(fmt.Sprint("-I", rel))
+		}
+	}
+
+	// only options declared by the module are kept, options of the toolchain belong to a bazel toolchain
+	for _, it := range module.CompilerOptions {
+		if !strings.Contains(it, "%") {
+			rule.Copts.AppendUniq(it)
+		}
+	}
+
+	rule.Defines.AppendUniq(unit.TransitiveFacet.Defines...)
+	for _, it := range module.Defines {
+		if !rule.Defines.Contains(it) {
+			rule.LocalDefines.AppendUniq(it)
+		}
+	}
+
+	for _, deps := range []compile.ModuleAliases{module.PublicDependencies, module.PrivateDependencies} {
+		for _, it := range deps {
+			rule.Deps.AppendUniq(fmt.Sprint(":", getBazelRuleName(it)))
+		}
+	}
+
+	rule.Srcs.Sort()
+	rule.Includes.Sort()
+	rule.HdrsGlobs.Sort()
+	rule.Defines.Sort()
+	rule.LocalDefines.Sort()
+	rule.Deps.Sort()
+	return
+}
+
+func (x *bazelRule) Write(w io.Writer) error {
+	writeList := func(name string, values base.StringSet) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "    %s = [\n", name)
+		for _, it := range values {
+			fmt.Fprintf(w, "        %s,\n", strconv.Quote(it))
+		}
+		fmt.Fprintln(w, "    ],")
+	}
+
+	fmt.Fprintf(w, "# ppb module <%v>\n", x.Module)
+	fmt.Fprintf(w, "%s(\n", x.Kind)
+	fmt.Fprintf(w, "    name = %s,\n", strconv.Quote(x.Name))
+	writeList("srcs", x.Srcs)
+	if len(x.HdrsGlobs) > 0 {
+		fmt.Fprintln(w, "    hdrs = glob([")
+		for _, it := range x.HdrsGlobs {
+			fmt.Fprintf(w, "        %s,\n", strconv.Quote(it))
+		}
+		fmt.Fprintln(w, "    ]),")
+	}
+	writeList("includes", x.Includes)
+	writeList("copts", x.Copts)
+	writeList("defines", x.Defines)
+	writeList("local_defines", x.LocalDefines)
+	writeList("deps", x.Deps)
+	if x.LinkShared {
+		fmt.Fprintln(w, "    linkshared = True,")
+	}
+	fmt.Fprintln(w, `    tags = ["ppb-generated"],`)
+	_, err := fmt.Fprintln(w, ")")
+	return err
+}
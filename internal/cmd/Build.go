@@ -9,10 +9,18 @@ import (
 )
 
 type BuildCommand struct {
-	Targets []compile.TargetAlias
-	Clean   utils.BoolVar
-	Glob    utils.BoolVar
-	Rebuild utils.BoolVar
+	Targets         []compile.TargetAlias
+	Clean           utils.BoolVar
+	Glob            utils.BoolVar
+	IncludeExcluded utils.BoolVar
+	Label           utils.StringVar
+	Manifest        utils.Filename
+	Rebuild         utils.BoolVar
+
+	PchReport    utils.BoolVar
+	Retention    compile.RetentionPolicy
+	SlowestUnits utils.IntVar
+	TimeTrace    utils.Filename
 }
 
 var CommandBuild = utils.NewCommandable(
@@ -20,20 +28,30 @@ var CommandBuild = utils.NewCommandable(
 	"build",
 	"launch action compilation process",
 	&BuildCommand{
-		Clean:   base.INHERITABLE_FALSE,
-		Glob:    base.INHERITABLE_FALSE,
-		Rebuild: base.INHERITABLE_FALSE,
+		Clean:           base.INHERITABLE_FALSE,
+		Glob:            base.INHERITABLE_FALSE,
+		IncludeExcluded: base.INHERITABLE_FALSE,
+		Rebuild:         base.INHERITABLE_FALSE,
+		PchReport:       base.INHERITABLE_FALSE,
 	})
 
 func (x *BuildCommand) Flags(cfv utils.CommandFlagsVisitor) {
 	cfv.Variable("Clean", "erase all by files outputted by selected actions", &x.Clean)
 	cfv.Variable("Glob", "treat provided targets as glob expressions", &x.Glob)
+	cfv.Variable("IncludeExcluded", "let glob expressions also select modules excluded from default build, which are otherwise only selected when named without wildcard", &x.IncludeExcluded)
+	cfv.Variable("Label", "select targets of modules matching a label expression, supports !/&/| operators (ex: 'tools|tests&!slow')", &x.Label)
+	cfv.Variable("Manifest", "write a json manifest listing all artifacts produced by selected targets after a successful build", &x.Manifest)
 	cfv.Variable("Rebuild", "rebuild selected actions, same as building after a clean", &x.Rebuild)
+	cfv.Variable("PchReport", "print size, number of translation units and estimated time saved of each precompiled header after a successful build, ranked by size per translation unit (use with -Rebuild to measure every PCH)", &x.PchReport)
+	cfv.Variable("Retention", "select which intermediate files are deleted after a successful build of selected targets", &x.Retention)
+	cfv.Variable("SlowestUnits", "print the N slowest translation units compiled after a successful build", &x.SlowestUnits)
+	cfv.Variable("TimeTrace", "merge clang -ftime-trace outputs of selected targets in a single chrome trace (requires -Benchmark)", &x.TimeTrace)
 	action.GetActionFlags().Flags(cfv)
 }
 func (x *BuildCommand) Init(ci utils.CommandContext) error {
 	ci.Options(
 		utils.OptionCommandParsableFlags("BuildCommand", "control compilation actions execution", x),
+		compile.OptionCommandAllCompilationFlags(),
 		utils.OptionCommandParsableAccessor("ClusterFlags", "action distribution in network cluster", cluster.GetClusterFlags),
 		utils.OptionCommandParsableAccessor("WorkerFlags", "set hardware limits for local action compilation", cluster.GetWorkerFlags),
 		utils.OptionCommandConsumeMany("TargetAlias", "build all targets specified as argument", &x.Targets),
@@ -47,6 +65,8 @@ func (x *BuildCommand) Prepare(cc utils.CommandContext) error {
 		go action.GetActionDist()
 	}
 
+	action.PrepareActionDiagnostics()
+
 	return nil
 }
 func (x *BuildCommand) Run(cc utils.CommandContext) error {
@@ -57,12 +77,13 @@ func (x *BuildCommand) Run(cc utils.CommandContext) error {
 
 	// select target that match input by globbing
 	if x.Glob.Get() {
-		units, err := compile.NeedAllBuildUnits(bg.GlobalContext())
+		patterns := base.MakeStringerSet(x.Targets...)
+		units, err := compile.NeedGlobBuildUnits(bg.GlobalContext(), x.IncludeExcluded.Get(), patterns...)
 		if err != nil {
 			return err
 		}
 
-		re := utils.MakeGlobRegexp(base.MakeStringerSet(x.Targets...)...)
+		re := utils.MakeGlobRegexp(patterns...)
 
 		// overwrite user input with matching targets found
 		for _, unit := range units {
@@ -99,12 +120,25 @@ func (x *BuildCommand) Run(cc utils.CommandContext) error {
 		}
 	}
 
+	// select targets of modules matching label expression
+	if !x.Label.IsInheritable() {
+		if err := x.selectTargetsByLabel(bg); err != nil {
+			return err
+		}
+	}
+
 	// select target that exactly match input,
 	targetActions, err := compile.NeedTargetActions(bg.GlobalContext(), x.Targets...)
 	if err != nil {
 		return err
 	}
 
+	if n, err := compile.GetNumForcedWarningsAsErrors(bg, targetActions...); err != nil {
+		return err
+	} else if n > 0 {
+		base.LogInfo(utils.LogCommand, "ForceWarningsAsErrors: promoted warnings to errors in %d module(s) which opted out", n)
+	}
+
 	if x.Clean.Get() || x.Rebuild.Get() {
 		if err := x.cleanBuild(bg, targetActions); err != nil {
 			return err
@@ -112,17 +146,94 @@ func (x *BuildCommand) Run(cc utils.CommandContext) error {
 	}
 
 	if !x.Clean.Get() || x.Rebuild.Get() {
+		if err := compile.CheckUnmanagedLibraries(bg, targetActions...); err != nil {
+			return err
+		}
+
 		if err := x.doBuild(bg, targetActions); err != nil {
 			return err
 		}
+
+		if err := compile.CheckPrivateIncludes(bg, targetActions...); err != nil {
+			return err
+		}
+
+		if err := compile.ReportCppStdDowngrades(bg, targetActions...); err != nil {
+			return err
+		}
+
+		if x.PchReport.Get() {
+			if err := compile.ReportPrecompiledHeaderUsages(bg, targetActions...); err != nil {
+				return err
+			}
+		}
+
+		if n := x.SlowestUnits.Get(); n > 0 {
+			if err := compile.ReportSlowestTranslationUnits(bg, n, targetActions...); err != nil {
+				return err
+			}
+		}
+
+		if x.TimeTrace.Valid() {
+			if err := compile.MergeTimeTraces(bg, x.TimeTrace, targetActions...); err != nil {
+				return err
+			}
+		}
+
+		if x.Manifest.Valid() {
+			manifest, err := NewBuildManifest(bg, targetActions...)
+			if err != nil {
+				return err
+			}
+			if err := manifest.Save(x.Manifest); err != nil {
+				return err
+			}
+		}
+
+		// last, since reports and manifest above can read intermediate files
+		if err := compile.ApplyRetentionPolicy(bg, x.Retention, targetActions...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+func (x *BuildCommand) selectTargetsByLabel(bg utils.BuildGraphWritePort) error {
+	expr, err := compile.ParseLabelExpression(x.Label.Get())
+	if err != nil {
+		return err
 	}
 
+	units, err := compile.NeedDefaultBuildUnits(bg.GlobalContext())
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range units {
+		module, err := compile.FindBuildModule(bg, unit.TargetAlias.ModuleAlias)
+		if err != nil {
+			return err
+		}
+		if expr.Match(module.GetModule().Labels) {
+			base.LogVerbose(utils.LogCommand, "label %v matched target <%v>", expr, unit.TargetAlias)
+			x.Targets = append(x.Targets, unit.TargetAlias)
+		}
+	}
 	return nil
 }
 func (x *BuildCommand) doBuild(bg utils.BuildGraphWritePort, targets []*compile.TargetActions) error {
+	// launch high priority targets first, so their actions are queued before others
+	highPriorities, err := x.launchHighPriorityBuild(bg, targets)
+	if err != nil {
+		return err
+	}
+
 	aliases := utils.BuildAliases{}
 	for _, ta := range targets {
 		if tp, err := ta.GetOutputPayload(bg); err == nil {
+			if _, launched := highPriorities[tp.Alias()]; launched {
+				continue // already launched with high priority, avoid building twice with -Rebuild
+			}
 			aliases.Append(tp.Alias())
 			base.LogVerbose(utils.LogCommand, "selected <%v> actions: %v", tp.Alias(), tp.ActionAliases)
 		} else {
@@ -130,11 +241,46 @@ func (x *BuildCommand) doBuild(bg utils.BuildGraphWritePort, targets []*compile.
 		}
 	}
 
-	_, err := bg.BuildMany(aliases,
+	_, err = bg.BuildMany(aliases,
 		utils.OptionBuildForceIf(x.Rebuild.Get()),
 		utils.OptionWarningOnMissingOutputIf(!x.Rebuild.Get()))
+
+	for _, future := range highPriorities {
+		if result := future.Join(); err == nil {
+			err = result.Failure()
+		}
+	}
 	return err
 }
+func (x *BuildCommand) launchHighPriorityBuild(bg utils.BuildGraphWritePort, targets []*compile.TargetActions) (map[utils.BuildAlias]base.Future[utils.BuildResult], error) {
+	highPriorityTargets, err := compile.SelectHighPriorityTargets(bg, base.Map(func(ta *compile.TargetActions) compile.TargetAlias {
+		return ta.TargetAlias
+	}, targets...)...)
+	if err != nil || len(highPriorityTargets) == 0 {
+		return nil, err
+	}
+
+	highPriorityActions, err := compile.NeedTargetActions(bg.GlobalContext(), highPriorityTargets...)
+	if err != nil {
+		return nil, err
+	}
+
+	futures := make(map[utils.BuildAlias]base.Future[utils.BuildResult], len(highPriorityActions))
+	for _, ta := range highPriorityActions {
+		tp, err := ta.GetOutputPayload(bg)
+		if err != nil {
+			return nil, err
+		}
+
+		base.LogVerbose(utils.LogCommand, "high priority <%v> actions: %v", tp.Alias(), tp.ActionAliases)
+		_, future := bg.Build(tp,
+			utils.OptionBuildForceIf(x.Rebuild.Get()),
+			utils.OptionWarningOnMissingOutputIf(!x.Rebuild.Get()),
+			utils.OptionBuildHighPriorityIf(true))
+		futures[tp.Alias()] = future
+	}
+	return futures, nil
+}
 func (x *BuildCommand) cleanBuild(bg utils.BuildGraphWritePort, targets []*compile.TargetActions) error {
 	aliases := action.ActionAliases{}
 	for _, ta := range targets {
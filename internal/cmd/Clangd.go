@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type ClangdCommand struct {
+	Target compile.TargetAlias
+	Output utils.Filename
+}
+
+var CommandClangd = utils.NewCommandable(
+	"Configure",
+	"clangd",
+	"generate .clangd config with compilation flags resolved from a representative target",
+	&ClangdCommand{
+		Output: utils.UFS.Root.File(".clangd"),
+	})
+
+func (x *ClangdCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Output", "override path of generated .clangd config", &x.Output)
+}
+func (x *ClangdCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("ClangdCommand", "control .clangd config generation", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "representative target used to resolve compilation flags", &x.Target),
+	)
+	return nil
+}
+func (x *ClangdCommand) Run(cc utils.CommandContext) error {
+	base.LogClaim(utils.LogCommand, "generating .clangd config in %q from <%v>", x.Output, x.Target)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Clangd"})
+	defer bg.Close()
+
+	targets, err := compile.NeedTargetActions(bg.GlobalContext(), x.Target)
+	if err != nil {
+		return err
+	}
+
+	objectList, err := targets[0].GetPayload(bg, compile.PAYLOAD_OBJECTLIST)
+	if err != nil {
+		return err
+	}
+	actions, err := objectList.GetActions(bg)
+	if err != nil {
+		return err
+	}
+
+	// same extraction than compilation database, so flags are spelled and ordered like the actual compilation of the unit
+	var flags base.StringSet
+	for _, it := range actions {
+		rules := it.GetAction()
+		if command, ok := compile.MakeCompileCommand(rules, rules.GetStaticInputFiles(bg)); ok {
+			flags = command.GetCompilationFlags(rules.OutputFiles...)
+			break
+		}
+	}
+	if len(flags) == 0 {
+		return fmt.Errorf("clangd: <%v> does not compile any source file", x.Target)
+	}
+
+	base.LogVerbose(utils.LogCommand, "clangd: resolved %d compilation flags from <%v>", len(flags), x.Target)
+
+	return utils.UFS.CreateBuffered(x.Output, func(w io.Writer) error {
+		if _, err := fmt.Fprintf(w, "# generated by ppb from <%v>, do not edit\nCompileFlags:\n  Add:\n", x.Target); err != nil {
+			return err
+		}
+		for _, it := range flags {
+			// quote every flag, since paths and defines can contain yaml special characters
+			if _, err := fmt.Fprintf(w, "    - %s\n", strconv.Quote(it)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, base.TransientPage4KiB)
+}
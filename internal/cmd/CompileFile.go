@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type CompileFileCommand struct {
+	SourceFile  utils.Filename
+	Environment compile.EnvironmentAlias
+	Rebuild     utils.BoolVar
+}
+
+var CommandCompileFile = utils.NewCommandable(
+	"Compilation",
+	"compile-file",
+	"compile a single source file with the flags of its owning module, without linking",
+	&CompileFileCommand{
+		Rebuild: base.INHERITABLE_FALSE,
+	})
+
+func (x *CompileFileCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Environment", "select compilation environment (default: <LocalHostPlatform>-Debug)", &x.Environment)
+	cfv.Variable("Rebuild", "compile source file even if its object is up-to-date", &x.Rebuild)
+	action.GetActionFlags().Flags(cfv)
+}
+func (x *CompileFileCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("CompileFileCommand", "control single source file compilation", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("SourceFile", "path of the source file to compile", &x.SourceFile),
+	)
+	return nil
+}
+func (x *CompileFileCommand) Run(cc utils.CommandContext) error {
+	if !x.Environment.Valid() {
+		x.Environment = compile.EnvironmentAlias{
+			PlatformAlias:      compile.GetLocalHostPlatformAlias(),
+			ConfigurationAlias: compile.NewConfigurationAlias("Debug"),
+		}
+	}
+
+	sourceFile := x.SourceFile.Normalize()
+	if !sourceFile.Exists() {
+		return fmt.Errorf("compile-file: source file %q does not exist", sourceFile)
+	}
+
+	base.LogClaim(utils.LogCommand, "compile-file %q in <%v>...", sourceFile, x.Environment)
+
+	action.PrepareActionDiagnostics()
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "CompileFile"})
+	defer bg.Close()
+
+	targets, err := compile.FindOwningTargets(bg, x.Environment, sourceFile)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("compile-file: source file %q is not part of any module", sourceFile)
+	}
+
+	aliases := utils.BuildAliases{}
+	for _, target := range targets {
+		if _, err := compile.NeedTargetActions(bg.GlobalContext(), target); err != nil {
+			return err
+		}
+
+		sourceFileActions := compile.NeedSourceFileActions(target, sourceFile).Build(bg)
+		if err := sourceFileActions.Failure(); err != nil {
+			return err
+		}
+
+		base.LogVerbose(utils.LogCommand, "compile-file: selected <%v> from <%v>", sourceFileActions.Success().ActionAlias, target)
+		aliases.Append(sourceFileActions.Success().ActionAlias.Alias())
+	}
+
+	_, err = bg.BuildMany(aliases, utils.OptionBuildForceIf(x.Rebuild.Get()))
+	return err
+}
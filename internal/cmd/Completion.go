@@ -239,13 +239,42 @@ var ListCommands = newCompletionCommand(
 var ListPlatforms = newCompletionCommand(
 	"Metadata",
 	"list-platforms",
-	"list all available platforms",
+	"list all available platforms, with their base defines when detailed",
 	func(cc utils.CommandContext, ca *CompletionArgs) error {
+		if ca.Detailed.Get() {
+			return printPlatformDefinitions(ca)
+		}
+
 		bg := utils.CommandEnv.BuildGraph().OpenReadPort(base.ThreadPoolDebugId{Category: "ListPlatforms"})
 		defer bg.Close()
 		return printCompletion(ca, base.MakeStringerSet(compile.GetAllPlatformAliases(bg)...))
 	})
 
+func printPlatformDefinitions(ca *CompletionArgs) error {
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "ListPlatforms"})
+	defer bg.Close()
+
+	return openCompletion(ca, func(w io.Writer) error {
+		return filterCompletion(ca, func(name string) error {
+			platform, _ := compile.AllPlatforms.Get(name)
+			definitions, err := compile.GetPlatformDefinitions(bg.GlobalContext(), platform.GetPlatform())
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintf(w, "%v%s%v\n", base.ANSI_FG1_MAGENTA, name, base.ANSI_RESET); err != nil {
+				return err
+			}
+			for _, it := range definitions {
+				if _, err := fmt.Fprintf(w, "  %-20s %v%s%v\n", it.Define, base.ANSI_FAINT, it.Description, base.ANSI_RESET); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, compile.AllPlatforms.Keys()...)
+	})
+}
+
 var ListConfigs = newCompletionCommand(
 	"Metadata",
 	"list-configs",
@@ -254,13 +283,81 @@ var ListConfigs = newCompletionCommand(
 		return printCompletion(ca, base.MakeStringerSet(compile.GetAllConfigurationAliases()...))
 	})
 
-var ListCompilers = newCompletionCommand(
+type ListCompilersEntry struct {
+	Detector  string
+	Arch      compile.ArchType
+	Error     string                     `json:",omitempty"`
+	Detection *compile.CompilerDetection `json:",omitempty"`
+}
+
+type ListCompilersCommand struct {
+	Json utils.BoolVar
+}
+
+var ListCompilers = utils.NewCommandable(
 	"Metadata",
 	"list-compilers",
-	"list all available compilers",
-	func(cc utils.CommandContext, ca *CompletionArgs) error {
-		return printCompletion(ca, base.MakeStringerSet(compile.AllCompilerNames.Slice()...))
-	})
+	"list all compilers detected on this host for every architecture",
+	&ListCompilersCommand{})
+
+func (x *ListCompilersCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Json", "output detected compilers as json, instead of raw text", &x.Json)
+}
+func (x *ListCompilersCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("ListCompilersCommand", "control compiler detection output", x),
+		compile.OptionCommandAllCompilationFlags(),
+	)
+	return nil
+}
+func (x *ListCompilersCommand) Run(cc utils.CommandContext) error {
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "ListCompilers"})
+	defer bg.Close()
+
+	detectors := compile.AllCompilerDetectors.Keys()
+	sort.Strings(detectors)
+
+	var results []ListCompilersEntry
+	for _, name := range detectors {
+		detector, _ := compile.AllCompilerDetectors.Get(name)
+		for _, arch := range compile.GetArchTypes() {
+			entry := ListCompilersEntry{Detector: name, Arch: arch}
+			// missing toolchains are expected, detection errors are reported without failing the command
+			if detection, err := detector(bg.GlobalContext(), arch); err == nil {
+				entry.Detection = &detection
+			} else {
+				base.LogVerbose(utils.LogCommand, "list-compilers: %s-%v not detected: %v", name, arch, err)
+				entry.Error = err.Error()
+			}
+			results = append(results, entry)
+		}
+	}
+
+	if x.Json.Get() {
+		return base.JsonSerialize(results, base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+
+	f := base.NewStructuredFile(base.GetLogger(), base.STRUCTUREDFILE_DEFAULT_TAB, false)
+	f.Println("")
+	for _, it := range results {
+		if it.Detection == nil {
+			f.Println("%v%s-%v: not found%v", base.ANSI_FAINT, it.Detector, it.Arch, base.ANSI_RESET)
+			continue
+		}
+
+		f.Println("%v%s-%v%v: %v%v%v", base.ANSI_FG1_MAGENTA, it.Detector, it.Arch, base.ANSI_RESET,
+			base.ANSI_FG1_GREEN, it.Detection.CompilerAlias, base.ANSI_RESET)
+		f.ScopeIndent(func() {
+			f.Println("Version:  %s", it.Detection.Version)
+			f.Println("Path:     %v", it.Detection.Executable)
+			f.Println("Host:     %s", it.Detection.Host)
+			f.Println("Target:   %s", it.Detection.Target)
+			f.Println("Features: %v", it.Detection.Features)
+		})
+	}
+	f.Println("")
+	return nil
+}
 
 var ListModules = newCompletionCommand(
 	"Metadata",
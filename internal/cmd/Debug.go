@@ -535,3 +535,33 @@ var OutputFiles = newBuildAliasesCommand(
 		}
 		return nil
 	})
+
+var TopologicalLevels = newBuildAliasesCommand(
+	"Debug",
+	"topological-levels",
+	"print waves of nodes which can be built concurrently for input nodes",
+	func(cc utils.CommandContext, args *BuildAliasesArgs) error {
+		bg := utils.CommandEnv.BuildGraph().OpenReadPort(base.ThreadPoolDebugId{Category: "TopologicalLevels"})
+		defer bg.Close()
+
+		levels, err := bg.GetTopologicalLevels(args.Aliases...)
+		if err != nil {
+			return err
+		}
+
+		for i, level := range levels {
+			base.LogForwardf("[%03d] %d nodes", i, len(level))
+			// a wave with a single node is serializing the whole build
+			if len(level) == 1 {
+				base.LogForwardf(" -> %v", level[0])
+			}
+			base.LogForwardln()
+
+			if args.Detailed.Get() && len(level) > 1 {
+				for _, a := range level {
+					base.LogForwardln("\t", a.String())
+				}
+			}
+		}
+		return nil
+	})
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type DiffConfigCommand struct {
+	Other    utils.Filename
+	AllNodes utils.BoolVar
+}
+
+var CommandDiffConfig = utils.NewCommandable(
+	"Debug",
+	"diff-config",
+	"compare translated units with another persisted build graph, reporting changed compiler options, defines and toolchains per target",
+	&DiffConfigCommand{
+		AllNodes: base.INHERITABLE_FALSE,
+	})
+
+func (x *DiffConfigCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("AllNodes", "also report every build node whose stamp differs, not only translated units", &x.AllNodes)
+}
+func (x *DiffConfigCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("DiffConfigCommand", "control build graph comparison", x),
+		utils.OptionCommandConsumeArg("OtherDatabase", "persisted build graph to compare with, ex: a database saved from another branch", &x.Other),
+	)
+	return nil
+}
+func (x *DiffConfigCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "diff-config <%v>...", x.Other)
+
+	// other graph is loaded apart, and never saved back
+	other := utils.NewBuildGraph(utils.GetCommandFlags())
+	if err := utils.UFS.OpenBuffered(x.Other, func(r io.Reader) error {
+		return other.Load(r)
+	}); err != nil {
+		return fmt.Errorf("diff-config: could not load build graph from %q: %w", x.Other, err)
+	}
+
+	bgOld := other.OpenReadPort(base.ThreadPoolDebugId{Category: "DiffConfigOther"})
+	defer bgOld.Close()
+	bgNew := utils.CommandEnv.BuildGraph().OpenReadPort(base.ThreadPoolDebugId{Category: "DiffConfig"})
+	defer bgNew.Close()
+
+	oldUnits, err := collectUnitsForDiff(bgOld)
+	if err != nil {
+		return err
+	}
+	newUnits, err := collectUnitsForDiff(bgNew)
+	if err != nil {
+		return err
+	}
+
+	targets := base.NewSet[compile.TargetAlias]()
+	for target := range oldUnits {
+		targets.AppendUniq(target)
+	}
+	for target := range newUnits {
+		targets.AppendUniq(target)
+	}
+	targets.Sort(func(a, b compile.TargetAlias) bool {
+		return a.Compare(b) < 0
+	})
+
+	numChanged := 0
+	for _, target := range targets {
+		oldUnit, inOld := oldUnits[target]
+		newUnit, inNew := newUnits[target]
+		switch {
+		case !inNew:
+			base.LogForwardf("- %v: only in %q", target, x.Other)
+			numChanged++
+		case !inOld:
+			base.LogForwardf("+ %v: only in current build graph", target)
+			numChanged++
+		default:
+			diff := &unitConfigDiff{}
+			diff.Compare(bgOld, bgNew, oldUnit, newUnit)
+			if len(diff.Lines) > 0 {
+				base.LogForwardf("~ %v:", target)
+				for _, line := range diff.Lines {
+					base.LogForwardf("    %s", line)
+				}
+				numChanged++
+			}
+		}
+	}
+
+	if x.AllNodes.Get() {
+		numChanged += diffBuildNodeStamps(bgOld, bgNew)
+	}
+
+	base.LogForwardf("\nFound %d differences between %d targets", numChanged, len(targets))
+	return nil
+}
+
+func collectUnitsForDiff(bg utils.BuildGraphReadPort) (map[compile.TargetAlias]*compile.Unit, error) {
+	result := make(map[compile.TargetAlias]*compile.Unit)
+	return result, utils.ForeachBuildable(bg, func(_ utils.BuildAlias, unit *compile.Unit) error {
+		result[unit.TargetAlias] = unit
+		return nil
+	})
+}
+
+// diffBuildNodeStamps reports nodes whose content stamp changed, which covers every buildable and not only units
+func diffBuildNodeStamps(bgOld, bgNew utils.BuildGraphReadPort) (numChanged int) {
+	oldAliases := bgOld.Aliases()
+	newAliases := bgNew.Aliases()
+
+	for _, alias := range oldAliases {
+		oldNode, _ := bgOld.Expect(alias)
+		if newNode, err := bgNew.Expect(alias); err != nil {
+			base.LogForwardf("- %v", alias)
+			numChanged++
+		} else if oldNode.GetBuildStamp().Content != newNode.GetBuildStamp().Content {
+			base.LogForwardf("~ %v: %v -> %v", alias, oldNode.GetBuildStamp(), newNode.GetBuildStamp())
+			numChanged++
+		}
+	}
+	for _, alias := range newAliases {
+		if _, err := bgOld.Expect(alias); err != nil {
+			base.LogForwardf("+ %v", alias)
+			numChanged++
+		}
+	}
+	return
+}
+
+/***************************************
+ * Unit Config Diff
+ ***************************************/
+
+type unitConfigDiff struct {
+	Lines []string
+}
+
+func (x *unitConfigDiff) Printf(format string, args ...any) {
+	x.Lines = append(x.Lines, fmt.Sprintf(format, args...))
+}
+
+func (x *unitConfigDiff) Compare(bgOld, bgNew utils.BuildGraphReadPort, oldUnit, newUnit *compile.Unit) {
+	if oldUnit.Payload != newUnit.Payload {
+		x.Printf("payload: %v -> %v", oldUnit.Payload, newUnit.Payload)
+	}
+	if !oldUnit.OutputFile.Equals(newUnit.OutputFile) {
+		x.Printf("output: %q -> %q", oldUnit.OutputFile, newUnit.OutputFile)
+	}
+	if err := base.SerializableDiff(&oldUnit.CppRules, &newUnit.CppRules); err != nil {
+		x.Printf("cpp rules: %v", err)
+	}
+
+	x.CompareToolchain(bgOld, bgNew, oldUnit, newUnit)
+
+	x.CompareStrings("defines", oldUnit.Defines, newUnit.Defines)
+	x.CompareStrings("compiler options", oldUnit.CompilerOptions, newUnit.CompilerOptions)
+	x.CompareStrings("preprocessor options", oldUnit.PreprocessorOptions, newUnit.PreprocessorOptions)
+	x.CompareStrings("precompiled header options", oldUnit.PrecompiledHeaderOptions, newUnit.PrecompiledHeaderOptions)
+	x.CompareStrings("librarian options", oldUnit.LibrarianOptions, newUnit.LibrarianOptions)
+	x.CompareStrings("linker options", oldUnit.LinkerOptions, newUnit.LinkerOptions)
+	x.CompareStrings("libraries", oldUnit.Libraries, newUnit.Libraries)
+	x.CompareStrings("frameworks", oldUnit.Frameworks, newUnit.Frameworks)
+	x.CompareStrings("environment", oldUnit.Environment.Export(), newUnit.Environment.Export())
+	x.CompareStrings("include paths", oldUnit.IncludePaths.StringSet(), newUnit.IncludePaths.StringSet())
+}
+
+func (x *unitConfigDiff) CompareToolchain(bgOld, bgNew utils.BuildGraphReadPort, oldUnit, newUnit *compile.Unit) {
+	if oldUnit.CompilerAlias != newUnit.CompilerAlias {
+		x.Printf("compiler: %v -> %v", oldUnit.CompilerAlias, newUnit.CompilerAlias)
+		return
+	}
+
+	oldCompiler, errOld := utils.FindBuildable[compile.Compiler](bgOld, oldUnit.CompilerAlias.Alias())
+	newCompiler, errNew := utils.FindBuildable[compile.Compiler](bgNew, newUnit.CompilerAlias.Alias())
+	if errOld != nil || errNew != nil {
+		return // compiler was not persisted in one of the graphs, options compared below are still relevant
+	}
+
+	oldRules, newRules := oldCompiler.GetCompiler(), newCompiler.GetCompiler()
+	if !oldRules.Executable.Equals(newRules.Executable) {
+		x.Printf("compiler executable: %q -> %q", oldRules.Executable, newRules.Executable)
+	}
+	if !oldRules.Linker.Equals(newRules.Linker) {
+		x.Printf("linker executable: %q -> %q", oldRules.Linker, newRules.Linker)
+	}
+	if oldRules.CppStd != newRules.CppStd {
+		x.Printf("compiler max C++ standard: %v -> %v", oldRules.CppStd, newRules.CppStd)
+	}
+	if err := base.SerializableDiff(oldCompiler, newCompiler); err != nil {
+		x.Printf("toolchain: %v", err)
+	}
+}
+
+func (x *unitConfigDiff) CompareStrings(name string, oldSet, newSet base.StringSet) {
+	var removed, added []string
+	for _, it := range oldSet {
+		if !newSet.Contains(it) {
+			removed = append(removed, it)
+		}
+	}
+	for _, it := range newSet {
+		if !oldSet.Contains(it) {
+			added = append(added, it)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	for _, it := range removed {
+		x.Printf("%s: - %s", name, it)
+	}
+	for _, it := range added {
+		x.Printf("%s: + %s", name, it)
+	}
+}
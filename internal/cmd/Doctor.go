@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type doctorCheck struct {
+	Category string
+	Name     string
+	Err      error
+}
+
+type DoctorCommand struct {
+	MinDiskSpace base.SizeInBytes
+	checks       []doctorCheck
+}
+
+var CommandDoctor = utils.NewCommandable(
+	"Configure",
+	"doctor",
+	"check toolchain environment is healthy before building, exit with an error if any check failed",
+	&DoctorCommand{
+		MinDiskSpace: 10 * 1024 * 1024 * 1024, // 10 GiB
+	})
+
+func (x *DoctorCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("MinDiskSpace", "minimum free disk space required in output directory", &x.MinDiskSpace)
+}
+func (x *DoctorCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("DoctorCommand", "control toolchain environment checks", x),
+		compile.OptionCommandAllCompilationFlags(),
+	)
+	return nil
+}
+func (x *DoctorCommand) Run(cc utils.CommandContext) error {
+	base.LogClaim(utils.LogCommand, "checking toolchain environment...")
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Doctor"})
+	defer bg.Close()
+
+	x.checkDirectories()
+	if err := x.checkCompilers(bg); err != nil {
+		return err
+	}
+
+	return x.printChecklist()
+}
+
+func (x *DoctorCommand) check(category, name string, err error) {
+	x.checks = append(x.checks, doctorCheck{Category: category, Name: name, Err: err})
+}
+
+func (x *DoctorCommand) checkDirectories() {
+	for _, dir := range []utils.Directory{utils.UFS.Output, utils.UFS.Intermediate, utils.UFS.Transient} {
+		x.check("Directories", fmt.Sprintf("%q is writable", dir), checkDirectoryWritable(dir))
+	}
+
+	freeSpace, err := utils.GetDiskFreeSpace(utils.UFS.Output)
+	if err == nil && base.SizeInBytes(freeSpace) < x.MinDiskSpace {
+		err = fmt.Errorf("only %v available, need at least %v", base.SizeInBytes(freeSpace), x.MinDiskSpace)
+	}
+	x.check("Directories", fmt.Sprintf("%q has enough free disk space", utils.UFS.Output), err)
+}
+
+func (x *DoctorCommand) checkCompilers(bg utils.BuildGraphWritePort) error {
+	var compilers []*compile.CompilerRules
+	visiteds := make(map[compile.CompilerAlias]bool)
+
+	if err := compile.ForeachEnvironmentAlias(func(ea compile.EnvironmentAlias) error {
+		env, err := compile.GetCompileEnvironment(ea).Need(bg.GlobalContext())
+		if err == nil {
+			var compiler compile.Compiler
+			if compiler, err = env.GetBuildCompiler(bg); err == nil {
+				rules := compiler.GetCompiler()
+				if _, ok := visiteds[rules.CompilerAlias]; !ok {
+					visiteds[rules.CompilerAlias] = true
+					compilers = append(compilers, rules)
+				}
+			}
+		}
+		x.check("Compilers", fmt.Sprintf("compiler detected for <%v>", ea), err)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, rules := range compilers {
+		x.checkCompiler(bg, rules)
+	}
+	return nil
+}
+
+func (x *DoctorCommand) checkCompiler(bg utils.BuildGraphWritePort, rules *compile.CompilerRules) {
+	category := fmt.Sprint("Compiler ", rules.CompilerAlias)
+
+	for _, exe := range []utils.Filename{rules.Executable, rules.Linker, rules.Librarian, rules.Preprocessor} {
+		if exe.Valid() {
+			version, err := checkExecutable(exe)
+			if err == nil {
+				x.check(category, fmt.Sprintf("%q is runnable: %s", exe, version), nil)
+			} else {
+				x.check(category, fmt.Sprintf("%q is runnable", exe), err)
+			}
+		}
+	}
+
+	if len(rules.ExtraFiles) > 0 {
+		x.check(category, fmt.Sprintf("%d toolchain files exist", len(rules.ExtraFiles)), checkFilesExist(rules.ExtraFiles...))
+	}
+
+	// compiler dependencies are expected to carry SDKs (ex: Windows SDK), check their include paths exist
+	node, err := bg.Expect(rules.Alias())
+	if err != nil {
+		x.check(category, "compiler node found in build graph", err)
+		return
+	}
+
+	for _, alias := range append(node.GetStaticDependencies(), node.GetDynamicDependencies()...) {
+		dep, err := bg.Expect(alias)
+		if err != nil {
+			x.check(category, fmt.Sprintf("dependency <%v> found in build graph", alias), err)
+			continue
+		}
+
+		if facetable, ok := dep.GetBuildable().(compile.Facetable); ok {
+			facet := facetable.GetFacet()
+			if len(facet.SystemIncludePaths) > 0 {
+				x.check(category, fmt.Sprintf("<%v> include paths exist", alias), checkDirectoriesExist(facet.SystemIncludePaths...))
+			}
+		}
+	}
+}
+
+func (x *DoctorCommand) printChecklist() error {
+	f := base.NewStructuredFile(base.GetLogger(), base.STRUCTUREDFILE_DEFAULT_TAB, false)
+
+	numFailed := 0
+	lastCategory := ""
+
+	f.Println("")
+	f.BeginIndent()
+	for _, it := range x.checks {
+		if lastCategory != it.Category {
+			lastCategory = it.Category
+			f.EndIndent()
+			f.Println("%v%v%s%v", base.ANSI_FG1_MAGENTA, base.ANSI_FAINT, it.Category, base.ANSI_RESET)
+			f.BeginIndent()
+		}
+
+		if it.Err == nil {
+			f.Println("%v[PASS]%v %s", base.ANSI_FG1_GREEN, base.ANSI_RESET, it.Name)
+		} else {
+			numFailed++
+			f.Println("%v[FAIL]%v %s", base.ANSI_FG1_RED, base.ANSI_RESET, it.Name)
+			f.ScopeIndent(func() {
+				f.Println("%v%v%v", base.ANSI_FG0_RED, it.Err, base.ANSI_RESET)
+			})
+		}
+	}
+	f.EndIndent()
+	f.Println("")
+
+	if numFailed > 0 {
+		return fmt.Errorf("doctor: %d/%d checks failed", numFailed, len(x.checks))
+	}
+	base.LogInfo(utils.LogCommand, "doctor: all %d checks passed", len(x.checks))
+	return nil
+}
+
+func checkDirectoryWritable(dir utils.Directory) error {
+	if err := utils.UFS.MkdirEx(dir); err != nil {
+		return err
+	}
+
+	probe := dir.File(".ppb-doctor")
+	if err := utils.UFS.Create(probe, func(io.Writer) error { return nil }); err != nil {
+		return err
+	}
+	return utils.UFS.Remove(probe)
+}
+
+// checkExecutable runs a version probe, since a broken install can still have its executables on disk
+func checkExecutable(exe utils.Filename) (string, error) {
+	info, err := exe.Info()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%q is a directory", exe)
+	}
+	// windows does not rely on permissions to run executables
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%q is not executable (mode: %v)", exe, info.Mode())
+	}
+
+	// msvc tools (and llvm-lib which mimics lib.exe) don't know --version, but print their banner with usage
+	versionArg := "--version"
+	switch strings.ToLower(exe.Basename) {
+	case "cl.exe", "link.exe", "lib.exe", "rc.exe", "llvm-lib.exe":
+		versionArg = "/?"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorProbeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, exe.String(), versionArg).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", fmt.Errorf("%q %s failed: %v\n%s", exe, versionArg, err, strings.TrimSpace(string(output)))
+	}
+
+	// first line not empty is expected to contain the version
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("%q %s did not output a version", exe, versionArg)
+}
+
+const doctorProbeTimeout = 30 * time.Second
+
+func checkFilesExist(files ...utils.Filename) error {
+	var missings []string
+	for _, it := range files {
+		if _, err := it.Info(); err != nil {
+			missings = append(missings, it.String())
+		}
+	}
+	if len(missings) > 0 {
+		return fmt.Errorf("missing files:\n\t%s", strings.Join(missings, "\n\t"))
+	}
+	return nil
+}
+
+func checkDirectoriesExist(dirs ...utils.Directory) error {
+	var missings []string
+	for _, it := range dirs {
+		if info, err := os.Stat(it.String()); err != nil || !info.IsDir() {
+			missings = append(missings, it.String())
+		}
+	}
+	if len(missings) > 0 {
+		return fmt.Errorf("missing directories:\n\t%s", strings.Join(missings, "\n\t"))
+	}
+	return nil
+}
@@ -179,6 +179,33 @@ var ExportUnit = newJsonExportCommand(
 		return nil
 	})
 
+var ExportFacet = newJsonExportCommand(
+	"Export",
+	"export-facet",
+	"export fully merged facet of translated unit to json",
+	func(cc utils.CommandContext, args *ExportNodeArgs[compile.TargetAlias, *compile.TargetAlias], yield jsonExportYieldFunc) error {
+		bg := utils.CommandEnv.BuildGraph().OpenReadPort(base.ThreadPoolDebugId{Category: "ExportFacet"})
+		defer bg.Close()
+
+		for _, a := range args.Aliases {
+			unit, err := compile.FindBuildUnit(bg, a)
+			if err == nil {
+				// facet fields are inlined next to target alias, so outputs of two configurations can be diffed directly
+				err = yield(struct {
+					Target string
+					*compile.Facet
+				}{
+					Target: a.String(),
+					Facet:  &unit.Facet,
+				})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
 var ExportNode = newJsonExportCommand(
 	"Export",
 	"export-node",
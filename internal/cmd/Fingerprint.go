@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type FingerprintCommand struct {
+	Target compile.TargetAlias
+	Source utils.Filename
+	Json   utils.BoolVar
+}
+
+var CommandFingerprint = utils.NewCommandable(
+	"Debug",
+	"fingerprint",
+	"print inputs hashed in build stamp and action cache key of a target action, to diff them after a cache miss",
+	&FingerprintCommand{
+		Json: base.INHERITABLE_FALSE,
+	})
+
+func (x *FingerprintCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Source", "select the action compiling this source file (default: actions producing target output)", &x.Source)
+	cfv.Variable("Json", "output fingerprints as json, instead of raw text", &x.Json)
+}
+func (x *FingerprintCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("FingerprintCommand", "control fingerprint inputs output", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "target owning the action to inspect", &x.Target),
+	)
+	return nil
+}
+func (x *FingerprintCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "fingerprint <%v>...", x.Target)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Fingerprint"})
+	defer bg.Close()
+
+	targets, err := compile.NeedTargetActions(bg.GlobalContext(), x.Target)
+	if err != nil {
+		return err
+	}
+
+	var actions []*action.ActionRules
+	if x.Source.Valid() {
+		source := x.Source.Normalize()
+		if err := compile.ForeachTranslationUnit(bg, func(_ compile.TargetAlias, input utils.Filename, rules *action.ActionRules) error {
+			if input.Equals(source) {
+				actions = append(actions, rules)
+			}
+			return nil
+		}, targets...); err != nil {
+			return err
+		}
+		if len(actions) == 0 {
+			return fmt.Errorf("fingerprint: <%v> does not compile %q", x.Target, source)
+		}
+	} else {
+		outputs, err := targets[0].GetOutputActions(bg)
+		if err != nil {
+			return err
+		}
+		for _, it := range outputs {
+			actions = append(actions, it.GetAction())
+		}
+	}
+
+	// compiler build stamp is inherited by all actions of the target, it changes when the toolchain was updated
+	unit, err := compile.FindBuildUnit(bg, x.Target)
+	if err != nil {
+		return err
+	}
+	compiler, err := bg.Expect(unit.CompilerAlias.Alias())
+	if err != nil {
+		return err
+	}
+
+	results := make([]actionFingerprint, len(actions))
+	for i, rules := range actions {
+		results[i].Compiler = unit.CompilerAlias
+		results[i].CompilerFingerprint = compiler.GetBuildStamp().Content
+		if err := results[i].Compute(bg, rules); err != nil {
+			return err
+		}
+	}
+
+	if x.Json.Get() {
+		return base.JsonSerialize(results, base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+	for _, it := range results {
+		it.Print()
+	}
+	return nil
+}
+
+/***************************************
+ * Action Fingerprint
+ ***************************************/
+
+type actionFingerprint struct {
+	Action utils.BuildAlias
+	// stored build stamp, compared with a fingerprint computed now from the action in build graph
+	BuildStamp  utils.BuildStamp
+	Fingerprint base.Fingerprint
+	CacheKey    action.ActionCacheKeyInputs
+	// compiler used by the target owning the action
+	Compiler            compile.CompilerAlias
+	CompilerFingerprint base.Fingerprint
+}
+
+func (x *actionFingerprint) Compute(bg utils.BuildGraphWritePort, rules *action.ActionRules) (err error) {
+	x.Action = rules.Alias()
+
+	node, err := bg.Expect(x.Action)
+	if err != nil {
+		return
+	}
+	x.BuildStamp = node.GetBuildStamp()
+	x.Fingerprint = utils.MakeBuildFingerprint(node.GetBuildable())
+
+	x.CacheKey, err = rules.GetCacheKeyInputs(bg)
+	return
+}
+
+func (x *actionFingerprint) Print() {
+	base.LogForwardf("%v%v%v", base.ANSI_UNDERLINE, x.Action, base.ANSI_RESET)
+	base.LogForwardf("  build stamp:  %v", x.BuildStamp.Content)
+	if x.Fingerprint == x.BuildStamp.Content {
+		base.LogForwardf("  fingerprint:  %v", x.Fingerprint)
+	} else {
+		base.LogForwardf("  fingerprint:  %v%v (outdated)%v", base.ANSI_FG1_YELLOW, x.Fingerprint, base.ANSI_RESET)
+	}
+	base.LogForwardf("  compiler:     %v  %v", x.CompilerFingerprint, x.Compiler)
+	base.LogForwardf("  cache key:    %v", x.CacheKey.Key)
+	base.LogForwardf("  cache seed:   %v", x.CacheKey.Seed)
+	base.LogForwardf("  executable:   %v", x.CacheKey.Executable)
+	base.LogForwardf("  working dir:  %v", x.CacheKey.WorkingDir)
+
+	base.LogForwardf("  arguments:")
+	for _, it := range x.CacheKey.Arguments {
+		base.LogForwardf("    %v", it)
+	}
+	base.LogForwardf("  environment:  (sanitized: %v)", x.CacheKey.SanitizeEnv)
+	for _, it := range x.CacheKey.Environment {
+		base.LogForwardf("    %v", it.String())
+	}
+	base.LogForwardf("  inputs:")
+	for i, it := range x.CacheKey.InputFiles {
+		base.LogForwardf("    %v  %v", x.CacheKey.Digests[i], it)
+	}
+	base.LogForwardf("  outputs:")
+	for _, it := range x.CacheKey.OutputFiles {
+		base.LogForwardf("    %v", it)
+	}
+}
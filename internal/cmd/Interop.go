@@ -74,6 +74,8 @@ func (x *ImportActionsCommand) Init(ci utils.CommandContext) error {
 func (x *ImportActionsCommand) Run(cc utils.CommandContext) error {
 	base.LogClaim(utils.LogCommand, "import <%v>...", base.JoinString(">, <", x.InputFiles...))
 
+	action.PrepareActionDiagnostics()
+
 	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "ImportActions"})
 	defer bg.Close()
 
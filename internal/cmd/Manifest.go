@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"io"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Build Manifest
+ ***************************************/
+
+// Build manifest enumerates every artifact produced by selected targets, it's meant to be consumed
+// by packaging tools (installers, SBOM...). Content is derived from output file nodes of the build
+// graph and only depends on build outputs, so identical inputs will give an identical manifest.
+
+type BuildManifestFile struct {
+	Path        utils.Filename
+	Kind        string
+	Size        int64
+	Fingerprint base.Fingerprint
+}
+
+type BuildManifestTarget struct {
+	Target  string
+	Payload compile.PayloadType
+	Files   []BuildManifestFile
+}
+
+type BuildManifest struct {
+	Targets []BuildManifestTarget
+}
+
+func NewBuildManifest(bg utils.BuildGraphReadPort, targets ...*compile.TargetActions) (*BuildManifest, error) {
+	manifest := &BuildManifest{
+		Targets: make([]BuildManifestTarget, 0, len(targets)),
+	}
+
+	for _, ta := range targets {
+		target, err := newBuildManifestTarget(bg, ta)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Targets = append(manifest.Targets, target)
+	}
+
+	sort.Slice(manifest.Targets, func(i, j int) bool {
+		return manifest.Targets[i].Target < manifest.Targets[j].Target
+	})
+	return manifest, nil
+}
+
+func (x *BuildManifest) Save(dst utils.Filename) error {
+	base.LogInfo(utils.LogCommand, "write build manifest with %d targets to %q", len(x.Targets), dst)
+	return utils.UFS.CreateBuffered(dst, func(w io.Writer) error {
+		return base.JsonSerialize(x, w, base.OptionJsonPrettyPrint(true))
+	}, base.TransientPage4KiB)
+}
+
+func newBuildManifestTarget(bg utils.BuildGraphReadPort, ta *compile.TargetActions) (BuildManifestTarget, error) {
+	result := BuildManifestTarget{
+		Target:  ta.TargetAlias.String(),
+		Payload: ta.OutputType,
+	}
+
+	unit, err := compile.FindBuildUnit(bg, ta.TargetAlias)
+	if err != nil {
+		return result, err
+	}
+
+	actions, err := ta.GetOutputActions(bg)
+	if err != nil {
+		return result, err
+	}
+
+	// output files are tracked as output dependencies of action nodes, after they were built
+	visiteds := make(map[utils.BuildAlias]bool)
+	for _, it := range actions {
+		node, err := bg.Expect(it.Alias())
+		if err != nil {
+			return result, err
+		}
+
+		for _, alias := range node.GetOutputDependencies() {
+			if _, ok := visiteds[alias]; ok {
+				continue
+			}
+			visiteds[alias] = true
+
+			file, err := utils.FindBuildable[*utils.FileDependency](bg, alias)
+			if err != nil {
+				continue // not a file, ignored
+			}
+
+			entry, err := newBuildManifestFile(unit, file)
+			if err != nil {
+				return result, err
+			}
+			result.Files = append(result.Files, entry)
+		}
+	}
+
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path.Compare(result.Files[j].Path) < 0
+	})
+	return result, nil
+}
+
+func newBuildManifestFile(unit *compile.Unit, file *utils.FileDependency) (BuildManifestFile, error) {
+	result := BuildManifestFile{
+		Path:        file.Filename,
+		Size:        file.Size,
+		Fingerprint: file.Digest,
+	}
+
+	switch {
+	case file.Filename.Equals(unit.OutputFile):
+		result.Kind = "Output"
+	case unit.SymbolsFile.Valid() && file.Filename.Equals(unit.SymbolsFile):
+		result.Kind = "Symbols"
+	default:
+		result.Kind = "Extra"
+	}
+
+	// digest is only computed by the build graph when content hash mode is enabled
+	if !result.Fingerprint.Valid() {
+		var err error
+		if result.Fingerprint, err = utils.UFS.Fingerprint(file.Filename, base.Fingerprint{}); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
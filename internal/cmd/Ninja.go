@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/internal/hal/generic"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type NinjaCommand struct {
+	Targets []compile.TargetAlias
+	Output  utils.Filename
+}
+
+var CommandNinja = utils.NewCommandable(
+	"Configure",
+	"ninja",
+	"generate a Ninja build file running compilation actions of selected targets",
+	&NinjaCommand{
+		Output: utils.UFS.Root.File("build.ninja"),
+	})
+
+func (x *NinjaCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Output", "override path of generated Ninja build file, paths inside are relative to root where ninja should run", &x.Output)
+}
+func (x *NinjaCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("NinjaCommand", "control Ninja build file generation", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeMany("TargetAlias", "export actions of all targets specified as argument", &x.Targets),
+	)
+	return nil
+}
+func (x *NinjaCommand) Run(cc utils.CommandContext) error {
+	base.LogClaim(utils.LogCommand, "generating Ninja build file in %q for <%v>", x.Output, base.JoinString(">, <", x.Targets...))
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Ninja"})
+	defer bg.Close()
+
+	targetActions, err := compile.NeedTargetActions(bg.GlobalContext(), x.Targets...)
+	if err != nil {
+		return err
+	}
+
+	aliases := action.ActionAliases{}
+	for _, ta := range targetActions {
+		if err := ta.ForeachPayload(bg, func(tp *compile.TargetPayload) error {
+			aliases.Append(tp.ActionAliases...)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	actions, err := action.GetBuildActions(bg, aliases...)
+	if err != nil {
+		return err
+	}
+	if actions, err = actions.ExpandDependencies(bg); err != nil {
+		return err
+	}
+
+	base.LogVerbose(utils.LogCommand, "ninja: export %d actions from %d targets", len(actions), len(targetActions))
+
+	return utils.UFS.CreateBuffered(x.Output, func(w io.Writer) error {
+		if _, err := fmt.Fprintf(w, "# generated by ppb for <%v>, do not edit\n", base.JoinString(">, <", x.Targets...)); err != nil {
+			return err
+		}
+		// action environments are not exported, ninja should run from a shell where compilers are available
+		// depfiles are written by the compiler and shared with ppb, so they are not consumed with deps = gcc, which would delete them
+		if _, err := fmt.Fprint(w, "ninja_required_version = 1.3\n"+
+			"\nrule ppb_action\n  command = $command\n  description = $description\n"+
+			"\nrule ppb_action_depfile\n  command = $command\n  description = $description\n  depfile = $depfile\n"+
+			"\nrule ppb_action_msvc\n  command = $command\n  description = $description\n  deps = msvc\n"); err != nil {
+			return err
+		}
+		for _, it := range actions {
+			if err := writeNinjaBuildStatement(w, bg, it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, base.TransientPage4KiB)
+}
+
+func writeNinjaBuildStatement(w io.Writer, bg utils.BuildGraphReadPort, it action.Action) error {
+	rules := it.GetAction()
+
+	var dependentActions action.ActionSet
+	if err := rules.AppendDependentActions(bg, &dependentActions); err != nil {
+		return err
+	}
+
+	// header dependencies are only tracked when the command run by ninja outputs them itself
+	ruleName := "ppb_action"
+	command := rules.CommandRules
+	var depFile utils.Filename
+	if rules.Options.Has(action.OPT_ALLOW_SOURCEDEPENDENCIES) {
+		if gnu, ok := it.(*generic.GnuSourceDependenciesAction); ok {
+			// clang writes the depfile with -MF, see GnuSourceDependenciesAction
+			ruleName = "ppb_action_depfile"
+			depFile = gnu.GnuDepFile
+		} else if strings.EqualFold(command.Executable.Basename, "cl.exe") {
+			// ninja parses included headers from cl.exe output, which is filtered from console
+			ruleName = "ppb_action_msvc"
+			command.Arguments = base.NewStringSet(command.Arguments...)
+			command.Arguments.Append("/showIncludes")
+		}
+	}
+	outputFiles := base.Map(makeNinjaPath, rules.OutputFiles...)
+
+	// outputs of dependent actions are implicit inputs, so ninja respects the order of ppb actions
+	inputFiles := strings.Join(base.Map(makeNinjaPath, rules.GetStaticInputFiles(bg)...), " ")
+	if len(dependentActions) > 0 {
+		inputFiles += " | " + strings.Join(base.Map(makeNinjaPath, dependentActions.GetExportFiles()...), " ")
+	}
+
+	_, err := fmt.Fprintf(w, "\nbuild %s: %s %s\n  command = %s\n  description = %s\n",
+		strings.Join(outputFiles, " "),
+		ruleName,
+		inputFiles,
+		escapeNinjaString(makeNinjaCommand(&command)),
+		escapeNinjaString(rules.Alias().String()))
+	if err == nil && depFile.Valid() {
+		_, err = fmt.Fprintf(w, "  depfile = %s\n", makeNinjaPath(depFile))
+	}
+	return err
+}
+
+func makeNinjaCommand(command *action.CommandRules) string {
+	args := make([]string, 0, len(command.Arguments)+1)
+	args = append(args, base.EscapeCommandLineArg(command.Executable.String()))
+	for _, it := range command.Arguments {
+		args = append(args, base.EscapeCommandLineArg(it))
+	}
+
+	// ninja runs commands from the directory of build file, which is root by default
+	cmdline := strings.Join(args, " ")
+	if command.WorkingDir.Valid() && !command.WorkingDir.Equals(utils.UFS.Root) {
+		workingDir := base.EscapeCommandLineArg(command.WorkingDir.String())
+		if runtime.GOOS == "windows" {
+			// ninja does not spawn a shell on Windows
+			cmdline = fmt.Sprintf("cmd /c cd /d %s && %s", workingDir, cmdline)
+		} else {
+			cmdline = fmt.Sprintf("cd %s && %s", workingDir, cmdline)
+		}
+	}
+	return cmdline
+}
+
+// paths are written relative to root, like in GNU Make dependency files written by ppb
+func makeNinjaPath(file utils.Filename) string {
+	path := file.String()
+	if file.IsIn(utils.UFS.Root) {
+		path = utils.ForceLocalFilename(file)
+	}
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(path)
+}
+func escapeNinjaString(in string) string {
+	return strings.NewReplacer("$", "$$", "\n", "$\n").Replace(in)
+}
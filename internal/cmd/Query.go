@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Unit Property Name
+ ***************************************/
+
+// UnitPropertyName selects an exported field of compile.Unit, fields promoted from embedded
+// structures (CppRules, Facet...) are accessed directly with their own name (ex: CppStd).
+
+type UnitPropertyName string
+
+func getUnitProperties() (results map[string][]int) {
+	results = make(map[string][]int)
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(compile.Unit{})) {
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		if _, ok := results[field.Name]; !ok { // shallowest field wins, like Go selectors
+			results[field.Name] = field.Index
+		}
+	}
+	return
+}
+
+func (x UnitPropertyName) String() string {
+	return string(x)
+}
+func (x *UnitPropertyName) Set(in string) error {
+	for name := range getUnitProperties() {
+		if strings.EqualFold(name, in) {
+			*x = UnitPropertyName(name)
+			return nil
+		}
+	}
+	return fmt.Errorf("query: unknown unit property %q", in)
+}
+func (x *UnitPropertyName) Serialize(ar base.Archive) {
+	ar.String((*string)(x))
+}
+func (x UnitPropertyName) AutoComplete(in base.AutoComplete) {
+	for name, index := range getUnitProperties() {
+		in.Add(name, reflect.TypeOf(compile.Unit{}).FieldByIndex(index).Type.String())
+	}
+}
+func (x UnitPropertyName) GetValue(unit *compile.Unit) (any, error) {
+	index, ok := getUnitProperties()[x.String()]
+	if !ok {
+		return nil, fmt.Errorf("query: unknown unit property %q", x)
+	}
+	return reflect.ValueOf(unit).Elem().FieldByIndex(index).Interface(), nil
+}
+
+/***************************************
+ * Query Command
+ ***************************************/
+
+type QueryCommand struct {
+	Target   compile.TargetAlias
+	Property UnitPropertyName
+	Json     utils.BoolVar
+}
+
+var CommandQuery = utils.NewCommandable(
+	"Compilation",
+	"query",
+	"print resolved value of a unit property, ex: query Win64-Debug-Runtime/Core CppStd",
+	&QueryCommand{})
+
+func (x *QueryCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Json", "output property value as json, instead of raw text", &x.Json)
+}
+func (x *QueryCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("QueryCommand", "control unit property query", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "target whose unit should be queried", &x.Target),
+		utils.OptionCommandConsumeArg("Property", "name of the queried unit property", &x.Property),
+	)
+	return nil
+}
+func (x *QueryCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "query <%v> %v...", x.Target, x.Property)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Query"})
+	defer bg.Close()
+
+	// make sure selected unit is generated
+	if _, future := bg.Build(&x.Target); future.Join().Failure() != nil {
+		return future.Join().Failure()
+	}
+
+	unit, err := compile.FindBuildUnit(bg, x.Target)
+	if err != nil {
+		return err
+	}
+
+	value, err := x.Property.GetValue(unit)
+	if err != nil {
+		return err
+	}
+
+	if x.Json.Get() {
+		return base.JsonSerialize(value, base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+
+	base.LogForwardln(strings.Join(formatQueryValue(value), "\n"))
+	return nil
+}
+
+// formatQueryValue prints one line per element for containers, so output can be consumed by scripts
+func formatQueryValue(value any) []string {
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		lines := make([]string, rv.Len())
+		for i := range lines {
+			lines[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		return lines
+	case reflect.Map:
+		lines := make([]string, 0, rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			lines = append(lines, fmt.Sprint(it.Key().Interface(), "=", it.Value().Interface()))
+		}
+		sort.Strings(lines)
+		return lines
+	default:
+		return []string{fmt.Sprint(value)}
+	}
+}
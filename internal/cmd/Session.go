@@ -0,0 +1,433 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Build Session
+ ***************************************/
+
+// Build session bundles what is needed to understand a build without the original tree: command-line,
+// host, toolchains with their version, input files with their digest, persistent config, resolved build
+// graph and outcome of every node executed. It is recorded with -RecordSession to be attached to bug
+// reports, then examined read-only with session-inspect.
+
+const (
+	BUILDSESSION_INDEX  = "session.json"
+	BUILDSESSION_CONFIG = "config.json"
+	BUILDSESSION_GRAPH  = "graph.db"
+)
+
+type BuildSessionToolchain struct {
+	Compiler   string
+	Version    string `json:",omitempty"`
+	Executable utils.Filename
+	Linker     utils.Filename
+	CppStd     compile.CppStdType
+}
+
+type BuildSessionInput struct {
+	Filename utils.Filename
+	Size     int64
+	Digest   base.Fingerprint
+}
+
+type BuildSessionOutcome struct {
+	Port     string
+	Alias    string
+	Status   string
+	Error    string `json:",omitempty"`
+	Duration time.Duration
+}
+
+type BuildSession struct {
+	Version     string
+	Host        string
+	OS          string
+	Arch        string
+	CommandLine []string
+	RootDir     utils.Directory
+	StartedAt   time.Time
+	Duration    time.Duration
+	Toolchains  []BuildSessionToolchain
+	Inputs      []BuildSessionInput
+	Outcomes    []BuildSessionOutcome
+}
+
+func (x *BuildSession) NumFailed() (n int) {
+	for _, it := range x.Outcomes {
+		if len(it.Error) > 0 {
+			n++
+		}
+	}
+	return
+}
+
+/***************************************
+ * Build Session Flags
+ ***************************************/
+
+type BuildSessionFlags struct {
+	RecordSession utils.Filename
+}
+
+var GetBuildSessionFlags = func() func() *BuildSessionFlags {
+	flags := &BuildSessionFlags{}
+	return utils.NewGlobalCommandParsableFlags(
+		"build session recording options",
+		flags,
+		utils.OptionCommandPrepare(func(cc utils.CommandContext) error {
+			if !flags.RecordSession.Valid() {
+				return nil
+			}
+
+			base.LogClaim(utils.LogCommand, "start build session recording in %q", flags.RecordSession)
+
+			utils.CommandEnv.OnBuildGraphLoaded(func(bg utils.BuildGraph) error {
+				recorder := &buildSessionRecorder{}
+
+				bg.OnBuildGraphFinished(func(port utils.BuildGraphWritePort) error {
+					recorder.RecordPort(port)
+					return nil
+				})
+
+				utils.CommandEnv.OnExit(func(env *utils.CommandEnvT) error {
+					return recorder.Save(bg, flags.RecordSession)
+				})
+				return nil
+			})
+
+			return nil
+		}))
+}()
+
+func (flags *BuildSessionFlags) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("RecordSession", "record command-line, toolchains, resolved build graph and node outcomes in a portable bundle, to examine later with session-inspect", &flags.RecordSession)
+}
+
+/***************************************
+ * Build Session Recorder
+ ***************************************/
+
+type buildSessionRecorder struct {
+	barrier  sync.Mutex
+	outcomes []BuildSessionOutcome
+}
+
+func (x *buildSessionRecorder) RecordPort(port utils.BuildGraphWritePort) {
+	reports := port.RecordNodeReports()
+
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	for _, it := range reports {
+		outcome := BuildSessionOutcome{
+			Port:     port.PortName().String(),
+			Alias:    it.Alias.String(),
+			Status:   it.Status.String(),
+			Duration: it.Stats.Duration.Exclusive,
+		}
+		if it.Error != nil {
+			outcome.Status = "FAILED"
+			outcome.Error = it.Error.Error()
+		}
+		x.outcomes = append(x.outcomes, outcome)
+	}
+}
+
+func (x *buildSessionRecorder) Save(bg utils.BuildGraph, dst utils.Filename) error {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	session := BuildSession{
+		Version:     utils.GetProcessInfo().Version,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		CommandLine: os.Args,
+		RootDir:     utils.UFS.Root,
+		StartedAt:   utils.CommandEnv.StartedAt(),
+		Duration:    time.Since(utils.CommandEnv.StartedAt()),
+		Outcomes:    x.outcomes,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		session.Host = hostname
+	}
+
+	port := bg.OpenReadPort(base.ThreadPoolDebugId{Category: "RecordSession"})
+	defer port.Close()
+
+	if err := utils.ForeachBuildable(port, func(_ utils.BuildAlias, compiler compile.Compiler) error {
+		rules := compiler.GetCompiler()
+		toolchain := BuildSessionToolchain{
+			Compiler:   rules.CompilerAlias.String(),
+			Executable: rules.Executable,
+			Linker:     rules.Linker,
+			CppStd:     rules.CppStd,
+		}
+
+		// same probe than doctor command: a toolchain path is meaningless without the original machine
+		if version, err := checkExecutable(rules.Executable); err == nil {
+			toolchain.Version = version
+		} else {
+			base.LogWarning(utils.LogCommand, "record session: could not probe %q version: %v", toolchain.Compiler, err)
+		}
+
+		session.Toolchains = append(session.Toolchains, toolchain)
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Slice(session.Toolchains, func(i, j int) bool {
+		return session.Toolchains[i].Compiler < session.Toolchains[j].Compiler
+	})
+
+	// inputs are files tracked by the graph without any static dependency, outputs being tracked with the node which produced them
+	if err := port.Range(func(_ utils.BuildAlias, node utils.BuildNode) error {
+		file, ok := node.GetBuildable().(*utils.FileDependency)
+		if !ok || len(node.GetStaticDependencies()) > 0 {
+			return nil
+		}
+
+		input := BuildSessionInput{
+			Filename: file.Filename,
+			Size:     file.Size,
+			Digest:   file.Digest,
+		}
+		// digest is only computed by the graph when content hash mode is enabled
+		if !input.Digest.Valid() && input.Filename.Exists() {
+			digest, err := utils.UFS.Fingerprint(input.Filename, base.Fingerprint{})
+			if err != nil {
+				return err
+			}
+			input.Digest = digest
+		}
+
+		session.Inputs = append(session.Inputs, input)
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Slice(session.Inputs, func(i, j int) bool {
+		return session.Inputs[i].Filename.Compare(session.Inputs[j].Filename) < 0
+	})
+
+	var sessionJson bytes.Buffer
+	if err := base.JsonSerialize(&session, &sessionJson, base.OptionJsonPrettyPrint(true)); err != nil {
+		return err
+	}
+
+	// graph is serialized apart instead of calling Save(), which would clear its dirty state before it is persisted
+	var graphArchive bytes.Buffer
+	if err := base.CompressedArchiveFileWrite(&graphArchive, bg.Serialize, base.TransientPage64KiB, base.TASKPRIORITY_HIGH); err != nil {
+		return err
+	}
+
+	base.LogClaim(utils.LogCommand, "write build session with %d outcomes (%d failed) to %q", len(session.Outcomes), session.NumFailed(), dst)
+
+	return utils.UFS.Create(dst, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+
+		if err := writeBuildSessionEntry(zw, BUILDSESSION_INDEX, zip.Deflate, &sessionJson); err != nil {
+			return err
+		}
+		if config := utils.CommandEnv.ConfigPath(); config.Exists() {
+			if err := utils.UFS.Open(config, func(r io.Reader) error {
+				return writeBuildSessionEntry(zw, BUILDSESSION_CONFIG, zip.Deflate, r)
+			}); err != nil {
+				return err
+			}
+		}
+		// graph archive is already compressed
+		if err := writeBuildSessionEntry(zw, BUILDSESSION_GRAPH, zip.Store, &graphArchive); err != nil {
+			return err
+		}
+
+		return zw.Close()
+	})
+}
+
+func writeBuildSessionEntry(zw *zip.Writer, name string, method uint16, r io.Reader) error {
+	// modified time is left zeroed, session timestamp is already stored in index
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+func openBuildSessionEntry(zr *zip.ReadCloser, name string, read func(io.Reader) error) error {
+	for _, it := range zr.File {
+		if it.Name != name {
+			continue
+		}
+
+		rc, err := it.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return read(rc)
+	}
+	return fmt.Errorf("build session is missing %q", name)
+}
+
+/***************************************
+ * Session Inspect Command
+ ***************************************/
+
+type SessionInspectCommand struct {
+	Bundle   utils.Filename
+	Outcomes utils.BoolVar
+	Inputs   utils.BoolVar
+	Config   utils.BoolVar
+	Node     utils.BuildAlias
+}
+
+var CommandSessionInspect = utils.NewCommandable(
+	"Debug",
+	"session-inspect",
+	"examine a build session recorded with -RecordSession, without access to the original tree",
+	&SessionInspectCommand{
+		Outcomes: base.INHERITABLE_FALSE,
+		Inputs:   base.INHERITABLE_FALSE,
+		Config:   base.INHERITABLE_FALSE,
+	})
+
+func (x *SessionInspectCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Outcomes", "list outcome of every node executed during the session, not only failures", &x.Outcomes)
+	cfv.Variable("Inputs", "list every input file recorded with the session, with its size and digest", &x.Inputs)
+	cfv.Variable("Config", "print persistent config recorded with the session", &x.Config)
+	cfv.Variable("Node", "print a node of the recorded build graph, with its dependencies and content", &x.Node)
+}
+func (x *SessionInspectCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("SessionInspectCommand", "control build session inspection", x),
+		utils.OptionCommandConsumeArg("Bundle", "build session bundle to inspect", &x.Bundle),
+	)
+	return nil
+}
+func (x *SessionInspectCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "session-inspect <%v>...", x.Bundle)
+
+	zr, err := zip.OpenReader(x.Bundle.String())
+	if err != nil {
+		return fmt.Errorf("session-inspect: could not open %q: %w", x.Bundle, err)
+	}
+	defer zr.Close()
+
+	var session BuildSession
+	if err := openBuildSessionEntry(zr, BUILDSESSION_INDEX, func(r io.Reader) error {
+		return base.JsonDeserialize(&session, r)
+	}); err != nil {
+		return err
+	}
+
+	x.printSession(&session)
+
+	if x.Config.Get() {
+		base.LogForwardf("\nConfig:")
+		if err := openBuildSessionEntry(zr, BUILDSESSION_CONFIG, func(r io.Reader) error {
+			_, err := io.Copy(base.GetLogger(), r)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if x.Node.Valid() {
+		// recorded graph is loaded apart, and never saved back
+		graph := utils.NewBuildGraph(utils.GetCommandFlags())
+		if err := openBuildSessionEntry(zr, BUILDSESSION_GRAPH, graph.Load); err != nil {
+			return fmt.Errorf("session-inspect: could not load recorded build graph: %w", err)
+		}
+
+		bg := graph.OpenReadPort(base.ThreadPoolDebugId{Category: "SessionInspect"})
+		defer bg.Close()
+
+		return x.printNode(bg)
+	}
+	return nil
+}
+
+func (x *SessionInspectCommand) printSession(session *BuildSession) {
+	base.LogForwardf("%vSession recorded by ppb %v on %v (%v/%v) at %v, took %.3f seconds%v",
+		base.ANSI_UNDERLINE, session.Version, session.Host, session.OS, session.Arch,
+		session.StartedAt.Format(time.RFC3339), session.Duration.Seconds(), base.ANSI_RESET)
+	base.LogForwardf("  command-line: %v", strings.Join(session.CommandLine, " "))
+	base.LogForwardf("  root dir:     %v", session.RootDir)
+
+	base.LogForwardf("\nToolchains:")
+	for _, it := range session.Toolchains {
+		base.LogForwardf("  %v (max %v)", it.Compiler, it.CppStd)
+		base.LogForwardf("    version:  %v", it.Version)
+		base.LogForwardf("    compiler: %v", it.Executable)
+		base.LogForwardf("    linker:   %v", it.Linker)
+	}
+
+	base.LogForwardf("\nInputs: %d files", len(session.Inputs))
+	if x.Inputs.Get() {
+		for _, it := range session.Inputs {
+			base.LogForwardf("  %v  %10v  %v", it.Digest.ShortString(), base.SizeInBytes(it.Size), it.Filename)
+		}
+	}
+
+	numByStatus := make(map[string]int)
+	for _, it := range session.Outcomes {
+		numByStatus[it.Status]++
+	}
+	statuses := base.Keys(numByStatus)
+	sort.Strings(statuses)
+
+	base.LogForwardf("\nOutcomes: %d nodes executed, %d failed", len(session.Outcomes), session.NumFailed())
+	for _, status := range statuses {
+		base.LogForwardf("  %-10s %d", status, numByStatus[status])
+	}
+
+	for _, it := range session.Outcomes {
+		switch {
+		case len(it.Error) > 0:
+			base.LogForwardf("%v  FAILED    %7.3f  %v%v\n    %v", base.ANSI_FG1_RED, it.Duration.Seconds(), it.Alias, base.ANSI_RESET, it.Error)
+		case x.Outcomes.Get():
+			base.LogForwardf("  %-9s %7.3f  %v", it.Status, it.Duration.Seconds(), it.Alias)
+		}
+	}
+}
+
+func (x *SessionInspectCommand) printNode(bg utils.BuildGraphReadPort) error {
+	node, err := bg.Expect(x.Node)
+	if err != nil {
+		return err
+	}
+
+	base.LogForwardf("\n%v%v%v", base.ANSI_UNDERLINE, node.Alias(), base.ANSI_RESET)
+	base.LogForwardf("  type:        %v", base.GetTypename(node.GetBuildable()))
+	base.LogForwardf("  build stamp: %v", node.GetBuildStamp())
+
+	printDependencies := func(name string, deps []utils.BuildNode) {
+		base.LogForwardf("  %s:", name)
+		for _, it := range deps {
+			base.LogForwardf("    %v", it.Alias())
+		}
+	}
+	printDependencies("static dependencies", bg.GetStaticDependencies(node))
+	printDependencies("dynamic dependencies", bg.GetDynamicDependencies(node))
+	printDependencies("output dependencies", bg.GetOutputDependencies(node))
+
+	base.LogForwardf("  content:")
+	return base.JsonSerialize(node.GetBuildable(), base.GetLogger(), base.OptionJsonPrettyPrint(true))
+}
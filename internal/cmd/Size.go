@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"os"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type SizeCommand struct {
+	Target   compile.TargetAlias
+	Json     utils.BoolVar
+	Sections utils.BoolVar
+}
+
+var CommandSize = utils.NewCommandable(
+	"Compilation",
+	"size",
+	"report size of a built target output, with text/data/bss breakdown for executables and shared libraries",
+	&SizeCommand{
+		Json:     base.INHERITABLE_FALSE,
+		Sections: base.INHERITABLE_FALSE,
+	})
+
+func (x *SizeCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Json", "output sizes as json, instead of raw text", &x.Json)
+	cfv.Variable("Sections", "also report size of every section", &x.Sections)
+}
+func (x *SizeCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("SizeCommand", "control binary size report", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "target whose output should be inspected", &x.Target),
+	)
+	return nil
+}
+func (x *SizeCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "size <%v>...", x.Target)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Size"})
+	defer bg.Close()
+
+	// make sure selected unit is generated, to know its output file
+	if _, future := bg.Build(&x.Target); future.Join().Failure() != nil {
+		return future.Join().Failure()
+	}
+
+	unit, err := compile.FindBuildUnit(bg, x.Target)
+	if err != nil {
+		return err
+	}
+	if !unit.Payload.HasOutput() {
+		return fmt.Errorf("size: <%v> has no output (payload: %v)", x.Target, unit.Payload)
+	}
+
+	info, err := unit.OutputFile.Info()
+	if err != nil {
+		return fmt.Errorf("size: <%v> output %q not found, target should be built first: %w", x.Target, unit.OutputFile, err)
+	}
+
+	report := BinarySizeReport{
+		Target:     x.Target,
+		OutputFile: unit.OutputFile,
+		Total:      info.Size(),
+	}
+	if err := report.ParseSections(unit.OutputFile); err != nil {
+		return err
+	}
+
+	if x.Json.Get() {
+		return base.JsonSerialize(report, base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+
+	base.LogForwardf("%v: %v\n", report.Target, report.OutputFile)
+	base.LogForwardf("%10s %10s %10s %10s  (%s)\n", "text", "data", "bss", "total", report.Format)
+	base.LogForwardf("%10d %10d %10d %10d\n", report.Text, report.Data, report.Bss, report.Total)
+	if x.Sections.Get() {
+		for _, it := range report.Sections {
+			base.LogForwardf("  %-24s %10d\n", it.Name, it.Size)
+		}
+	}
+	return nil
+}
+
+/***************************************
+ * Binary Size Report
+ ***************************************/
+
+// BinarySizeReport is parsed directly from PE/ELF/Mach-O headers, so it does not depend on platform tools
+// (dumpbin, size...). Other outputs, like static libraries, only report their total size.
+type BinarySizeReport struct {
+	Target     compile.TargetAlias
+	OutputFile utils.Filename
+	Format     string
+
+	Total int64 // size of the file on disk
+	Text  uint64
+	Data  uint64
+	Bss   uint64
+
+	Sections []BinarySectionSize
+}
+
+type BinarySectionSize struct {
+	Name string
+	Size uint64
+}
+
+func (x *BinarySizeReport) ParseSections(file utils.Filename) error {
+	return utils.UFS.OpenFile(file, func(f *os.File) error {
+		if ef, err := elf.NewFile(f); err == nil {
+			x.Format = "ELF"
+			x.parseElf(ef)
+			return nil
+		}
+		if pf, err := pe.NewFile(f); err == nil {
+			x.Format = "PE"
+			x.parsePe(pf)
+			return nil
+		}
+		if mf, err := macho.NewFile(f); err == nil {
+			x.Format = "Mach-O"
+			x.parseMacho(mf)
+			return nil
+		}
+
+		x.Format = "unknown"
+		base.LogVerbose(utils.LogCommand, "size: %q is not a PE/ELF/Mach-O binary, only total size is reported", file)
+		return nil
+	})
+}
+
+func (x *BinarySizeReport) parseElf(f *elf.File) {
+	for _, it := range f.Sections {
+		if it.Flags&elf.SHF_ALLOC == 0 {
+			continue // debug info and symbols are not loaded in memory
+		}
+		switch {
+		case it.Type == elf.SHT_NOBITS:
+			x.Bss += it.Size
+		case it.Flags&elf.SHF_EXECINSTR != 0 || it.Flags&elf.SHF_WRITE == 0:
+			x.Text += it.Size // like size(1), read-only data is accounted in text
+		default:
+			x.Data += it.Size
+		}
+		x.Sections = append(x.Sections, BinarySectionSize{Name: it.Name, Size: it.Size})
+	}
+}
+func (x *BinarySizeReport) parsePe(f *pe.File) {
+	const (
+		IMAGE_SCN_CNT_CODE               = 0x00000020
+		IMAGE_SCN_CNT_INITIALIZED_DATA   = 0x00000040
+		IMAGE_SCN_CNT_UNINITIALIZED_DATA = 0x00000080
+		IMAGE_SCN_MEM_WRITE              = 0x80000000
+	)
+	for _, it := range f.Sections {
+		size := uint64(it.VirtualSize)
+		switch {
+		case it.Characteristics&IMAGE_SCN_CNT_CODE != 0:
+			x.Text += size
+		case it.Characteristics&IMAGE_SCN_CNT_UNINITIALIZED_DATA != 0:
+			x.Bss += size
+		case it.Characteristics&IMAGE_SCN_CNT_INITIALIZED_DATA != 0 && it.Characteristics&IMAGE_SCN_MEM_WRITE == 0:
+			x.Text += size // read-only data, accounted in text like ELF
+		default:
+			x.Data += size
+		}
+		x.Sections = append(x.Sections, BinarySectionSize{Name: it.Name, Size: size})
+	}
+}
+func (x *BinarySizeReport) parseMacho(f *macho.File) {
+	for _, it := range f.Sections {
+		switch {
+		case it.Seg == "__TEXT":
+			x.Text += it.Size
+		case it.Flags&0xff == 0x1 || it.Flags&0xff == 0xc: // S_ZEROFILL, S_GB_ZEROFILL
+			x.Bss += it.Size
+		default:
+			x.Data += it.Size
+		}
+		x.Sections = append(x.Sections, BinarySectionSize{Name: it.Seg + "," + it.Name, Size: it.Size})
+	}
+}
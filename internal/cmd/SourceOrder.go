@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type SourceOrderCommand struct {
+	Targets  []compile.TargetAlias
+	Relative utils.BoolVar
+}
+
+var CommandSourceOrder = utils.NewCommandable(
+	"Metadata",
+	"source-order",
+	"list sources of each target module ordered by their include relationships, for amalgamation or documentation tools",
+	&SourceOrderCommand{
+		Relative: base.INHERITABLE_FALSE,
+	})
+
+func (x *SourceOrderCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Relative", "print paths relative to root directory", &x.Relative)
+}
+func (x *SourceOrderCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("SourceOrderCommand", "control dependency-ordered source list output", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeMany("TargetAlias", "list sources of all targets specified as argument, which should have been built first", &x.Targets),
+	)
+	return nil
+}
+func (x *SourceOrderCommand) Run(cc utils.CommandContext) error {
+	base.LogVerbose(utils.LogCommand, "source-order <%v>...", base.JoinString(">, <", x.Targets...))
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "SourceOrder"})
+	defer bg.Close()
+
+	targetActions, err := compile.NeedTargetActions(bg.GlobalContext(), x.Targets...)
+	if err != nil {
+		return err
+	}
+
+	for _, ta := range targetActions {
+		files, err := compile.FindOrderedSourceFiles(bg, ta)
+		if err != nil {
+			return err
+		}
+
+		base.LogForwardf("# %v", ta.TargetAlias)
+		for _, it := range files {
+			if x.Relative.Get() {
+				base.LogForwardln(it.Relative(utils.UFS.Root))
+			} else {
+				base.LogForwardln(it.String())
+			}
+		}
+	}
+	return nil
+}
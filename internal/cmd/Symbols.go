@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Symbols Package
+ ***************************************/
+
+// Symbols package collects debug symbols of a target and of all its shared dependencies in a
+// single archive, with an index mapping each binary to its symbols. It can be distributed along
+// a build for offline crash analysis, without shipping symbols with the binaries.
+
+const SYMBOLSPACKAGE_INDEX = "index.json"
+
+type SymbolsPackageEntry struct {
+	Target  string
+	Binary  string
+	Symbols string
+	Size    int64
+}
+
+type SymbolsPackageIndex struct {
+	Target  string
+	Entries []SymbolsPackageEntry
+}
+
+type SymbolsPackageCommand struct {
+	Target compile.TargetAlias
+	Output utils.Filename
+}
+
+var CommandSymbolsPackage = utils.NewCommandable(
+	"Compilation",
+	"symbols-package",
+	"build target and collect its debug symbols in a single archive for offline crash analysis",
+	&SymbolsPackageCommand{})
+
+func (x *SymbolsPackageCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Output", "override path of generated symbols package (default: <Binaries>/<target>.symbols.zip)", &x.Output)
+}
+func (x *SymbolsPackageCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("SymbolsPackageCommand", "control symbols package generation", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("TargetAlias", "target whose debug symbols should be packaged", &x.Target),
+	)
+	return nil
+}
+func (x *SymbolsPackageCommand) Run(cc utils.CommandContext) error {
+	if !x.Output.Valid() {
+		x.Output = utils.UFS.Binaries.File(x.Target.String() + ".symbols.zip")
+	}
+
+	base.LogClaim(utils.LogCommand, "symbols-package <%v> in %q", x.Target, x.Output)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "SymbolsPackage"})
+	defer bg.Close()
+
+	// symbols are only available after linking, so selected target must be built first
+	targets, err := compile.NeedTargetActions(bg.GlobalContext(), x.Target)
+	if err != nil {
+		return err
+	}
+
+	payload, err := targets[0].GetOutputPayload(bg)
+	if err != nil {
+		return err
+	}
+	if _, future := bg.Build(payload); future.Join().Failure() != nil {
+		return future.Join().Failure()
+	}
+
+	units, err := collectSymbolsPackageUnits(bg, x.Target)
+	if err != nil {
+		return err
+	}
+
+	index := SymbolsPackageIndex{
+		Target:  x.Target.String(),
+		Entries: make([]SymbolsPackageEntry, 0, len(units)),
+	}
+	symbolFiles := make(utils.FileSet, 0, len(units))
+
+	for _, unit := range units {
+		info, err := unit.SymbolsFile.Info()
+		if err != nil {
+			return fmt.Errorf("symbols-package: missing debug symbols for <%v>: %v", unit.TargetAlias, err)
+		}
+
+		index.Entries = append(index.Entries, SymbolsPackageEntry{
+			Target:  unit.TargetAlias.String(),
+			Binary:  unit.OutputFile.Basename,
+			Symbols: unit.SymbolsFile.Basename,
+			Size:    info.Size(),
+		})
+		symbolFiles.Append(unit.SymbolsFile)
+	}
+
+	if len(symbolFiles) == 0 {
+		return fmt.Errorf("symbols-package: no debug symbols found for <%v>, check debug info is enabled", x.Target)
+	}
+
+	base.LogInfo(utils.LogCommand, "symbols-package: collected %d symbols files for <%v>", len(symbolFiles), x.Target)
+	return writeSymbolsPackage(x.Output, &index, symbolFiles)
+}
+
+func collectSymbolsPackageUnits(bg utils.BuildGraphReadPort, target compile.TargetAlias) ([]*compile.Unit, error) {
+	var results []*compile.Unit
+	visiteds := make(map[compile.TargetAlias]bool)
+
+	queue := compile.TargetAliases{target}
+	for len(queue) > 0 {
+		targetAlias := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if _, ok := visiteds[targetAlias]; ok {
+			continue
+		}
+		visiteds[targetAlias] = true
+
+		unit, err := compile.FindBuildUnit(bg, targetAlias)
+		if err != nil {
+			return nil, err
+		}
+
+		// only linked binaries can be loaded by a debugger, and they reference symbols of their static dependencies
+		switch unit.Payload {
+		case compile.PAYLOAD_EXECUTABLE, compile.PAYLOAD_SHAREDLIB:
+			if unit.SymbolsFile.Valid() {
+				results = append(results, unit)
+			}
+		}
+
+		queue = append(queue, unit.LinkDependencies...)
+		queue = append(queue, unit.RuntimeDependencies...)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].TargetAlias.Compare(results[j].TargetAlias) < 0
+	})
+	return results, nil
+}
+
+func writeSymbolsPackage(dst utils.Filename, index *SymbolsPackageIndex, symbolFiles utils.FileSet) error {
+	var indexJson bytes.Buffer
+	if err := base.JsonSerialize(index, &indexJson, base.OptionJsonPrettyPrint(true)); err != nil {
+		return err
+	}
+
+	return utils.UFS.Create(dst, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+
+		// modified time is left zeroed to produce the same archive for the same symbols
+		iw, err := zw.CreateHeader(&zip.FileHeader{Name: SYMBOLSPACKAGE_INDEX, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := iw.Write(indexJson.Bytes()); err != nil {
+			return err
+		}
+
+		for _, file := range symbolFiles {
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.Basename, Method: zip.Deflate})
+			if err != nil {
+				return err
+			}
+
+			if err := utils.UFS.Open(file, func(r io.Reader) error {
+				_, err := io.Copy(fw, r)
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+
+		return zw.Close()
+	})
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+var LogTest = base.NewLogCategory("Test")
+
+type TestCommand struct {
+	Patterns        []utils.StringVar
+	Label           utils.StringVar
+	IncludeExcluded utils.BoolVar
+	Timeout         utils.IntVar
+	Jobs            utils.IntVar
+	Json            utils.BoolVar
+	ShowOutput      utils.BoolVar
+}
+
+var CommandTest = utils.NewCommandable(
+	"Compilation",
+	"test",
+	"build and run all test executables in parallel, then print a summary of their results",
+	&TestCommand{
+		Timeout:         300,
+		IncludeExcluded: base.INHERITABLE_FALSE,
+		Json:            base.INHERITABLE_FALSE,
+		ShowOutput:      base.INHERITABLE_FALSE,
+	})
+
+func (x *TestCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Label", "select tests of modules matching a label expression, supports !/&/| operators (ex: 'core&!slow')", &x.Label)
+	cfv.Variable("IncludeExcluded", "also run tests of modules excluded from default build, which are otherwise only run when named by a pattern without wildcard", &x.IncludeExcluded)
+	cfv.Variable("Timeout", "kill a test and report it as failed when it runs longer than this many seconds (0 to disable)", &x.Timeout)
+	cfv.Variable("Jobs", "maximum number of tests running concurrently (default to number of cores)", &x.Jobs)
+	cfv.Variable("Json", "output test results as json, for CI ingestion", &x.Json)
+	cfv.Variable("ShowOutput", "print output of passing tests, output of failed tests is always printed", &x.ShowOutput)
+}
+func (x *TestCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("TestCommand", "control test execution", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeMany("Pattern", "only run tests whose target matches one of these glob expressions, ex: Runtime/Core/*", &x.Patterns, utils.COMMANDARG_OPTIONAL),
+	)
+	return nil
+}
+func (x *TestCommand) Run(cc utils.CommandContext) error {
+	base.LogClaim(utils.LogCommand, "test <%v>...", base.JoinString(">, <", x.Patterns...))
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Test"})
+	defer bg.Close()
+
+	units, err := x.selectTestUnits(bg)
+	if err != nil {
+		return err
+	}
+	if len(units) == 0 {
+		base.LogWarning(LogTest, "no test executable matched input")
+		return nil
+	}
+
+	// build every selected test before running any of them
+	aliases := utils.BuildAliases{}
+	for _, unit := range units {
+		aliases.Append(unit.OutputFile.Alias())
+	}
+	if _, err := bg.BuildMany(aliases); err != nil {
+		return err
+	}
+
+	results := x.runTests(units)
+
+	if x.Json.Get() {
+		if err := base.JsonSerialize(&results, base.GetLogger(), base.OptionJsonPrettyPrint(true)); err != nil {
+			return err
+		}
+	} else {
+		results.Print(x.ShowOutput.Get())
+	}
+
+	if results.NumFailed > 0 {
+		return fmt.Errorf("test: %d of %d test(s) failed", results.NumFailed, len(results.Tests))
+	}
+	return nil
+}
+
+func (x *TestCommand) selectTestUnits(bg utils.BuildGraphWritePort) ([]*compile.Unit, error) {
+	var expr compile.LabelExpression
+	if !x.Label.IsInheritable() {
+		var err error
+		if expr, err = compile.ParseLabelExpression(x.Label.Get()); err != nil {
+			return nil, err
+		}
+	}
+
+	patterns := base.MakeStringerSet(x.Patterns...)
+	re := utils.MakeGlobRegexp(patterns...)
+
+	// tests excluded from default build only run when named by a pattern, or with -IncludeExcluded
+	units, err := compile.NeedGlobBuildUnits(bg.GlobalContext(), x.IncludeExcluded.Get(), patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*compile.Unit, 0, len(units))
+	for _, unit := range units {
+		if unit.Payload != compile.PAYLOAD_EXECUTABLE || !unit.Tagged(compile.TAG_TEST) {
+			continue
+		}
+		if len(x.Patterns) > 0 && !re.MatchString(unit.TargetAlias.String()) {
+			continue
+		}
+		if expr != nil {
+			module, err := compile.FindBuildModule(bg, unit.TargetAlias.ModuleAlias)
+			if err != nil {
+				return nil, err
+			}
+			if !expr.Match(module.GetModule().Labels) {
+				continue
+			}
+		}
+
+		base.LogVerbose(LogTest, "selected test <%v>", unit.TargetAlias)
+		result = append(result, unit)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TargetAlias.Compare(result[j].TargetAlias) < 0
+	})
+	return result, nil
+}
+
+func (x *TestCommand) runTests(units []*compile.Unit) (results TestResults) {
+	numJobs := x.Jobs.Get()
+	if numJobs <= 0 {
+		numJobs = runtime.NumCPU()
+	}
+	timeout := time.Duration(x.Timeout.Get()) * time.Second
+
+	pbar := base.LogProgress(0, int64(len(units)), "running %d tests", len(units))
+	defer pbar.Close()
+
+	results.Tests = make([]TestResult, len(units))
+
+	// bounded pool: each test is a process, which should not saturate the host
+	wg := sync.WaitGroup{}
+	jobs := make(chan struct{}, numJobs)
+	for i, unit := range units {
+		wg.Add(1)
+		jobs <- struct{}{}
+		go func(result *TestResult, unit *compile.Unit) {
+			defer func() {
+				<-jobs
+				pbar.Inc()
+				wg.Done()
+			}()
+			result.Run(unit, timeout)
+		}(&results.Tests[i], unit)
+	}
+	wg.Wait()
+
+	for _, it := range results.Tests {
+		switch it.Status {
+		case TEST_PASSED:
+			results.NumPassed++
+		default:
+			results.NumFailed++
+		}
+	}
+	return
+}
+
+/***************************************
+ * Test Results
+ ***************************************/
+
+type TestStatus string
+
+const (
+	TEST_PASSED   TestStatus = "PASSED"
+	TEST_FAILED   TestStatus = "FAILED"
+	TEST_TIMEDOUT TestStatus = "TIMEDOUT"
+)
+
+type TestResult struct {
+	Target     compile.TargetAlias
+	Executable utils.Filename
+	Status     TestStatus
+	ExitCode   int32
+	Duration   time.Duration
+	Error      string   `json:",omitempty"`
+	Output     []string `json:",omitempty"`
+}
+
+func (x *TestResult) Run(unit *compile.Unit, timeout time.Duration) {
+	x.Target = unit.TargetAlias
+	x.Executable = unit.OutputFile
+
+	startedAt := time.Now()
+
+	err := internal_io.RunProcess(unit.OutputFile, base.StringSet{},
+		internal_io.OptionProcessCaptureOutput,
+		internal_io.OptionProcessNoSpinner,
+		internal_io.OptionProcessExitCode(&x.ExitCode),
+		internal_io.OptionProcessTimeout(timeout),
+		internal_io.OptionProcessWorkingDir(utils.UFS.Binaries),
+		internal_io.OptionProcessOutput(func(line string) error {
+			x.Output = append(x.Output, line)
+			return nil
+		}))
+
+	x.Duration = time.Since(startedAt)
+
+	switch {
+	case err == nil:
+		x.Status = TEST_PASSED
+	case errors.Is(err, internal_io.ErrProcessTimeout):
+		x.Status = TEST_TIMEDOUT
+		x.Error = err.Error()
+	default:
+		x.Status = TEST_FAILED
+		x.Error = err.Error()
+	}
+
+	base.LogVerbose(LogTest, "%v: %v in %v", x.Target, x.Status, x.Duration)
+}
+
+type TestResults struct {
+	NumPassed int
+	NumFailed int
+	Tests     []TestResult
+}
+
+func (x *TestResults) Print(showOutput bool) {
+	for _, it := range x.Tests {
+		if it.Status == TEST_PASSED && !showOutput {
+			continue
+		}
+		base.LogForwardf("\n---- %v (%v) ----", it.Target, it.Status)
+		for _, line := range it.Output {
+			base.LogForwardln(line)
+		}
+		if len(it.Error) > 0 {
+			base.LogForwardf("error: %v", it.Error)
+		}
+	}
+
+	base.LogForwardln("\nTest summary:")
+	for _, it := range x.Tests {
+		base.LogForwardf("  %-8v %v (%v)", it.Status, it.Target, it.Duration.Round(time.Millisecond))
+	}
+	base.LogForwardf("\n%d passed, %d failed, %d total", x.NumPassed, x.NumFailed, len(x.Tests))
+}
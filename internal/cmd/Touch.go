@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+var CommandTouch = newBuildAliasesCommand(
+	"Compilation",
+	"touch",
+	"mark build graph aliases as dirty, so next build will rebuild them",
+	func(cc utils.CommandContext, args *BuildAliasesArgs) error {
+		base.LogClaim(utils.LogCommand, "touch <%v>...", base.JoinString(">, <", args.Aliases...))
+
+		bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Touch"})
+		defer bg.Close()
+
+		// aliases are all validated before the graph is mutated
+		return bg.Touch(args.Aliases)
+	})
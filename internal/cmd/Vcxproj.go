@@ -19,6 +19,7 @@ var CommandVcxproj = NewCommand(
 	"Configure",
 	"vcxproj",
 	"generate projects and solution for Visual Studio",
+	compile.OptionCommandAllCompilationFlags(),
 	OptionCommandRun(func(cc CommandContext) error {
 		solutionFile := UFS.Output.File(CommandEnv.Prefix() + ".sln")
 		base.LogClaim(LogCommand, "generating Microsoft Visual Studio SLN solution in '%v'", solutionFile)
@@ -31,6 +32,64 @@ var CommandVcxproj = NewCommand(
 		return result.Failure()
 	}))
 
+/***************************************
+ * SlnSolutionFlags
+ ***************************************/
+
+// SlnSolutionFlags orders solution configurations, since Visual Studio selects the first one when a solution
+// is opened for the first time. Entries missing from those lists are sorted alphabetically after listed ones.
+type SlnSolutionFlags struct {
+	SlnConfigOrder   StringVar
+	SlnPlatformOrder StringVar
+}
+
+var GetSlnSolutionFlags = compile.NewCompilationFlags("SlnSolution", "Visual Studio solution generation", SlnSolutionFlags{
+	SlnConfigOrder: "Debug,FastDebug,Devel,Test,Shipping",
+})
+
+func (flags *SlnSolutionFlags) Flags(cfv CommandFlagsVisitor) {
+	cfv.Persistent("SlnConfigOrder", "comma-separated list of configurations, in order of appearance in generated solution", &flags.SlnConfigOrder)
+	cfv.Persistent("SlnPlatformOrder", "comma-separated list of solution platforms (ex: x64,Win32), in order of appearance in generated solution", &flags.SlnPlatformOrder)
+}
+
+func (flags *SlnSolutionFlags) SortConfigs(configs []SlnSolutionConfig) {
+	configRanks := getSlnSolutionOrderRanks(flags.SlnConfigOrder.Get())
+	platformRanks := getSlnSolutionOrderRanks(flags.SlnPlatformOrder.Get())
+
+	rankOf := func(ranks map[string]int, name string) int {
+		if rank, ok := ranks[name]; ok {
+			return rank
+		}
+		return len(ranks) // unlisted entries are sorted after listed ones
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		a, b := &configs[i], &configs[j]
+		if ra, rb := rankOf(platformRanks, a.Platform), rankOf(platformRanks, b.Platform); ra != rb {
+			return ra < rb
+		}
+		if cmp := strings.Compare(a.Platform, b.Platform); cmp != 0 {
+			return cmp < 0
+		}
+		if ra, rb := rankOf(configRanks, a.Config), rankOf(configRanks, b.Config); ra != rb {
+			return ra < rb
+		}
+		return a.Config < b.Config
+	})
+}
+
+func getSlnSolutionOrderRanks(order string) map[string]int {
+	ranks := make(map[string]int)
+	for _, it := range strings.Split(order, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			if _, ok := ranks[it]; !ok {
+				ranks[it] = len(ranks)
+			}
+		}
+	}
+	return ranks
+}
+
 /***************************************
  * SlnSolutionBuilder
  ***************************************/
@@ -95,17 +154,26 @@ func (x *SlnSolutionBuilder) Build(bc BuildContext) error {
 	}); err != nil {
 		return err
 	}
-	sort.Slice(x.Configs, func(i, j int) bool {
-		if cmp := strings.Compare(x.Configs[i].Platform, x.Configs[j].Platform); cmp == 0 {
-			return x.Configs[i].Config < x.Configs[j].Config
-		} else {
-			return cmp < 0
-		}
-	})
 
+	slnFlags, err := GetSlnSolutionFlags(bc)
+	if err != nil {
+		return err
+	}
+	slnFlags.SortConfigs(x.Configs)
+
+	// generate every project concurrently first, since they are independent nodes of the build graph
+	benchmark := base.LogBenchmark(LogCommand, "generated %d vcxproj projects", len(x.ModuleAliases))
+	err = bc.NeedFactories(base.Map(func(moduleAlias compile.ModuleAlias) BuildFactory {
+		return NeedVcxProjectBuilder(moduleAlias)
+	}, x.ModuleAliases...)...)
+	benchmark.Close()
+	if err != nil {
+		return err
+	}
+
+	// then assemble solution sequentially, in sorted module order to stay deterministic
 	solutionFolders := make(map[string]*base.StringSet)
 
-	// collect every generated projects
 	projects := make([]*VcxProject, len(x.ModuleAliases))
 	for i, moduleAlias := range x.ModuleAliases {
 		project, err := NeedVcxProjectBuilder(moduleAlias).Need(bc)
@@ -160,6 +228,11 @@ func (x *SlnSolutionBuilder) Build(bc BuildContext) error {
 	// finally, generate sln solution file
 	generator := NewSlnSolutionGenerator(&x.SlnSolution)
 
+	// fail early instead of letting Visual Studio report projects as unavailable
+	if err := generator.ValidateSLN(projects...); err != nil {
+		return err
+	}
+
 	if err := UFS.CreateBuffered(x.SolutionOutput, func(w io.Writer) error {
 		return generator.GenerateSLN(
 			base.NewStructuredFile(w, "\t", false),
@@ -299,7 +372,7 @@ func (x *VcxProjectBuilder) Build(bc BuildContext) error {
 		return err
 	}
 
-	relativePath := moduleRules.ModuleDir.Relative(UFS.Source)
+	relativePath := moduleRules.RelativePath()
 
 	x.ProjectGuid = base.StringFingerprint(x.ModuleAlias.String()).Guid()
 	x.BasePath = moduleRules.ModuleDir
@@ -326,8 +399,9 @@ func (x *VcxProjectBuilder) Build(bc BuildContext) error {
 
 	// parse every project config from build units
 	x.Configs = make([]VcxProjectConfig, len(units))
+	configFiles := make([]FileSet, len(units))
 	for i, u := range units {
-		x.ShouldBuild = x.ShouldBuild || (u.Payload != compile.PAYLOAD_HEADERS)
+		x.ShouldBuild = x.ShouldBuild || (u.Payload != compile.PAYLOAD_HEADERS && !moduleRules.ExcludeFromDefaultBuild.Get())
 
 		if err := x.vcxProjectConfig(&x.Configs[i], u); err != nil {
 			return err
@@ -357,7 +431,24 @@ func (x *VcxProjectBuilder) Build(bc BuildContext) error {
 			return err
 		}
 
+		for _, asset := range u.Assets {
+			assetFiles, err := asset.GetInputFiles(bc)
+			if err != nil {
+				return err
+			}
+			sourceFiles.AppendUniq(assetFiles...)
+			sourceFiles.AppendUniq(asset.Dependencies...)
+		}
+
 		x.Files.AppendUniq(sourceFiles...)
+		configFiles[i] = sourceFiles
+	}
+
+	// source files can differ by environment, all files are still listed but marked as excluded in other configs
+	for i, sourceFiles := range configFiles {
+		x.Configs[i].ExcludedFiles = base.RemoveUnless(func(f Filename) bool {
+			return !sourceFiles.Contains(f)
+		}, x.Files...)
 	}
 
 	// sort everything so we are deterministic
@@ -382,13 +473,31 @@ func (x *VcxProjectBuilder) Build(bc BuildContext) error {
 
 	// finally, generate vcxproject file
 	generator := NewVcxProjectGenerator(&x.VcxProject)
-	if err = generator.GenerateAll(); err != nil {
+
+	// skip writing project files when they are identical to last generation, unless forced
+	if x.isUpToDate(bc, &generator) {
+		base.LogVerbose(LogCommand, "vcxproj: project %q is up-to-date, skip generation", x.ProjectOutput)
+		bc.Annotate(AnnocateBuildComment(`UPTODATE`))
+	} else if err = generator.GenerateAll(); err != nil {
 		return err
 	}
 
 	return bc.OutputFile(generator.ProjectOutput, generator.FiltersOutput)
 }
 
+func (x *VcxProjectBuilder) isUpToDate(bc BuildContext, generator *VcxProjectGenerator) bool {
+	if GetCommandFlags().Force.Get() {
+		return false
+	}
+	if !generator.ProjectOutput.Exists() || !generator.FiltersOutput.Exists() {
+		return false
+	}
+
+	// previous stamp content is the fingerprint of project description outputted by last generation
+	previousStamp := bc.GetPreviousBuildStamp()
+	return previousStamp.Content.Valid() && previousStamp.Content == MakeBuildFingerprint(x)
+}
+
 func (x *VcxProjectBuilder) vcxProjectConfig(config *VcxProjectConfig, u *compile.Unit) (err error) {
 	if config.PlatformToolset, err = u.Facet.Exports.Get("VisualStudio/PlatformToolset"); err == nil {
 		config.PlatformToolset = fmt.Sprint("v", config.PlatformToolset)
@@ -522,6 +631,42 @@ func NewSlnSolutionGenerator(sln *SlnSolution) (result SlnSolutionGenerator) {
 	return
 }
 
+func (x *SlnSolutionGenerator) ValidateSLN(projects ...*VcxProject) error {
+	emittedProjects := make(map[string]*VcxProject, len(projects))
+	for _, project := range projects {
+		emittedProjects[project.ProjectOutput.String()] = project
+	}
+
+	var errs []string
+
+	// every referenced project file must exist on disk
+	for _, project := range projects {
+		if !project.ProjectOutput.Exists() {
+			errs = append(errs, fmt.Sprintf("project file %q {%s} was not generated", project.ProjectOutput, project.ProjectGuid))
+		}
+	}
+
+	// every project listed by solution must have been emitted
+	for _, projectPath := range x.Projects {
+		if _, ok := emittedProjects[projectPath]; !ok {
+			errs = append(errs, fmt.Sprintf("project %q is listed in solution but was not emitted", projectPath))
+		}
+	}
+
+	// every dependency must resolve to the guid of an emitted project
+	for _, deps := range x.Dependencies {
+		for _, dependency := range deps.Dependencies {
+			if _, ok := emittedProjects[dependency]; !ok {
+				errs = append(errs, fmt.Sprintf("dependency %q of [%v] does not correspond to an emitted project", dependency, strings.Join(deps.Projects, ", ")))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("sln: solution %q has %d invalid project references:\n\t%s", x.SolutionOutput, len(errs), strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
 func (x *SlnSolutionGenerator) GenerateSLN(sln *base.StructuredFile, projects ...*VcxProject) error {
 	solutionBasePath := x.SolutionOutput.Dirname
 
@@ -840,12 +985,15 @@ type VcxProjectConfig struct {
 	Platform string
 	Config   string
 
+	ExcludedFiles FileSet
+
 	VcxAdditionalOptions
 }
 
 func (x *VcxProjectConfig) Serialize(ar base.Archive) {
 	ar.String(&x.Platform)
 	ar.String(&x.Config)
+	ar.Serializable(&x.ExcludedFiles)
 	ar.Serializable(&x.VcxAdditionalOptions)
 }
 
@@ -972,10 +1120,23 @@ func (x *VcxProjectGenerator) GenerateVCXProj(xml *internal_io.XmlFile) error {
 				}
 			}
 
+			var excludedConfigs []*VcxProjectConfig
+			for i, config := range x.Configs {
+				if config.ExcludedFiles.Contains(file) {
+					excludedConfigs = append(excludedConfigs, &x.Configs[i])
+				}
+			}
+
 			closure := func() {
-				xml.InnerString("FileType", fileType.FileType)
+				if fileType != nil {
+					xml.InnerString("FileType", fileType.FileType)
+				}
+				for _, config := range excludedConfigs {
+					xml.InnerString("ExcludedFromBuild", "true",
+						internal_io.XmlAttr{Name: "Condition", Value: fmt.Sprintf("'$(Configuration)|$(Platform)'=='%s|%s'", config.Config, config.Platform)})
+				}
 			}
-			xml.Tag("CustomBuild", base.Blend(nil, closure, fileType != nil), internal_io.XmlAttr{Name: "Include", Value: relative})
+			xml.Tag("CustomBuild", base.Blend(nil, closure, fileType != nil || len(excludedConfigs) > 0), internal_io.XmlAttr{Name: "Include", Value: relative})
 		}
 	})
 
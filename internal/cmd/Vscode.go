@@ -25,7 +25,8 @@ var Vscode = NewCommand(
 		bg := CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "Vscode"})
 		defer bg.Close()
 
-		result := BuildVscode(outputDir).Build(bg, OptionBuildForce)
+		// workspace is only regenerated when a module, an environment or a unit changed
+		result := BuildVscode(outputDir).Build(bg)
 		return result.Failure()
 	}))
 
@@ -42,7 +43,7 @@ func BuildVscode(outputDir Directory) BuildFactoryTyped[*VscodeBuilder] {
 	})
 }
 
-var VscodeBuilderVersion = "VscodeBuilder-1-0-0"
+var VscodeBuilderVersion = "VscodeBuilder-1-1-0"
 
 type VscodeBuilder struct {
 	Version   string
@@ -73,6 +74,11 @@ func (vsc *VscodeBuilder) Build(bc BuildContext) error {
 		return err
 	}
 
+	// sort everything to be deterministic
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].GetModule().ModuleAlias.String() < modules[j].GetModule().ModuleAlias.String()
+	})
+
 	moduleAliases := base.Map(func(m compile.Module) compile.ModuleAlias { return m.GetModule().ModuleAlias }, modules...)
 
 	c_cpp_properties_json := vsc.OutputDir.File("c_cpp_properties.json")
@@ -131,6 +137,8 @@ func sanitizeEnvironmentDefines(defines base.StringSet) (base.StringSet, error)
 		}
 	}
 
+	// map iteration order is random, but generated files should be deterministic
+	result.Sort()
 	return result, nil
 }
 
@@ -158,19 +166,29 @@ func (vsc *VscodeBuilder) c_cpp_properties(bc BuildContext, moduleAliases []comp
 			return err
 		}
 
+		// same resolution than vcxproj, but merged for all units of each environment
 		defines := base.StringSet{}
 		includePaths := DirSet{}
-		if err := compile.ForeachBuildUnits(bc, env.EnvironmentAlias, func(u *compile.Unit) error {
+		for _, moduleAlias := range moduleAliases {
+			// need units instead of finding them, so workspace is regenerated when a unit changed
+			buildable, err := bc.NeedBuildable(compile.TargetAlias{
+				EnvironmentAlias: env.EnvironmentAlias,
+				ModuleAlias:      moduleAlias,
+			})
+			if err != nil {
+				return err
+			}
+
+			u := buildable.(*compile.Unit)
 			defines.AppendUniq(u.Defines...)
 			includePaths.AppendUniq(u.IncludePaths...)
-			return nil
-		}, moduleAliases...); err != nil {
-			return err
+			includePaths.AppendUniq(u.ExternIncludePaths...)
+			includePaths.AppendUniq(u.SystemIncludePaths...)
 		}
 
 		defines, err = sanitizeEnvironmentDefines(defines)
 		if err != nil {
-			return nil
+			return err
 		}
 
 		configurations = append(configurations, base.JsonMap{
@@ -214,13 +232,14 @@ func (vsc *VscodeBuilder) tasks(moduleAliases compile.ModuleAliases, outputFile
 		return base.MakeUnexpectedValueError(problemMatcher, base.GetCurrentHost().Id)
 	}
 
+	// "--" stops option parsing, so target alias can't be mistaken for a flag
 	const buildCommand = "build"
 	tasks := base.Map(func(moduleAliases compile.ModuleAlias) base.JsonMap {
 		label := moduleAliases.String()
 		return base.JsonMap{
 			"label":   label,
 			"command": UFS.Executable.String(),
-			"args":    []string{buildCommand, "-Ide", label + "-${command:cpptools.activeConfigName}"},
+			"args":    []string{buildCommand, "-Ide", "--", label + "-${command:cpptools.activeConfigName}"},
 			"options": base.JsonMap{
 				"cwd": UFS.Root,
 			},
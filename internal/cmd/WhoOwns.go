@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/poppolopoppo/ppb/compile"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+type WhoOwnsCommand struct {
+	SourceFile  utils.Filename
+	Environment compile.EnvironmentAlias
+}
+
+var CommandWhoOwns = utils.NewCommandable(
+	"Compilation",
+	"whoowns",
+	"print which module owns a given file, and which targets compile it",
+	&WhoOwnsCommand{})
+
+func (x *WhoOwnsCommand) Flags(cfv utils.CommandFlagsVisitor) {
+	cfv.Variable("Environment", "only resolve targets of this compilation environment (default: every environment)", &x.Environment)
+}
+func (x *WhoOwnsCommand) Init(ci utils.CommandContext) error {
+	ci.Options(
+		utils.OptionCommandParsableFlags("WhoOwnsCommand", "control source file ownership resolution", x),
+		compile.OptionCommandAllCompilationFlags(),
+		utils.OptionCommandConsumeArg("SourceFile", "path of the file to resolve", &x.SourceFile),
+	)
+	return nil
+}
+func (x *WhoOwnsCommand) Run(cc utils.CommandContext) error {
+	sourceFile := x.SourceFile.Normalize()
+	base.LogVerbose(utils.LogCommand, "whoowns %q...", sourceFile)
+
+	bg := utils.CommandEnv.BuildGraph().OpenWritePort(base.ThreadPoolDebugId{Category: "WhoOwns"})
+	defer bg.Close()
+
+	modules, err := compile.FindOwningModules(bg.GlobalContext(), sourceFile)
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("whoowns: file %q is not part of any module", sourceFile)
+	}
+
+	var environments []compile.EnvironmentAlias
+	if x.Environment.Valid() {
+		environments = append(environments, x.Environment)
+	} else if err := compile.ForeachEnvironmentAlias(func(ea compile.EnvironmentAlias) error {
+		environments = append(environments, ea)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, module := range modules {
+		rules := module.GetModule()
+		base.LogForwardf("%v  %v(%v)%v", rules.ModuleAlias, base.ANSI_FAINT, rules.ModuleDir.Relative(utils.UFS.Root), base.ANSI_RESET)
+
+		numTargets := 0
+		for _, ea := range environments {
+			targets, err := compile.FindOwningTargets(bg, ea, sourceFile, module)
+			if err != nil {
+				return err
+			}
+			for _, target := range targets {
+				base.LogForwardf("    %v", target)
+				numTargets++
+			}
+		}
+
+		if numTargets == 0 {
+			// headers and extra files are owned by the module, but never compiled directly
+			base.LogForwardf("    %vnot compiled by any target%v", base.ANSI_FAINT, base.ANSI_RESET)
+		}
+	}
+	return nil
+}
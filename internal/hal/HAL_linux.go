@@ -25,6 +25,9 @@ func InitHAL() {
 	})
 
 	base.SetEnableInteractiveShell(isInteractiveShell())
+	if base.GetTerminalWidth() == 0 { // do not override -Width flag
+		base.SetTerminalWidth(getTerminalWidth())
+	}
 
 	generic.InitGenericHAL()
 	linux.InitLinuxHAL()
@@ -41,6 +44,14 @@ func isTty() bool {
 	return err != nil
 }
 
+func getTerminalWidth() int {
+	// fails when stdout is not a terminal, width is unknown in this case
+	if ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ); err == nil {
+		return int(ws.Col)
+	}
+	return 0
+}
+
 func isInteractiveShell() bool {
 	// if !isTty() {
 	// 	return false
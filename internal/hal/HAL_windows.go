@@ -7,6 +7,8 @@ import (
 	"os"
 	"syscall"
 
+	sys_windows "golang.org/x/sys/windows"
+
 	"github.com/poppolopoppo/ppb/internal/base"
 	"github.com/poppolopoppo/ppb/internal/hal/generic"
 	"github.com/poppolopoppo/ppb/internal/hal/windows"
@@ -43,6 +45,15 @@ func setConsoleMode() bool {
 	return false
 }
 
+func getTerminalWidth() int {
+	// fails when stdout is not a console, width is unknown in this case
+	var info sys_windows.ConsoleScreenBufferInfo
+	if err := sys_windows.GetConsoleScreenBufferInfo(sys_windows.Handle(os.Stdout.Fd()), &info); err == nil {
+		return int(info.Window.Right-info.Window.Left) + 1
+	}
+	return 0
+}
+
 func InitHAL() {
 	base.SetCurrentHost(&base.HostPlatform{
 		Id:   base.HOST_WINDOWS,
@@ -50,6 +61,9 @@ func InitHAL() {
 	})
 
 	base.SetEnableInteractiveShell(setConsoleMode())
+	if base.GetTerminalWidth() == 0 { // do not override -Width flag
+		base.SetTerminalWidth(getTerminalWidth())
+	}
 
 	generic.InitGenericHAL()
 	windows.InitWindowsHAL()
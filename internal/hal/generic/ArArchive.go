@@ -0,0 +1,107 @@
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/poppolopoppo/ppb/action"
+	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/utils"
+)
+
+/***************************************
+ * Deterministic Archive
+ ***************************************/
+
+// Unix archives (.a) store modification time, owner and permissions of every member in its header,
+// which are only zeroed when the archiver is running in deterministic mode (ex: `ar D`).
+// https://en.wikipedia.org/wiki/Ar_(Unix)#File_header
+
+var (
+	arArchiveMagic     = []byte("!<arch>\n")
+	arThinArchiveMagic = []byte("!<thin>\n") // members are not stored in thin archives, only referenced by path
+)
+
+const arArchiveHeaderSize = 60
+
+// CheckDeterministicArchive returns an error when a member of given archive embeds a timestamp or an owner,
+// since the archive would differ from one build to another with the same inputs
+func CheckDeterministicArchive(archive utils.Filename) error {
+	return utils.UFS.Open(archive, func(r io.Reader) error {
+		rd := bufio.NewReader(r)
+
+		magic := make([]byte, len(arArchiveMagic))
+		if _, err := io.ReadFull(rd, magic); err != nil {
+			return err
+		}
+		thin := bytes.Equal(magic, arThinArchiveMagic)
+		if !thin && !bytes.Equal(magic, arArchiveMagic) {
+			return fmt.Errorf("%q is not an ar archive", archive)
+		}
+
+		header := make([]byte, arArchiveHeaderSize)
+		for {
+			if _, err := io.ReadFull(rd, header); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			name := string(bytes.TrimSpace(header[0:16]))
+			for _, field := range []struct {
+				Name  string
+				Value []byte
+			}{
+				{"timestamp", header[16:28]},
+				{"owner id", header[28:34]},
+				{"group id", header[34:40]},
+			} {
+				if value := string(bytes.TrimSpace(field.Value)); len(value) > 0 && value != "0" {
+					return fmt.Errorf("%q is not deterministic: member %q has %s %s", archive, name, field.Name, value)
+				}
+			}
+
+			size, err := strconv.ParseInt(string(bytes.TrimSpace(header[48:58])), 10, 64)
+			if err != nil {
+				return fmt.Errorf("%q has an invalid header for member %q: %w", archive, name, err)
+			}
+
+			// only symbol table and long names table are embedded in thin archives
+			if thin && name != "/" && name != "//" {
+				continue
+			}
+
+			// member data is aligned on 2 bytes
+			if _, err := rd.Discard(int(size + size%2)); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+/***************************************
+ * Deterministic Archive Action
+ ***************************************/
+
+// DeterministicArchiveAction verifies the archive produced by the librarian, so cache keys computed from
+// this archive by dependent actions can actually hit when nothing changed
+type DeterministicArchiveAction struct {
+	action.ActionRules
+}
+
+func (x *DeterministicArchiveAction) Build(bc utils.BuildContext) error {
+	if err := x.ActionRules.Build(bc); err != nil {
+		return err
+	}
+
+	archive := x.GetGeneratedFile()
+	base.LogVeryVerbose(LogGeneric, "verify archive %q is deterministic", archive)
+	return CheckDeterministicArchive(archive)
+}
+
+func (x *DeterministicArchiveAction) Serialize(ar base.Archive) {
+	ar.Serializable(&x.ActionRules)
+}
@@ -12,6 +12,7 @@ func InitGenericHAL() {
 
 func InitGenericCompile() {
 	base.RegisterSerializable[GnuSourceDependenciesAction]()
+	base.RegisterSerializable[DeterministicArchiveAction]()
 
 	base.RegisterSerializable[ExternalSDKHeaderGenerator]()
 	base.RegisterSerializable[ExternalSDKGeneratedHeader]()
@@ -2,6 +2,7 @@ package generic
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
@@ -94,6 +95,36 @@ func (x *GnuDepFile) Load(src utils.Filename) error {
 	})
 }
 
+// Save writes a single rule where target depends on all dependencies, in the format parsed by Load().
+// Paths are written relative to root when possible, so the file can be shared between machines, and
+// since absolute paths on Windows would contain a rule separator.
+func (x *GnuDepFile) Save(dst, target utils.Filename) error {
+	return utils.UFS.Create(dst, func(w io.Writer) error {
+		if _, err := fmt.Fprintf(w, "%s:", escapeGnuDepFilename(makeGnuDepFilename(target))); err != nil {
+			return err
+		}
+		for _, it := range x.Dependencies {
+			if _, err := fmt.Fprintf(w, " \\\n  %s", escapeGnuDepFilename(makeGnuDepFilename(it))); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	})
+}
+
+func makeGnuDepFilename(file utils.Filename) string {
+	if file.IsIn(utils.UFS.Root) {
+		return utils.ForceLocalFilename(file)
+	}
+	return file.String()
+}
+func escapeGnuDepFilename(filename string) string {
+	filename = strings.ReplaceAll(filename, " ", "\\ ")
+	filename = strings.ReplaceAll(filename, "#", "\\#")
+	return strings.ReplaceAll(filename, "$", "$$")
+}
+
 /***************************************
  * GnuDepFileAction
  ***************************************/
@@ -103,6 +134,9 @@ type GnuSourceDependenciesAction struct {
 	action.ActionRules
 }
 
+func (x *GnuSourceDependenciesAction) GetGnuDepFile() utils.Filename {
+	return x.GnuDepFile
+}
 func (x *GnuSourceDependenciesAction) Build(bc utils.BuildContext) error {
 	// compile the action with /sourceDependencies
 	return x.ActionRules.BuildWithSourceDependencies(bc, x)
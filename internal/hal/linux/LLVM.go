@@ -39,6 +39,9 @@ func (llvm *LlvmCompiler) Serialize(ar base.Archive) {
 }
 
 func (llvm *LlvmCompiler) Extname(x PayloadType) string {
+	if extname, ok := llvm.CompilerRules.Extnames.Get(x); ok {
+		return extname
+	}
 	switch x {
 	case PAYLOAD_EXECUTABLE:
 		return ".out"
@@ -177,6 +180,20 @@ func (llvm *LlvmCompiler) Sanitizer(f *Facet, sanitizer SanitizerType) {
 	}
 	f.Defines.Append("USE_PPE_SANITIZER=1")
 }
+func (llvm *LlvmCompiler) OptimizeFile(u *Unit, level OptimizationLevel) base.StringSet {
+	options := base.NewStringSet(u.CompilerOptions...)
+	if u.PCH != PCH_DISABLED {
+		// clang rejects a PCH compiled with another optimization level, so the header is included without it
+		options.Remove("-include-pch", MakeLocalFilename(u.PrecompiledObject))
+	}
+	if flag, ok := llvm_CXX_optimizationFlag(u.Optimize); ok {
+		options.Remove(flag)
+	}
+	if flag, ok := llvm_CXX_optimizationFlag(level); ok {
+		options.AppendUniq(flag)
+	}
+	return options
+}
 
 func (llvm *LlvmCompiler) ForceInclude(f *Facet, inc ...Filename) {
 	for _, x := range inc {
@@ -210,6 +227,21 @@ func (llvm *LlvmCompiler) LibraryPath(f *Facet, dirs ...Directory) {
 		f.LinkerOptions.Append("-I" + s)
 	}
 }
+func (llvm *LlvmCompiler) Framework(f *Facet, frameworks ...string) {
+	for _, s := range frameworks {
+		f.LinkerOptions.Append("-framework", s)
+	}
+}
+func (llvm *LlvmCompiler) FrameworkPath(f *Facet, dirs ...Directory) {
+	for _, x := range dirs {
+		s := "-F" + MakeLocalDirectory(x)
+		f.AddCompilationFlag_NoAnalysis(s)
+		f.LinkerOptions.Append(s)
+	}
+}
+func (llvm *LlvmCompiler) ExportMap(f *Facet, versionScript Filename) {
+	f.LinkerOptions.Append("-Wl,--version-script=" + MakeLocalFilename(versionScript))
+}
 func (llvm *LlvmCompiler) GetPayloadOutput(u *compile.Unit, payload compile.PayloadType, file Filename) Filename {
 	if payload == PAYLOAD_PRECOMPILEDOBJECT {
 		return file // clang does not output a compiled object when emitting PCH, only a pre-parsed AST
@@ -233,7 +265,15 @@ func (llvm *LlvmCompiler) CreateAction(u *Unit, payload PayloadType, model *acti
 		}
 		fallthrough
 
-	case PAYLOAD_DEBUGSYMBOLS, PAYLOAD_DEPENDENCIES, PAYLOAD_EXECUTABLE, PAYLOAD_HEADERS, PAYLOAD_SHAREDLIB, PAYLOAD_SOURCES, PAYLOAD_STATICLIB:
+	case PAYLOAD_DEBUGSYMBOLS, PAYLOAD_DEPENDENCIES, PAYLOAD_EXECUTABLE, PAYLOAD_HEADERS, PAYLOAD_SHAREDLIB, PAYLOAD_SOURCES:
+		rules := model.CreateActionRules()
+		return &rules
+	case PAYLOAD_STATICLIB:
+		if u.Deterministic.Get() {
+			return &DeterministicArchiveAction{
+				ActionRules: model.CreateActionRules(),
+			}
+		}
 		rules := model.CreateActionRules()
 		return &rules
 	default:
@@ -249,6 +289,13 @@ func (llvm *LlvmCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		u.LinkerOptions.AppendUniq("-v")
 	}
 
+	if u.Deterministic.Get() {
+		// zero timestamps, uids and gids of archive members (llvm-ar default, but explicit for gnu ar compatibility)
+		if i, ok := u.LibrarianOptions.IndexOf("rcs"); ok {
+			u.LibrarianOptions[i] = "rcsD"
+		}
+	}
+
 	switch compileEnv.GetPlatform(bg).Arch {
 	case ARCH_X86:
 		u.AddCompilationFlag_NoAnalysis("-m32")
@@ -272,16 +319,34 @@ func (llvm *LlvmCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		u.Defines.Append("_LIBCPP_DEBUG=0")
 	}
 
+	// resolved per-module, since modules can opt out of warnings as errors
+	if u.GetWarningsAsErrors() {
+		u.AddCompilationFlag_NoAnalysis("-Werror")
+	}
+
+	// set floating-point model (https://clang.llvm.org/docs/UsersManual.html#controlling-floating-point-behavior)
+	switch u.FloatModel {
+	case FLOATMODEL_INHERIT:
+		// clang default is precise
+	case FLOATMODEL_FAST:
+		// only for optimized builds like before, unoptimized builds keep precise semantics which are easier to debug
+		switch u.Optimize {
+		case OPTIMIZE_FOR_SIZE, OPTIMIZE_FOR_SPEED, OPTIMIZE_FOR_SHIPPING:
+			u.AddCompilationFlag("-ffast-math")
+		}
+	case FLOATMODEL_PRECISE:
+		u.AddCompilationFlag("-ffp-model=precise")
+	case FLOATMODEL_STRICT:
+		u.AddCompilationFlag("-ffp-model=strict")
+	default:
+		base.UnexpectedValue(u.FloatModel)
+	}
+
+	if flag, ok := llvm_CXX_optimizationFlag(u.Optimize); ok {
+		u.AddCompilationFlag(flag)
+	}
 	switch u.Optimize {
-	case OPTIMIZE_NONE:
-		u.AddCompilationFlag("-O0")
-	case OPTIMIZE_FOR_DEBUG:
-		u.AddCompilationFlag("-O1")
-	case OPTIMIZE_FOR_SIZE:
-		u.AddCompilationFlag("-O2", "-ffast-math")
 	case OPTIMIZE_FOR_SPEED, OPTIMIZE_FOR_SHIPPING:
-		u.AddCompilationFlag("-O3", "-Ofast", "-ffast-math")
-
 		// https://blog.quarkslab.com/clang-hardening-cheat-sheet.html
 		if u.Payload == PAYLOAD_SHAREDLIB {
 			u.AddCompilationFlag("-fPIC")
@@ -309,6 +374,20 @@ func (llvm *LlvmCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
  * Compiler options per configuration
  ***************************************/
 
+func llvm_CXX_optimizationFlag(level OptimizationLevel) (string, bool) {
+	switch level {
+	case OPTIMIZE_NONE:
+		return "-O0", true
+	case OPTIMIZE_FOR_DEBUG:
+		return "-O1", true
+	case OPTIMIZE_FOR_SIZE:
+		return "-O2", true
+	case OPTIMIZE_FOR_SPEED, OPTIMIZE_FOR_SHIPPING:
+		return "-O3", true // -Ofast is not used, since it implies -ffast-math regardless of floating-point model
+	default:
+		return "", false
+	}
+}
 func llvm_CXX_linkTimeCodeGeneration(u *Unit, enabled bool, incremental bool) {
 	if enabled {
 		u.LibrarianOptions.Append("-T")
@@ -352,6 +431,8 @@ func llvm_CXX_runtimeChecks(u *Unit, enabled bool, strong bool) {
 type LlvmProductInstall struct {
 	Arch      string
 	WantedVer LlvmVersion
+	// skip looking for clang++ in PATH when pinned with -CompilerPath
+	PinnedClangPlusPlus Filename
 
 	ActualVer     LlvmVersion
 	InstallDir    Directory
@@ -362,11 +443,12 @@ type LlvmProductInstall struct {
 }
 
 func (x *LlvmProductInstall) Alias() BuildAlias {
-	return MakeBuildAlias("HAL", "Linux", "LLVM", x.WantedVer.String(), x.Arch)
+	return MakeBuildAlias("HAL", "Linux", "LLVM", x.WantedVer.String(), x.Arch, x.PinnedClangPlusPlus.String())
 }
 func (x *LlvmProductInstall) Serialize(ar base.Archive) {
 	ar.String(&x.Arch)
 	ar.Serializable(&x.WantedVer)
+	ar.Serializable(&x.PinnedClangPlusPlus)
 
 	ar.Serializable(&x.ActualVer)
 	ar.Serializable(&x.InstallDir)
@@ -377,15 +459,22 @@ func (x *LlvmProductInstall) Serialize(ar base.Archive) {
 }
 func (x *LlvmProductInstall) Build(bc BuildContext) error {
 	buildCompilerVer := func(suffix string) error {
-		base.LogDebug(LogLinux, "llvm: looking for clang++%s...", suffix)
-		c := exec.Command("/bin/sh", "-c", "which clang++"+suffix)
-		if outp, err := c.Output(); err == nil {
-			x.ClangPlusPlus = MakeFilename(strings.TrimSpace(base.UnsafeStringFromBytes(outp)))
+		var c *exec.Cmd
+		if x.PinnedClangPlusPlus.Valid() {
+			base.LogDebug(LogLinux, "llvm: using pinned %q", x.PinnedClangPlusPlus)
+			x.ClangPlusPlus = x.PinnedClangPlusPlus
+			c = exec.Command("realpath", x.PinnedClangPlusPlus.String())
 		} else {
-			return err
+			base.LogDebug(LogLinux, "llvm: looking for clang++%s...", suffix)
+			c = exec.Command("/bin/sh", "-c", "which clang++"+suffix)
+			if outp, err := c.Output(); err == nil {
+				x.ClangPlusPlus = MakeFilename(strings.TrimSpace(base.UnsafeStringFromBytes(outp)))
+			} else {
+				return err
+			}
+			c = exec.Command("/bin/sh", "-c", "realpath $(which clang++"+suffix+")")
 		}
 
-		c = exec.Command("/bin/sh", "-c", "realpath $(which clang++"+suffix+")")
 		if outp, err := c.Output(); err == nil {
 			x.Clang = MakeFilename(strings.TrimSpace(base.UnsafeStringFromBytes(outp)))
 		} else {
@@ -429,14 +518,16 @@ func (x *LlvmProductInstall) Build(bc BuildContext) error {
 	}
 
 	var err error
-	switch x.WantedVer {
-	case LLVM_LATEST:
+	switch {
+	case x.PinnedClangPlusPlus.Valid():
+		err = buildCompilerVer("" /* pinned, no lookup */)
+	case x.WantedVer == LLVM_LATEST:
 		for _, actualVer := range GetLlvmVersions() {
 			if err = buildCompilerVer("-" + actualVer.String()); err == nil {
 				break
 			}
 		}
-	case llvm_any:
+	case x.WantedVer == llvm_any:
 		err = buildCompilerVer("" /* no suffix */)
 	default:
 		err = buildCompilerVer("-" + x.WantedVer.String())
@@ -451,9 +542,15 @@ func (llvm *LlvmCompiler) Build(bc BuildContext) error {
 		return err
 	}
 
+	pins, err := GetCompilerPathPins(bc, llvm.CompilerAlias)
+	if err != nil {
+		return err
+	}
+
 	llvm.ProductInstall, err = GetLlvmProductInstall(LlvmProductVer{
-		Arch:    llvm.Arch,
-		LlvmVer: linuxFlags.LlvmVer,
+		Arch:          llvm.Arch,
+		LlvmVer:       linuxFlags.LlvmVer,
+		ClangPlusPlus: pins.Compiler,
 	}).Need(bc)
 	if err != nil {
 		return err
@@ -465,6 +562,13 @@ func (llvm *LlvmCompiler) Build(bc BuildContext) error {
 	}
 
 	llvm.Version = llvm.ProductInstall.ActualVer
+	llvm.CompilerRules.CppStd = getCppStdFromLlvm(llvm.Version)
+	llvm.CompilerRules.Extnames = linuxFlags.Extnames
+	llvm.CompilerRules.Conflicts = CompilerFlagConflicts{}
+	llvm.CompilerRules.Conflicts.Append("-O0", "-O1", "-O2", "-O3", "-Os", "-Oz", "-Ofast")
+	llvm.CompilerRules.Conflicts.Append("-frtti", "-fno-rtti")
+	llvm.CompilerRules.Conflicts.Append("-ffast-math", "-ffp-model=precise", "-ffp-model=strict")
+	llvm.CompilerRules.Conflicts.Append("-std=c++11", "-std=c++14", "-std=c++17", "-std=c++20", "-std=c++23", "-std=c++2c")
 	llvm.CompilerRules.Features = base.NewEnumSet(
 		COMPILER_ALLOW_CACHING,
 		COMPILER_ALLOW_DISTRIBUTION,
@@ -473,6 +577,7 @@ func (llvm *LlvmCompiler) Build(bc BuildContext) error {
 	llvm.CompilerRules.Executable = llvm.ProductInstall.ClangPlusPlus
 	llvm.CompilerRules.Librarian = llvm.ProductInstall.Ar
 	llvm.CompilerRules.Linker = llvm.ProductInstall.ClangPlusPlus
+	pins.Apply(&llvm.CompilerRules)
 
 	llvm.CompilerRules.Environment = internal_io.NewProcessEnvironment()
 	llvm.CompilerRules.Facet = NewFacet()
@@ -486,7 +591,7 @@ func (llvm *LlvmCompiler) Build(bc BuildContext) error {
 	facet.AddCompilationFlag_NoAnalysis(
 		"-o", "%2", "%1", // input file injection
 		"-Wformat", "-Wformat-security", // detect Potential Formatting Attack
-		"-Wall", "-Wextra", "-Werror", "-Wfatal-errors",
+		"-Wall", "-Wextra", "-Wfatal-errors",
 		"-Wshadow",
 		"-Wno-#pragma-messages",             // silence Unity pragma messages, which are interpreted as warnings by clang
 		"-Wno-unused-command-line-argument", // #TODO: unsilence this warning (-lxxx are generating warnings when do not directly consumes specified libraries)
@@ -522,15 +627,17 @@ func (llvm *LlvmCompiler) Build(bc BuildContext) error {
 }
 
 type LlvmProductVer struct {
-	Arch    ArchType
-	LlvmVer LlvmVersion
+	Arch          ArchType
+	LlvmVer       LlvmVersion
+	ClangPlusPlus Filename // pinned, optional
 }
 
 func GetLlvmProductInstall(productVer LlvmProductVer) BuildFactoryTyped[*LlvmProductInstall] {
 	return MakeBuildFactory(func(bi BuildInitializer) (LlvmProductInstall, error) {
 		return LlvmProductInstall{
-			Arch:      productVer.Arch.String(),
-			WantedVer: productVer.LlvmVer,
+			Arch:                productVer.Arch.String(),
+			WantedVer:           productVer.LlvmVer,
+			PinnedClangPlusPlus: productVer.ClangPlusPlus,
 		}, nil
 	})
 }
@@ -35,6 +35,26 @@ func InitLinuxCompile() {
 	compile.AllCompilerNames.Append(
 		compile.CompilerName{PersistentVar: &compilerTypes[0]},
 		compile.CompilerName{PersistentVar: &compilerTypes[1]})
+
+	// #TODO: add a GCC detector when GCC will be supported
+	compile.AllCompilerDetectors.Add("LLVM", func(bc BuildContext, arch compile.ArchType) (compile.CompilerDetection, error) {
+		llvm, err := GetLlvmCompiler(arch).Need(bc)
+		if err != nil {
+			return compile.CompilerDetection{}, err
+		}
+		return compile.NewCompilerDetection(llvm, llvm.Version.String(), compile.CurrentArch().String()), nil
+	})
+
+	compile.AllPostLinkActions.Add("copy", &compile.PostLinkCommand{
+		Executable: "cp",
+		Arguments:  base.NewStringSet("-f", "%1", "%2"),
+	})
+	compile.AllPostLinkActions.Add("strip", &compile.PostLinkCommand{
+		Executable: "strip",
+		Arguments:  base.NewStringSet("--strip-unneeded", "-o", "%2", "%1"),
+		DefaultDir: "Stripped",
+		Cacheable:  true,
+	})
 }
 
 /***************************************
@@ -45,6 +65,7 @@ type LinuxFlags struct {
 	Compiler          CompilerType
 	LlvmVer           LlvmVersion
 	DumpRecordLayouts DumpRecordLayoutsType
+	Extnames          compile.PayloadExtnames
 	StackSize         IntVar
 }
 
@@ -58,6 +79,7 @@ var GetLinuxFlags = compile.NewCompilationFlags("LinuxCompilation", "linux-speci
 func (flags *LinuxFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Persistent("Compiler", "select windows compiler", &flags.Compiler)
 	cfv.Persistent("DumpRecordLayouts", "use to investigate structure layouts", &flags.DumpRecordLayouts)
+	cfv.Persistent("Extnames", "override output file extensions, ex: SHAREDLIB=.so.1,EXECUTABLE=", &flags.Extnames)
 	cfv.Persistent("LlvmVer", "select LLVM toolchain version", &flags.LlvmVer)
 	cfv.Persistent("StackSize", "set default thread stack size in bytes", &flags.StackSize)
 }
@@ -101,31 +123,43 @@ func (linux *LinuxPlatform) GetCompiler() BuildFactoryTyped[compile.Compiler] {
 
 func makeLinuxPlatform(p *compile.PlatformRules) {
 	p.Os = "Linux"
-	p.Defines.Append(
-		"PLATFORM_PC",
-		"PLATFORM_GLFW",
-		"PLATFORM_LINUX",
-		"PLATFORM_POSIX",
-		"__LINUX__",
+	p.Definitions.Append(
+		compile.PlatformDefinition{Define: "PLATFORM_PC", Description: "target is a desktop computer"},
+		compile.PlatformDefinition{Define: "PLATFORM_GLFW", Description: "windowing relies on GLFW"},
+		compile.PlatformDefinition{Define: "PLATFORM_LINUX", Description: "target Linux operating system"},
+		compile.PlatformDefinition{Define: "PLATFORM_POSIX", Description: "target exposes POSIX api"},
+		compile.PlatformDefinition{Define: "__LINUX__", Description: "target Linux operating system, legacy spelling"},
 	)
 }
 func getLinuxPlatform_X86() compile.Platform {
 	p := &LinuxPlatform{}
 	p.Arch = compile.Platform_X86.Arch
+	p.Instructions = compile.Platform_X86.Instructions
 	p.Facet = compile.NewFacet()
 	p.Facet.Append(compile.Platform_X86)
+	p.Definitions.Append(compile.Platform_X86.Definitions...)
 	makeLinuxPlatform(&p.PlatformRules)
 	p.PlatformAlias.PlatformName = "Linux32"
-	p.Defines.Append("_LINUX32", "_POSIX32", "__X86__")
+	p.Definitions.Append(
+		compile.PlatformDefinition{Define: "_LINUX32", Description: "target 32-bit Linux"},
+		compile.PlatformDefinition{Define: "_POSIX32", Description: "target 32-bit POSIX"},
+		compile.PlatformDefinition{Define: "__X86__", Description: "target x86 architecture, legacy spelling"},
+	)
 	return p
 }
 func getLinuxPlatform_X64() compile.Platform {
 	p := &LinuxPlatform{}
 	p.Arch = compile.Platform_X64.Arch
+	p.Instructions = compile.Platform_X64.Instructions
 	p.Facet = compile.NewFacet()
 	p.Facet.Append(compile.Platform_X64)
+	p.Definitions.Append(compile.Platform_X64.Definitions...)
 	makeLinuxPlatform(&p.PlatformRules)
 	p.PlatformAlias.PlatformName = "Linux64"
-	p.Defines.Append("_LINUX64", "_POSIX64", "__X64__")
+	p.Definitions.Append(
+		compile.PlatformDefinition{Define: "_LINUX64", Description: "target 64-bit Linux"},
+		compile.PlatformDefinition{Define: "_POSIX64", Description: "target 64-bit POSIX"},
+		compile.PlatformDefinition{Define: "__X64__", Description: "target x64 architecture, legacy spelling"},
+	)
 	return p
 }
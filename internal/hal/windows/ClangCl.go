@@ -46,6 +46,9 @@ func (clang *ClangCompiler) GetLlvmProduct(bg BuildGraphReadPort) (*LlvmProductI
  ***************************************/
 
 func (clang *ClangCompiler) Extname(x compile.PayloadType) string {
+	if extname, ok := clang.CompilerRules.Extnames.Get(x); ok {
+		return extname
+	}
 	switch x {
 	case compile.PAYLOAD_DEPENDENCIES:
 		return ".obj.d"
@@ -152,7 +155,7 @@ func (clang *ClangCompiler) Decorate(bg BuildGraphReadPort, compileEnv *compile.
 	// flags added by msvc but not supported by clang-cl, llvm-lib or lld-link
 	u.RemoveCompilationFlag("/JMC-")
 	if !clang.UseMsvcLibrarian {
-		u.LibrarianOptions.Remove("/WX", "/SUBSYSTEM:WINDOWS", "/NODEFAULTLIB")
+		u.LibrarianOptions.Remove("/WX", clang.WindowsFlags.GetSubsystemOption(), "/NODEFAULTLIB")
 	}
 	if !clang.UseMsvcLinker {
 		u.LinkerOptions.Remove("/WX", "/LTCG", "/LTCG:INCREMENTAL", "/LTCG:OFF", "/NODEFAULTLIB", "/d2:-cgsummary", "/NOEXP", "/NOIMPLIB")
@@ -214,6 +217,14 @@ func (clang *ClangCompiler) Build(bc BuildContext) error {
 	rules.PrecompiledHeaderOptions.Append("-Xclang", "-fuse-ctor-homing")
 	rules.CompilerOptions.Append("-Xclang", "-fuse-ctor-homing")
 
+	// pins of MSVC compiler do not apply to clang-cl, which is pinned with its own alias below
+	if msvcProduct, err := clang.GetMsvcProduct(bc); err == nil {
+		rules.Librarian = msvcProduct.Lib_exe
+		rules.Linker = msvcProduct.Link_exe
+	} else {
+		return err
+	}
+
 	rules.Executable = llvm.ClangCl_exe
 	rules.ExtraFiles = FileSet{
 		llvm.InstallDir.Folder("bin").File("msvcp140.dll"),
@@ -228,6 +239,11 @@ func (clang *ClangCompiler) Build(bc BuildContext) error {
 		base.LogVeryVerbose(LogWindows, "%v: use llvm linker %q", clang.CompilerAlias, llvm.LldLink_exe)
 		rules.Linker = llvm.LldLink_exe
 	}
+	if pins, err := compile.GetCompilerPathPins(bc, clang.CompilerAlias); err == nil {
+		pins.Apply(rules)
+	} else {
+		return err
+	}
 
 	rules.Defines.Append("CPP_CLANG", "LLVM_FOR_WINDOWS", "_CRT_SECURE_NO_WARNINGS")
 	rules.AddCompilationFlag_NoAnalysis(
@@ -253,8 +269,8 @@ func (clang *ClangCompiler) Build(bc BuildContext) error {
 	if clang.WindowsFlags.Permissive.Get() {
 		rules.AddCompilationFlag_NoAnalysis("-Wno-error")
 	} else {
+		// warnings as errors are resolved per-module with /WX, see MsvcCompiler.Decorate()
 		rules.AddCompilationFlag_NoAnalysis(
-			"-Werror",
 			"-Wno-assume",                        // the argument to '__assume' has side effects that will be discarded
 			"-Wno-ignored-pragma-optimize",       // pragma optimize n'est pas supporté
 			"-Wno-unused-command-line-argument",  // ignore les options non suportées par CLANG (sinon échoue a cause de /WError)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	//lint:ignore ST1001 ignore dot imports warning
@@ -85,6 +86,9 @@ func (msvc *MsvcCompiler) GetResourceCompiler(bg BuildGraphReadPort) (*ResourceC
  ***************************************/
 
 func (msvc *MsvcCompiler) Extname(x PayloadType) string {
+	if extname, ok := msvc.CompilerRules.Extnames.Get(x); ok {
+		return extname
+	}
 	switch x {
 	case PAYLOAD_EXECUTABLE:
 		return ".exe"
@@ -161,7 +165,7 @@ func (msvc *MsvcCompiler) AllowCaching(u *Unit, payload PayloadType) (result act
 			base.LogVeryVerbose(LogWindows, "%v/%v: can't use caching with %v debug symbols", u, payload, u.DebugInfo)
 		}
 	case PAYLOAD_EXECUTABLE, PAYLOAD_SHAREDLIB:
-		if u.Incremental.Get() {
+		if u.Incremental.IsEnabled() {
 			result = action.CACHE_NONE
 			base.LogVeryVerbose(LogWindows, "%v/%v: can't use caching with incremental linker", u, payload)
 		} else if u.DebugFastLink.Get() {
@@ -266,7 +270,18 @@ func (msvc *MsvcCompiler) DebugSymbols(u *Unit) {
 			}
 		}
 
-		u.AddCompilationFlag_NoPreprocessor("/Zi", "/Zf", "/FS", "/Fd"+MakeLocalFilename(artifactPDB))
+		if !msvc.WindowsFlags.PdbPerObject.Get() {
+			u.AddCompilationFlag_NoPreprocessor("/Zi", "/Zf", "/FS", "/Fd"+MakeLocalFilename(artifactPDB))
+		} else if u.PCH == PCH_DISABLED {
+			// each translation unit writes its own PDB next to its object (%2 is substituted with object path), so parallel
+			// compilations do not serialize through mspdbsrv.exe: the linker still has to merge every object PDB in final symbols,
+			// which is slower and uses more disk space than merging a single PDB per unit
+			u.AddCompilationFlag_NoPreprocessor("/Zi", "/Zf", "/Fd%2.pdb")
+		} else {
+			// objects compiled with a PCH must reference the PDB used when creating it, or cl.exe fails with C2859
+			base.LogVeryVerbose(LogWindows, "%v: can't use a PDB per object with %v, fallback to a shared PDB", u, u.PCH)
+			u.AddCompilationFlag_NoPreprocessor("/Zi", "/Zf", "/FS", "/Fd"+MakeLocalFilename(artifactPDB))
+		}
 
 	case DEBUGINFO_HOTRELOAD:
 		u.SymbolsFile = artifactPDB
@@ -274,7 +289,7 @@ func (msvc *MsvcCompiler) DebugSymbols(u *Unit) {
 		if u.Payload.HasLinker() {
 			u.LinkerOptions.Append("/DEBUG", "/EDITANDCONTINUE", "/PDB:"+MakeLocalFilename(artifactPDB))
 
-			if u.Incremental.Get() && !u.LinkerOptions.Contains("/INCREMENTAL:NO") {
+			if u.Incremental.IsEnabled() && !u.LinkerOptions.Contains("/INCREMENTAL:NO") {
 				u.LinkerOptions.AppendUniq("/INCREMENTAL")
 			}
 
@@ -347,6 +362,38 @@ func (msvc *MsvcCompiler) Sanitizer(f *Facet, sanitizer SanitizerType) {
 	}
 }
 
+// only code generation flags are replaced: /GL and /Gm are unit wide, since they depend on the linker and C++ standard
+func (msvc *MsvcCompiler) OptimizeFile(u *Unit, level OptimizationLevel) base.StringSet {
+	options := base.NewStringSet(u.CompilerOptions...)
+
+	// precompiled header was compiled with unit optimization level: error C2855 if used with other code generation flags,
+	// so the header is still force included but parsed again for this file
+	switch u.PCH {
+	case PCH_MONOLITHIC, PCH_SHARED:
+		options.Remove(
+			"/Yu"+u.PrecompiledHeader.Basename,
+			"/Fp"+MakeLocalFilename(u.PrecompiledObject))
+	case PCH_HEADERUNIT:
+		headerFile := MakeLocalFilename(u.PrecompiledHeader)
+		options.Remove(
+			"/translateInclude",
+			"/headerUnit", fmt.Sprintf("%v=%v", headerFile, MakeLocalFilename(u.PrecompiledObject)),
+			"/reference", MakeLocalFilename(u.PrecompiledObject))
+	}
+
+	options.Remove(msvc_CXX_optimizationFlags(u.Optimize)...)
+	if level.IsEnabled() {
+		// Command line error D8016 : '/RTC1' and '/O2' command-line options are incompatible
+		options.Remove("/RTC1")
+	}
+	options.AppendUniq(msvc_CXX_optimizationFlags(level)...)
+	if flag, ok := msvc_CXX_functionLevelLinking(u); ok {
+		options.Remove("/Gy", "/Gy-")
+		options.Append(flag)
+	}
+	return options
+}
+
 func (msvc *MsvcCompiler) ForceInclude(f *Facet, inc ...Filename) {
 	for _, x := range inc {
 		f.AddCompilationFlag_NoAnalysis("/FI" + x.Relative(UFS.Source))
@@ -378,16 +425,32 @@ func (msvc *MsvcCompiler) LibraryPath(f *Facet, dirs ...Directory) {
 		f.LinkerOptions.Append(libPath)
 	}
 }
+func (msvc *MsvcCompiler) Framework(*Facet, ...string)        {} // frameworks are specific to Apple toolchains
+func (msvc *MsvcCompiler) FrameworkPath(*Facet, ...Directory) {}
+func (msvc *MsvcCompiler) ExportMap(f *Facet, def Filename) {
+	f.LinkerOptions.Append("/DEF:" + MakeLocalFilename(def))
+}
 func (msvc *MsvcCompiler) GetPayloadOutput(u *Unit, payload PayloadType, file Filename) Filename {
 	if payload == PAYLOAD_OBJECTLIST && u.DebugInfo == DEBUGINFO_HOTRELOAD {
-		// cl.exe creates a new file with all letters switched to lowercase when recompiling a TU for hot-reload, which defeats all the efforts made for conserving file case everywhere
-		// #TODO: find a better workaround, for now we will generate lower case TU when using hot-reload (should report the issue first)
-
-		workaround := Filename{
-			Dirname:  file.Dirname,
-			Basename: strings.ToLower(file.Basename),
+		// cl.exe creates a new file with all letters switched to lowercase when recompiling a TU for hot-reload, which defeats all the efforts made for conserving file case everywhere.
+		// Both workarounds make the object name invariant to this lowercase conversion, with different tradeoffs:
+		//  - HotReloadLowerCase: readable object names, but tools comparing paths with case see different files than the sources,
+		//    and translation units only differing by case in the same directory would collide
+		//  - default: object names are a stable fingerprint of the original path, case of every path is preserved but objects are harder to identify
+
+		var workaround Filename
+		if msvc.WindowsFlags.HotReloadLowerCase.Get() {
+			workaround = Filename{
+				Dirname:  file.Dirname,
+				Basename: strings.ToLower(file.Basename),
+			}
+		} else {
+			workaround = Filename{
+				Dirname:  file.Dirname,
+				Basename: "tu-" + base.StringFingerprint(file.String()).ShortString(),
+			}
 		}
-		base.LogTrace(action.LogAction, "force lower case for output because MSVC hotreload:\n\torig: %q\n\thack: %q", file, workaround)
+		base.LogTrace(action.LogAction, "force case invariant name for output because MSVC hotreload:\n\torig: %q\n\thack: %q", file, workaround)
 
 		file = workaround
 	}
@@ -401,8 +464,12 @@ func (msvc *MsvcCompiler) CreateAction(u *Unit, payload PayloadType, model *acti
 
 	} else {
 		// use explicit compiler support with /sourceDependencies
-		return NewMsvcSourceDependenciesAction(model,
+		result := NewMsvcSourceDependenciesAction(model,
 			model.ExportFile.ReplaceExt(msvc.Extname(PAYLOAD_DEPENDENCIES)))
+		if msvc.WindowsFlags.GnuDepFile.Get() {
+			result.SetGnuDepFile(model.ExportFile.ReplaceExt(".d"))
+		}
+		return result
 	}
 }
 
@@ -500,6 +567,18 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		}
 	}
 
+	// set floating-point model (https://learn.microsoft.com/en-us/cpp/build/reference/fp-specify-floating-point-behavior)
+	switch u.FloatModel {
+	case FLOATMODEL_FAST, FLOATMODEL_INHERIT:
+		u.AddCompilationFlag("/fp:fast") // non-deterministic, allow vendor specific float intrinsics
+	case FLOATMODEL_PRECISE:
+		u.AddCompilationFlag("/fp:precise")
+	case FLOATMODEL_STRICT:
+		u.AddCompilationFlag("/fp:strict")
+	default:
+		base.UnexpectedValue(u.FloatModel)
+	}
+
 	// set compiler options from configuration
 	switch u.RuntimeLib {
 	case RUNTIMELIB_DYNAMIC, RUNTIMELIB_INHERIT:
@@ -515,24 +594,34 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 	msvc_STL_debugHeap(u, u.RuntimeLib.IsDebug())
 	msvc_STL_iteratorDebug(u, u.RuntimeLib.IsDebug())
 
+	u.AddCompilationFlag(msvc_CXX_optimizationFlags(u.Optimize)...)
 	switch u.Optimize {
 	case OPTIMIZE_NONE:
-		u.AddCompilationFlag("/Od", "/Oy-", "/Gm-", "/Gw-")
+		u.AddCompilationFlag("/Gm-")
 		u.LinkerOptions.Append("/DYNAMICBASE:NO", "/HIGHENTROPYVA:NO", "/OPT:NOREF", "/OPT:NOICF")
 	case OPTIMIZE_FOR_DEBUG:
-		u.AddCompilationFlag("/Od", "/Ob1", "/Oy-", "/Gw-", "/Gm")
+		u.AddCompilationFlag("/Gm")
 		u.LinkerOptions.Append("/DYNAMICBASE:NO", "/HIGHENTROPYVA:NO")
 	case OPTIMIZE_FOR_SIZE:
-		u.AddCompilationFlag("/O2", "/Oy-", "/GA", "/Gm-", "/Zo", "/GL")
+		u.AddCompilationFlag("/Gm-", "/GL")
 		u.LinkerOptions.Append("/DYNAMICBASE:NO", "/HIGHENTROPYVA:NO", "/OPT:NOICF")
 	case OPTIMIZE_FOR_SPEED:
-		u.AddCompilationFlag("/O2", "/Ob3", "/Gw", "/Gm-", "/Gy", "/GL", "/GA", "/Zo")
+		u.AddCompilationFlag("/Gm-", "/GL")
 		u.LinkerOptions.Append("/DYNAMICBASE", "/HIGHENTROPYVA", "/PROFILE", "/OPT:REF")
 	case OPTIMIZE_FOR_SHIPPING:
-		u.AddCompilationFlag("/O2", "/Ob3", "/Gw", "/Gm-", "/Gy", "/GL", "/GA", "/Zo-")
+		u.AddCompilationFlag("/Gm-", "/GL")
 		u.LinkerOptions.Append("/DYNAMICBASE", "/HIGHENTROPYVA", "/OPT:REF", "/OPT:ICF=3")
 	}
 
+	// modules relying on distinct function addresses can opt out of folding, while others keep optimization level defaults
+	if flag, ok := msvc_CXX_functionLevelLinking(u); ok {
+		u.RemoveCompilationFlag("/Gy", "/Gy-")
+		u.AddCompilationFlag(flag)
+	}
+	if !u.IdenticalComdatFolding.IsInheritable() {
+		msvc_LINK_identicalComdatFolding(u, u.IdenticalComdatFolding)
+	}
+
 	// can only enable LTCG when optimizations are enabled
 	if u.Optimize.IsEnabled() {
 		msvc_CXX_linkTimeCodeGeneration(u, u.LTO.Get())
@@ -543,11 +632,7 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 
 	// fine tune warning levels
 	switch u.Warnings.Default {
-	case WARNING_ERROR:
-		base.LogVeryVerbose(LogWindows, "%v: treat warnings as errors", u)
-		u.AddCompilationFlag("/WX")
-		fallthrough
-	case WARNING_WARN:
+	case WARNING_ERROR, WARNING_WARN:
 		if u.Warnings.Pedantic.IsEnabled() {
 			base.LogVeryVerbose(LogWindows, "%v: enable standard and pedantic warnings", u)
 			u.AddCompilationFlag("/W4")
@@ -557,7 +642,14 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		}
 	case WARNING_DISABLED, WARNING_INHERIT:
 		base.LogVeryVerbose(LogWindows, "%v: disable all warnings", u)
-		u.AddCompilationFlag("/W0", "/WX-")
+		u.AddCompilationFlag("/W0")
+	}
+
+	// resolved per-module, since modules can opt out of warnings as errors (or have them forced from command-line)
+	u.RemoveCompilationFlag("/WX", "/WX-")
+	if u.GetWarningsAsErrors() {
+		base.LogVeryVerbose(LogWindows, "%v: treat warnings as errors", u)
+		u.AddCompilationFlag("/WX")
 	}
 
 	msvc_CXX_set_warning_level(u, 4996, "deprecated function, class member, variable or typedef", u.Warnings.Deprecation)
@@ -566,6 +658,24 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 	msvc_CXX_set_warning_level(u, 4244, "conversion of integral type to a smaller integral type", u.Warnings.UnsafeTypeCast)
 	msvc_CXX_set_warning_level(u, 4800, "implicit conversion with possible information loss", u.Warnings.UnsafeTypeCast)
 
+	// per-module warning overrides, applied last to take precedence over defaults above and from base facet
+	if err := u.Warnings.Overrides.Resolve(func(id string, level WarningLevel) error {
+		warningId, err := strconv.Atoi(id)
+		if err != nil {
+			return fmt.Errorf("msvc: invalid warning id %q, expected a number (ex: 4100=DISABLED)", id)
+		}
+		u.RemoveCompilationFlag(fmt.Sprint("/wd", warningId), fmt.Sprint("/w1", warningId), fmt.Sprint("/we", warningId))
+
+		warningDesc, ok := AllCompilerWarnings.Get(id)
+		if !ok {
+			warningDesc = "module override"
+		}
+		msvc_CXX_set_warning_level(u, warningId, warningDesc, level)
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	// check if C++20 at least is enabled
 	if u.CppStd >= CPPSTD_20 {
 		// C++20 deprecates /Gm
@@ -587,6 +697,8 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		u.AddCompilationFlag("/arch:AVX2")
 	} else if u.Instructions.Has(INSTRUCTIONSET_AVX) {
 		u.AddCompilationFlag("/arch:AVX")
+	} else if u.Instructions.Has(INSTRUCTIONSET_SSE2) && msvc.Arch == ARCH_X86 {
+		u.AddCompilationFlag("/arch:SSE2") // already implied on x64
 	}
 
 	// set default thread stack size
@@ -631,11 +743,11 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		// - tweak asan log output
 		asanOptions += ":debug=1:verbose=1"
 
-		u.Environment.Append("ASAN_OPTIONS", asanOptions)
+		u.ActionEnvironment.Append("ASAN_OPTIONS", asanOptions)
 
-		if u.Incremental.Get() {
+		if u.Incremental.IsEnabled() {
 			base.LogWarning(LogWindows, "%v: can't enable incremental linker while %v is enabled", u, u.Sanitizer)
-			u.Incremental.Assign(false)
+			u.Incremental = INCREMENTAL_OFF
 		}
 
 		if u.RemoveCompilationFlag("/INCREMENTAL") > 0 {
@@ -653,13 +765,13 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		switch u.DebugInfo {
 		case DEBUGINFO_SYMBOLS, DEBUGINFO_EMBEDDED, DEBUGINFO_DISABLED:
 			// https://nikhilism.com/post/2020/windows-deterministic-builds/
-			u.Incremental.Disable()
+			u.Incremental = INCREMENTAL_OFF
 			pathMap := msvc.GetPathmap()
 			u.AddCompilationFlag("/Brepro", "/experimental:deterministic", pathMap, "/d1nodatetime")
 			//u.AddCompilationFlag("/d1trimfile:"+UFS.Root.String()) // implied by /experimental:deterministic + /pathmap:
 			u.PrecompiledHeaderOptions.Append("/wd5049") // Embedding a full path may result in machine-dependent output (always happen with PCH)
 			u.LibrarianOptions.Append("/Brepro", "/experimental:deterministic")
-			if !u.Incremental.Get() {
+			if !u.Incremental.IsEnabled() {
 				u.LinkerOptions.Append("/Brepro", "/experimental:deterministic", pathMap, "/pdbaltpath:%_PDB%")
 			}
 		case DEBUGINFO_HOTRELOAD:
@@ -669,7 +781,7 @@ func (msvc *MsvcCompiler) Decorate(bg BuildGraphReadPort, compileEnv *CompileEnv
 		}
 	}
 
-	if u.Incremental.Get() {
+	if u.Incremental.IsEnabled() {
 		base.LogVeryVerbose(LogWindows, "%v: using msvc incremental linker", u)
 		if u.LinkerOptions.Contains("/INCREMENTAL") {
 			u.LinkerOptions.Remove("/LTCG")
@@ -787,6 +899,58 @@ func msvc_CXX_runtimeLibrary(u *Unit, staticCrt bool, debug bool) {
 
 	u.AddCompilationFlag(runtimeFlag + suffix)
 }
+func msvc_CXX_optimizationFlags(level OptimizationLevel) []string {
+	switch level {
+	case OPTIMIZE_NONE:
+		return []string{"/Od", "/Oy-", "/Gw-"}
+	case OPTIMIZE_FOR_DEBUG:
+		return []string{"/Od", "/Ob1", "/Oy-", "/Gw-"}
+	case OPTIMIZE_FOR_SIZE:
+		return []string{"/O2", "/Oy-", "/GA", "/Zo"}
+	case OPTIMIZE_FOR_SPEED:
+		return []string{"/O2", "/Ob3", "/Gw", "/Gy", "/GA", "/Zo"}
+	case OPTIMIZE_FOR_SHIPPING:
+		return []string{"/O2", "/Ob3", "/Gw", "/Gy", "/GA", "/Zo-"}
+	default:
+		return []string{}
+	}
+}
+// objects of a module without linker are linked by another module, whose linker options decide folding: disabling folding
+// is still honored by not packaging functions as COMDATs (/Gy-), while enabling it can't be decided by this module
+func msvc_CXX_functionLevelLinking(u *Unit) (string, bool) {
+	if !u.FunctionLevelLinking.IsInheritable() {
+		return base.Blend("/Gy-", "/Gy", u.FunctionLevelLinking.Get()), true
+	}
+	if !u.Payload.HasLinker() && u.IdenticalComdatFolding == ICF_DISABLED {
+		base.LogVeryVerbose(LogWindows, "%v: disabling msvc function-level linking, since identical COMDAT folding is disabled without linker", u)
+		return "/Gy-", true
+	}
+	return "", false
+}
+func msvc_LINK_identicalComdatFolding(u *Unit, folding IdenticalComdatFoldingType) {
+	if !u.Payload.HasLinker() {
+		switch {
+		case folding == ICF_DISABLED && u.FunctionLevelLinking.Get():
+			base.LogWarning(LogWindows, "%v: identical COMDAT folding can't be disabled with function-level linking on %v payload, since folding is decided by the module linking it", u, u.Payload)
+		case folding != ICF_DISABLED:
+			base.LogWarning(LogWindows, "%v: identical COMDAT folding has no effect on %v payload, since folding is decided by the module linking it", u, u.Payload)
+		}
+		return
+	}
+
+	u.LinkerOptions.Remove("/OPT:NOICF", "/OPT:ICF", "/OPT:ICF=3")
+	switch folding {
+	case ICF_DISABLED:
+		base.LogVeryVerbose(LogWindows, "%v: disabling msvc identical COMDAT folding", u)
+		u.LinkerOptions.Append("/OPT:NOICF")
+	case ICF_ENABLED:
+		u.LinkerOptions.Append("/OPT:ICF")
+	case ICF_AGGRESSIVE:
+		u.LinkerOptions.Append("/OPT:ICF=3")
+	default:
+		base.UnexpectedValuePanic(folding, folding)
+	}
+}
 func msvc_CXX_linkTimeCodeGeneration(u *Unit, enabled bool) {
 	if !u.LinkerOptions.Any("/LTCG", "/LTCG:OFF", "/LTCG:INCREMENTAL") {
 		if enabled {
@@ -884,9 +1048,10 @@ func (x *VsWhereEntry) Serialize(ar base.Archive) {
 }
 
 type MsvcProductInstall struct {
-	Arch      string
-	WantedVer MsvcVersion
-	Insider   bool
+	Arch       string
+	WantedVer  MsvcVersion
+	MinimumVer string
+	Insider    bool
 
 	ActualVer      MsvcVersion
 	HostArch       string
@@ -912,11 +1077,15 @@ func (x *MsvcProductInstall) Alias() BuildAlias {
 	if x.Insider {
 		variant = "Insider"
 	}
+	if len(x.MinimumVer) > 0 {
+		return MakeBuildAlias("HAL", "Windows", "MSVC", x.WantedVer.String()+"+"+x.MinimumVer, x.Arch, variant)
+	}
 	return MakeBuildAlias("HAL", "Windows", "MSVC", x.WantedVer.String(), x.Arch, variant)
 }
 func (x *MsvcProductInstall) Serialize(ar base.Archive) {
 	ar.String(&x.Arch)
 	ar.Serializable(&x.WantedVer)
+	ar.String(&x.MinimumVer)
 	ar.Bool(&x.Insider)
 
 	ar.Serializable(&x.ActualVer)
@@ -941,44 +1110,73 @@ func (x *MsvcProductInstall) Build(bc BuildContext) error {
 	// https://github.com/microsoft/vswhere/wiki/Find-VC#powershell
 	var args = []string{
 		"-format", "json",
-		"-latest",
 		"-products", "*",
 		"-requires", "Microsoft.VisualStudio.Component.VC.Tools.x86.x64",
 	}
 
+	if x.Insider {
+		args = append(args, "-prerelease")
+	}
+
+	var lowerVer, upperVer string
 	switch x.WantedVer {
 	case msc_ver_any: // don't filter
 	case MSC_VER_2022:
-		args = append(args, "-version", "[17.0,18.0)")
+		lowerVer, upperVer = "17.0", "18.0"
 	case MSC_VER_2019:
-		args = append(args, "-version", "[16.0,17.0)")
+		lowerVer, upperVer = "16.0", "17.0"
 	case MSC_VER_2017:
-		args = append(args, "-version", "[15.0,16.0)")
+		lowerVer, upperVer = "15.0", "16.0"
 	case MSC_VER_2015:
-		args = append(args, "-version", "[14.0,15.0)")
+		lowerVer, upperVer = "14.0", "15.0"
 	case MSC_VER_2013:
-		args = append(args, "-version", "[13.0,14.0)")
+		lowerVer, upperVer = "13.0", "14.0"
 	default:
 		base.UnexpectedValue(x.WantedVer)
 	}
 
-	if x.Insider {
-		args = append(args, "-prerelease")
+	// minimum version only raises lower bound, -latest will still prefer the newest install
+	if len(x.MinimumVer) > 0 {
+		if cmp, err := compareVsWhereVersions(x.MinimumVer, lowerVer); err != nil {
+			return err
+		} else if cmp > 0 {
+			lowerVer = x.MinimumVer
+		}
+		if len(upperVer) > 0 {
+			if cmp, err := compareVsWhereVersions(lowerVer, upperVer); err != nil {
+				return err
+			} else if cmp >= 0 {
+				return fmt.Errorf("msvc: minimum version %q is not compatible with MSVC %v (expected [%s,%s))", x.MinimumVer, x.WantedVer, lowerVer, upperVer)
+			}
+		}
 	}
 
-	cmd := exec.Command(MSVC_VSWHERE_EXE.String(), args...)
+	queryArgs := append([]string{"-latest"}, args...)
+	if len(lowerVer) > 0 {
+		queryArgs = append(queryArgs, "-version", fmt.Sprintf("[%s,%s)", lowerVer, upperVer))
+	}
 
-	var entries []VsWhereEntry
-	if outp, err := cmd.Output(); err != nil {
+	entries, err := runVsWhere(queryArgs...)
+	if err != nil {
 		return err
-	} else if len(outp) > 0 {
-		if err := json.Unmarshal(outp, &entries); err != nil {
-			return err
-		}
 	}
 
 	if len(entries) == 0 {
-		return fmt.Errorf("msvc: vswhere did not find any compiler")
+		if len(x.MinimumVer) == 0 {
+			return fmt.Errorf("msvc: vswhere did not find any compiler")
+		}
+
+		// list all installed versions to help the user, since none satisfied the constraint
+		installed, err := runVsWhere(args...)
+		if err != nil {
+			return err
+		}
+		available := make([]string, len(installed))
+		for i, it := range installed {
+			available[i] = it.Catalog.ProductDisplayVersion
+		}
+		return fmt.Errorf("msvc: vswhere did not find any compiler matching MSVC %v with minimum version %q, available versions: [%s]",
+			x.WantedVer, x.MinimumVer, strings.Join(available, ", "))
 	}
 
 	x.Selected = entries[0]
@@ -1058,9 +1256,10 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 	}
 
 	msvcProductInstall, err := GetMsvcProductInstall(MsvcProductVer{
-		Arch:    msvc.Arch,
-		MscVer:  msvc.WindowsFlags.MscVer,
-		Insider: msvc.WindowsFlags.Insider,
+		Arch:      msvc.Arch,
+		MscVer:    msvc.WindowsFlags.MscVer,
+		MscMinVer: msvc.WindowsFlags.MscMinVer,
+		Insider:   msvc.WindowsFlags.Insider,
 	}).Need(bc)
 	if err != nil {
 		return
@@ -1097,6 +1296,16 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 		return err
 	}
 
+	msvc.CompilerRules.Extnames = msvc.WindowsFlags.Extnames
+	msvc.CompilerRules.Conflicts = CompilerFlagConflicts{}
+	msvc.CompilerRules.Conflicts.Append("/MD", "/MDd", "/MT", "/MTd")
+	msvc.CompilerRules.Conflicts.Append("/Od", "/O1", "/O2", "/Ox")
+	msvc.CompilerRules.Conflicts.Append("/GR", "/GR-")
+	msvc.CompilerRules.Conflicts.Append("/Gm", "/Gm-")
+	msvc.CompilerRules.Conflicts.Append("/Z7", "/Zi", "/ZI")
+	msvc.CompilerRules.Conflicts.Append("/fp:fast", "/fp:precise", "/fp:strict")
+	msvc.CompilerRules.Conflicts.Append("/permissive", "/permissive-")
+	msvc.CompilerRules.Conflicts.Append("/std:c++11", "/std:c++14", "/std:c++17", "/std:c++20", "/std:c++latest")
 	msvc.CompilerRules.Features = base.NewEnumSet(
 		COMPILER_ALLOW_CACHING,
 		COMPILER_ALLOW_DISTRIBUTION,
@@ -1107,19 +1316,27 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 	msvc.CompilerRules.Executable = msvcProductInstall.Cl_exe
 	msvc.CompilerRules.Librarian = msvcProductInstall.Lib_exe
 	msvc.CompilerRules.Linker = msvcProductInstall.Link_exe
-
-	tmpDir := UFS.Transient.Folder("TMP")
-	if err := internal_io.CreateDirectory(bc, tmpDir); err != nil {
+	// Visual Studio is still detected when executables are pinned, since it provides headers and libraries
+	if pins, err := GetCompilerPathPins(bc, msvc.CompilerAlias); err == nil {
+		pins.Apply(&msvc.CompilerRules)
+	} else {
 		return err
 	}
 
+	tmpDir := getMsvcTemporaryDir()
+
+	// system directories are declared explicitly, since %PATH% is a reference to host environment which is dropped
+	// when actions run with a sanitized environment (see -SanitizeEnv and internal_io.ProcessEnvironmentAllowList)
+	systemRoot := MakeDirectory(os.Getenv("SystemRoot"))
 	msvc.CompilerRules.Environment = internal_io.NewProcessEnvironment()
 	msvc.CompilerRules.Environment.Append("PATH",
 		msvcProductInstall.VcToolsHostPath().String(),
 		msvcProductInstall.Commond7IdePath().String(),
 		resourceCompiler.Executable.Dirname.String(),
+		systemRoot.Folder("System32").String(),
+		systemRoot.String(),
 		"%PATH%")
-	msvc.CompilerRules.Environment.Append("SystemRoot", os.Getenv("SystemRoot"))
+	msvc.CompilerRules.Environment.Append("SystemRoot", systemRoot.String())
 	msvc.CompilerRules.Environment.Append("TMP", tmpDir.String())
 
 	msvc.CompilerRules.ExtraFiles = msvcProductInstall.VcToolsFileSet
@@ -1129,6 +1346,17 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 
 	facet.Append(windowsSDKInstall)
 
+	if minWindowsVersion, ok, err := msvc.WindowsFlags.GetMinWindowsVersion(); err != nil {
+		return err
+	} else if ok {
+		checkMinWindowsVersionWithSDK(minWindowsVersion, windowsSDKInstall)
+
+		// must match linker subsystem version, or the loader could fail with "entry point not found" on older OSes
+		facet.Defines.Append(
+			"_WIN32_WINNT="+minWindowsVersion.GetWin32Winnt(),
+			"WINVER="+minWindowsVersion.GetWin32Winnt())
+	}
+
 	facet.Defines.Append(
 		"CPP_VISUALSTUDIO",
 		"_ENABLE_EXTENDED_ALIGNED_STORAGE",              // https://devblogs.microsoft.com/cppblog/stl-features-and-fixes-in-vs-2017-15-8/
@@ -1162,7 +1390,6 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 		"/bigobj",  // more sections inside obj files, support larger translation units, needed for unity builds
 		"/d2FH4",   // https://devblogs.microsoft.com/cppblog/msvc-backend-updates-in-visual-studio-2019-preview-2/
 		"/EHsc",    // structure exception support (#TODO: optional ?)
-		"/fp:fast", // non-deterministic, allow vendor specific float intrinsics (https://msdn.microsoft.com/fr-fr/library/tzkfha43.aspx)
 		"/vmb",     // class is always defined before pointer to member (https://docs.microsoft.com/en-us/cpp/build/reference/vmb-vmg-representation-method?view=vs-2019)
 		"/openmp-", // disable OpenMP automatic parallelization
 		//"/Za",                // disable non-ANSI features
@@ -1184,7 +1411,7 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 		"/OUT:%2",
 		"%1",
 		"/nologo",
-		"/SUBSYSTEM:WINDOWS",
+		msvc.WindowsFlags.GetSubsystemOption(),
 		"/IGNORE:4221",
 	)
 
@@ -1204,9 +1431,10 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 		"/IGNORE:4099",       // don't have PDB for some externals
 		"/NXCOMPAT:NO",       // disable Data Execution Prevention (DEP)
 		"/LARGEADDRESSAWARE", // indicate support for VM > 2Gb (if 3Gb flag is toggled)
-		"/SUBSYSTEM:WINDOWS", // ~Windows~ application type (vs Console)
 		"/fastfail",          // better error reporting
 	)
+	// ~Windows~ application type (vs Console), suffixed with minimum Windows version when requested
+	facet.LinkerOptions.Append(msvc.WindowsFlags.GetSubsystemOption())
 
 	// ignored warnings
 	facet.AddCompilationFlag(
@@ -1274,10 +1502,64 @@ func (msvc *MsvcCompiler) Build(bc BuildContext) (err error) {
 	return nil
 }
 
+func runVsWhere(args ...string) (entries []VsWhereEntry, err error) {
+	cmd := exec.Command(MSVC_VSWHERE_EXE.String(), args...)
+
+	var outp []byte
+	if outp, err = cmd.Output(); err == nil && len(outp) > 0 {
+		err = json.Unmarshal(outp, &entries)
+	}
+	return
+}
+
+// compare dotted versions like "17.8" and "17.10.2", an empty version is lower than any other
+func compareVsWhereVersions(a, b string) (int, error) {
+	parse := func(in string) (result []int, err error) {
+		if len(in) == 0 {
+			return
+		}
+		for _, it := range strings.Split(in, ".") {
+			var n int
+			if n, err = strconv.Atoi(it); err != nil {
+				return nil, fmt.Errorf("msvc: invalid version %q: %v", in, err)
+			}
+			result = append(result, n)
+		}
+		return
+	}
+
+	va, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var na, nb int
+		if i < len(va) {
+			na = va[i]
+		}
+		if i < len(vb) {
+			nb = vb[i]
+		}
+		if na != nb {
+			if na < nb {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
 type MsvcProductVer struct {
-	Arch    ArchType
-	MscVer  MsvcVersion
-	Insider BoolVar
+	Arch      ArchType
+	MscVer    MsvcVersion
+	MscMinVer StringVar
+	Insider   BoolVar
 }
 
 func GetMsvcProductInstall(prms MsvcProductVer) BuildFactoryTyped[*MsvcProductInstall] {
@@ -1287,9 +1569,10 @@ func GetMsvcProductInstall(prms MsvcProductVer) BuildFactoryTyped[*MsvcProductIn
 		}
 
 		return MsvcProductInstall{
-			WantedVer: prms.MscVer,
-			Arch:      prms.Arch.String(),
-			Insider:   prms.Insider.Get(),
+			WantedVer:  prms.MscVer,
+			MinimumVer: prms.MscMinVer.Get(),
+			Arch:       prms.Arch.String(),
+			Insider:    prms.Insider.Get(),
 		}, bi.NeedFiles(MSVC_VSWHERE_EXE)
 	})
 }
@@ -1304,6 +1587,11 @@ func GetMsvcCompiler(arch ArchType) BuildFactoryTyped[*MsvcCompiler] {
 			var compiler Compiler = &msvc
 			return compiler == &msvc
 		})
-		return msvc, nil
+		// static dependency: compiler is dirty when transient directory is moved with -TempDir, since TMP is stored in its environment
+		return msvc, internal_io.CreateDirectory(bi, getMsvcTemporaryDir())
 	})
 }
+
+func getMsvcTemporaryDir() Directory {
+	return UFS.Transient.Folder("TMP")
+}
@@ -50,6 +50,9 @@ func (res *ResourceCompiler) Link(*compile.Facet, compile.LinkType) {}
 func (res *ResourceCompiler) PrecompiledHeader(*compile.Unit) {
 }
 func (res *ResourceCompiler) Sanitizer(*compile.Facet, compile.SanitizerType) {}
+func (res *ResourceCompiler) OptimizeFile(u *compile.Unit, _ compile.OptimizationLevel) base.StringSet {
+	return u.CompilerOptions
+}
 
 func (res *ResourceCompiler) ForceInclude(*compile.Facet, ...utils.Filename) {}
 func (res *ResourceCompiler) IncludePath(facet *compile.Facet, dirs ...utils.Directory) {
@@ -63,8 +66,11 @@ func (res *ResourceCompiler) ExternIncludePath(facet *compile.Facet, dirs ...uti
 func (res *ResourceCompiler) SystemIncludePath(facet *compile.Facet, dirs ...utils.Directory) {
 	res.IncludePath(facet, dirs...)
 }
-func (res *ResourceCompiler) Library(*compile.Facet, ...string)              {}
-func (res *ResourceCompiler) LibraryPath(*compile.Facet, ...utils.Directory) {}
+func (res *ResourceCompiler) Library(*compile.Facet, ...string)                {}
+func (res *ResourceCompiler) LibraryPath(*compile.Facet, ...utils.Directory)   {}
+func (res *ResourceCompiler) Framework(*compile.Facet, ...string)              {}
+func (res *ResourceCompiler) FrameworkPath(*compile.Facet, ...utils.Directory) {}
+func (res *ResourceCompiler) ExportMap(*compile.Facet, utils.Filename)         {}
 
 func (res *ResourceCompiler) GetPayloadOutput(u *compile.Unit, payload compile.PayloadType, file utils.Filename) utils.Filename {
 	return file.ReplaceExt(res.Extname(payload))
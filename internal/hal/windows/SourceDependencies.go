@@ -3,10 +3,16 @@
 package windows
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/poppolopoppo/ppb/action"
 	"github.com/poppolopoppo/ppb/internal/base"
+	"github.com/poppolopoppo/ppb/internal/hal/generic"
+	internal_io "github.com/poppolopoppo/ppb/internal/io"
 	"github.com/poppolopoppo/ppb/utils"
 )
 
@@ -38,7 +44,30 @@ type MsvcSourceDependencies struct {
 }
 
 func (x *MsvcSourceDependencies) Load(r io.Reader) error {
-	return base.JsonDeserialize(x, r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// some versions of cl.exe prepend an utf-8 byte order mark, or leave the file empty when interrupted
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return errors.New("empty json file")
+	}
+
+	// unknown fields are allowed here, since newer versions of cl.exe can add more informations
+	if err := json.Unmarshal(data, x); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return fmt.Errorf("invalid json at offset %d/%d: %w", syntaxErr.Offset, len(data), err)
+		}
+		return err
+	}
+
+	if !x.Data.Source.Valid() {
+		return fmt.Errorf("no source file found in json (version: %q)", x.Version)
+	}
+	return nil
 }
 func (x MsvcSourceDependencies) Files() (result []utils.Filename) {
 	result = x.Data.Includes.Normalize()
@@ -54,12 +83,28 @@ func (x MsvcSourceDependencies) Files() (result []utils.Filename) {
 	return
 }
 
+// MsvcSourceDependenciesError is returned when cl.exe outputted a malformed json file, which happens with some MSVC versions
+type MsvcSourceDependenciesError struct {
+	SourceFile utils.Filename
+	JsonFile   utils.Filename
+	Err        error
+}
+
+func (x MsvcSourceDependenciesError) Error() string {
+	return fmt.Sprintf("sourceDependencies: malformed %q for translation unit %q: %v", x.JsonFile, x.SourceFile, x.Err)
+}
+func (x MsvcSourceDependenciesError) Unwrap() error {
+	return x.Err
+}
+
 /***************************************
  * MsvcSourceDependenciesAction
  ***************************************/
 
 type MsvcSourceDependenciesAction struct {
+	SourceFile             utils.Filename
 	SourceDependenciesFile utils.Filename
+	GnuDepFile             utils.Filename
 	action.ActionRules
 }
 
@@ -69,6 +114,11 @@ func NewMsvcSourceDependenciesAction(model *action.ActionModel, output utils.Fil
 		SourceDependenciesFile: output,
 	}
 
+	// only used for diagnostics, translation unit is always the first input file
+	if len(model.StaticInputFiles) > 0 {
+		result.SourceFile = model.StaticInputFiles[0]
+	}
+
 	allowRelativePath := result.Options.Has(action.OPT_ALLOW_RELATIVEPATH)
 
 	result.Arguments.Append("/sourceDependencies", utils.MakeLocalFilenameIFP(output, allowRelativePath))
@@ -76,9 +126,50 @@ func NewMsvcSourceDependenciesAction(model *action.ActionModel, output utils.Fil
 	return result
 }
 
+// SetGnuDepFile makes the action convert parsed json to a GNU Make dependency file, which is tracked as an output
+// once written: it is not an output of the process, so it can't be checked with other action outputs
+func (x *MsvcSourceDependenciesAction) SetGnuDepFile(depFile utils.Filename) {
+	x.GnuDepFile = depFile
+}
+
 func (x *MsvcSourceDependenciesAction) Build(bc utils.BuildContext) error {
 	// compile the action with /sourceDependencies
-	return x.ActionRules.BuildWithSourceDependencies(bc, x)
+	err := x.ActionRules.BuildWithSourceDependencies(bc, x)
+
+	var malformed MsvcSourceDependenciesError
+	if !errors.As(err, &malformed) {
+		return err
+	}
+
+	// fallback to IO detouring to track dependencies of this action, when available
+	if internal_io.OnRunCommandWithDetours != nil {
+		base.LogWarning(LogWindows, "%v, fallback to IO detouring", err)
+		bc.Annotate(utils.AnnocateBuildComment(`DETOURS`))
+		if err = x.ActionRules.Build(bc); err != nil {
+			return err
+		}
+	} else {
+		// otherwise remove json file so this action will be built again next time, since its dependencies are unknown
+		base.LogWarning(LogWindows, "%v, action will be rebuilt until its dependencies can be parsed", err)
+		if err = utils.UFS.Remove(x.SourceDependenciesFile); err != nil {
+			return err
+		}
+	}
+
+	// dependencies are unknown to external tools, so the depfile also references the json file: when missing, or
+	// rewritten by next compilation, the translation unit is considered outdated
+	return x.writeGnuDepFile(bc, utils.FileSet{x.SourceDependenciesFile})
+}
+
+func (x *MsvcSourceDependenciesAction) writeGnuDepFile(bc utils.BuildContext, dependentFiles utils.FileSet) error {
+	if !x.GnuDepFile.Valid() {
+		return nil
+	}
+	depFile := generic.GnuDepFile{Dependencies: append(utils.FileSet{x.SourceFile}, dependentFiles...)}
+	if err := depFile.Save(x.GnuDepFile, x.GetGeneratedFile()); err != nil {
+		return err
+	}
+	return bc.OutputFile(x.GnuDepFile)
 }
 
 func (x *MsvcSourceDependenciesAction) GetActionSourceDependencies(bc utils.BuildContext) (sourceFiles utils.FileSet, err error) {
@@ -90,6 +181,11 @@ func (x *MsvcSourceDependenciesAction) GetActionSourceDependencies(bc utils.Buil
 	// parse source dependencies outputted by cl.exe
 	var sourceDeps MsvcSourceDependencies
 	if err = utils.UFS.OpenBuffered(x.SourceDependenciesFile, sourceDeps.Load); err != nil {
+		err = MsvcSourceDependenciesError{
+			SourceFile: x.SourceFile,
+			JsonFile:   x.SourceDependenciesFile,
+			Err:        err,
+		}
 		return
 	}
 
@@ -99,10 +195,17 @@ func (x *MsvcSourceDependenciesAction) GetActionSourceDependencies(bc utils.Buil
 		return base.PrettyPrint(dependentFiles)
 	}))
 
+	// convert to a GNU Make dependency file when requested, for tools which can't parse cl.exe json
+	if err = x.writeGnuDepFile(bc, dependentFiles); err != nil {
+		return
+	}
+
 	return dependentFiles, nil
 }
 
 func (x *MsvcSourceDependenciesAction) Serialize(ar base.Archive) {
+	ar.Serializable(&x.SourceFile)
 	ar.Serializable(&x.SourceDependenciesFile)
+	ar.Serializable(&x.GnuDepFile)
 	ar.Serializable(&x.ActionRules)
 }
@@ -0,0 +1,57 @@
+//go:build windows
+
+package windows
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMsvcSourceDependenciesTruncated(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "sourceDependencies_truncated.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var sourceDeps MsvcSourceDependencies
+	err = sourceDeps.Load(f)
+	if err == nil {
+		t.Fatal("truncated json should not be parsed without error")
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("expected a json syntax error, got: %v", err)
+	}
+}
+
+func TestMsvcSourceDependenciesEmpty(t *testing.T) {
+	var sourceDeps MsvcSourceDependencies
+	if err := sourceDeps.Load(strings.NewReader(" \r\n")); err == nil {
+		t.Error("empty json should not be parsed without error")
+	}
+}
+
+func TestMsvcSourceDependenciesWithBOM(t *testing.T) {
+	input := "\xef\xbb\xbf" + `{
+	"Version": "1.2",
+	"Data": {
+		"Source": "c:\\src\\unit.cpp",
+		"Includes": ["c:\\src\\unit.h"],
+		"UnknownField": true
+	}
+}`
+
+	var sourceDeps MsvcSourceDependencies
+	if err := sourceDeps.Load(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	if files := sourceDeps.Files(); len(files) != 1 {
+		t.Errorf("invalid number of dependencies: %d != %d", len(files), 1)
+	}
+}
@@ -3,7 +3,9 @@
 package windows
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	//lint:ignore ST1001 ignore dot imports warning
 	. "github.com/poppolopoppo/ppb/compile"
@@ -45,6 +47,51 @@ func InitWindowsCompile() {
 	AllCompilerNames.Append(
 		CompilerName{PersistentVar: &compilerTypes[0]},
 		CompilerName{PersistentVar: &compilerTypes[1]})
+
+	AllCompilerDetectors.Add("MSVC", func(bc BuildContext, arch ArchType) (CompilerDetection, error) {
+		msvc, err := GetMsvcCompiler(arch).Need(bc)
+		if err != nil {
+			return CompilerDetection{}, err
+		}
+		return NewCompilerDetection(msvc, msvc.MSC_VER.String()+" ("+msvc.MinorVer+")", msvc.Host), nil
+	})
+	AllCompilerDetectors.Add("ClangCl", func(bc BuildContext, arch ArchType) (CompilerDetection, error) {
+		clang, err := GetClangCompiler(arch).Need(bc)
+		if err != nil {
+			return CompilerDetection{}, err
+		}
+		llvm, err := clang.GetLlvmProduct(bc)
+		if err != nil {
+			return CompilerDetection{}, err
+		}
+		return NewCompilerDetection(clang, llvm.Version, clang.Host), nil
+	})
+
+	// symbols are already stripped in a separate PDB with MSVC, so there is no "strip" action on Windows
+	AllPostLinkActions.Add("copy", &PostLinkCommand{
+		Executable: "cmd",
+		Arguments:  base.NewStringSet("/c", "copy", "/y", "%1", "%2"),
+	})
+
+	// commonly tuned msvc warnings, proposed when completing -Warning:Overrides
+	for id, description := range map[string]string{
+		"4100": "unreferenced formal parameter",
+		"4127": "conditional expression is constant",
+		"4189": "local variable is initialized but not referenced",
+		"4201": "nonstandard extension used: nameless struct/union",
+		"4245": "conversion from 'type1' to 'type2', signed/unsigned mismatch",
+		"4251": "class needs to have dll-interface to be used by clients of another class",
+		"4267": "conversion from 'size_t' to 'type', possible loss of data",
+		"4324": "structure was padded due to alignment specifier",
+		"4389": "signed/unsigned mismatch in comparison",
+		"4505": "unreferenced function with internal linkage has been removed",
+		"4701": "potentially uninitialized local variable used",
+		"4702": "unreachable code",
+		"4706": "assignment within conditional expression",
+		"5054": "operator between enumerations of different types is deprecated",
+	} {
+		AllCompilerWarnings.Add(id, description)
+	}
 }
 
 /***************************************
@@ -52,41 +99,56 @@ func InitWindowsCompile() {
  ***************************************/
 
 type WindowsFlags struct {
-	Compiler         CompilerType
-	Analyze          BoolVar
-	Insider          BoolVar
-	JustMyCode       BoolVar
-	LlvmToolchain    BoolVar
-	MscVer           MsvcVersion
-	PerfSDK          BoolVar
-	Permissive       BoolVar
-	StackSize        base.SizeInBytes
-	TranslateInclude BoolVar
-	UseAfterReturn   BoolVar
-	WindowsSDK       Directory
+	Compiler           CompilerType
+	Analyze            BoolVar
+	Extnames           PayloadExtnames
+	GnuDepFile         BoolVar
+	HotReloadLowerCase BoolVar
+	Insider            BoolVar
+	JustMyCode         BoolVar
+	LlvmToolchain      BoolVar
+	MscVer             MsvcVersion
+	MscMinVer          StringVar
+	MinWindowsVersion  StringVar
+	PdbPerObject       BoolVar
+	PerfSDK            BoolVar
+	Permissive         BoolVar
+	StackSize          base.SizeInBytes
+	TranslateInclude   BoolVar
+	UseAfterReturn     BoolVar
+	WindowsSDK         Directory
 }
 
 var GetWindowsFlags = NewCompilationFlags("WindowsCompilation", "windows-specific compilation flags", WindowsFlags{
-	Analyze:          base.INHERITABLE_FALSE,
-	Compiler:         COMPILER_MSVC,
-	Insider:          base.INHERITABLE_FALSE,
-	JustMyCode:       base.INHERITABLE_FALSE,
-	LlvmToolchain:    base.INHERITABLE_TRUE,
-	MscVer:           MSC_VER_LATEST,
-	PerfSDK:          base.INHERITABLE_FALSE,
-	Permissive:       base.INHERITABLE_FALSE,
-	StackSize:        2000000,
-	TranslateInclude: base.INHERITABLE_TRUE,
-	UseAfterReturn:   base.INHERITABLE_FALSE,
+	Analyze:            base.INHERITABLE_FALSE,
+	Compiler:           COMPILER_MSVC,
+	GnuDepFile:         base.INHERITABLE_FALSE,
+	HotReloadLowerCase: base.INHERITABLE_FALSE,
+	Insider:            base.INHERITABLE_FALSE,
+	JustMyCode:         base.INHERITABLE_FALSE,
+	LlvmToolchain:      base.INHERITABLE_TRUE,
+	MscVer:             MSC_VER_LATEST,
+	PdbPerObject:       base.INHERITABLE_FALSE,
+	PerfSDK:            base.INHERITABLE_FALSE,
+	Permissive:         base.INHERITABLE_FALSE,
+	StackSize:          2000000,
+	TranslateInclude:   base.INHERITABLE_TRUE,
+	UseAfterReturn:     base.INHERITABLE_FALSE,
 })
 
 func (flags *WindowsFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Persistent("Analyze", "enable/disable MSCV analysis", &flags.Analyze)
 	cfv.Persistent("Compiler", "select windows compiler", &flags.Compiler)
+	cfv.Persistent("Extnames", "override output file extensions, ex: EXECUTABLE=.exe,SHAREDLIB=.dll", &flags.Extnames)
+	cfv.Persistent("GnuDepFile", "also write GNU Make dependency files (.d) converted from /sourceDependencies, for external tools", &flags.GnuDepFile)
+	cfv.Persistent("HotReloadLowerCase", "name objects compiled for hot-reload in lowercase instead of a fingerprint of their path, since cl.exe lowercases them when recompiling", &flags.HotReloadLowerCase)
 	cfv.Persistent("Insider", "enable/disable support for pre-release toolchain", &flags.Insider)
 	cfv.Persistent("JustMyCode", "enable/disable MSCV just-my-code", &flags.JustMyCode)
 	cfv.Persistent("LlvmToolchain", "if enabled clang-cl will use llvm-lib and lld-link", &flags.LlvmToolchain)
 	cfv.Persistent("MscVer", "select MSVC toolchain version", &flags.MscVer)
+	cfv.Persistent("MscMinVer", "require a minimum Visual Studio product version (ex: 17.8), newest install satisfying it is selected", &flags.MscMinVer)
+	cfv.Persistent("MinWindowsVersion", "set minimum Windows version supported by outputs (vista, win7, win8, win81, win10 or major.minor), controls linker subsystem version and _WIN32_WINNT", &flags.MinWindowsVersion)
+	cfv.Persistent("PdbPerObject", "write a PDB per object with DEBUGINFO_SYMBOLS to avoid mspdbsrv.exe contention, at the cost of slower PDB merging when linking (ignored with PCH)", &flags.PdbPerObject)
 	cfv.Persistent("PerfSDK", "enable/disable Visual Studio Performance SDK", &flags.PerfSDK)
 	cfv.Persistent("Permissive", "enable/disable MSCV permissive", &flags.Permissive)
 	cfv.Persistent("StackSize", "set default thread stack size in bytes", &flags.StackSize)
@@ -95,6 +157,67 @@ func (flags *WindowsFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Persistent("WindowsSDK", "override Windows SDK install path (use latest otherwise)", &flags.WindowsSDK)
 }
 
+/***************************************
+ * Windows Version
+ ***************************************/
+
+type WindowsVersion struct {
+	Name  string
+	Major int
+	Minor int
+}
+
+var windowsVersions = []WindowsVersion{
+	{Name: "vista", Major: 6, Minor: 0},
+	{Name: "win7", Major: 6, Minor: 1},
+	{Name: "win8", Major: 6, Minor: 2},
+	{Name: "win81", Major: 6, Minor: 3},
+	{Name: "win10", Major: 10, Minor: 0},
+}
+
+func (x WindowsVersion) String() string {
+	return fmt.Sprintf("%s (%d.%d)", x.Name, x.Major, x.Minor)
+}
+func (x WindowsVersion) Less(o WindowsVersion) bool {
+	return x.Major < o.Major || (x.Major == o.Major && x.Minor < o.Minor)
+}
+
+// GetWin32Winnt returns the value expected by _WIN32_WINNT and WINVER, ex: 0x0601 for Windows 7
+func (x WindowsVersion) GetWin32Winnt() string {
+	return fmt.Sprintf("0x%02X%02X", x.Major, x.Minor)
+}
+
+// GetSubsystemVersion returns the suffix expected by /SUBSYSTEM, ex: ",6.01" for Windows 7
+func (x WindowsVersion) GetSubsystemVersion() string {
+	return fmt.Sprintf(",%d.%02d", x.Major, x.Minor)
+}
+
+func ParseWindowsVersion(in string) (WindowsVersion, error) {
+	for _, it := range windowsVersions {
+		if strings.EqualFold(in, it.Name) || in == fmt.Sprintf("%d.%d", it.Major, it.Minor) || in == fmt.Sprintf("%d.%02d", it.Major, it.Minor) {
+			return it, nil
+		}
+	}
+	return WindowsVersion{}, fmt.Errorf("windows: unsupported minimum Windows version %q, expected one of %v", in, windowsVersions)
+}
+
+// GetMinWindowsVersion returns false when no minimum version was requested, and linker/SDK defaults should be kept
+func (flags *WindowsFlags) GetMinWindowsVersion() (WindowsVersion, bool, error) {
+	if flags.MinWindowsVersion.IsInheritable() || len(flags.MinWindowsVersion.Get()) == 0 {
+		return WindowsVersion{}, false, nil
+	}
+	version, err := ParseWindowsVersion(flags.MinWindowsVersion.Get())
+	return version, err == nil, err
+}
+
+// GetSubsystemOption returns linker/librarian subsystem, with a version suffix when a minimum Windows version was requested
+func (flags *WindowsFlags) GetSubsystemOption() string {
+	if version, ok, _ := flags.GetMinWindowsVersion(); ok {
+		return "/SUBSYSTEM:WINDOWS" + version.GetSubsystemVersion()
+	}
+	return "/SUBSYSTEM:WINDOWS"
+}
+
 /***************************************
  * Windows Platform
  ***************************************/
@@ -141,32 +264,43 @@ func (win *WindowsPlatform) GetCompiler() BuildFactoryTyped[Compiler] {
 
 func makeWindowsPlatform(p *PlatformRules) {
 	p.Os = "Windows"
-	p.Defines.Append(
-		"PLATFORM_PC",
-		"PLATFORM_WINDOWS",
-		"WIN32", "__WINDOWS__",
+	p.Definitions.Append(
+		PlatformDefinition{Define: "PLATFORM_PC", Description: "target is a desktop computer"},
+		PlatformDefinition{Define: "PLATFORM_WINDOWS", Description: "target Windows operating system"},
+		PlatformDefinition{Define: "WIN32", Description: "target Windows api, defined for both 32 and 64-bit"},
+		PlatformDefinition{Define: "__WINDOWS__", Description: "target Windows operating system, legacy spelling"},
 	)
 	p.ForceIncludes.Append(UFS.Source.File("winnt_version.h"))
 }
 func getWindowsPlatform_X86() Platform {
 	p := &WindowsPlatform{}
 	p.Arch = Platform_X86.Arch
+	p.Instructions = Platform_X86.Instructions
 	p.Facet = NewFacet()
 	p.Facet.Append(Platform_X86)
+	p.Definitions.Append(Platform_X86.Definitions...)
 	makeWindowsPlatform(&p.PlatformRules)
 	p.PlatformAlias.PlatformName = "Win32"
-	p.Defines.Append("_WIN32", "__X86__")
+	p.Definitions.Append(
+		PlatformDefinition{Define: "_WIN32", Description: "target 32-bit Windows"},
+		PlatformDefinition{Define: "__X86__", Description: "target x86 architecture, legacy spelling"},
+	)
 	p.Exports.Add("Windows/Platform", "x86")
 	return p
 }
 func getWindowsPlatform_X64() Platform {
 	p := &WindowsPlatform{}
 	p.Arch = Platform_X64.Arch
+	p.Instructions = Platform_X64.Instructions
 	p.Facet = NewFacet()
 	p.Facet.Append(Platform_X64)
+	p.Definitions.Append(Platform_X64.Definitions...)
 	makeWindowsPlatform(&p.PlatformRules)
 	p.PlatformAlias.PlatformName = "Win64"
-	p.Defines.Append("_WIN64", "__X64__")
+	p.Definitions.Append(
+		PlatformDefinition{Define: "_WIN64", Description: "target 64-bit Windows"},
+		PlatformDefinition{Define: "__X64__", Description: "target x64 architecture, legacy spelling"},
+	)
 	p.Exports.Add("Windows/Platform", "x64")
 	return p
 }
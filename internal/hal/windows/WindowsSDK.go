@@ -4,6 +4,7 @@ package windows
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/poppolopoppo/ppb/compile"
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -68,6 +69,20 @@ type WindowsSDKInstall struct {
 	WindowsSDK
 }
 
+// Windows 10 SDK can't target anything older than Windows 7, and Windows 8.1 SDK does not know about Windows 10 APIs
+func checkMinWindowsVersionWithSDK(version WindowsVersion, sdk *WindowsSDKInstall) {
+	switch {
+	case strings.HasPrefix(sdk.Version, "10."):
+		if win7 := (WindowsVersion{Major: 6, Minor: 1}); version.Less(win7) {
+			base.LogWarning(LogWindows, "minimum Windows version %v is older than what WindowsSDK@%v supports (win7), outputs may not run on this version", version, sdk.Version)
+		}
+	case strings.HasPrefix(sdk.Version, "8.1"):
+		if win81 := (WindowsVersion{Major: 6, Minor: 3}); win81.Less(version) {
+			base.LogWarning(LogWindows, "minimum Windows version %v is newer than what WindowsSDK@%v supports (win81), APIs of this version won't be declared", version, sdk.Version)
+		}
+	}
+}
+
 func (x *WindowsSDKInstall) Alias() utils.BuildAlias {
 	return utils.MakeBuildAlias("HAL", "Windows", "SDK", x.MajorVer)
 }
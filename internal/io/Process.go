@@ -2,10 +2,14 @@ package io
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/poppolopoppo/ppb/utils"
 
@@ -90,6 +94,7 @@ type ProcessOnSpinnerFunc = func(executable utils.Filename, arguments base.Strin
 
 type ProcessOptions struct {
 	Environment     ProcessEnvironment
+	SanitizeEnv     bool
 	OnFileAccess    base.EventDelegate[FileAccessRecord]
 	OnOutput        base.EventDelegate[string]
 	WorkingDir      utils.Directory
@@ -100,6 +105,7 @@ type ProcessOptions struct {
 	UseResponseFile bool
 	NewProcessGroup bool
 	ExitCodeRef     *int32
+	Timeout         time.Duration
 }
 
 type ProcessOptionFunc func(*ProcessOptions)
@@ -129,6 +135,14 @@ func OptionProcessEnvironment(environment ProcessEnvironment) ProcessOptionFunc
 		po.Environment.Overwrite(environment)
 	}
 }
+func OptionProcessSanitizeEnvironment(po *ProcessOptions) {
+	po.SanitizeEnv = true
+}
+func OptionProcessSanitizeEnvironmentIf(enabled bool) ProcessOptionFunc {
+	return func(po *ProcessOptions) {
+		po.SanitizeEnv = enabled
+	}
+}
 func OptionProcessExitCode(exitCodeRef *int32) ProcessOptionFunc {
 	return func(po *ProcessOptions) {
 		po.ExitCodeRef = exitCodeRef
@@ -159,6 +173,13 @@ func OptionProcessOutput(onOuptut base.EventDelegate[string]) ProcessOptionFunc
 		po.OnOutput = onOuptut
 	}
 }
+func OptionProcessOutputIf(enabled bool, onOuptut base.EventDelegate[string]) ProcessOptionFunc {
+	return func(po *ProcessOptions) {
+		if enabled {
+			po.OnOutput = onOuptut
+		}
+	}
+}
 func OptionProcessWorkingDir(value utils.Directory) ProcessOptionFunc {
 	return func(po *ProcessOptions) {
 		po.WorkingDir = value
@@ -212,6 +233,13 @@ func OptionProcessNewProcessGroupIf(enabled bool) ProcessOptionFunc {
 	}
 }
 
+// OptionProcessTimeout kills the process when it runs longer than given duration (ignored when zero)
+func OptionProcessTimeout(timeout time.Duration) ProcessOptionFunc {
+	return func(po *ProcessOptions) {
+		po.Timeout = timeout
+	}
+}
+
 /***************************************
  * RunProcess
  ***************************************/
@@ -235,17 +263,7 @@ func RunProcess(executable utils.Filename, arguments base.StringSet, userOptions
 
 	if options.UseResponseFile {
 		tempFile, err := utils.UFS.CreateTemp("ResponseFiles", func(w io.Writer) error {
-			for i, a := range arguments {
-				if i > 0 {
-					if _, err := w.Write(base.UnsafeBytesFromString(" ")); err != nil {
-						return err
-					}
-				}
-				if _, err := w.Write(base.UnsafeBytesFromString(base.EscapeCommandLineArg(a))); err != nil {
-					return err
-				}
-			}
-			return nil
+			return WriteResponseFile(w, utils.LongPathArguments(arguments))
 		}, base.TransientPage4KiB)
 		if err != nil {
 			return err
@@ -270,6 +288,9 @@ func RunProcess(executable utils.Filename, arguments base.StringSet, userOptions
 		return base.JoinString("\", \"", arguments)
 	})).Close()
 
+	// long paths are prefixed only when spawning the process, logged/serialized arguments are left untouched
+	arguments = utils.LongPathArguments(arguments)
+
 	if options.OnFileAccess != nil && OnRunCommandWithDetours != nil {
 		return OnRunCommandWithDetours(executable, arguments, &options)
 	} else if options.AttachDebugger && OnRunCommandWithDebugger != nil {
@@ -279,9 +300,44 @@ func RunProcess(executable utils.Filename, arguments base.StringSet, userOptions
 	}
 }
 
+// WriteResponseFile writes arguments escaped for current host, as expected by `@file` command-line argument
+func WriteResponseFile(w io.Writer, arguments base.StringSet) error {
+	for i, a := range arguments {
+		if i > 0 {
+			if _, err := w.Write(base.UnsafeBytesFromString(" ")); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(base.UnsafeBytesFromString(base.EscapeCommandLineArg(a))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var ErrProcessTimeout = errors.New("process timed out")
+
 func RunProcess_Vanilla(executable utils.Filename, arguments base.StringSet, options *ProcessOptions) (err error) {
-	cmd := exec.Command(executable.String(), arguments...)
-	cmd.Env = append(cmd.Env, options.Environment.Export()...)
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+
+		defer func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w after %v: %q", ErrProcessTimeout, options.Timeout, executable)
+			}
+		}()
+	}
+
+	cmd := exec.CommandContext(ctx, executable.String(), arguments...)
+	if options.SanitizeEnv {
+		// never nil: an empty environment must not fallback on inheriting from host
+		cmd.Env = options.Environment.Sanitize(ProcessEnvironmentAllowList...).Export()
+	} else {
+		cmd.Env = append(cmd.Env, options.Environment.Export()...)
+	}
 
 	if len(options.WorkingDir.Path) > 0 {
 		cmd.Dir = options.WorkingDir.String()
@@ -408,6 +464,17 @@ func NewProcessEnvironment() ProcessEnvironment {
 	return ProcessEnvironment([]EnvironmentDefinition{})
 }
 
+// Clone returns a deep copy, since Append() modifies values of existing variables in place
+func (x ProcessEnvironment) Clone() ProcessEnvironment {
+	result := make(ProcessEnvironment, len(x))
+	for i, it := range x {
+		result[i] = EnvironmentDefinition{
+			Name:   it.Name,
+			Values: base.CopySlice(it.Values...),
+		}
+	}
+	return result
+}
 func (x ProcessEnvironment) Export() []string {
 	result := make([]string, len(x))
 	for i, it := range x {
@@ -431,7 +498,7 @@ func (x *ProcessEnvironment) Append(name string, values ...string) {
 	} else {
 		*x = append(*x, EnvironmentDefinition{
 			Name:   EnvironmentVar(name),
-			Values: values,
+			Values: base.CopySlice(values...), // values could be appended later, and should not alias the caller
 		})
 	}
 }
@@ -446,6 +513,51 @@ func (x *ProcessEnvironment) Overwrite(other ProcessEnvironment) {
 		x.Append(it.Name.String(), it.Values...)
 	}
 }
+
+// Replace sets values of variables from other, instead of appending them to existing values like Overwrite()
+func (x *ProcessEnvironment) Replace(other ProcessEnvironment) {
+	for _, it := range other {
+		if i, ok := x.IndexOf(it.Name.String()); ok {
+			(*x)[i].Values = base.CopySlice(it.Values...)
+		} else {
+			x.Append(it.Name.String(), it.Values...)
+		}
+	}
+}
 func (x *ProcessEnvironment) Serialize(ar base.Archive) {
 	base.SerializeSlice(ar, (*[]EnvironmentDefinition)(x))
 }
+
+// minimal set of host variables forwarded to sanitized processes, when not explicitly declared
+var ProcessEnvironmentAllowList = []string{
+	"SystemRoot",
+	"TEMP",
+	"TMP",
+	"TMPDIR",
+}
+
+// Sanitize returns a copy of the environment without host references (`%NAME%`), completed
+// with host values of allowed variables only when they were not already declared.
+func (x ProcessEnvironment) Sanitize(allowList ...string) ProcessEnvironment {
+	result := make(ProcessEnvironment, 0, len(x)+len(allowList))
+	for _, it := range x {
+		values := make(base.StringSet, 0, len(it.Values))
+		for _, value := range it.Values {
+			if len(value) > 2 && strings.HasPrefix(value, "%") && strings.HasSuffix(value, "%") {
+				base.LogVeryVerbose(LogProcess, "sanitize: ignore host reference %q in %q", value, it.Name)
+				continue
+			}
+			values = append(values, value)
+		}
+		result.Append(it.Name.String(), values...)
+	}
+	for _, name := range allowList {
+		if _, ok := result.IndexOf(name); ok {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			result.Append(name, value)
+		}
+	}
+	return result
+}
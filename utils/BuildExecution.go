@@ -19,6 +19,7 @@ type BuildOptions struct {
 	Force                    bool
 	Recursive                bool
 	NoWarningOnMissingOutput bool
+	HighPriority             bool // propagated to dependencies, so external processes they run are queued first
 }
 
 type BuildOptionFunc func(*BuildOptions)
@@ -38,6 +39,7 @@ type BuildContext interface {
 	CheckForAbort() error
 
 	GetStaticDependencyBuildResults() []BuildResult
+	GetPreviousBuildStamp() BuildStamp // Content is invalid when node was never built
 
 	NeedBuildAliasables(n int, buildAliasables func(int) BuildAliasable, onBuildResult func(int, BuildResult) error) error
 	NeedBuildResult(...BuildResult)
@@ -98,6 +100,58 @@ func (x buildDependencyError) Error() string {
 	return fmt.Sprintf("%s dependency of node %q failed with:\n\t%v", x.link, x.alias, x.inner)
 }
 
+// buildFailures collects nodes which failed to build with -KeepGoing or -MaxErrors, dependent nodes are skipped and not recorded
+type buildFailures struct {
+	barrier sync.Mutex
+	errors  []buildExecuteError
+}
+
+// add returns the number of distinct failed nodes
+func (x *buildFailures) add(err buildExecuteError) int {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+	for _, it := range x.errors {
+		if it.alias.Equals(err.alias) {
+			return len(x.errors)
+		}
+	}
+	x.errors = append(x.errors, err)
+	return len(x.errors)
+}
+func (x *buildFailures) join(numFailedTargets, numTargets, maxErrors int) error {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+	if len(x.errors) == 0 {
+		return nil
+	}
+	return buildKeepGoingError{
+		errors:           append([]buildExecuteError{}, x.errors...),
+		numFailedTargets: numFailedTargets,
+		numTargets:       numTargets,
+		stoppedEarly:     maxErrors > 0 && len(x.errors) >= maxErrors,
+	}
+}
+
+type buildKeepGoingError struct {
+	errors           []buildExecuteError
+	numFailedTargets int
+	numTargets       int
+	stoppedEarly     bool
+}
+
+func (x buildKeepGoingError) Error() string {
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "%d/%d target(s) failed, after %d node(s) failed to build", x.numFailedTargets, x.numTargets, len(x.errors))
+	if x.stoppedEarly {
+		sb.WriteString(" (stopped early: reached -MaxErrors, remaining nodes were not built)")
+	}
+	sb.WriteRune(':')
+	for _, it := range x.errors {
+		fmt.Fprintf(&sb, "\n\t- %v", it)
+	}
+	return sb.String()
+}
+
 func makeBuildExecuteContext(g *buildGraphWritePort, node *buildNode, options *BuildOptions) (result buildExecuteContext) {
 	result = buildExecuteContext{
 		buildGraphWritePort: g,
@@ -133,8 +187,10 @@ func (x *buildExecuteContext) prepareStaticDependencies_rlock() error {
 }
 
 func (x *buildExecuteContext) buildOutputFiles_assumeLocked() base.Future[[]BuildResult] {
-	results := make([]BuildResult, 0, len(x.node.OutputFiles))
-	for _, it := range x.node.OutputFiles {
+	results := make([]BuildResult, len(x.node.OutputFiles))
+	paths := make([]Filename, 0, len(x.node.OutputFiles))
+	untrusted := make([]int, 0, len(x.node.OutputFiles))
+	for i, it := range x.node.OutputFiles {
 		node, err := x.Expect(it.Alias)
 		if err != nil {
 			return base.MakeFutureError[[]BuildResult](err)
@@ -143,17 +199,30 @@ func (x *buildExecuteContext) buildOutputFiles_assumeLocked() base.Future[[]Buil
 		file, ok := node.GetBuildable().(*FileDependency)
 		base.AssertIn(ok, true)
 
-		fileStamp, err := buildFileStampWithoutDeps(file.Filename)
-		if err != nil {
-			return base.MakeFutureError[[]BuildResult](err)
-		}
-
-		results = append(results, BuildResult{
+		results[i] = BuildResult{
 			BuildAlias: it.Alias,
 			Buildable:  file,
-			BuildStamp: fileStamp,
-		})
+		}
+
+		if stamp, trusted := trustBuildOutputFileStamp(file); trusted {
+			results[i].BuildStamp = stamp
+			numBuildOutputsTrusted.Add(1)
+		} else {
+			paths = append(paths, file.Filename)
+			untrusted = append(untrusted, i)
+		}
+	}
+
+	verifyStartedAt := time.Now()
+	fileStamps, err := buildFileStampsWithoutDeps(paths...)
+	if err != nil {
+		return base.MakeFutureError[[]BuildResult](err)
 	}
+	for i, stamp := range fileStamps {
+		results[untrusted[i]].BuildStamp = stamp
+	}
+	numBuildOutputsVerified.Add(int64(len(fileStamps)))
+	buildOutputsVerifyDuration.Add(int64(time.Since(verifyStartedAt)))
 	return base.MakeFutureLiteral(results)
 }
 func (x *buildExecuteContext) needToBuild_assumeLocked() (bool, error) {
@@ -323,8 +392,15 @@ func (x *buildExecuteContext) Execute(state *buildState) (BuildResult, bool, err
 
 		err = buildExecuteError{alias: x.Alias(), inner: err}
 
-		// abort every other build if stop-on-error is enabled
-		if GetCommandFlags().StopOnError.Get() {
+		// abort every other build if stop-on-error is enabled, or record the failure to report it when build finished
+		flags := GetCommandFlags()
+		if maxErrors := flags.MaxErrors.Get(); flags.KeepGoing.Get() || maxErrors > 0 {
+			if numFailures := x.failures.add(err.(buildExecuteError)); maxErrors > 0 && numFailures == maxErrors {
+				base.LogError(LogBuildGraph, "%d node(s) failed to build, stop scheduling new nodes (-MaxErrors=%d)", numFailures, maxErrors)
+				x.Abort(fmt.Errorf("reached maximum number of failed nodes (-MaxErrors=%d)", maxErrors))
+			}
+		}
+		if !flags.KeepGoing.Get() && flags.StopOnError.Get() {
 			x.Abort(err)
 		}
 
@@ -342,6 +418,9 @@ func (x *buildExecuteContext) Alias() BuildAlias {
 func (x *buildExecuteContext) GetBuildOptions() *BuildOptions {
 	return x.options
 }
+func (x *buildExecuteContext) GetPreviousBuildStamp() BuildStamp {
+	return x.previousStamp
+}
 func (x *buildExecuteContext) GetStaticDependencyBuildResults() []BuildResult {
 	x.barrier.Lock()
 	defer x.barrier.Unlock()
@@ -476,7 +555,9 @@ func (x *buildExecuteContext) OutputFile(files ...Filename) error {
 	x.lock_for_dependency()
 	defer x.unlock_for_dependency()
 
-	for _, it := range files {
+	outputFiles := make([]*FileDependency, len(files))
+	outputPaths := make([]Filename, len(files))
+	for i, it := range files {
 		it = it.Normalize()
 
 		base.LogDebug(LogBuildGraph, "%v: output file %q", x.Alias(), it)
@@ -491,15 +572,20 @@ func (x *buildExecuteContext) OutputFile(files ...Filename) error {
 			return err
 		}
 
-		// output files are an exception: we need file build stamp to track external modifications
-		// in the creator, but we also need to add a dependency from the creator on the file, creating a recursion.
-		// to avoid looping (actually more dead-locking) we compute the build stamp of the file without
-		// actually building its node:
-		fileStamp, err := buildFileStampWithoutDeps(file.Filename)
-		if err != nil {
-			return err
-		}
-		x.node.addOutputFile_AssumeLocked(file.Alias(), fileStamp)
+		outputFiles[i] = file
+		outputPaths[i] = file.Filename
+	}
+
+	// output files are an exception: we need file build stamp to track external modifications
+	// in the creator, but we also need to add a dependency from the creator on the file, creating a recursion.
+	// to avoid looping (actually more dead-locking) we compute the build stamp of the file without
+	// actually building its node:
+	fileStamps, err := buildFileStampsWithoutDeps(outputPaths...)
+	if err != nil {
+		return err
+	}
+	for i, file := range outputFiles {
+		x.node.addOutputFile_AssumeLocked(file.Alias(), fileStamps[i])
 	}
 
 	return nil
@@ -570,6 +656,7 @@ func makeBuildGraphContext(g *buildGraphWritePort, options *BuildOptions) buildG
 
 func (x buildGraphContext) GetBuildOptions() *BuildOptions                         { return x.options }
 func (x buildGraphContext) GetStaticDependencyBuildResults() (empty []BuildResult) { return }
+func (x buildGraphContext) GetPreviousBuildStamp() (empty BuildStamp)              { return }
 
 func (x buildGraphContext) NeedBuildResult(...BuildResult) { /*NOOP*/ }
 func (x buildGraphContext) DependsOn(aliases ...BuildAlias) error {
@@ -683,8 +770,24 @@ func (x *buildGraphContext) OnBuilt(e func(BuildNode) error) {
  * Build Stamp
  ***************************************/
 
+// algorithm used for every build stamp, including content hashes of files: it can not change while a build graph is loaded
+var buildFingerprintAlgorithm = base.FINGERPRINT_SHA256
+
+func GetBuildFingerprintAlgorithm() base.FingerprintAlgorithm {
+	return buildFingerprintAlgorithm
+}
+func SetBuildFingerprintAlgorithm(algorithm base.FingerprintAlgorithm) {
+	buildFingerprintAlgorithm = algorithm
+	if algorithm != base.FINGERPRINT_SHA256 {
+		// stamps computed with another algorithm can't be compared: archives written with another algorithm will be discarded
+		base.SetArchiveSchemaVariant(fmt.Sprint("fingerprint:", algorithm))
+	} else {
+		base.SetArchiveSchemaVariant("")
+	}
+}
+
 func MakeBuildFingerprint(buildable Buildable) (result base.Fingerprint) {
-	result = base.SerializeFingerpint(buildable, GetProcessSeed())
+	result = base.SerializeFingerpintWith(buildFingerprintAlgorithm, buildable, GetProcessSeed())
 	if !result.Valid() {
 		base.LogPanic(LogBuildGraph, "buildgraph: invalid buildstamp for %q", buildable.Alias())
 	}
@@ -743,6 +846,7 @@ func (x *BuildOptions) Recurse(node BuildNode) (result BuildOptions) {
 	}
 	result.Caller = node
 	result.NoWarningOnMissingOutput = x.NoWarningOnMissingOutput
+	result.HighPriority = x.HighPriority
 
 	if x.Recursive {
 		result.Force = x.Force
@@ -826,6 +930,11 @@ func OptionBuildForceRecursive(opts *BuildOptions) {
 func OptionNoWarningOnMissingOutput(opts *BuildOptions) {
 	opts.NoWarningOnMissingOutput = true
 }
+func OptionBuildHighPriorityIf(highPriority bool) BuildOptionFunc {
+	return func(opts *BuildOptions) {
+		opts.HighPriority = highPriority
+	}
+}
 func OptionWarningOnMissingOutputIf(warn bool) BuildOptionFunc {
 	return func(bo *BuildOptions) {
 		bo.NoWarningOnMissingOutput = !warn
@@ -86,6 +86,7 @@ type BuildGraphReadPort interface {
 	GetDependencyInputFiles(recursive bool, queue ...BuildAlias) (FileSet, error)
 	GetDependencyOutputFiles(queue ...BuildAlias) (FileSet, error)
 	GetDependencyLinks(a BuildAlias, includeOutputs bool) ([]BuildDependencyLink, error)
+	GetTopologicalLevels(queue ...BuildAlias) ([]BuildAliases, error)
 }
 
 type BuildGraphWritePort interface {
@@ -98,6 +99,7 @@ type BuildGraphWritePort interface {
 	Create(buildable Buildable, staticDeps BuildAliases, options ...BuildOptionFunc) BuildNode
 	Build(alias BuildAliasable, options ...BuildOptionFunc) (BuildNode, base.Future[BuildResult])
 	BuildMany(aliases BuildAliases, options ...BuildOptionFunc) ([]BuildResult, error)
+	Touch(aliases BuildAliases) error
 
 	GetAggregatedBuildStats() BuildStats
 	GetBuildStats(node BuildNode) (BuildStats, bool)
@@ -105,6 +107,7 @@ type BuildGraphWritePort interface {
 	GetMostExpansiveNodes(n int, inclusive bool) []BuildState
 
 	RecordSummary(startedAt time.Time) BuildSummary
+	RecordNodeReports() []BuildNodeReport
 }
 
 type BuildGraph interface {
@@ -164,8 +167,9 @@ type buildGraphWritePortPrivate interface {
 type buildGraphWritePort struct {
 	buildGraphReadPort
 
-	state base.SharedMapT[BuildAlias, *buildState]
-	stats BuildStats
+	state    base.SharedMapT[BuildAlias, *buildState]
+	stats    BuildStats
+	failures buildFailures
 
 	numRunningTasks atomic.Int32
 }
@@ -242,6 +246,13 @@ func (g *buildGraph) PostLoad() {
 	}
 }
 func (g *buildGraph) Serialize(ar base.Archive) {
+	g.serializeNodes(ar)
+}
+
+// serializeNodes can save the graph while it is being built: nodes which are building are write-locked and can't be read,
+// they are skipped and returned count is not zero. Those nodes were dirtied before building anyway, and nodes depending
+// on them are pruned when loading (see pruneMissingDependencies).
+func (g *buildGraph) serializeNodes(ar base.Archive) (numSkipped int) {
 	var pinned []*buildNode
 	serialize := func(node **buildNode) {
 		*node = new(buildNode)
@@ -252,6 +263,20 @@ func (g *buildGraph) Serialize(ar base.Archive) {
 			ar.Serializable(*node)
 		}
 		pinned = g.nodes.Values()
+		locked := pinned[:0]
+		for _, node := range pinned {
+			if node.TryRLock() {
+				locked = append(locked, node)
+			} else {
+				numSkipped++
+			}
+		}
+		pinned = locked
+		defer func() {
+			for _, node := range pinned {
+				node.RUnlock()
+			}
+		}()
 		sort.Slice(pinned, func(i, j int) bool {
 			return pinned[i].BuildAlias.Compare(pinned[j].BuildAlias) < 0
 		})
@@ -264,11 +289,53 @@ func (g *buildGraph) Serialize(ar base.Archive) {
 		for _, node := range pinned {
 			g.nodes.Add(node.Alias(), node)
 		}
+
+		g.pruneMissingDependencies()
+	}
+	return
+}
+
+// pruneMissingDependencies repairs a graph saved while building: nodes with a missing static dependency are removed,
+// since they can't be built without it and will be created again by their factory, while nodes with other missing
+// dependencies are only dirtied
+func (g *buildGraph) pruneMissingDependencies() {
+	exist := func(aliases ...BuildAlias) bool {
+		for _, a := range aliases {
+			if _, ok := g.nodes.Get(a); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	for numPruned := -1; numPruned != 0; {
+		numPruned = 0
+		for _, node := range g.nodes.Values() {
+			if !exist(node.Static.Aliases()...) {
+				g.nodes.Delete(node.Alias())
+				numPruned++
+			} else if !exist(node.Dynamic.Aliases()...) || !exist(node.OutputFiles.Aliases()...) || !exist(node.OutputNodes...) {
+				node.makeDirty_AssumeLocked()
+				node.Static.makeDirty()
+				node.Stamp = BuildStamp{}
+				g.makeDirty(fmt.Sprintf("%v: dirtied node saved without its dependencies", node))
+			}
+		}
+		if numPruned > 0 {
+			g.makeDirty(fmt.Sprintf("pruned %d node(s) saved without their dependencies", numPruned))
+		}
 	}
 }
 func (g *buildGraph) Save(dst io.Writer) (err error) {
-	if err = base.CompressedArchiveFileWrite(dst, g.Serialize, base.TransientPage64KiB, base.TASKPRIORITY_HIGH); err == nil {
-		g.dirty.Store(false)
+	// cleared before serializing, so nodes built while saving still dirty the graph for next save
+	g.dirty.Store(false)
+
+	numSkipped := 0
+	if err = base.CompressedArchiveFileWrite(dst, func(ar base.Archive) {
+		numSkipped = g.serializeNodes(ar)
+	}, base.TransientPage64KiB, base.TASKPRIORITY_HIGH); err != nil {
+		g.makeDirty(err.Error())
+	} else if numSkipped > 0 {
+		g.makeDirty(fmt.Sprintf("skipped %d node(s) which were building while saving", numSkipped))
 	}
 	return
 }
@@ -470,6 +537,64 @@ func (g *buildGraphReadPort) GetDependencyOutputFiles(queue ...BuildAlias) (File
 	return files, nil
 }
 
+// GetTopologicalLevels returns waves of nodes which can be built concurrently: each node only depends
+// on nodes from previous levels, following static and dynamic edges like launchBuildMany() does.
+func (g *buildGraphReadPort) GetTopologicalLevels(queue ...BuildAlias) ([]BuildAliases, error) {
+	levels := make(map[BuildAlias]int, 32)
+
+	var visit func(a BuildAlias, path []BuildAlias) (int, error)
+	visit = func(a BuildAlias, path []BuildAlias) (int, error) {
+		if level, ok := levels[a]; ok {
+			if level < 0 {
+				return 0, fmt.Errorf("topological-levels: found a dependency cycle with %v", append(path, a))
+			}
+			return level, nil
+		}
+		levels[a] = -1 // mark as visiting, to detect cycles
+
+		node, err := g.findNode(a)
+		if err != nil {
+			return 0, err
+		}
+
+		node.RLock()
+		dependencies := append(node.Static.Aliases(), node.Dynamic.Aliases()...)
+		node.RUnlock()
+
+		level := 0
+		for _, dep := range dependencies {
+			depLevel, err := visit(dep, append(path, a))
+			if err != nil {
+				return 0, err
+			}
+			level = max(level, depLevel+1)
+		}
+
+		levels[a] = level
+		return level, nil
+	}
+
+	for _, a := range queue {
+		if _, err := visit(a, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []BuildAliases
+	for a, level := range levels {
+		for len(result) <= level {
+			result = append(result, BuildAliases{})
+		}
+		result[level].Append(a)
+	}
+	for _, it := range result {
+		sort.Slice(it, func(i, j int) bool {
+			return it[i].Compare(it[j]) < 0
+		})
+	}
+	return result, nil
+}
+
 func (g *buildGraphReadPort) GetDependencyChain(src, dst BuildAlias, weight func(BuildDependencyLink) float32) ([]BuildDependencyLink, error) {
 	// https://en.wikipedia.org/wiki/Dijkstra%27s_algorithm#:~:text=in%20some%20topologies.-,Pseudocode,-%5Bedit%5D
 
@@ -702,9 +827,49 @@ func (g *buildGraphWritePort) BuildMany(targets BuildAliases, options ...BuildOp
 			return nil
 		},
 		options...)
+
+	// with -KeepGoing or -MaxErrors, report every failed node instead of only the last error
+	if flags := GetCommandFlags(); err != nil && (flags.KeepGoing.Get() || flags.MaxErrors.Get() > 0) {
+		numFailedTargets := 0
+		for _, br := range results {
+			if base.IsNil(br.Buildable) {
+				numFailedTargets++
+			}
+		}
+		if failures := g.failures.join(numFailedTargets, targets.Len(), flags.MaxErrors.Get()); failures != nil {
+			err = failures
+		}
+	}
 	return
 }
 
+func (g *buildGraphWritePort) Touch(targets BuildAliases) error {
+	// validate every alias before mutating the graph, so we don't touch a subset of the nodes
+	nodes := make([]*buildNode, targets.Len())
+	for i, a := range targets {
+		node, err := g.findNode(a)
+		if err != nil {
+			return fmt.Errorf("touch: unknown node %q", a)
+		}
+		nodes[i] = node
+	}
+
+	for _, node := range nodes {
+		base.LogVerbose(LogBuildGraph, "%v: touch node, will be rebuilt by next build", node.BuildAlias)
+
+		node.Lock()
+		node.makeDirty_AssumeLocked()
+		node.Static.makeDirty()
+		node.Stamp = BuildStamp{}
+		node.Unlock()
+	}
+
+	if len(nodes) > 0 {
+		g.makeDirty("touched nodes")
+	}
+	return nil
+}
+
 func (g *buildGraphWritePort) hasRunningTasks() bool {
 	return g.numRunningTasks.Load() > 0
 }
@@ -829,6 +994,22 @@ func (g *buildGraphWritePort) GetMostExpansiveNodes(n int, inclusive bool) []Bui
  * Build Node Status
  ***************************************/
 
+func (x BuildStatus) String() string {
+	switch x {
+	case BUILDSTATUS_UNBUILT:
+		return "UNBUILT"
+	case BUILDSTATUS_BUILT:
+		return "BUILT"
+	case BUILDSTATUS_UPDATED:
+		return "UPDATED"
+	case BUILDSTATUS_UPTODATE:
+		return "UPTODATE"
+	default:
+		base.UnexpectedValue(x)
+		return ""
+	}
+}
+
 func (x BuildStatus) WasUpdated() bool {
 	switch x {
 	case BUILDSTATUS_UPDATED:
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"os"
 	"sync"
+	"time"
 
 	"github.com/poppolopoppo/ppb/internal/base"
 
@@ -18,9 +20,18 @@ type GlobalBuildGraph struct {
 
 	onBuildGraphLoadedEvent base.PublicEvent[BuildGraph]
 	onBuildGraphSavedEvent  base.PublicEvent[BuildGraph]
+
+	// intermediate saves are performed every -SaveInterval while building, final save on exit is always performed
+	checkpointTicker *time.Ticker
+	checkpointDone   chan struct{}
+	lastSavedAt      time.Time
+	numSaves         int
+	saveDuration     time.Duration
 }
 
 func (x *GlobalBuildGraph) Close() (err error) {
+	x.stopCheckpoints()
+
 	x.barrier.Lock()
 	defer x.barrier.Unlock()
 
@@ -46,13 +57,20 @@ func (x *GlobalBuildGraph) Get(env *CommandEnvT) BuildGraph {
 		base.LogPanicIfFailed(LogBuildGraph, err)
 
 		err = x.protected.LoadBuildGraph(env)
-		if err != nil {
+		if base.IsArchiveVersionError(err) {
+			base.LogWarning(LogBuildGraph, "discarding build graph database written by another version, everything will be rebuilt: %v", err)
+		} else if err != nil {
 			base.LogError(LogBuildGraph, "failed to load build graph database: %v", err)
 		}
 
 		base.LogPanicIfFailed(LogBuildGraph, x.onBuildGraphLoadedEvent.FireAndForget(x.protected.BuildGraph))
 
 		x.protected.BuildGraph.PostLoad()
+
+		x.lastSavedAt = time.Now()
+		if interval := GetCommandFlags().SaveInterval.Get(); interval > 0 {
+			x.startCheckpoints(env, time.Duration(interval)*time.Second)
+		}
 	}
 
 	return x.protected.BuildGraph
@@ -65,8 +83,68 @@ func (x *GlobalBuildGraph) Save(env *CommandEnvT) error {
 		return nil
 	}
 
+	err := x.save(env)
+	if x.numSaves > 0 {
+		// overhead of checkpoints, compared to process duration
+		elapsed := time.Since(env.StartedAt())
+		base.LogVerbose(LogBuildGraph, "saved build graph %d times, took %v total (%.2f%% of %v)", x.numSaves, x.saveDuration,
+			100*x.saveDuration.Seconds()/elapsed.Seconds(), elapsed)
+	}
+	return err
+}
+
+// checkpoints are saved from a timer while building: nodes being built are skipped (see buildGraph.Save()), and they
+// will dirty the graph again when finished, so the final save still records them
+func (x *GlobalBuildGraph) startCheckpoints(env *CommandEnvT, interval time.Duration) {
+	x.checkpointTicker = time.NewTicker(interval)
+	x.checkpointDone = make(chan struct{})
+
+	ticker, done := x.checkpointTicker, x.checkpointDone
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := x.checkpoint(env, interval); err != nil {
+					base.LogWarning(LogBuildGraph, "failed to checkpoint build graph: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+func (x *GlobalBuildGraph) stopCheckpoints() {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	if x.checkpointTicker != nil {
+		x.checkpointTicker.Stop()
+		close(x.checkpointDone)
+		x.checkpointTicker = nil
+	}
+}
+func (x *GlobalBuildGraph) checkpoint(env *CommandEnvT, interval time.Duration) error {
+	x.barrier.Lock()
+	defer x.barrier.Unlock()
+
+	if x.protected == nil || x.checkpointTicker == nil || !x.protected.BuildGraph.Dirty() || time.Since(x.lastSavedAt) < interval {
+		return nil
+	}
+
+	base.LogVerbose(LogBuildGraph, "checkpoint dirty build graph, last saved %v ago", time.Since(x.lastSavedAt))
+	return x.save(env)
+}
+func (x *GlobalBuildGraph) save(env *CommandEnvT) error {
+	dirty := x.protected.BuildGraph.Dirty()
+	startedAt := time.Now()
+
 	err := x.protected.SaveBuildGraph(env)
 	if err == nil {
+		if dirty {
+			x.lastSavedAt = time.Now()
+			x.saveDuration += x.lastSavedAt.Sub(startedAt)
+			x.numSaves++
+		}
 		err = x.onBuildGraphSavedEvent.FireAndForget(x.protected.BuildGraph)
 	}
 	return err
@@ -107,8 +185,11 @@ func (x *GlobalBuildGraph) OnBuildGraphSaved(e base.EventDelegate[BuildGraph]) e
  * processSafeBuildGraph: only 1 process is allowed to open the database
  ***************************************/
 
+// database is locked with a companion file, since it is replaced atomically when saved: a crash while saving can't
+// leave a truncated database behind
 type processSafeBuildGraph struct {
 	BuildGraph BuildGraph
+	Database   Filename
 	GlobalLock fslock.Handle
 }
 
@@ -117,7 +198,7 @@ func newProcessSafeBuildGraph(database Filename) (*processSafeBuildGraph, error)
 		return nil, err
 	}
 
-	globalLock, err := fslock.Lock(database.String())
+	globalLock, err := fslock.Lock(database.ReplaceExt(".lock").String())
 	if err != nil {
 		return nil, err
 	}
@@ -125,8 +206,9 @@ func newProcessSafeBuildGraph(database Filename) (*processSafeBuildGraph, error)
 	base.LogTrace(LogBuildGraph, "locked database file %q", globalLock.LockFile().Name())
 
 	return &processSafeBuildGraph{
-		GlobalLock: globalLock,
 		BuildGraph: NewBuildGraph(GetCommandFlags()),
+		Database:   database,
+		GlobalLock: globalLock,
 	}, nil
 }
 func (x *processSafeBuildGraph) Close() error {
@@ -142,15 +224,16 @@ func (x *processSafeBuildGraph) SaveBuildGraph(env *CommandEnvT) (err error) {
 		benchmark := base.LogBenchmark(LogCommand, "saving build graph to '%v'...", env.databasePath)
 		defer benchmark.Close()
 
-		handle := x.GlobalLock.LockFile()
-		if _, err = handle.Seek(0, 0); err != nil {
-			return
+		// written in a temporary file first, then renamed over previous database
+		tmp := x.Database.Dirname.File(x.Database.Basename + ".tmp")
+		if err = UFS.CreateFile(tmp, func(w *os.File) error {
+			return x.BuildGraph.Save(w)
+		}); err == nil {
+			err = UFS.Rename(tmp, x.Database)
 		}
-		if err = handle.Truncate(0); err != nil {
-			return
+		if err != nil {
+			UFS.Remove(tmp)
 		}
-
-		err = x.BuildGraph.Save(handle)
 	} else {
 		base.LogTrace(LogCommand, "skipped saving unmodified build graph")
 	}
@@ -160,21 +243,20 @@ func (x *processSafeBuildGraph) LoadBuildGraph(env *CommandEnvT) (err error) {
 	benchmark := base.LogBenchmark(LogCommand, "loading build graph from '%v'...", env.databasePath)
 	defer benchmark.Close()
 
-	handle := x.GlobalLock.LockFile()
-	if len, err := handle.Seek(0, 2); err != nil {
-		return err
-	} else if len == 0 {
+	info, err := x.Database.Info()
+	if err != nil || info.Size() == 0 {
 		x.BuildGraph.(*buildGraph).makeDirty("new database")
 		return nil
 	}
 
-	if _, err = handle.Seek(0, 0); err != nil {
-		return
-	}
-
-	err = x.BuildGraph.Load(handle)
+	err = UFS.OpenFile(x.Database, func(r *os.File) error {
+		return x.BuildGraph.Load(r)
+	})
 	if err != nil {
 		x.BuildGraph.(*buildGraph).makeDirty(err.Error())
+	} else {
+		// every output recorded in the graph was written before it was saved
+		setBuildOutputTrustedBefore(info.ModTime(), x.Database)
 	}
 	return
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -223,13 +224,16 @@ type BuildSummary struct {
 	AggregatedStats    BuildStats
 	MostExpansiveNodes []BuildNodeReport
 	CriticalPath       []BuildNodeReport
+	NumBuilt           int
+	NumUpToDate        int
+	NumFailed          int
 }
 
 func (g *buildGraphWritePort) RecordSummary(startedAt time.Time) BuildSummary {
 	totalDuration := time.Since(startedAt)
 	criticalPath, criticalDuration := g.GetCriticalPathNodes()
 
-	return BuildSummary{
+	summary := BuildSummary{
 		TotalDuration:      totalDuration,
 		CriticalDuration:   criticalDuration,
 		PortName:           g.PortName(),
@@ -237,6 +241,109 @@ func (g *buildGraphWritePort) RecordSummary(startedAt time.Time) BuildSummary {
 		MostExpansiveNodes: base.Map(newBuildNodeReport, g.GetMostExpansiveNodes(10, false)...),
 		CriticalPath:       base.Map(newBuildNodeReport, criticalPath...),
 	}
+
+	base.LogPanicIfFailed(LogBuildGraph, g.state.Range(func(_ BuildAlias, state *buildState) error {
+		future := state.future.Load()
+		if future == nil {
+			return nil // node was not executed by this port
+		}
+		if result, err := future.Join().Get(); err != nil {
+			summary.NumFailed++
+		} else {
+			switch result.Status {
+			case BUILDSTATUS_BUILT, BUILDSTATUS_UPDATED:
+				summary.NumBuilt++
+			case BUILDSTATUS_UPTODATE:
+				summary.NumUpToDate++
+			}
+		}
+		return nil
+	}))
+
+	return summary
+}
+
+// RecordNodeReports returns a report for every node executed by this port, sorted by alias
+func (g *buildGraphWritePort) RecordNodeReports() (reports []BuildNodeReport) {
+	base.LogPanicIfFailed(LogBuildGraph, g.state.Range(func(_ BuildAlias, state *buildState) error {
+		if state.future.Load() != nil {
+			reports = append(reports, newBuildNodeReport(state))
+		}
+		return nil
+	}))
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Alias.Compare(reports[j].Alias) < 0
+	})
+	return
+}
+
+// PrintBuildFooter prints a concise report of all given summaries, meant to be shown after every build
+func PrintBuildFooter(summaries []BuildSummary, slowest int) {
+	var footer BuildSummary
+	for _, it := range summaries {
+		footer.TotalDuration += it.TotalDuration
+		footer.NumBuilt += it.NumBuilt
+		footer.NumUpToDate += it.NumUpToDate
+		footer.NumFailed += it.NumFailed
+		footer.MostExpansiveNodes = append(footer.MostExpansiveNodes, it.MostExpansiveNodes...)
+	}
+
+	if footer.NumBuilt+footer.NumUpToDate+footer.NumFailed == 0 {
+		return
+	}
+
+	statusColor := base.ANSI_FG1_GREEN
+	if footer.NumFailed > 0 {
+		statusColor = base.ANSI_FG1_RED
+	}
+
+	base.LogForwardf("\n%vBuild finished in %.3f seconds:%v %d built, %d up-to-date, %d failed",
+		statusColor, footer.TotalDuration.Seconds(), base.ANSI_RESET,
+		footer.NumBuilt, footer.NumUpToDate, footer.NumFailed)
+
+	sort.SliceStable(footer.MostExpansiveNodes, func(i, j int) bool {
+		return footer.MostExpansiveNodes[i].Stats.Duration.Exclusive > footer.MostExpansiveNodes[j].Stats.Duration.Exclusive
+	})
+
+	for i, node := range footer.MostExpansiveNodes {
+		if i == slowest || node.Stats.Duration.Exclusive == 0 {
+			break
+		}
+		base.LogForwardf("%v[%02d]%v %7.3f  %v%v", base.ANSI_FAINT, i+1, base.ANSI_RESET,
+			node.Stats.Duration.Exclusive.Seconds(), node.Alias, node.Annotation)
+	}
+}
+
+// PrintThreadPoolUtilization prints a histogram of time spent with each number of busy workers: a low average
+// concurrency means the build is serialized by dependencies (ex: a link), while a high one means it is CPU-bound
+func PrintThreadPoolUtilization(utilization base.ThreadPoolUtilization, name string) {
+	if utilization.Duration == 0 {
+		return
+	}
+
+	idle := utilization.Idle()
+	base.LogForwardf("\nThread pool %q utilization over %.3f seconds with %d workers:", name, utilization.Duration.Seconds(), utilization.Arity)
+	base.LogForwardf("average concurrency x%.2f, peak %d, idle %.3f seconds (%.1f%%)",
+		utilization.Average(), utilization.Peak, idle.Seconds(), 100*idle.Seconds()/utilization.Duration.Seconds())
+
+	const barWidth = 40
+	for busy, d := range utilization.Durations {
+		fract := d.Seconds() / utilization.Duration.Seconds()
+		rowColor := base.NewColdHotColor(float64(busy) / float64(max(1, utilization.Arity)))
+
+		base.LogForwardf("%v[%02d]%v %v%-*s%v %6.2f%%  %7.3f",
+			base.ANSI_FAINT, busy, base.ANSI_RESET,
+			rowColor.Quantize(true).Ansi(true), barWidth, strings.Repeat("#", int(fract*barWidth+0.5)), base.ANSI_RESET,
+			100*fract, d.Seconds())
+	}
+
+	switch ratio := utilization.Average() / float64(max(1, utilization.Arity)); {
+	case ratio < 0.5:
+		base.LogForwardf("build was parallelism-starved: more jobs won't help, check the critical path with -Summary")
+	case ratio > 0.9:
+		base.LogForwardf("build was CPU-bound: more jobs could help, if host has more cores available")
+	}
 }
 
 func (g *BuildSummary) PrintSummary(level base.LogLevel) {
@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/poppolopoppo/ppb/internal/base"
@@ -25,6 +30,7 @@ func (x *Filename) Alias() BuildAlias {
 type FileDependency struct {
 	Filename
 	Size          int64
+	Digest        base.Fingerprint // only computed when content hash mode is enabled
 	SourceControl SourceControlState
 }
 
@@ -52,6 +58,7 @@ func (x *FileDependency) Build(bc BuildContext) (err error) {
 func (x *FileDependency) Serialize(ar Archive) {
 	ar.Serializable(&x.Filename)
 	ar.Int64(&x.Size)
+	ar.Serializable(&x.Digest)
 }
 
 func BuildFile(source Filename, staticDeps ...BuildAlias) BuildFactoryTyped[*FileDependency] {
@@ -78,6 +85,15 @@ func buildFileWithoutDeps(path Filename) (FileDependency, time.Time, error) {
 		Size:     info.Size(),
 	}
 
+	modTime := info.ModTime()
+	if enableBuildFileContentHash.Load() {
+		// mtime is ignored in this mode: only file content is tracked by build stamps
+		if file.Digest, err = getBuildFileContentHash(path, file.Size, modTime); err != nil {
+			return FileDependency{}, time.Time{}, err
+		}
+		modTime = time.Time{}
+	}
+
 	if scm := GetSourceControlProvider(); scm.IsInRepository(path) {
 		fileStatus := SourceControlFileStatus{Path: path}
 		if err = scm.GetFileStatus(&fileStatus); err == nil {
@@ -85,7 +101,7 @@ func buildFileWithoutDeps(path Filename) (FileDependency, time.Time, error) {
 		}
 	}
 
-	return file, info.ModTime(), err
+	return file, modTime, err
 }
 func buildFileStampWithoutDeps(path Filename) (BuildStamp, error) {
 	if file, modTime, err := buildFileWithoutDeps(path); err == nil {
@@ -95,6 +111,167 @@ func buildFileStampWithoutDeps(path Filename) (BuildStamp, error) {
 	}
 }
 
+// below this number of files, dispatching to workers costs more than computing stamps serially
+const buildFileStampParallelThreshold = 8
+
+// buildFileStampsWithoutDeps computes the same stamps than buildFileStampWithoutDeps, in the same order,
+// but dispatches files to a bounded worker pool: nodes with thousands of files would otherwise serialize on IO
+func buildFileStampsWithoutDeps(paths ...Filename) ([]BuildStamp, error) {
+	stamps := make([]BuildStamp, len(paths))
+	serialStamps := func(first, last int) error {
+		for i := first; i < last; i++ {
+			var err error
+			if stamps[i], err = buildFileStampWithoutDeps(paths[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	pool := base.GetIOHashThreadPool()
+	numChunks := pool.GetArity()
+	if len(paths) < buildFileStampParallelThreshold || numChunks < 2 {
+		return stamps, serialStamps(0, len(paths))
+	}
+
+	// one task per worker, with contiguous ranges of files, to keep scheduling overhead negligible
+	chunkSize := (len(paths) + numChunks - 1) / numChunks
+	futures := make([]base.Future[int], 0, numChunks)
+	for first := 0; first < len(paths); first += chunkSize {
+		last := min(first+chunkSize, len(paths))
+		futures = append(futures, base.MakeWorkerFuture(pool, func(base.ThreadContext) (int, error) {
+			return last - first, serialStamps(first, last)
+		}, base.TASKPRIORITY_NORMAL, base.ThreadPoolDebugId{Category: "BuildFileStamp", Arg: paths[first]}))
+	}
+
+	var lastErr error
+	for _, future := range futures {
+		// always join every future, so no task is still writing in stamps when returning
+		if err := future.Join().Failure(); err != nil {
+			lastErr = err
+		}
+	}
+	return stamps, lastErr
+}
+
+/***************************************
+ * Content hash mode, when file modification times are unreliable
+ ***************************************/
+
+var enableBuildFileContentHash atomic.Bool
+
+// hashing large inputs is expensive: computed digests are cached by (path, size, mtime),
+// so each file is only read once per process as long as it was not modified
+type buildFileContentHashKey struct {
+	Path    string
+	Size    int64
+	ModTime int64
+}
+
+var buildFileContentHashes = base.NewSharedMapT[buildFileContentHashKey, base.Fingerprint]()
+
+func SetBuildFileContentHash(enabled bool) {
+	enableBuildFileContentHash.Store(enabled)
+}
+
+func getBuildFileContentHash(path Filename, size int64, modTime time.Time) (base.Fingerprint, error) {
+	key := buildFileContentHashKey{
+		Path:    path.String(),
+		Size:    size,
+		ModTime: modTime.UnixNano(),
+	}
+	if digest, ok := buildFileContentHashes.Get(key); ok {
+		return digest, nil
+	}
+
+	digest, err := UFS.FingerprintWith(GetBuildFingerprintAlgorithm(), path, base.Fingerprint{})
+	if err == nil {
+		buildFileContentHashes.Add(key, digest)
+	}
+	return digest, err
+}
+
+/***************************************
+ * Trust output modification times, to skip hashing outputs in content hash mode
+ ***************************************/
+
+// outputs are written by ppb itself and rarely tampered with, so hashing thousands of them on every build is mostly wasted:
+// when enabled, an output whose size did not change and which was not modified after the build graph was last saved
+// reuses the digest recorded by the graph. An output modified externally while keeping both its size and an older mtime
+// won't be detected, this is the documented risk of this mode: to bound it, every output is still verified by content
+// once every N runs, counted in a file next to the build graph database.
+var enableBuildOutputTrustModTime atomic.Bool
+var buildOutputTrustFullVerifyEvery atomic.Int32
+var buildOutputTrustedBefore atomic.Int64
+
+var numBuildOutputsTrusted atomic.Int64
+var numBuildOutputsVerified atomic.Int64
+var buildOutputsVerifyDuration atomic.Int64
+
+func SetBuildOutputTrustModTime(enabled bool, fullVerifyEvery int) {
+	enableBuildOutputTrustModTime.Store(enabled)
+	buildOutputTrustFullVerifyEvery.Store(int32(fullVerifyEvery))
+}
+
+// GetBuildOutputTrustStats also returns time spent verifying outputs by content, to estimate time saved by trusted ones
+func GetBuildOutputTrustStats() (trusted, verified int64, verifyDuration time.Duration) {
+	return numBuildOutputsTrusted.Load(), numBuildOutputsVerified.Load(), time.Duration(buildOutputsVerifyDuration.Load())
+}
+
+// outputs modified after this time are suspicious, since build graph could not record their digest
+func setBuildOutputTrustedBefore(lastSaved time.Time, database Filename) {
+	if !enableBuildOutputTrustModTime.Load() {
+		return
+	}
+
+	// number of runs since last full verification, a missing or invalid counter triggers a full verification
+	counter := database.ReplaceExt(".trust")
+	numRuns := -1
+	if raw, err := UFS.ReadAll(counter); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(raw))); err == nil {
+			numRuns = n
+		}
+	}
+
+	if every := int(buildOutputTrustFullVerifyEvery.Load()); numRuns < 0 || (every > 0 && numRuns+1 >= every) {
+		base.LogVerbose(LogBuildGraph, "TrustOutputs: full verification of output files, last one was %d runs ago", numRuns+1)
+		buildOutputTrustedBefore.Store(0)
+		numRuns = 0
+	} else {
+		buildOutputTrustedBefore.Store(lastSaved.UnixNano())
+		numRuns++
+	}
+
+	if err := UFS.Create(counter, func(w io.Writer) error {
+		_, err := fmt.Fprint(w, numRuns)
+		return err
+	}); err != nil {
+		base.LogWarning(LogBuildGraph, "TrustOutputs: failed to update %q: %v", counter, err)
+	}
+}
+
+func trustBuildOutputFileStamp(previous *FileDependency) (BuildStamp, bool) {
+	if !enableBuildOutputTrustModTime.Load() || !enableBuildFileContentHash.Load() {
+		return BuildStamp{}, false // output stamps do not depend on content without content hash mode
+	}
+
+	trustedBefore := buildOutputTrustedBefore.Load()
+	if trustedBefore == 0 || !previous.Digest.Valid() {
+		return BuildStamp{}, false
+	}
+
+	path := previous.Filename
+	path.Invalidate()
+
+	info, err := path.Info()
+	if err != nil || info.Size() != previous.Size || info.ModTime().UnixNano() >= trustedBefore {
+		return BuildStamp{}, false
+	}
+
+	// same stamp than buildFileStampWithoutDeps() would compute for an unmodified file, so trusted outputs never trigger a rebuild
+	return MakeTimedBuildFingerprint(time.Time{}, previous), true
+}
+
 /***************************************
  * Track file creation
  ***************************************/
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeBuildFileStampsTestFiles(tb testing.TB, n int) []Filename {
+	dir := tb.TempDir()
+	files := make([]Filename, n)
+	for i := range files {
+		path := filepath.Join(dir, fmt.Sprintf("file_%04d.obj", i))
+		// vary sizes, so stamps of different files can't be equal by accident
+		if err := os.WriteFile(path, make([]byte, 64+i), 0644); err != nil {
+			tb.Fatal(err)
+		}
+		files[i] = MakeFilename(path)
+	}
+	return files
+}
+
+func buildFileStampsSerial(paths ...Filename) ([]BuildStamp, error) {
+	stamps := make([]BuildStamp, len(paths))
+	for i, it := range paths {
+		var err error
+		if stamps[i], err = buildFileStampWithoutDeps(it); err != nil {
+			return nil, err
+		}
+	}
+	return stamps, nil
+}
+
+func TestBuildFileStampsParallelMatchesSerial(t *testing.T) {
+	// below and above the threshold, to test both code paths
+	for _, n := range []int{buildFileStampParallelThreshold - 1, 257} {
+		files := makeBuildFileStampsTestFiles(t, n)
+
+		serial, err := buildFileStampsSerial(files...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parallel, err := buildFileStampsWithoutDeps(files...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(serial) != len(parallel) {
+			t.Fatalf("build file stamps: expected %d stamps, got %d", len(serial), len(parallel))
+		}
+		for i := range serial {
+			if serial[i] != parallel[i] {
+				t.Errorf("build file stamps: %q differs, serial %v vs parallel %v", files[i], serial[i], parallel[i])
+			}
+		}
+	}
+}
+
+func TestBuildFileStampsMissingFile(t *testing.T) {
+	files := makeBuildFileStampsTestFiles(t, 64)
+	files[42] = files[42].ReplaceExt(".missing")
+	if _, err := buildFileStampsWithoutDeps(files...); err == nil {
+		t.Error("build file stamps: expected an error for missing file")
+	}
+}
+
+func benchmarkBuildFileStamps(b *testing.B, stamps func(...Filename) ([]BuildStamp, error)) {
+	files := makeBuildFileStampsTestFiles(b, 4096)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := stamps(files...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildFileStampsSerial(b *testing.B) {
+	benchmarkBuildFileStamps(b, buildFileStampsSerial)
+}
+func BenchmarkBuildFileStampsParallel(b *testing.B) {
+	benchmarkBuildFileStamps(b, buildFileStampsWithoutDeps)
+}
@@ -3,8 +3,10 @@ package utils
 import (
 	"fmt"
 	"io"
+	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/poppolopoppo/ppb/internal/base"
 )
@@ -666,7 +668,7 @@ func (x *commandParsableArgument) Help(w *base.StructuredFile) {
 			switch v.Value.(type) {
 			case *StringVar, *Filename, *Directory:
 				colorFG = base.ANSI_FG1_YELLOW
-			case *IntVar, *BigIntVar:
+			case *IntVar, *IntRangeVar, *BigIntVar:
 				colorFG = base.ANSI_FG1_CYAN
 			case *BoolVar:
 				colorFG = base.ANSI_FG1_GREEN
@@ -902,7 +904,7 @@ func (x *commandItem) Parse(cl CommandLine) error {
 		}
 	}
 
-	// then detect unknown command flags (warning)
+	// then detect unknown command flags (warning, or error with -StrictFlags)
 	if !x.IsNaked() {
 		unknownFlags := []string{}
 		for i := 0; ; {
@@ -922,6 +924,10 @@ func (x *commandItem) Parse(cl CommandLine) error {
 			}
 		}
 		if len(unknownFlags) > 0 {
+			if GetCommandFlags().StrictFlags.Get() {
+				// global flags were parsed before any command, so this is already set
+				return fmt.Errorf("unknown command flags: %q", strings.Join(unknownFlags, ", "))
+			}
 			// report a warning about unknown flag: dont' die on thie
 			base.LogWarning(LogCommand, "unknown command flags: %q", strings.Join(unknownFlags, ", "))
 		}
@@ -971,7 +977,9 @@ func (x *commandItem) Usage() (format string) {
 }
 func (x *commandItem) Help(w *base.StructuredFile) {
 	if w.Minify() {
-		w.Println(" %s%-25s%s %s", base.ANSI_FG1_GREEN, x.Name, base.ANSI_RESET, x.Description)
+		w.Print(" %s%s%s", base.ANSI_FG1_GREEN, x.Name, base.ANSI_RESET)
+		w.Align(30)
+		w.Println("%s", x.Description)
 	} else {
 		w.Println("%s", x.Usage())
 		w.ScopeIndent(func() {
@@ -1142,11 +1150,27 @@ func FindCommand(name string) (CommandItem, error) {
 	}
 }
 
+// newHelpStructuredFile adapts help columns to terminal width, and strips ansi codes when output is not interactive
+func newHelpStructuredFile(w io.Writer, minify bool) (*base.StructuredFile, func()) {
+	f := base.NewStructuredFile(w, "  ", minify)
+	f.SetWidth(base.GetTerminalWidth())
+
+	restoreAnsiColor := base.EnableAnsiColor()
+	if !base.EnableInteractiveShell() {
+		base.SetEnableAnsiColor(false)
+	}
+
+	return f, func() {
+		base.SetEnableAnsiColor(restoreAnsiColor)
+	}
+}
+
 func PrintCommandHelp(w io.Writer, detailed bool) {
 	restoreLogLevel := base.GetLogger().SetLevelMaximum(base.LOG_VERBOSE)
 	defer base.GetLogger().SetLevel(restoreLogLevel)
 
-	f := base.NewStructuredFile(w, "  ", !detailed)
+	f, restoreAnsiColor := newHelpStructuredFile(w, !detailed)
+	defer restoreAnsiColor()
 	pi := GetProcessInfo()
 
 	f.Print(`%v  v.%v  [%v]
@@ -1157,7 +1181,7 @@ build-system for PoPpOlOpPoPo Engine`,
 		f.Print("%v%v", base.ANSI_FG1_MAGENTA, base.ANSI_FAINT)
 		f.Pad(2, "-")
 		f.Print(" %s ", title)
-		f.Pad(80, "-")
+		f.Pad(f.Width(), "-")
 		f.Println("%v", base.ANSI_RESET)
 	}
 
@@ -1250,7 +1274,8 @@ func (x *HelpCommand) Run(cc CommandContext) (err error) {
 	if cmd == nil {
 		PrintCommandHelp(w, base.IsLogLevelActive(base.LOG_VERBOSE))
 	} else {
-		f := base.NewStructuredFile(w, "  ", false)
+		f, restoreAnsiColor := newHelpStructuredFile(w, false)
+		defer restoreAnsiColor()
 
 		f.Println("")
 		f.ScopeIndent(func() {
@@ -1385,14 +1410,68 @@ var CommandAutoComplete = NewCommandable(
  * Show Version
  ***************************************/
 
-var CommandBuildVersion = NewCommand(
+type BuildVersionInfo struct {
+	Path      string
+	Version   string
+	Timestamp time.Time
+	Seed      string
+	GoVersion string
+	Os        string
+	Arch      string
+	Features  base.StringSet
+}
+
+func GetBuildVersionInfo() (result BuildVersionInfo) {
+	pi := GetProcessInfo()
+	result = BuildVersionInfo{
+		Path:      pi.Path,
+		Version:   pi.Version,
+		Timestamp: pi.Timestamp,
+		Seed:      GetProcessSeed().String(),
+		GoVersion: runtime.Version(),
+		Os:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Features:  base.StringSet{},
+	}
+
+	// features depending on build tags, which can change the behavior of the program
+	if base.DEBUG_ENABLED {
+		result.Features.Append("debug")
+	}
+	if base.TRACE_ENABLED {
+		result.Features.Append("trace")
+	}
+	if PROFILING_ENABLED {
+		result.Features.Append("profiling")
+	}
+	return
+}
+
+type BuildVersionCommand struct {
+	Json BoolVar
+}
+
+func (x *BuildVersionCommand) Flags(cfv CommandFlagsVisitor) {
+	cfv.Variable("Json", "output build version as json, instead of raw text", &x.Json)
+}
+func (x *BuildVersionCommand) Init(cc CommandContext) error {
+	cc.Options(OptionCommandParsableFlags("BuildVersionCommand", "control build version output", x))
+	return nil
+}
+func (x *BuildVersionCommand) Run(cc CommandContext) error {
+	if x.Json.Get() {
+		return base.JsonSerialize(GetBuildVersionInfo(), base.GetLogger(), base.OptionJsonPrettyPrint(true))
+	}
+
+	base.LogForwardln(GetProcessInfo().String())
+	return nil
+}
+
+var CommandBuildVersion = NewCommandable(
 	"Misc",
 	"version",
 	"print build version",
-	OptionCommandRun(func(cc CommandContext) error {
-		base.LogForwardln(GetProcessInfo().String())
-		return nil
-	}))
+	&BuildVersionCommand{})
 
 /***************************************
  * Show Build Seed
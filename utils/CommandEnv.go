@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/pprof"
 	"sync/atomic"
 	"time"
 
@@ -15,53 +17,79 @@ import (
  ***************************************/
 
 type CommandFlags struct {
-	Force          BoolVar
-	Purge          BoolVar
-	Quiet          BoolVar
-	Verbose        BoolVar
-	Trace          BoolVar
-	VeryVerbose    BoolVar
-	Debug          BoolVar
-	Timestamp      BoolVar
-	Diagnostics    BoolVar
-	Jobs           IntVar
-	Color          BoolVar
-	Ide            BoolVar
-	LogAll         base.LogCategorySet
-	LogMute        base.LogCategorySet
-	LogImmediate   BoolVar
-	LogFile        Filename
-	OutputDir      Directory
-	RootDir        Directory
-	StopOnError    BoolVar
-	Summary        BoolVar
-	WarningAsError BoolVar
-	ErrorAsPanic   BoolVar
+	Force                BoolVar
+	Purge                BoolVar
+	Quiet                BoolVar
+	Verbose              BoolVar
+	Trace                BoolVar
+	VeryVerbose          BoolVar
+	Debug                BoolVar
+	Timestamp            BoolVar
+	Diagnostics          BoolVar
+	Jobs                 IntRangeVar
+	Width                IntVar
+	Color                BoolVar
+	Ide                  BoolVar
+	LogAll               base.LogCategorySet
+	LogMute              base.LogCategorySet
+	LogImmediate         BoolVar
+	LogFile              Filename
+	ProgressEvents       Filename
+	OutputDir            Directory
+	RootDir              Directory
+	TempDir              Directory
+	StopOnError          BoolVar
+	KeepGoing            BoolVar
+	MaxErrors            IntVar
+	Summary              BoolVar
+	Footer               BoolVar
+	Utilization          BoolVar
+	WarningAsError       BoolVar
+	ErrorAsPanic         BoolVar
+	ContentHash          BoolVar
+	TrustOutputs         BoolVar
+	TrustOutputsVerify   IntVar
+	StrictFlags          BoolVar
+	FingerprintAlgorithm base.FingerprintAlgorithm
+	SaveInterval         IntVar
+	Profile              Filename
+	MemProfile           Filename
 }
 
 var GetCommandFlags = NewGlobalCommandParsableFlags("global command options", &CommandFlags{
-	Force:          base.INHERITABLE_FALSE,
-	Purge:          base.INHERITABLE_FALSE,
-	Quiet:          base.INHERITABLE_FALSE,
-	Verbose:        base.INHERITABLE_FALSE,
-	Trace:          base.INHERITABLE_FALSE,
-	VeryVerbose:    base.INHERITABLE_FALSE,
-	Debug:          base.MakeBoolVar(base.DEBUG_ENABLED),
-	Diagnostics:    base.MakeBoolVar(base.DEBUG_ENABLED),
-	Jobs:           base.InheritableInt(base.INHERIT_VALUE),
-	Color:          base.INHERITABLE_INHERIT,
-	Ide:            base.INHERITABLE_INHERIT,
-	Timestamp:      base.INHERITABLE_FALSE,
-	StopOnError:    base.INHERITABLE_FALSE,
-	Summary:        base.INHERITABLE_FALSE,
-	WarningAsError: base.INHERITABLE_FALSE,
-	ErrorAsPanic:   base.INHERITABLE_FALSE,
+	Force:                base.INHERITABLE_FALSE,
+	Purge:                base.INHERITABLE_FALSE,
+	Quiet:                base.INHERITABLE_FALSE,
+	Verbose:              base.INHERITABLE_FALSE,
+	Trace:                base.INHERITABLE_FALSE,
+	VeryVerbose:          base.INHERITABLE_FALSE,
+	Debug:                base.MakeBoolVar(base.DEBUG_ENABLED),
+	Diagnostics:          base.MakeBoolVar(base.DEBUG_ENABLED),
+	Jobs:                 IntRangeVar{Min: base.InheritableInt(base.INHERIT_VALUE), Max: base.InheritableInt(base.INHERIT_VALUE)},
+	Width:                base.InheritableInt(base.INHERIT_VALUE),
+	Color:                base.INHERITABLE_INHERIT,
+	Ide:                  base.INHERITABLE_INHERIT,
+	Timestamp:            base.INHERITABLE_FALSE,
+	StopOnError:          base.INHERITABLE_FALSE,
+	KeepGoing:            base.INHERITABLE_FALSE,
+	MaxErrors:            0,
+	Summary:              base.INHERITABLE_FALSE,
+	Footer:               base.INHERITABLE_TRUE,
+	Utilization:          base.INHERITABLE_FALSE,
+	WarningAsError:       base.INHERITABLE_FALSE,
+	ErrorAsPanic:         base.INHERITABLE_FALSE,
+	ContentHash:          base.INHERITABLE_FALSE,
+	TrustOutputs:         base.INHERITABLE_FALSE,
+	TrustOutputsVerify:   10,
+	StrictFlags:          base.INHERITABLE_FALSE,
+	FingerprintAlgorithm: base.FINGERPRINT_SHA256,
+	SaveInterval:         0,
 })
 
 func (flags *CommandFlags) Flags(cfv CommandFlagsVisitor) {
 	cfv.Variable("f", "force build even if up-to-date", &flags.Force)
 	cfv.Variable("F", "force build and ignore cache", &flags.Purge)
-	cfv.Variable("j", "override number of worker threads (default: numCpu-1)", &flags.Jobs)
+	cfv.Variable("j", "override number of worker threads, or clamp the default with a range (default: numCpu-1, ex: -j4 or -j=2..8)", &flags.Jobs)
 	cfv.Variable("q", "disable all messages", &flags.Quiet)
 	cfv.Variable("v", "turn on verbose mode", &flags.Verbose)
 	cfv.Variable("t", "print more informations about progress", &flags.Trace)
@@ -71,19 +99,43 @@ func (flags *CommandFlags) Flags(cfv CommandFlagsVisitor) {
 	}
 	cfv.Variable("T", "turn on timestamp logging", &flags.Timestamp)
 	cfv.Variable("X", "turn on diagnostics mode", &flags.Diagnostics)
+	cfv.Variable("Width", "override terminal width in columns used to format output (default: detected from terminal, or 80)", &flags.Width)
 	cfv.Variable("Color", "control ansi color output in log messages", &flags.Color)
 	cfv.Variable("Ide", "set output to IDE mode (disable interactive shell)", &flags.Ide)
 	cfv.Variable("LogAll", "force to output all messages for given log categories", &flags.LogAll)
 	cfv.Variable("LogMute", "force mute all messages for given log categories", &flags.LogMute)
 	cfv.Variable("LogImmediate", "disable buffering of log messages", &flags.LogImmediate)
 	cfv.Variable("LogFile", "output log to specified file (default: stdout)", &flags.LogFile)
+	cfv.Variable("ProgressEvents", "stream progress events as json lines to specified file or named pipe, for external progress UIs", &flags.ProgressEvents)
 	cfv.Variable("OutputDir", "override default output directory", &flags.OutputDir)
 	cfv.Variable("RootDir", "override root directory", &flags.RootDir)
+	cfv.Variable("TempDir", "override root of transient directory used for temporary files, ex: to move them on a fast scratch disk (files are stored in a 'ppb' subfolder)", &flags.TempDir)
 	cfv.Variable("StopOnError", "interrupt build process immediately when an error occurred", &flags.StopOnError)
+	cfv.Variable("KeepGoing", "keep building independent nodes after an error occurred, and report all failed nodes when build finished (takes precedence over StopOnError)", &flags.KeepGoing)
+	cfv.Variable("MaxErrors", "stop scheduling new nodes and abort build after N nodes failed to build (default: 0, unlimited)", &flags.MaxErrors)
 	cfv.Variable("Summary", "print build graph execution summary when build finished", &flags.Summary)
+	cfv.Variable("Footer", "print a concise build report with the slowest actions when build finished", &flags.Footer)
+	cfv.Variable("Utilization", "print a histogram of worker thread utilization when build finished (average/peak concurrency, idle time), to tune -j", &flags.Utilization)
 	cfv.Variable("WX", "consider warnings as errors", &flags.WarningAsError)
 	cfv.Variable("EX", "consider errors as panics", &flags.ErrorAsPanic)
+	cfv.Variable("ContentHash", "ignore file modification times and compare content hashes instead (slower: every tracked file is read, hashes are cached by path/size/mtime)", &flags.ContentHash)
+	cfv.Variable("TrustOutputs", "with -ContentHash, skip hashing output files which kept their size and were not modified since build graph was last saved (faster, but an output modified externally while keeping an older mtime won't be rebuilt)", &flags.TrustOutputs)
+	cfv.Variable("TrustOutputsVerify", "with -TrustOutputs, still verify every output file by content once every N runs (default: 10, 0 to never verify)", &flags.TrustOutputsVerify)
+	cfv.Persistent("FingerprintAlgorithm", "select hash algorithm used for build stamps, file content hashes and action cache keys, changing it discards the build graph and uses another action cache folder", &flags.FingerprintAlgorithm)
+	cfv.Variable("StrictFlags", "fail when a command receives an unknown flag instead of only printing a warning, ex: to catch typos in CI scripts", &flags.StrictFlags)
+	cfv.Variable("SaveInterval", "also persist dirty build graph every N seconds while building, to keep progress if the process crashed (default: 0, only saved on exit)", &flags.SaveInterval)
+	cfv.Variable("Profile", "write a pprof cpu profile of ppb itself to given file, for the whole command duration", &flags.Profile)
+	cfv.Variable("MemProfile", "write a pprof heap profile of ppb itself to given file, when command finished", &flags.MemProfile)
 }
+
+// named pipes are created by the reader beforehand, and can't be truncated
+func openProgressEventsWriter(dst Filename) (*os.File, error) {
+	if info, err := os.Stat(dst.String()); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return os.OpenFile(dst.String(), os.O_WRONLY, 0)
+	}
+	return UFS.CreateWriter(dst)
+}
+
 func (flags *CommandFlags) Apply() error {
 	for _, category := range flags.LogAll {
 		if err := base.GetLogManager().SetCategoryLevel(category, base.LOG_ALL); err != nil {
@@ -110,6 +162,14 @@ func (flags *CommandFlags) Apply() error {
 		}
 	}
 
+	if flags.ProgressEvents.Valid() {
+		if outp, err := openProgressEventsWriter(flags.ProgressEvents); err == nil {
+			base.SetLogProgressEvents(outp)
+		} else {
+			return err
+		}
+	}
+
 	base.SetEnableDiagnostics(flags.Diagnostics.Get())
 	base.GetLogger().SetShowTimestamp(flags.Timestamp.Get())
 
@@ -127,6 +187,10 @@ func (flags *CommandFlags) Apply() error {
 		base.SetEnableAnsiColor(flags.Color.Get())
 	}
 
+	if !flags.Width.IsInheritable() && flags.Width.Get() > 0 {
+		base.SetTerminalWidth(flags.Width.Get())
+	}
+
 	if flags.Verbose.Get() {
 		base.SetLogVisibleLevel(base.LOG_VERBOSE)
 	}
@@ -146,6 +210,33 @@ func (flags *CommandFlags) Apply() error {
 		base.SetLogErrorAsPanic(true)
 	}
 
+	if flags.ContentHash.Get() {
+		base.LogTrace(LogCommand, "file changes will be detected with content hashes due to '-ContentHash' command-line option")
+		SetBuildFileContentHash(true)
+	}
+
+	if flags.FingerprintAlgorithm != GetBuildFingerprintAlgorithm() {
+		base.LogTrace(LogCommand, "build stamps will be computed with %v due to '-FingerprintAlgorithm' command-line option", flags.FingerprintAlgorithm)
+		SetBuildFingerprintAlgorithm(flags.FingerprintAlgorithm)
+	}
+
+	if flags.TrustOutputs.Get() {
+		base.LogTrace(LogCommand, "output files will be verified with their modification time due to '-TrustOutputs' command-line option")
+		SetBuildOutputTrustModTime(true, flags.TrustOutputsVerify.Get())
+
+		CommandEnv.OnExit(func(cet *CommandEnvT) error {
+			trusted, verified, verifyDuration := GetBuildOutputTrustStats()
+			if verified > 0 {
+				// estimated from average time spent verifying an output by content
+				saved := time.Duration(int64(verifyDuration) / verified * trusted)
+				base.LogVerbose(LogCommand, "TrustOutputs: trusted %d output files, verified %d by content in %v (~%v saved)", trusted, verified, verifyDuration, saved)
+			} else {
+				base.LogVerbose(LogCommand, "TrustOutputs: trusted %d output files, verified none by content", trusted)
+			}
+			return nil
+		})
+	}
+
 	if flags.Purge.Get() {
 		base.LogTrace(LogCommand, "build will be forced due to '-F' command-line option")
 		flags.Force.Enable()
@@ -154,20 +245,28 @@ func (flags *CommandFlags) Apply() error {
 		base.LogTrace(LogCommand, "fbuild will be forced due to '-f' command-line option")
 	}
 
-	if flags.Summary.Get() || (flags.Ide.Get() && !flags.Quiet.Get()) {
+	printFooter := flags.Footer.Get() && !flags.Quiet.Get()
+	if flags.Summary.Get() || printFooter || (flags.Ide.Get() && !flags.Quiet.Get()) {
 		var buildSummaries []BuildSummary
 		CommandEnv.OnExit(func(cet *CommandEnvT) error {
+			// interactive logger must be flushed first, or pinned messages would overwrite output
 			base.PurgePinnedLogs()
 
-			// ide mode only prints execution time as a feedback for process termination
-			logLevel := base.LOG_CLAIM
-			if flags.Summary.Get() {
-				// queue print summary if specified on command-line
-				logLevel = base.LOG_ALL
+			if flags.Summary.Get() || (flags.Ide.Get() && !flags.Quiet.Get()) {
+				// ide mode only prints execution time as a feedback for process termination
+				logLevel := base.LOG_CLAIM
+				if flags.Summary.Get() {
+					// queue print summary if specified on command-line
+					logLevel = base.LOG_ALL
+				}
+
+				for _, it := range buildSummaries {
+					it.PrintSummary(logLevel)
+				}
 			}
 
-			for _, it := range buildSummaries {
-				it.PrintSummary(logLevel)
+			if printFooter {
+				PrintBuildFooter(buildSummaries, 5)
 			}
 
 			return nil
@@ -188,6 +287,37 @@ func (flags *CommandFlags) Apply() error {
 		})
 	}
 
+	if flags.Utilization.Get() {
+		var utilization base.ThreadPoolUtilization
+		var utilizationSampled bool
+
+		// registered after summaries, so it is printed below build footer
+		CommandEnv.OnExit(func(cet *CommandEnvT) error {
+			if utilizationSampled {
+				base.PurgePinnedLogs()
+				PrintThreadPoolUtilization(utilization, base.GetGlobalThreadPool().GetName())
+			}
+			return nil
+		})
+		// only sample while building, so command parsing and saving the build graph are not reported as idle time
+		CommandEnv.OnBuildGraphLoaded(func(bg BuildGraph) error {
+			bg.OnBuildGraphStart(func(port BuildGraphWritePort) error {
+				if !port.PortFlags().Any(BUILDGRAPH_QUIET) {
+					base.GetGlobalThreadPool().EnableUtilization()
+				}
+				return nil
+			})
+			bg.OnBuildGraphFinished(func(port BuildGraphWritePort) error {
+				if !port.PortFlags().Any(BUILDGRAPH_QUIET) {
+					utilization = base.GetGlobalThreadPool().GetUtilization()
+					utilizationSampled = true
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+
 	if flags.RootDir.Valid() {
 		if err := UFS.MountRootDirectory(flags.RootDir); err != nil {
 			return err
@@ -200,13 +330,57 @@ func (flags *CommandFlags) Apply() error {
 		}
 	}
 
-	if !flags.Jobs.IsInheritable() && flags.Jobs.Get() > 0 {
-		base.GetGlobalThreadPool().Resize(flags.Jobs.Get())
+	// mounted after output directory, which resets transient directory to its default location
+	if flags.TempDir.Valid() {
+		if err := UFS.MountTransientDir(flags.TempDir); err != nil {
+			return err
+		}
+	}
+
+	CheckLongPathLayout()
+
+	if !flags.Jobs.IsInheritable() {
+		if jobs := flags.Jobs.Clamp(base.GetGlobalThreadPool().GetArity()); jobs > 0 {
+			base.GetGlobalThreadPool().Resize(jobs)
+		}
 	}
 
 	return nil
 }
 
+// profiles ppb own go code, not the compiled programs: see `go tool pprof`
+func (flags *CommandFlags) StartProfile() (func() error, error) {
+	var cpuProfile *os.File
+	if flags.Profile.Valid() {
+		var err error
+		if cpuProfile, err = UFS.CreateWriter(flags.Profile); err != nil {
+			return nil, err
+		}
+		if err = pprof.StartCPUProfile(cpuProfile); err != nil {
+			cpuProfile.Close()
+			return nil, err
+		}
+		base.LogVerbose(LogCommand, "started cpu profiling in %q", flags.Profile)
+	}
+
+	return func() (err error) {
+		if cpuProfile != nil {
+			pprof.StopCPUProfile()
+			err = cpuProfile.Close()
+			base.LogInfo(LogCommand, "wrote cpu profile in %q", flags.Profile)
+		}
+		if flags.MemProfile.Valid() {
+			runtime.GC() // get up-to-date statistics
+			if er := UFS.Create(flags.MemProfile, pprof.WriteHeapProfile); er == nil {
+				base.LogInfo(LogCommand, "wrote heap profile in %q", flags.MemProfile)
+			} else if err == nil {
+				err = er
+			}
+		}
+		return
+	}, nil
+}
+
 /***************************************
  * Command Env
  ***************************************/
@@ -381,8 +555,16 @@ func (env *CommandEnvT) Run(defaults ...base.AnyDelegate) error {
 		return nil
 	}
 
-	err := env.commandEvents.Run()
+	stopProfile, err := GetCommandFlags().StartProfile()
+	if err != nil {
+		return err
+	}
+
+	err = env.commandEvents.Run()
 
+	if er := stopProfile(); er != nil && err == nil {
+		err = er
+	}
 	if er := env.saveConfig(); er != nil && err == nil {
 		err = er
 	}
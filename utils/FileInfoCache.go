@@ -50,14 +50,14 @@ func (x dummyFileInfoCache) SetFileInfo(f Filename, stat os.FileInfo, err error)
 func (x dummyFileInfoCache) SetDirectoryInfo(d Directory, stat os.FileInfo, err error) {}
 
 func (x dummyFileInfoCache) GetFileInfo(f Filename) (os.FileInfo, error) {
-	return os.Stat(f.String())
+	return os.Stat(LongPath(f.String()))
 }
 func (x dummyFileInfoCache) GetDirectoryInfo(d Directory) (os.FileInfo, error) {
-	return os.Stat(d.String())
+	return os.Stat(LongPath(d.String()))
 }
 func (x dummyFileInfoCache) EnumerateDirectory(d Directory) (files FileSet, directories DirSet, err error) {
 	var entries []os.DirEntry
-	entries, err = os.ReadDir(d.String())
+	entries, err = os.ReadDir(LongPath(d.String()))
 	if err != nil {
 		return
 	}
@@ -101,7 +101,7 @@ func newGetState(f Filename) func() base.Optional[os.FileInfo] {
 			onceFileCacheStats.GetFileStat.OnExecute()
 		}
 
-		if info, err := os.Stat(f.String()); err == nil {
+		if info, err := os.Stat(LongPath(f.String())); err == nil {
 			return base.NewOption(info)
 		} else {
 			return base.UnexpectedOption[os.FileInfo](err)
@@ -123,7 +123,7 @@ func newEnumerateDir(d Directory) func() base.Optional[onceFileInfoCacheDir] {
 	return base.Memoize(func() base.Optional[onceFileInfoCacheDir] {
 		onceFileCacheStats.EnumerateDir.OnExecute()
 
-		entries, err := os.ReadDir(d.String())
+		entries, err := os.ReadDir(LongPath(d.String()))
 		if err != nil {
 			return base.UnexpectedOption[onceFileInfoCacheDir](err)
 		}
@@ -19,6 +19,7 @@ type PersistentVar interface {
 
 type BoolVar = base.InheritableBool
 type IntVar = base.InheritableInt
+type IntRangeVar = base.InheritableIntRange
 type BigIntVar = base.InheritableBigInt
 type StringVar = base.InheritableString
 
@@ -567,6 +567,22 @@ func (list DirSet) Normalize() DirSet {
 		return it.Normalize()
 	}, list...)))
 }
+func (list DirSet) String() string {
+	return list.Join(",")
+}
+func (list *DirSet) Set(in string) error {
+	list.Clear()
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			var dir Directory
+			if err := dir.Set(it); err != nil {
+				return err
+			}
+			list.AppendUniq(dir)
+		}
+	}
+	return nil
+}
 
 /***************************************
  * FileSet
@@ -677,6 +693,22 @@ func (list FileSet) Normalize() FileSet {
 		return it.Normalize()
 	}, list...))
 }
+func (list FileSet) String() string {
+	return list.Join(",")
+}
+func (list *FileSet) Set(in string) error {
+	list.Clear()
+	for _, it := range strings.Split(in, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			var file Filename
+			if err := file.Set(it); err != nil {
+				return err
+			}
+			list.AppendUniq(file)
+		}
+	}
+	return nil
+}
 
 /***************************************
  * JSON: marshal as string instead of array
@@ -733,7 +765,7 @@ func (ufs *UFSFrontEnd) Touch(dst Filename) error {
 }
 func (ufs *UFSFrontEnd) SetMTime(dst Filename, mtime time.Time) error {
 	base.LogDebug(LogUFS, "chtimes %v", dst)
-	localPath := dst.String()
+	localPath := LongPath(dst.String())
 	if err := os.Chtimes(localPath, mtime, mtime); err == nil {
 		dst.Invalidate()
 		return nil
@@ -743,7 +775,7 @@ func (ufs *UFSFrontEnd) SetMTime(dst Filename, mtime time.Time) error {
 }
 func (ufs *UFSFrontEnd) Remove(dst Filename) error {
 	defer dst.Invalidate()
-	if err := os.Remove(dst.String()); err != nil {
+	if err := os.Remove(LongPath(dst.String())); err != nil {
 		base.LogError(LogUFS, "%v", err)
 		return err
 	}
@@ -755,7 +787,7 @@ func (ufs *UFSFrontEnd) Mkdir(dst Directory) {
 	}
 }
 func (ufs *UFSFrontEnd) MkdirEx(dst Directory) error {
-	localPath := dst.String()
+	localPath := LongPath(dst.String())
 	if st, err := os.Stat(localPath); st != nil && (err == nil || os.IsExist(err)) {
 		if !st.IsDir() {
 			base.LogDebug(LogUFS, "mkdir %v", dst)
@@ -774,7 +806,7 @@ func (ufs *UFSFrontEnd) CreateWriter(dst Filename) (*os.File, error) {
 	defer dst.Invalidate()
 	ufs.Mkdir(dst.Dirname)
 	base.LogDebug(LogUFS, "create '%v'", dst)
-	return os.Create(dst.String())
+	return os.Create(LongPath(dst.String()))
 }
 func (ufs *UFSFrontEnd) CreateFile(dst Filename, write func(*os.File) error) error {
 	outp, err := ufs.CreateWriter(dst)
@@ -835,7 +867,7 @@ func (ufs *UFSFrontEnd) MTime(src Filename) time.Time {
 	}
 }
 func (ufs *UFSFrontEnd) OpenFile(src Filename, read func(*os.File) error) error {
-	input, err := os.Open(src.String())
+	input, err := os.Open(LongPath(src.String()))
 	base.LogDebug(LogUFS, "open '%v'", src)
 
 	if err == nil {
@@ -957,7 +989,7 @@ func (ufs *UFSFrontEnd) Rename(src, dst Filename) error {
 		dst.Invalidate()
 	}()
 	base.LogDebug(LogUFS, "rename file '%v' to '%v'", src, dst)
-	return os.Rename(src.String(), dst.String())
+	return os.Rename(LongPath(src.String()), LongPath(dst.String()))
 }
 func (ufs *UFSFrontEnd) Copy(src, dst Filename) error {
 	ufs.Mkdir(dst.Dirname)
@@ -1001,7 +1033,10 @@ func (ufs *UFSFrontEnd) Crc32(src Filename) (checksum uint32, err error) {
 	return
 }
 func (ufs *UFSFrontEnd) Fingerprint(src Filename, seed base.Fingerprint) (base.Fingerprint, error) {
-	base.LogDebug(LogUFS, "fingerprint file %q", src)
+	return ufs.FingerprintWith(base.FINGERPRINT_SHA256, src, seed)
+}
+func (ufs *UFSFrontEnd) FingerprintWith(algorithm base.FingerprintAlgorithm, src Filename, seed base.Fingerprint) (base.Fingerprint, error) {
+	base.LogDebug(LogUFS, "fingerprint file %q with %v", src, algorithm)
 	var fingerprint base.Fingerprint
 	if err := ufs.OpenFile(src, func(f *os.File) error {
 		stat, err := f.Stat()
@@ -1012,7 +1047,7 @@ func (ufs *UFSFrontEnd) Fingerprint(src Filename, seed base.Fingerprint) (base.F
 
 		totalSize := stat.Size()
 		pageAlloc := base.GetBytesRecyclerBySize(totalSize)
-		fingerprint, err = base.ReaderFingerprint(f, seed, pageAlloc, totalSize > int64(pageAlloc.Stride()))
+		fingerprint, err = base.ReaderFingerprintWith(algorithm, f, seed, pageAlloc, totalSize > int64(pageAlloc.Stride()))
 		return err
 	}); err != nil {
 		return base.Fingerprint{}, err
@@ -1033,6 +1068,27 @@ func (ufs *UFSFrontEnd) MountOutputDir(output Directory) error {
 	ufs.Saved = ufs.Output.Folder("Saved")
 	return nil
 }
+func (ufs *UFSFrontEnd) MountTransientDir(root Directory) error {
+	// transient files are stored in a subfolder owned by ppb, since the directory given by the user can be shared (ex: /tmp)
+	transient := root.Folder("ppb")
+	base.LogVerbose(LogUFS, "mount transient directory %q", transient)
+	if err := ufs.MkdirEx(transient); err != nil {
+		return err
+	}
+
+	// check early that directory is writable, rather than failing later in the middle of a build
+	if probe, err := os.CreateTemp(transient.String(), ".probe-*"); err == nil {
+		probe.Close()
+		if err := os.Remove(probe.Name()); err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("transient directory %q is not writable: %w", transient, err)
+	}
+
+	ufs.Transient = transient
+	return nil
+}
 func (ufs *UFSFrontEnd) MountRootDirectory(root Directory) error {
 	base.LogVerbose(LogUFS, "mount root directory %q", root)
 	if err := os.Chdir(root.String()); err != nil {
@@ -5,6 +5,7 @@ package utils
 import (
 	"fmt"
 	"path/filepath"
+	"syscall"
 
 	"github.com/poppolopoppo/ppb/internal/base"
 )
@@ -27,3 +28,16 @@ func CleanPath(in string) utils.Directory {
 
 	return utils.SplitPath(base.result)
 }
+
+func GetDiskFreeSpace(dir Directory) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir.String(), &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// LongPath is only needed on Windows, see UFS_windows.go
+func LongPath(path string) string                               { return path }
+func LongPathArguments(arguments base.StringSet) base.StringSet { return arguments }
+func CheckLongPathLayout()                                      {}
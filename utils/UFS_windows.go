@@ -18,7 +18,7 @@ import (
  ***************************************/
 
 func ComputeSha256(path string, seed base.Fingerprint) ([]byte, error) {
-	utf16Path, err := windows.UTF16FromString(path)
+	utf16Path, err := windows.UTF16FromString(LongPath(path))
 	if err != nil {
 		return nil, err
 	}
@@ -283,3 +283,71 @@ func CleanPath(in string) string {
 
 	return result
 }
+
+func GetDiskFreeSpace(dir Directory) (uint64, error) {
+	utf16Path, err := windows.UTF16PtrFromString(LongPath(dir.String()))
+	if err != nil {
+		return 0, err
+	}
+	var freeBytesAvailable uint64
+	if err := windows.GetDiskFreeSpaceEx(utf16Path, &freeBytesAvailable, nil, nil); err != nil {
+		return 0, err
+	}
+	return freeBytesAvailable, nil
+}
+
+/***************************************
+ * Long paths support (\\?\ prefix)
+ ***************************************/
+
+// Win32 API fails with paths longer than MAX_PATH, unless they are prefixed with \\?\ (which also disables path normalization).
+// Prefix is only added when calling the OS or spawning a process, so it never leaks in serialized or displayed paths.
+const longPathPrefix = `\\?\`
+
+// directories are limited to MAX_PATH minus 8.3 filename (12 chars), see os.fixLongPath()
+const longPathThreshold = syscall.MAX_PATH - 12
+
+func LongPath(path string) string {
+	if len(path) < longPathThreshold || strings.HasPrefix(path, longPathPrefix) || !filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, `\\`) { // UNC: \\server\share -> \\?\UNC\server\share
+		return longPathPrefix + `UNC` + path[1:]
+	}
+	return longPathPrefix + path
+}
+
+// LongPathArguments prefixes long absolute paths found in process arguments, including when appended to a switch (ex: /Fo<path>)
+func LongPathArguments(arguments base.StringSet) base.StringSet {
+	var result base.StringSet
+	for i, arg := range arguments {
+		if len(arg) < longPathThreshold {
+			continue
+		}
+		// look for a drive letter, ex: `/FoC:\path\to\file.obj`
+		if off := strings.Index(arg, `:\`); off >= 1 && !strings.Contains(arg, longPathPrefix) {
+			if prefixed := LongPath(arg[off-1:]); len(prefixed) != len(arg)-off+1 {
+				if result == nil {
+					result = base.NewStringSet(arguments...)
+				}
+				result[i] = arg[:off-1] + prefixed
+			}
+		}
+	}
+	if result == nil {
+		return arguments
+	}
+	return result
+}
+
+// CheckLongPathLayout warns when output layout is so deep that intermediate files will probably exceed MAX_PATH
+func CheckLongPathLayout() {
+	// module relative path, unity/object file name and extension usually add around 100 chars
+	const expectedSuffixLen = 100
+	if intermediate := UFS.Intermediate.String(); len(intermediate)+expectedSuffixLen >= syscall.MAX_PATH {
+		base.LogWarning(LogUFS, "intermediate directory %q is %d chars long, generated paths will likely exceed MAX_PATH (%d chars): "+
+			"they will be prefixed with %s, but external tools may still fail unless LongPathsEnabled is set in registry (consider a shorter -OutputDir)",
+			intermediate, len(intermediate), syscall.MAX_PATH, longPathPrefix)
+	}
+}